@@ -21,6 +21,6 @@ func BenchmarkPackageBlock(b *testing.B) {
 		return
 	}
 
-	transaction := buildTransaction(privateKey)
-	transaction.Verify()
+	transaction := buildTransaction(privateKey, 0)
+	transaction.Verify(0)
 }
@@ -9,7 +9,6 @@ package benchmark
 import (
 	"crypto/ecdsa"
 	"crypto/rand"
-	"crypto/sha256"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/crypto"
 	log "github.com/sirupsen/logrus"
@@ -17,7 +16,7 @@ import (
 	"time"
 )
 
-func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
+func buildTransaction(key *ecdsa.PrivateKey, chainID int64) *common.Transaction {
 	data := make([]byte, 32)
 	rand.Read(data)
 	timestamp := time.Now().UnixMilli()
@@ -35,9 +34,7 @@ func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
 	txBody.WriteAsRoot(writer)
 	txBodyBytes := writer.Bytes()
 
-	hash := sha256.New()
-	hash.Write(txBodyBytes)
-	txHashBytes := hash.Sum(nil)
+	txHashBytes := common.TransactionSigningHash(txBodyBytes, chainID)
 	txSignatureBytes, err := ecdsa.SignASN1(rand.Reader, key, txHashBytes)
 
 	if err != nil {
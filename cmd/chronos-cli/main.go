@@ -11,7 +11,6 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/hex"
 	"flag"
 	"fmt"
@@ -38,6 +37,7 @@ func main() {
 	sendAddress := sendCmd.String("receiver", "", "receiver address")
 	sendKey := sendCmd.String("key", "", "transaction set key")
 	sendValue := sendCmd.String("value", "", "transaction set value")
+	sendChainID := sendCmd.Int64("chain-id", 0, "chain id of target network")
 
 	getCmd := flag.NewFlagSet("get", flag.ExitOnError)
 	getAddress := getCmd.String("address", "", "data storage address")
@@ -57,14 +57,14 @@ func main() {
 			return
 		}
 
-		executeSendCommand(*sendAddress, *sendKey, *sendValue)
+		executeSendCommand(*sendAddress, *sendKey, *sendValue, *sendChainID)
 	case "get":
 		getCmd.Parse(os.Args[2:])
 		executeGetCommand(*getAddress, *getKey)
 	}
 }
 
-func executeSendCommand(receiver, key, value string) {
+func executeSendCommand(receiver, key, value string, chainID int64) {
 	prv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		log.WithError(err).Errorln("Generate random key failed.")
@@ -109,9 +109,7 @@ func executeSendCommand(receiver, key, value string) {
 	txBody.WriteAsRoot(writer)
 	txBodyBytes := writer.Bytes()
 
-	hash := sha256.New()
-	hash.Write(txBodyBytes)
-	txHashBytes := hash.Sum(nil)
+	txHashBytes := common.TransactionSigningHash(txBodyBytes, chainID)
 	txSignatureBytes, err := ecdsa.SignASN1(rand.Reader, prv, txHashBytes)
 
 	if err != nil {
@@ -54,7 +54,7 @@ func main() {
 		return
 	}
 
-	chain := core.NewBlockchain(db)
+	chain := core.NewBlockchain(db, nil, nil)
 
 	var timestamps []int64
 	var txs []int
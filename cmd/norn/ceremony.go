@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/chain-lab/go-norn/crypto"
+	log "github.com/sirupsen/logrus"
+	"os"
+)
+
+// 创世参数生成仪式，各方式的信任假设见 crypto/ceremony.go：
+// 多方生成类群 VDF 的判别式，不需要信任任何参与者，每个参与者在本地生成随机数贡献文件，由任意一方合并：
+// ./norn genesis-ceremony contribute -name alice -o alice.json
+// ./norn genesis-ceremony combine -o ceremony.json alice.json bob.json carol.json
+// 使用 RSA-2048 挑战数，需要信任 RSA-2048 的素因子已经被销毁：
+// ./norn genesis-ceremony challenge -o ceremony.json
+// 多方生成 RSA 模数，需要信任所有参与者都销毁了素因子：
+// ./norn genesis-ceremony contribute -mode multiparty -name alice -o alice.json
+// 其他节点在加入前校验参数文件，并在配置文件中设置 consensus.ceremony 为文件路径：
+// ./norn genesis-ceremony verify ceremony.json
+
+const ceremonyCommand = "genesis-ceremony"
+
+// runCeremony
+//
+//	@Description: genesis-ceremony 子命令的入口
+//	@param args - 子命令之后的参数
+//	@return int - 进程的退出码
+func runCeremony(args []string) int {
+	if len(args) < 1 {
+		ceremonyUsage()
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "challenge":
+		err = ceremonyChallenge(args[1:])
+	case "contribute":
+		err = ceremonyContribute(args[1:])
+	case "combine":
+		err = ceremonyCombine(args[1:])
+	case "verify":
+		err = ceremonyVerify(args[1:])
+	default:
+		ceremonyUsage()
+		return 2
+	}
+
+	if err != nil {
+		log.WithError(err).Errorln("Genesis ceremony failed.")
+		return 1
+	}
+	return 0
+}
+
+func ceremonyChallenge(args []string) error {
+	fs := flag.NewFlagSet("challenge", flag.ExitOnError)
+	output := fs.String("o", "./ceremony.json", "Output ceremony params file")
+	timeParam := fs.Int64("t", crypto.CeremonyTimeParam, "VDF time param")
+	fs.Parse(args)
+
+	params := crypto.RSAChallengeCeremony(*timeParam)
+	if err := params.Save(*output); err != nil {
+		return err
+	}
+
+	log.Infof("Ceremony params written to %s.", *output)
+	return nil
+}
+
+func ceremonyContribute(args []string) error {
+	fs := flag.NewFlagSet("contribute", flag.ExitOnError)
+	name := fs.String("name", "", "Participant name")
+	output := fs.String("o", "./contribution.json", "Output contribution file")
+	mode := fs.String("mode", crypto.CeremonyModeClassGroup, "Ceremony mode, classgroup or multiparty")
+	bits := fs.Int("bits", crypto.CeremonyContributionBits, "Modulus bits, only for multiparty mode")
+	fs.Parse(args)
+
+	var contribution *crypto.CeremonyContribution
+	var err error
+	switch *mode {
+	case crypto.CeremonyModeClassGroup:
+		contribution, err = crypto.NewCeremonyEntropy(*name)
+	case crypto.CeremonyModeMultiParty:
+		log.Warnln("Multiparty RSA modulus requires trusting every participant to discard the prime factors.")
+		contribution, err = crypto.NewCeremonyContribution(*name, *bits)
+	default:
+		return fmt.Errorf("unknown ceremony mode %s", *mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = contribution.Save(*output); err != nil {
+		return err
+	}
+
+	log.Infof("Contribution written to %s.", *output)
+	return nil
+}
+
+func ceremonyCombine(args []string) error {
+	fs := flag.NewFlagSet("combine", flag.ExitOnError)
+	output := fs.String("o", "./ceremony.json", "Output ceremony params file")
+	timeParam := fs.Int64("t", 0, "VDF time param, use the default of the ceremony mode if not set")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no contribution files")
+	}
+
+	contributions := make([]crypto.CeremonyContribution, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		contribution, err := crypto.LoadCeremonyContribution(path)
+		if err != nil {
+			return err
+		}
+		contributions = append(contributions, *contribution)
+	}
+
+	// 贡献文件中包含随机数时为 classgroup 方式，否则为 multiparty 方式
+	var params *crypto.CeremonyParams
+	var err error
+	if contributions[0].Entropy != "" {
+		if *timeParam == 0 {
+			*timeParam = crypto.DefaultClassGroupTimeParam
+		}
+		params, err = crypto.CombineCeremonyEntropy(contributions, *timeParam)
+	} else {
+		if *timeParam == 0 {
+			*timeParam = crypto.CeremonyTimeParam
+		}
+		params, err = crypto.CombineCeremonyContributions(contributions, *timeParam)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = params.Save(*output); err != nil {
+		return err
+	}
+
+	log.Infof("Combined %d contributions, ceremony params written to %s.",
+		len(contributions), *output)
+	return nil
+}
+
+func ceremonyVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expect one ceremony params file")
+	}
+
+	params, err := crypto.LoadCeremonyParams(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if err = params.Validate(); err != nil {
+		return err
+	}
+
+	log.Infof("Ceremony params valid, mode: %s, contributions: %d.",
+		params.Mode, len(params.Contributions))
+	return nil
+}
+
+func ceremonyUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: chronos genesis-ceremony <command> [options]
+
+Commands:
+  contribute  Generate a contribution, random entropy for classgroup mode (default)
+              or a modulus with discarded factors for multiparty mode
+  combine     Combine contribution files into ceremony params
+  challenge   Use RSA-2048 challenge number as modulus
+  verify      Validate a ceremony params file
+`)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/chain-lab/go-norn/core"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"time"
+)
+
+// 创世文件生成：
+// ./norn generate -c config.yml -o genesis.json --chain-id 1 --alloc alloc.json
+// alloc.json 为 [{"address": "...", "key": "...", "value": "..."}] 格式的创世数据
+// 所有节点使用同一个创世文件启动：
+// ./norn -d ./data -c config.yml --genesis-file genesis.json
+
+const generateCommand = "generate"
+
+// runGenerate
+//
+//	@Description: generate 子命令的入口，读取配置文件中的共识参数和私钥生成创世文件
+//	@param args - 子命令之后的参数
+//	@return int - 进程的退出码
+func runGenerate(args []string) int {
+	fs := flag.NewFlagSet(generateCommand, flag.ExitOnError)
+	cfgPath := fs.String("c", "./config.yml", "Config file path")
+	output := fs.String("o", "./genesis.json", "Output genesis file")
+	chainID := fs.Int64("chain-id", 1, "Chain ID")
+	allocPath := fs.String("alloc", "", "Genesis data allocation file")
+	timestamp := fs.Int64("timestamp", 0, "Genesis timestamp in milliseconds, default now")
+	fs.Parse(args)
+
+	core.LoadConfig(*cfgPath)
+
+	var alloc []core.GenesisAlloc
+	if *allocPath != "" {
+		data, err := os.ReadFile(*allocPath)
+		if err != nil {
+			log.WithError(err).Errorln("Read alloc file failed.")
+			return 1
+		}
+
+		if err = json.Unmarshal(data, &alloc); err != nil {
+			log.WithError(err).Errorln("Decode alloc file failed.")
+			return 1
+		}
+	}
+
+	if *timestamp == 0 {
+		*timestamp = time.Now().UnixMilli()
+	}
+
+	genesis, err := core.NewGenesis(*chainID, *timestamp, alloc)
+	if err != nil {
+		log.WithError(err).Errorln("Generate genesis failed.")
+		return 1
+	}
+
+	if err = genesis.Save(*output); err != nil {
+		log.WithError(err).Errorln("Write genesis file failed.")
+		return 1
+	}
+
+	log.Infof("Genesis %s written to %s.", genesis.Hash, *output)
+	return 0
+}
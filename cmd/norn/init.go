@@ -18,6 +18,9 @@ var (
 	pp        bool
 	metrics   bool
 	random    bool
+	light     bool
+
+	genesisFile string
 )
 
 func init() {
@@ -42,13 +45,19 @@ func init() {
 	// 是否随机启动，如果否，则会在 10s 后启动
 	flag.BoolVar(&random, "random", false, "Random time start")
 	flag.Int64Var(&delta, "delta", 0, "Initial time delta (for test)")
+	// 创世文件 --genesis-file [path]，由 generate 子命令生成
+	flag.StringVar(&genesisFile, "genesis-file", "", "Genesis file path")
+	// 轻节点模式 --light，只同步并验证区块头，交易和数据通过 Merkle 证明向完整节点查询
+	flag.BoolVar(&light, "light", false, "Run as light client, sync and verify headers only")
 
 	flag.Usage = usage
 }
 
 func usage() {
 	fmt.Fprintf(os.Stderr, `chronos version: 1.0.0
-Usage: chronos [-b bootstrap] [-d datadir] [-c config] [-h help] [-g genesis] [--debug]
+Usage: chronos [-b bootstrap] [-d datadir] [-c config] [-h help] [-g genesis] [--light] [--debug]
+       chronos generate [-c config] [-o genesis.json] [--chain-id id] [--alloc alloc.json]
+       chronos genesis-ceremony <challenge|contribute|combine|verify> [options]
 
 Options:
 `)
@@ -8,8 +8,7 @@ import (
 	"github.com/chain-lab/go-norn/core"
 	metrics2 "github.com/chain-lab/go-norn/metrics"
 	"github.com/chain-lab/go-norn/node"
-	"github.com/chain-lab/go-norn/pubsub"
-	"github.com/chain-lab/go-norn/rpc"
+	"github.com/chain-lab/go-norn/norn"
 	"github.com/chain-lab/go-norn/utils"
 	"github.com/gookit/config/v2"
 	"github.com/libp2p/go-libp2p"
@@ -24,9 +23,13 @@ import (
 	"os"
 	"os/signal"
 	"runtime/pprof"
+	"syscall"
 	"time"
 )
 
+// shutdownTimeout 收到退出信号后等待所有子系统停止的最长时间
+const shutdownTimeout = 30 * time.Second
+
 // 测试指令：
 // ./chronos -d ./data1 -g -c config1.yml
 // ./chronos -d ./data -g --metrics --pprof -c config.yml
@@ -43,6 +46,15 @@ import (
 // go tool pprof -http=:8080 cpu.profile
 
 func main() {
+	// 子命令在解析节点参数之前处理
+	if len(os.Args) > 1 && os.Args[1] == ceremonyCommand {
+		os.Exit(runCeremony(os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == generateCommand {
+		os.Exit(runGenerate(os.Args[2:]))
+	}
+
 	flag.Parse()
 
 	var f *os.File
@@ -56,7 +68,7 @@ func main() {
 
 	if pp {
 		fileName := fmt.Sprintf("cpu-%d.profile", time.Now().UnixMilli())
-		f, _ := os.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0644)
+		f, _ = os.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0644)
 		pprof.StartCPUProfile(f)
 		go http.ListenAndServe(":6060", nil)
 	}
@@ -77,27 +89,30 @@ func main() {
 		log.SetLevel(log.DebugLevel)
 	}
 
+	// 轻节点不保存区块，不能作为创世节点出块
+	if light && genesis {
+		log.Errorln("Light client can not create genesis block.")
+		return
+	}
+
 	// 加载 config 配置文件
 	core.LoadConfig(cfg)
 
+	port := config.Int("node.port")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	//metrics2.RegisterMetrics()
 	if metrics {
 		metricPort := ":" + config.String("metrics.port")
 		http.Handle("/metrics", promhttp.Handler())
 		metrics2.RoutineCreateCounterObserve(0)
-		go metrics2.RegularMetricsRoutine()
+		go metrics2.RegularMetricsRoutine(ctx)
 		go http.ListenAndServe(metricPort, nil)
 		log.Infof("Metric server start on localhost%s", metricPort)
 	}
 
-	// RPC 协程服务开启
-	metrics2.RoutineCreateCounterObserve(2)
-	go rpc.RPCServerStart()
-	port := config.Int("node.port")
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	// 数据库、节点的启动
 	db, err := utils.NewLevelDB(datadir)
 
@@ -106,20 +121,42 @@ func main() {
 		return
 	}
 
-	chain := core.NewBlockchain(db)
-	txPool := core.NewTxPool(chain)
-	hConfig := node.P2PManagerConfig{
-		TxPool:       txPool,
-		Chain:        chain,
-		Genesis:      genesis,
-		InitialDelta: delta,
+	// 读取创世文件，链 ID 以创世文件为准，未使用创世文件时读取配置文件
+	var genesisConfig *core.Genesis
+	if genesisFile != "" {
+		genesisConfig, err = core.LoadGenesis(genesisFile)
+		if err != nil {
+			log.WithError(err).Errorln("Load genesis file failed.")
+			return
+		}
 	}
 
-	pm, err := node.NewP2PManager(&hConfig)
+	n, err := norn.New(&norn.Config{
+		DB:            db,
+		Genesis:       genesis,
+		GenesisConfig: genesisConfig,
+		InitialDelta:  delta,
+		ChainID:       config.Int64("consensus.chain_id", 0),
+		EventAddress:  ":8888",
+		Light:         light,
+	})
 	if err != nil {
-		log.WithField("error", err).Errorln("Create handler failed.")
+		log.WithError(err).Errorln("Create node failed.")
+		_ = db.Close()
+		return
+	}
+
+	metrics2.RoutineCreateCounterObserve(2)
+	if err = n.Start(ctx); err != nil {
+		log.WithError(err).Errorln("Start node services failed.")
+		_ = db.Close()
 		return
 	}
+	// 启动过程中出错退出时同样需要停止子系统并关闭数据库
+	defer stopNode(n, db)
+
+	chain, pm := n.Chain(), n.Manager()
+	chainID := chain.ChainID()
 
 	// 网络部分的启动
 	localMultiAddr, err := multiaddr.NewMultiaddr(
@@ -186,15 +223,10 @@ func main() {
 
 	// 节点发现协程
 	metrics2.RoutineCreateCounterObserve(3)
-	go pm.Discover(ctx, host, kdht, node.NetworkRendezvous)
+	go pm.Discover(ctx, host, kdht, node.ChainRendezvous(chainID))
 
-	// 事件订阅/发布协程
-	router := pubsub.CreateNewEventRouter()
-	http.HandleFunc("/subscribe", router.HandleConnect)
-	go router.Process()
-	go http.ListenAndServe(":8888", nil)
-
-	if genesis {
+	if genesis && genesisFile == "" {
+		log.Warningln("Runtime generated genesis block is deprecated, use generate subcommand instead.")
 		log.Infof("Create genesis block after 10s...")
 		metrics2.RoutineCreateCounterObserve(4)
 		go func() {
@@ -202,6 +234,8 @@ func main() {
 
 			// 在 10s 后创建创世区块
 			select {
+			case <-ctx.Done():
+				return
 			case <-ticker.C:
 				log.Infof("Create genesis block.")
 				chain.NewGenesisBlock()
@@ -214,21 +248,31 @@ func main() {
 		}()
 	}
 
-	c := make(chan os.Signal)
-	signal.Notify(c, os.Interrupt)
-	select {
-	case sign := <-c:
-		log.Infof("Got %s signal. Aborting...", sign)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	sign := <-c
+	log.Infof("Got %s signal. Shutting down...", sign)
+
+	// 取消节点发现等依赖根 context 的协程，再按照依赖顺序停止所有子系统
+	cancel()
+	exitCode := 0
+	if err = stopNode(n, db); err != nil {
+		exitCode = 1
+	}
 
-		if pp {
-			memFileName := fmt.Sprintf("mem-%d.profile", time.Now().UnixMilli())
-			memf, _ := os.OpenFile(memFileName, os.O_CREATE|os.O_RDWR, 0644)
-			pprof.WriteHeapProfile(memf)
+	_ = kdht.Close()
+	_ = host.Close()
 
-			pprof.StopCPUProfile()
-			f.Close()
-		}
+	if pp {
+		memFileName := fmt.Sprintf("mem-%d.profile", time.Now().UnixMilli())
+		memf, _ := os.OpenFile(memFileName, os.O_CREATE|os.O_RDWR, 0644)
+		pprof.WriteHeapProfile(memf)
+		memf.Close()
 
-		os.Exit(1)
+		pprof.StopCPUProfile()
+		f.Close()
 	}
+
+	log.Infoln("Node stopped.")
+	os.Exit(exitCode)
 }
@@ -5,10 +5,11 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/sha256"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/crypto"
 	"github.com/chain-lab/go-norn/node"
+	"github.com/chain-lab/go-norn/norn"
+	"github.com/chain-lab/go-norn/utils"
 	"github.com/libp2p/go-libp2p"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	"github.com/libp2p/go-libp2p/core/host"
@@ -67,7 +68,7 @@ func sendTransaction(pm *node.P2PManager) {
 	for {
 		//select {
 		//case <-ticket.C:
-		tx := buildTransaction(prv)
+		tx := buildTransaction(prv, pm.GetBlockChain().ChainID())
 		pm.AddTransaction(tx)
 		//}
 	}
@@ -101,7 +102,7 @@ func buildResourceManager() *network.ResourceManager {
 	return &rm
 }
 
-func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
+func buildTransaction(key *ecdsa.PrivateKey, chainID int64) *common.Transaction {
 	data := make([]byte, 32)
 	rand.Read(data)
 	timestamp := time.Now().UnixMilli()
@@ -121,9 +122,7 @@ func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
 	txBody.WriteAsRoot(writer)
 	txBodyBytes := writer.Bytes()
 
-	hash := sha256.New()
-	hash.Write(txBodyBytes)
-	txHashBytes := hash.Sum(nil)
+	txHashBytes := common.TransactionSigningHash(txBodyBytes, chainID)
 	txSignatureBytes, err := ecdsa.SignASN1(rand.Reader, key, txHashBytes)
 
 	if err != nil {
@@ -139,3 +138,26 @@ func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
 
 	return &tx
 }
+
+// stopNode
+//
+//	@Description: 按照依赖顺序停止节点的所有子系统，在所有写入数据库的协程退出之后关闭数据库
+//	@param n - 节点实例
+//	@param db - 数据库实例
+//	@return error - 子系统停止超时或者数据库关闭失败时返回错误
+func stopNode(n *norn.Node, db *utils.LevelDB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	result := n.Stop(ctx)
+	if result != nil {
+		log.WithError(result).Warningln("Stop node services failed.")
+	}
+
+	if err := db.Close(); err != nil {
+		log.WithError(err).Errorln("Close database failed.")
+		return err
+	}
+
+	return result
+}
@@ -27,6 +27,7 @@ import (
 func main() {
 	LoadConfig("./config.yml")
 	addresses := config.Strings("rpc.address")
+	chainID := config.Int64("consensus.chain_id", 0)
 
 	for {
 		// 随机选取列表中的节点
@@ -61,7 +62,7 @@ func main() {
 		log.Infof("Start send transactions.")
 		for {
 			// 构建新的交易
-			tx := buildTransaction(prv)
+			tx := buildTransaction(prv, chainID)
 			bytesTransaction, err := utils.SerializeTransaction(tx)
 			if err != nil {
 				log.WithError(err).Errorln("Build transaction failed.")
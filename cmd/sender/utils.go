@@ -3,7 +3,6 @@ package main
 import (
 	"crypto/ecdsa"
 	"crypto/rand"
-	"crypto/sha256"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/crypto"
 	log "github.com/sirupsen/logrus"
@@ -11,7 +10,7 @@ import (
 	"time"
 )
 
-func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
+func buildTransaction(key *ecdsa.PrivateKey, chainID int64) *common.Transaction {
 	// 生成随机数据
 	data := make([]byte, 32)
 	rand.Read(data)
@@ -36,9 +35,7 @@ func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
 	txBodyBytes := writer.Bytes()
 
 	// 哈希序列化后的交易，然后签名
-	hash := sha256.New()
-	hash.Write(txBodyBytes)
-	txHashBytes := hash.Sum(nil)
+	txHashBytes := common.TransactionSigningHash(txBodyBytes, chainID)
 	txSignatureBytes, err := ecdsa.SignASN1(rand.Reader, key, txHashBytes)
 
 	if err != nil {
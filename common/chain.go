@@ -0,0 +1,30 @@
+// Package common
+// @Description: 链 ID，用于隔离测试网与主网。链 ID 由创世参数确定，交易签名的哈希中会混入链 ID，
+// 为 0 时表示未设置链 ID 的旧链，交易哈希的计算方式保持不变
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// TransactionSigningHash
+//
+//	@Description: 计算交易签名所使用的哈希，链 ID 不为 0 时在交易体之后追加链 ID，
+//	其他链上签名的交易在本链上验证时哈希不一致
+//	@param body - 哈希和签名置空后序列化的交易体
+//	@param id - 链 ID
+//	@return []byte - 交易哈希
+func TransactionSigningHash(body []byte, id int64) []byte {
+	hash := sha256.New()
+	hash.Write(body)
+
+	if id != 0 {
+		idBytes := make([]byte, 8)
+		binary.BigEndian.PutUint64(idBytes, uint64(id))
+		hash.Write([]byte("norn-chain-id"))
+		hash.Write(idBytes)
+	}
+
+	return hash.Sum(nil)
+}
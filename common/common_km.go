@@ -7,7 +7,7 @@ import (
 
 var _ unsafe.Pointer
 
-var _Null = make([]byte, 256)
+var _Null = make([]byte, 512)
 var _NullReader = karmem.NewReader(_Null)
 
 type (
@@ -22,6 +22,7 @@ const (
 	PacketIdentifierBlockHeader     = 6888014730382219470
 	PacketIdentifierBlock           = 1202114546008698459
 	PacketIdentifierDataCommand     = 11594215842612946088
+	PacketIdentifierVDFCheckpoint   = 9438941068274308248
 )
 
 type TransactionBody struct {
@@ -299,10 +300,24 @@ func (x *Transaction) Read(viewer *TransactionViewer, reader *karmem.Reader) {
 }
 
 type GenesisParams struct {
-	Order       [128]byte
-	TimeParam   int64
-	Seed        [32]byte
-	VerifyParam [32]byte
+	Order             [128]byte
+	TimeParam         int64
+	Seed              [32]byte
+	VerifyParam       [32]byte
+	ExpectedProducers int64
+	TotalWeight       int64
+	WeightRegistry    [20]byte
+	VRFVersion        uint8
+	VDFScheme         uint8
+	Discriminant      []byte
+	Modulus           []byte
+	ChainID           int64
+	AllocRoot         [32]byte
+	SlotInterval      int64
+	MaxFutureDrift    int64
+	MerkleVersion     uint8
+	DataVersion       uint8
+	DataRoot          [32]byte
 }
 
 func NewGenesisParams() GenesisParams {
@@ -323,14 +338,14 @@ func (x *GenesisParams) WriteAsRoot(writer *karmem.Writer) (offset uint, err err
 
 func (x *GenesisParams) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
 	offset = start
-	size := uint(208)
+	size := uint(360)
 	if offset == 0 {
 		offset, err = writer.Alloc(size)
 		if err != nil {
 			return 0, err
 		}
 	}
-	writer.Write4At(offset, uint32(204))
+	writer.Write4At(offset, uint32(356))
 	__OrderOffset := offset + 4
 	writer.WriteAt(__OrderOffset, (*[128]byte)(unsafe.Pointer(&x.Order))[:])
 	__TimeParamOffset := offset + 132
@@ -339,6 +354,54 @@ func (x *GenesisParams) Write(writer *karmem.Writer, start uint) (offset uint, e
 	writer.WriteAt(__SeedOffset, (*[32]byte)(unsafe.Pointer(&x.Seed))[:])
 	__VerifyParamOffset := offset + 172
 	writer.WriteAt(__VerifyParamOffset, (*[32]byte)(unsafe.Pointer(&x.VerifyParam))[:])
+	__ExpectedProducersOffset := offset + 204
+	writer.Write8At(__ExpectedProducersOffset, *(*uint64)(unsafe.Pointer(&x.ExpectedProducers)))
+	__TotalWeightOffset := offset + 212
+	writer.Write8At(__TotalWeightOffset, *(*uint64)(unsafe.Pointer(&x.TotalWeight)))
+	__WeightRegistryOffset := offset + 220
+	writer.WriteAt(__WeightRegistryOffset, (*[20]byte)(unsafe.Pointer(&x.WeightRegistry))[:])
+	__VRFVersionOffset := offset + 240
+	writer.Write1At(__VRFVersionOffset, *(*uint8)(unsafe.Pointer(&x.VRFVersion)))
+	__VDFSchemeOffset := offset + 241
+	writer.Write1At(__VDFSchemeOffset, *(*uint8)(unsafe.Pointer(&x.VDFScheme)))
+	__DiscriminantSize := uint(1 * len(x.Discriminant))
+	__DiscriminantOffset, err := writer.Alloc(__DiscriminantSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+242, uint32(__DiscriminantOffset))
+	writer.Write4At(offset+242+4, uint32(__DiscriminantSize))
+	writer.Write4At(offset+242+4+4, 1)
+	__DiscriminantSlice := *(*[3]uint)(unsafe.Pointer(&x.Discriminant))
+	__DiscriminantSlice[1] = __DiscriminantSize
+	__DiscriminantSlice[2] = __DiscriminantSize
+	writer.WriteAt(__DiscriminantOffset, *(*[]byte)(unsafe.Pointer(&__DiscriminantSlice)))
+	__ModulusSize := uint(1 * len(x.Modulus))
+	__ModulusOffset, err := writer.Alloc(__ModulusSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+254, uint32(__ModulusOffset))
+	writer.Write4At(offset+254+4, uint32(__ModulusSize))
+	writer.Write4At(offset+254+4+4, 1)
+	__ModulusSlice := *(*[3]uint)(unsafe.Pointer(&x.Modulus))
+	__ModulusSlice[1] = __ModulusSize
+	__ModulusSlice[2] = __ModulusSize
+	writer.WriteAt(__ModulusOffset, *(*[]byte)(unsafe.Pointer(&__ModulusSlice)))
+	__ChainIDOffset := offset + 266
+	writer.Write8At(__ChainIDOffset, *(*uint64)(unsafe.Pointer(&x.ChainID)))
+	__AllocRootOffset := offset + 274
+	writer.WriteAt(__AllocRootOffset, (*[32]byte)(unsafe.Pointer(&x.AllocRoot))[:])
+	__SlotIntervalOffset := offset + 306
+	writer.Write8At(__SlotIntervalOffset, *(*uint64)(unsafe.Pointer(&x.SlotInterval)))
+	__MaxFutureDriftOffset := offset + 314
+	writer.Write8At(__MaxFutureDriftOffset, *(*uint64)(unsafe.Pointer(&x.MaxFutureDrift)))
+	__MerkleVersionOffset := offset + 322
+	writer.Write1At(__MerkleVersionOffset, *(*uint8)(unsafe.Pointer(&x.MerkleVersion)))
+	__DataVersionOffset := offset + 323
+	writer.Write1At(__DataVersionOffset, *(*uint8)(unsafe.Pointer(&x.DataVersion)))
+	__DataRootOffset := offset + 324
+	writer.WriteAt(__DataRootOffset, (*[32]byte)(unsafe.Pointer(&x.DataRoot))[:])
 
 	return offset, nil
 }
@@ -367,6 +430,53 @@ func (x *GenesisParams) Read(viewer *GenesisParamsViewer, reader *karmem.Reader)
 	for i := __VerifyParamLen; i < len(x.VerifyParam); i++ {
 		x.VerifyParam[i] = 0
 	}
+	x.ExpectedProducers = viewer.ExpectedProducers()
+	x.TotalWeight = viewer.TotalWeight()
+	__WeightRegistrySlice := viewer.WeightRegistry()
+	__WeightRegistryLen := len(__WeightRegistrySlice)
+	copy(x.WeightRegistry[:], __WeightRegistrySlice)
+	for i := __WeightRegistryLen; i < len(x.WeightRegistry); i++ {
+		x.WeightRegistry[i] = 0
+	}
+	x.VRFVersion = viewer.VRFVersion()
+	x.VDFScheme = viewer.VDFScheme()
+	__DiscriminantSlice := viewer.Discriminant(reader)
+	__DiscriminantLen := len(__DiscriminantSlice)
+	if __DiscriminantLen > cap(x.Discriminant) {
+		x.Discriminant = append(x.Discriminant, make([]byte, __DiscriminantLen-len(x.Discriminant))...)
+	}
+	x.Discriminant = x.Discriminant[:__DiscriminantLen]
+	copy(x.Discriminant, __DiscriminantSlice)
+	for i := __DiscriminantLen; i < len(x.Discriminant); i++ {
+		x.Discriminant[i] = 0
+	}
+	__ModulusSlice := viewer.Modulus(reader)
+	__ModulusLen := len(__ModulusSlice)
+	if __ModulusLen > cap(x.Modulus) {
+		x.Modulus = append(x.Modulus, make([]byte, __ModulusLen-len(x.Modulus))...)
+	}
+	x.Modulus = x.Modulus[:__ModulusLen]
+	copy(x.Modulus, __ModulusSlice)
+	for i := __ModulusLen; i < len(x.Modulus); i++ {
+		x.Modulus[i] = 0
+	}
+	x.ChainID = viewer.ChainID()
+	__AllocRootSlice := viewer.AllocRoot()
+	__AllocRootLen := len(__AllocRootSlice)
+	copy(x.AllocRoot[:], __AllocRootSlice)
+	for i := __AllocRootLen; i < len(x.AllocRoot); i++ {
+		x.AllocRoot[i] = 0
+	}
+	x.SlotInterval = viewer.SlotInterval()
+	x.MaxFutureDrift = viewer.MaxFutureDrift()
+	x.MerkleVersion = viewer.MerkleVersion()
+	x.DataVersion = viewer.DataVersion()
+	__DataRootSlice := viewer.DataRoot()
+	__DataRootLen := len(__DataRootSlice)
+	copy(x.DataRoot[:], __DataRootSlice)
+	for i := __DataRootLen; i < len(x.DataRoot); i++ {
+		x.DataRoot[i] = 0
+	}
 }
 
 type GeneralParams struct {
@@ -375,6 +485,7 @@ type GeneralParams struct {
 	RandomNumber [33]byte
 	S            []byte
 	T            []byte
+	DataRoot     [32]byte
 }
 
 func NewGeneralParams() GeneralParams {
@@ -395,14 +506,14 @@ func (x *GeneralParams) WriteAsRoot(writer *karmem.Writer) (offset uint, err err
 
 func (x *GeneralParams) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
 	offset = start
-	size := uint(88)
+	size := uint(120)
 	if offset == 0 {
 		offset, err = writer.Alloc(size)
 		if err != nil {
 			return 0, err
 		}
 	}
-	writer.Write4At(offset, uint32(85))
+	writer.Write4At(offset, uint32(117))
 	__ResultSize := uint(1 * len(x.Result))
 	__ResultOffset, err := writer.Alloc(__ResultSize)
 	if err != nil {
@@ -453,6 +564,8 @@ func (x *GeneralParams) Write(writer *karmem.Writer, start uint) (offset uint, e
 	__TSlice[1] = __TSize
 	__TSlice[2] = __TSize
 	writer.WriteAt(__TOffset, *(*[]byte)(unsafe.Pointer(&__TSlice)))
+	__DataRootOffset := offset + 85
+	writer.WriteAt(__DataRootOffset, (*[32]byte)(unsafe.Pointer(&x.DataRoot))[:])
 
 	return offset, nil
 }
@@ -508,6 +621,12 @@ func (x *GeneralParams) Read(viewer *GeneralParamsViewer, reader *karmem.Reader)
 	for i := __TLen; i < len(x.T); i++ {
 		x.T[i] = 0
 	}
+	__DataRootSlice := viewer.DataRoot()
+	__DataRootLen := len(__DataRootSlice)
+	copy(x.DataRoot[:], __DataRootSlice)
+	for i := __DataRootLen; i < len(x.DataRoot); i++ {
+		x.DataRoot[i] = 0
+	}
 }
 
 type BlockHeader struct {
@@ -519,6 +638,7 @@ type BlockHeader struct {
 	PublicKey     [33]byte
 	Params        []byte
 	GasLimit      int64
+	Signature     []byte
 }
 
 func NewBlockHeader() BlockHeader {
@@ -539,14 +659,14 @@ func (x *BlockHeader) WriteAsRoot(writer *karmem.Writer) (offset uint, err error
 
 func (x *BlockHeader) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
 	offset = start
-	size := uint(176)
+	size := uint(184)
 	if offset == 0 {
 		offset, err = writer.Alloc(size)
 		if err != nil {
 			return 0, err
 		}
 	}
-	writer.Write4At(offset, uint32(169))
+	writer.Write4At(offset, uint32(181))
 	__TimestampOffset := offset + 4
 	writer.Write8At(__TimestampOffset, *(*uint64)(unsafe.Pointer(&x.Timestamp)))
 	__PrevBlockHashOffset := offset + 12
@@ -573,6 +693,18 @@ func (x *BlockHeader) Write(writer *karmem.Writer, start uint) (offset uint, err
 	writer.WriteAt(__ParamsOffset, *(*[]byte)(unsafe.Pointer(&__ParamsSlice)))
 	__GasLimitOffset := offset + 161
 	writer.Write8At(__GasLimitOffset, *(*uint64)(unsafe.Pointer(&x.GasLimit)))
+	__SignatureSize := uint(1 * len(x.Signature))
+	__SignatureOffset, err := writer.Alloc(__SignatureSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+169, uint32(__SignatureOffset))
+	writer.Write4At(offset+169+4, uint32(__SignatureSize))
+	writer.Write4At(offset+169+4+4, 1)
+	__SignatureSlice := *(*[3]uint)(unsafe.Pointer(&x.Signature))
+	__SignatureSlice[1] = __SignatureSize
+	__SignatureSlice[2] = __SignatureSize
+	writer.WriteAt(__SignatureOffset, *(*[]byte)(unsafe.Pointer(&__SignatureSlice)))
 
 	return offset, nil
 }
@@ -619,6 +751,16 @@ func (x *BlockHeader) Read(viewer *BlockHeaderViewer, reader *karmem.Reader) {
 		x.Params[i] = 0
 	}
 	x.GasLimit = viewer.GasLimit()
+	__SignatureSlice := viewer.Signature(reader)
+	__SignatureLen := len(__SignatureSlice)
+	if __SignatureLen > cap(x.Signature) {
+		x.Signature = append(x.Signature, make([]byte, __SignatureLen-len(x.Signature))...)
+	}
+	x.Signature = x.Signature[:__SignatureLen]
+	copy(x.Signature, __SignatureSlice)
+	for i := __SignatureLen; i < len(x.Signature); i++ {
+		x.Signature[i] = 0
+	}
 }
 
 type Block struct {
@@ -652,7 +794,7 @@ func (x *Block) Write(writer *karmem.Writer, start uint) (offset uint, err error
 		}
 	}
 	writer.Write4At(offset, uint32(20))
-	__HeaderSize := uint(176)
+	__HeaderSize := uint(184)
 	__HeaderOffset, err := writer.Alloc(__HeaderSize)
 	if err != nil {
 		return 0, err
@@ -805,6 +947,146 @@ func (x *DataCommand) Read(viewer *DataCommandViewer, reader *karmem.Reader) {
 	}
 }
 
+type VDFCheckpoint struct {
+	Seed      []byte
+	Round     int64
+	TimeParam int64
+	Result    []byte
+	Proof     []byte
+	Remainder []byte
+}
+
+func NewVDFCheckpoint() VDFCheckpoint {
+	return VDFCheckpoint{}
+}
+
+func (x *VDFCheckpoint) PacketIdentifier() PacketIdentifier {
+	return PacketIdentifierVDFCheckpoint
+}
+
+func (x *VDFCheckpoint) Reset() {
+	x.Read((*VDFCheckpointViewer)(unsafe.Pointer(&_Null)), _NullReader)
+}
+
+func (x *VDFCheckpoint) WriteAsRoot(writer *karmem.Writer) (offset uint, err error) {
+	return x.Write(writer, 0)
+}
+
+func (x *VDFCheckpoint) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
+	offset = start
+	size := uint(72)
+	if offset == 0 {
+		offset, err = writer.Alloc(size)
+		if err != nil {
+			return 0, err
+		}
+	}
+	writer.Write4At(offset, uint32(68))
+	__SeedSize := uint(1 * len(x.Seed))
+	__SeedOffset, err := writer.Alloc(__SeedSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+4, uint32(__SeedOffset))
+	writer.Write4At(offset+4+4, uint32(__SeedSize))
+	writer.Write4At(offset+4+4+4, 1)
+	__SeedSlice := *(*[3]uint)(unsafe.Pointer(&x.Seed))
+	__SeedSlice[1] = __SeedSize
+	__SeedSlice[2] = __SeedSize
+	writer.WriteAt(__SeedOffset, *(*[]byte)(unsafe.Pointer(&__SeedSlice)))
+	__RoundOffset := offset + 16
+	writer.Write8At(__RoundOffset, *(*uint64)(unsafe.Pointer(&x.Round)))
+	__TimeParamOffset := offset + 24
+	writer.Write8At(__TimeParamOffset, *(*uint64)(unsafe.Pointer(&x.TimeParam)))
+	__ResultSize := uint(1 * len(x.Result))
+	__ResultOffset, err := writer.Alloc(__ResultSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+32, uint32(__ResultOffset))
+	writer.Write4At(offset+32+4, uint32(__ResultSize))
+	writer.Write4At(offset+32+4+4, 1)
+	__ResultSlice := *(*[3]uint)(unsafe.Pointer(&x.Result))
+	__ResultSlice[1] = __ResultSize
+	__ResultSlice[2] = __ResultSize
+	writer.WriteAt(__ResultOffset, *(*[]byte)(unsafe.Pointer(&__ResultSlice)))
+	__ProofSize := uint(1 * len(x.Proof))
+	__ProofOffset, err := writer.Alloc(__ProofSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+44, uint32(__ProofOffset))
+	writer.Write4At(offset+44+4, uint32(__ProofSize))
+	writer.Write4At(offset+44+4+4, 1)
+	__ProofSlice := *(*[3]uint)(unsafe.Pointer(&x.Proof))
+	__ProofSlice[1] = __ProofSize
+	__ProofSlice[2] = __ProofSize
+	writer.WriteAt(__ProofOffset, *(*[]byte)(unsafe.Pointer(&__ProofSlice)))
+	__RemainderSize := uint(1 * len(x.Remainder))
+	__RemainderOffset, err := writer.Alloc(__RemainderSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+56, uint32(__RemainderOffset))
+	writer.Write4At(offset+56+4, uint32(__RemainderSize))
+	writer.Write4At(offset+56+4+4, 1)
+	__RemainderSlice := *(*[3]uint)(unsafe.Pointer(&x.Remainder))
+	__RemainderSlice[1] = __RemainderSize
+	__RemainderSlice[2] = __RemainderSize
+	writer.WriteAt(__RemainderOffset, *(*[]byte)(unsafe.Pointer(&__RemainderSlice)))
+
+	return offset, nil
+}
+
+func (x *VDFCheckpoint) ReadAsRoot(reader *karmem.Reader) {
+	x.Read(NewVDFCheckpointViewer(reader, 0), reader)
+}
+
+func (x *VDFCheckpoint) Read(viewer *VDFCheckpointViewer, reader *karmem.Reader) {
+	__SeedSlice := viewer.Seed(reader)
+	__SeedLen := len(__SeedSlice)
+	if __SeedLen > cap(x.Seed) {
+		x.Seed = append(x.Seed, make([]byte, __SeedLen-len(x.Seed))...)
+	}
+	x.Seed = x.Seed[:__SeedLen]
+	copy(x.Seed, __SeedSlice)
+	for i := __SeedLen; i < len(x.Seed); i++ {
+		x.Seed[i] = 0
+	}
+	x.Round = viewer.Round()
+	x.TimeParam = viewer.TimeParam()
+	__ResultSlice := viewer.Result(reader)
+	__ResultLen := len(__ResultSlice)
+	if __ResultLen > cap(x.Result) {
+		x.Result = append(x.Result, make([]byte, __ResultLen-len(x.Result))...)
+	}
+	x.Result = x.Result[:__ResultLen]
+	copy(x.Result, __ResultSlice)
+	for i := __ResultLen; i < len(x.Result); i++ {
+		x.Result[i] = 0
+	}
+	__ProofSlice := viewer.Proof(reader)
+	__ProofLen := len(__ProofSlice)
+	if __ProofLen > cap(x.Proof) {
+		x.Proof = append(x.Proof, make([]byte, __ProofLen-len(x.Proof))...)
+	}
+	x.Proof = x.Proof[:__ProofLen]
+	copy(x.Proof, __ProofSlice)
+	for i := __ProofLen; i < len(x.Proof); i++ {
+		x.Proof[i] = 0
+	}
+	__RemainderSlice := viewer.Remainder(reader)
+	__RemainderLen := len(__RemainderSlice)
+	if __RemainderLen > cap(x.Remainder) {
+		x.Remainder = append(x.Remainder, make([]byte, __RemainderLen-len(x.Remainder))...)
+	}
+	x.Remainder = x.Remainder[:__RemainderLen]
+	copy(x.Remainder, __RemainderSlice)
+	for i := __RemainderLen; i < len(x.Remainder); i++ {
+		x.Remainder[i] = 0
+	}
+}
+
 type TransactionBodyViewer struct {
 	_data [256]byte
 }
@@ -1004,7 +1286,7 @@ func (x *TransactionViewer) Body(reader *karmem.Reader) (v *TransactionBodyViewe
 }
 
 type GenesisParamsViewer struct {
-	_data [208]byte
+	_data [360]byte
 }
 
 func NewGenesisParamsViewer(reader *karmem.Reader, offset uint32) (v *GenesisParamsViewer) {
@@ -1054,9 +1336,120 @@ func (x *GenesisParamsViewer) VerifyParam() (v []byte) {
 	}
 	return *(*[]byte)(unsafe.Pointer(&slice))
 }
+func (x *GenesisParamsViewer) ExpectedProducers() (v int64) {
+	if 204+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 204))
+}
+func (x *GenesisParamsViewer) TotalWeight() (v int64) {
+	if 212+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 212))
+}
+func (x *GenesisParamsViewer) WeightRegistry() (v []byte) {
+	if 220+20 > x.size() {
+		return []byte{}
+	}
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(unsafe.Pointer(&x._data), 220)), 20, 20,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *GenesisParamsViewer) VRFVersion() (v uint8) {
+	if 240+1 > x.size() {
+		return v
+	}
+	return *(*uint8)(unsafe.Add(unsafe.Pointer(&x._data), 240))
+}
+func (x *GenesisParamsViewer) VDFScheme() (v uint8) {
+	if 241+1 > x.size() {
+		return v
+	}
+	return *(*uint8)(unsafe.Add(unsafe.Pointer(&x._data), 241))
+}
+func (x *GenesisParamsViewer) Discriminant(reader *karmem.Reader) (v []byte) {
+	if 242+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 242))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 242+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *GenesisParamsViewer) Modulus(reader *karmem.Reader) (v []byte) {
+	if 254+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 254))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 254+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *GenesisParamsViewer) ChainID() (v int64) {
+	if 266+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 266))
+}
+func (x *GenesisParamsViewer) AllocRoot() (v []byte) {
+	if 274+32 > x.size() {
+		return []byte{}
+	}
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(unsafe.Pointer(&x._data), 274)), 32, 32,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *GenesisParamsViewer) SlotInterval() (v int64) {
+	if 306+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 306))
+}
+func (x *GenesisParamsViewer) MaxFutureDrift() (v int64) {
+	if 314+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 314))
+}
+func (x *GenesisParamsViewer) MerkleVersion() (v uint8) {
+	if 322+1 > x.size() {
+		return v
+	}
+	return *(*uint8)(unsafe.Add(unsafe.Pointer(&x._data), 322))
+}
+func (x *GenesisParamsViewer) DataVersion() (v uint8) {
+	if 323+1 > x.size() {
+		return v
+	}
+	return *(*uint8)(unsafe.Add(unsafe.Pointer(&x._data), 323))
+}
+func (x *GenesisParamsViewer) DataRoot() (v []byte) {
+	if 324+32 > x.size() {
+		return []byte{}
+	}
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(unsafe.Pointer(&x._data), 324)), 32, 32,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
 
 type GeneralParamsViewer struct {
-	_data [88]byte
+	_data [120]byte
 }
 
 func NewGeneralParamsViewer(reader *karmem.Reader, offset uint32) (v *GeneralParamsViewer) {
@@ -1142,9 +1535,18 @@ func (x *GeneralParamsViewer) T(reader *karmem.Reader) (v []byte) {
 	}
 	return *(*[]byte)(unsafe.Pointer(&slice))
 }
+func (x *GeneralParamsViewer) DataRoot() (v []byte) {
+	if 85+32 > x.size() {
+		return []byte{}
+	}
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(unsafe.Pointer(&x._data), 85)), 32, 32,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
 
 type BlockHeaderViewer struct {
-	_data [176]byte
+	_data [184]byte
 }
 
 func NewBlockHeaderViewer(reader *karmem.Reader, offset uint32) (v *BlockHeaderViewer) {
@@ -1230,6 +1632,21 @@ func (x *BlockHeaderViewer) GasLimit() (v int64) {
 	}
 	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 161))
 }
+func (x *BlockHeaderViewer) Signature(reader *karmem.Reader) (v []byte) {
+	if 169+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 169))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 169+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
 
 type BlockViewer struct {
 	_data [24]byte
@@ -1335,3 +1752,94 @@ func (x *DataCommandViewer) Value(reader *karmem.Reader) (v []byte) {
 	}
 	return *(*[]byte)(unsafe.Pointer(&slice))
 }
+
+type VDFCheckpointViewer struct {
+	_data [72]byte
+}
+
+func NewVDFCheckpointViewer(reader *karmem.Reader, offset uint32) (v *VDFCheckpointViewer) {
+	if !reader.IsValidOffset(offset, 8) {
+		return (*VDFCheckpointViewer)(unsafe.Pointer(&_Null))
+	}
+	v = (*VDFCheckpointViewer)(unsafe.Add(reader.Pointer, offset))
+	if !reader.IsValidOffset(offset, v.size()) {
+		return (*VDFCheckpointViewer)(unsafe.Pointer(&_Null))
+	}
+	return v
+}
+
+func (x *VDFCheckpointViewer) size() uint32 {
+	return *(*uint32)(unsafe.Pointer(&x._data))
+}
+func (x *VDFCheckpointViewer) Seed(reader *karmem.Reader) (v []byte) {
+	if 4+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 4))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 4+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *VDFCheckpointViewer) Round() (v int64) {
+	if 16+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 16))
+}
+func (x *VDFCheckpointViewer) TimeParam() (v int64) {
+	if 24+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 24))
+}
+func (x *VDFCheckpointViewer) Result(reader *karmem.Reader) (v []byte) {
+	if 32+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 32))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 32+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *VDFCheckpointViewer) Proof(reader *karmem.Reader) (v []byte) {
+	if 44+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 44))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 44+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *VDFCheckpointViewer) Remainder(reader *karmem.Reader) (v []byte) {
+	if 56+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 56))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 56+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
@@ -4,7 +4,6 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/sha256"
 	"github.com/chain-lab/go-norn/crypto"
 	log "github.com/sirupsen/logrus"
 	karmem "karmem.org/golang"
@@ -12,7 +11,7 @@ import (
 	"time"
 )
 
-func buildTransaction(key *ecdsa.PrivateKey) *Transaction {
+func buildTransaction(key *ecdsa.PrivateKey, chainID int64) *Transaction {
 	data := make([]byte, 32)
 	rand.Read(data)
 	timestamp := time.Now().UnixMilli()
@@ -32,9 +31,7 @@ func buildTransaction(key *ecdsa.PrivateKey) *Transaction {
 	txBody.WriteAsRoot(writer)
 	txBodyBytes := writer.Bytes()
 
-	hash := sha256.New()
-	hash.Write(txBodyBytes)
-	txHashBytes := hash.Sum(nil)
+	txHashBytes := TransactionSigningHash(txBodyBytes, chainID)
 	txSignatureBytes, err := ecdsa.SignASN1(rand.Reader, key, txHashBytes)
 
 	if err != nil {
@@ -59,11 +56,11 @@ func TestBuildTransactionAndVerifyTransaction(t *testing.T) {
 		t.Fatal(err)
 	}
 	st := time.Now()
-	transaction := buildTransaction(privateKey)
+	transaction := buildTransaction(privateKey, 0)
 	buildTimeUsed := time.Since(st)
 
 	st = time.Now()
-	result := transaction.Verify()
+	result := transaction.Verify(0)
 	verifyTimeUsed := time.Since(st)
 	if !result {
 		t.Fatal("Verify transaction failed.")
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/sha256"
 	"encoding/hex"
 	"github.com/chain-lab/go-norn/metrics"
 	log "github.com/sirupsen/logrus"
@@ -14,8 +13,8 @@ import (
 
 //var writerPool = sync.Pool{New: func() any { return karmem.NewWriter(1024) }}
 
-// Verify 交易验证方法
-func (tx *Transaction) Verify() bool {
+// Verify 交易验证方法，chainID 为本地节点所在链的链 ID，其他链上签名的交易无法通过验证
+func (tx *Transaction) Verify(chainID int64) bool {
 	//writer := writerPool.Get().(*karmem.Writer)
 	//defer writerPool.Put(writer)
 	//defer writer.Reset()
@@ -61,9 +60,8 @@ func (tx *Transaction) Verify() bool {
 		return false
 	}
 
-	hash := sha256.New()
-	hash.Write(writer.Bytes())
-	txHashBytes := hash.Sum(nil)
+	// 交易哈希中混入本地的链 ID
+	txHashBytes := TransactionSigningHash(writer.Bytes(), chainID)
 
 	if bytes.Compare(txHashBytes, byteHash) != 0 {
 		log.WithFields(log.Fields{
@@ -6,12 +6,14 @@ package core
 
 import (
 	"bytes"
-	"crypto/sha256"
+	"context"
+	"crypto/ecdsa"
 	"encoding/hex"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/crypto"
 	"github.com/chain-lab/go-norn/interfaces"
 	"github.com/chain-lab/go-norn/metrics"
+	"github.com/chain-lab/go-norn/pubsub"
 	"github.com/gookit/config/v2"
 	lru "github.com/hashicorp/golang-lru"
 	log "github.com/sirupsen/logrus"
@@ -28,6 +30,7 @@ import (
 const (
 	maxBlockCache         = 64    // 区块 LRU 缓存大小
 	maxTransactionCache   = 40960 // 交易 LRU 缓存大小
+	maxVerifiedRootCache  = 2048  // 已经验证状态根的区块 LRU 缓存大小
 	maxBlockProcessList   = 12    // （已弃用）区块处理队列长度
 	maxBlockChannel       = 128   // 区块缓冲长度
 	maxDbChannel          = 256   // 数据库缓冲长度
@@ -45,6 +48,7 @@ type BlockChain struct {
 	blockHeightMap *lru.Cache
 	blockCache     *lru.Cache
 	txCache        *lru.Cache
+	verifiedRoots  *lru.Cache // 状态根已经验证过的区块哈希，缓冲区验证过的区块插入数据库时不再重复执行
 
 	// 当前的最新区块 latestBlock、最新高度 latestHeight
 	latestBlock  *common.Block
@@ -56,22 +60,41 @@ type BlockChain struct {
 	bufferChan chan *common.Block
 	buffer     *BlockBuffer
 	appendLock sync.RWMutex
+	clock      utils.Clock // 缓冲区使用的时钟
 
 	// 数据存放处理与对应的任务 channel
 	dp     *DataProcessor
 	dpChan chan *DataTask
 
-	// genesisParams 当前所维护的链的创世区块参数
+	// genesisParams 当前所维护的链的创世区块参数，需要 paramsLock 加锁才能读取、修改
 	genesisParams *common.GenesisParams
 	genesisTime   int64
+	slotRules     *SlotRules       // 由创世参数确定的区块时间规则
+	weights       map[string]int64 // 创世时登记的打包节点权重快照，key 为公钥的 16 进制编码
+	genesisAlloc  []GenesisAlloc   // 创世文件中的创世数据，在插入创世区块时写入
+	chainID       int64            // 链 ID，创世区块插入之前为配置的链 ID，之后以创世参数为准
+
+	// 由创世参数初始化的 VDF 计算实例，以及打包和插入区块时使用的交易池
+	calculator   *crypto.Calculator
+	txPool       *TxPool
+	consensusKey *ecdsa.PrivateKey // 打包区块使用的共识私钥，为 nil 时读取配置文件中的 consensus.prv
+	paramsLock   sync.RWMutex
+
+	// 区块处理协程的 context，以及用于等待协程退出的 WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	runLock sync.Mutex
 }
 
 // NewBlockchain
 //
 //	@Description: 创建一个 BlockChain ，需要传入一个 LevelDB 实例 db
 //	@param db - 已初始化的 levelDB 数据库实例
+//	@param router - 数据变更事件的发布路由，为 nil 时不发布事件
+//	@param clock - 区块缓冲区使用的时钟，为 nil 时使用系统时钟
 //	@return *BlockChain - 实例化的数据库处理对象，如果出错返回 nil
-func NewBlockchain(db interfaces.DBInterface) *BlockChain {
+func NewBlockchain(db interfaces.DBInterface, router *pubsub.EventRouter, clock utils.Clock) *BlockChain {
 	// 实例化一系列的 cache 并处理可能出现的错误，创建三个 LRU 缓存
 	blockCache, err := lru.New(maxBlockCache)
 	if err != nil {
@@ -91,9 +114,19 @@ func NewBlockchain(db interfaces.DBInterface) *BlockChain {
 		return nil
 	}
 
+	verifiedRoots, err := lru.New(maxVerifiedRootCache)
+	if err != nil {
+		log.WithField("error", err).Debugln("Create verified root cache failed.")
+		return nil
+	}
+
+	if clock == nil {
+		clock = utils.NewRealClock()
+	}
+
 	dp := NewDataProcessor()
 	dp.db = db
-	go dp.Run()
+	dp.router = router
 
 	// 使用数据库实例 db 实例化一个 Blockchain 对象
 	chain := &BlockChain{
@@ -101,6 +134,7 @@ func NewBlockchain(db interfaces.DBInterface) *BlockChain {
 		blockHeightMap: blockHeightMap,
 		blockCache:     blockCache,
 		txCache:        txCache,
+		verifiedRoots:  verifiedRoots,
 
 		latestBlock:  nil,
 		latestHeight: -1,
@@ -109,6 +143,7 @@ func NewBlockchain(db interfaces.DBInterface) *BlockChain {
 		dpChan: dp.taskChannel,
 
 		bufferChan: make(chan *common.Block, maxBlockChannel),
+		clock:      clock,
 	}
 
 	// 初始化最新区块，从数据库中进行读取
@@ -116,29 +151,95 @@ func NewBlockchain(db interfaces.DBInterface) *BlockChain {
 
 	// 如果最新区块存在，说明当前不是新的区块链，处理缓冲逻辑并且读取创世参数
 	if latest != nil {
+		// 加载创世区块参数，缓冲区使用创世参数初始化的 VDF 计算实例
+		genesis, _ := chain.GetBlockByHeight(0)
+		if err = chain.genesisInitialization(genesis); err != nil {
+			log.WithError(err).Errorln("Load genesis params failed.")
+			return nil
+		}
+
 		log.Traceln("Block database is not null, create buffer.")
 		chain.createBlockBuffer(latest)
+	}
 
-		// 加载创世区块参数
-		genesis, _ := chain.GetBlockByHeight(0)
-		chain.genesisInitialization(genesis)
+	return chain
+}
+
+// Start
+//
+//	@Description: 启动数据处理协程、区块处理协程以及已经创建的区块缓冲区
+//	@receiver BlockChain 实例
+//	@param ctx - 父 context
+//	@return error
+func (bc *BlockChain) Start(ctx context.Context) error {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+
+	// 数据处理器使用独立的 context，在区块处理协程全部退出之后才停止
+	if err := bc.dp.Start(ctx); err != nil {
+		return err
+	}
+
+	bc.ctx, bc.cancel = context.WithCancel(ctx)
+	if bc.buffer != nil {
+		_ = bc.buffer.Start(bc.ctx)
 	}
 
 	// 区块处理协程启动
 	metrics.RoutineCreateCounterObserve(7)
-	go chain.BlockProcessRoutine()
-	return chain
+	bc.wg.Add(1)
+	go bc.BlockProcessRoutine(bc.ctx)
+	return nil
+}
+
+// Stop
+//
+//	@Description: 依次停止区块缓冲区、区块处理协程、VDF 计算和数据处理器，数据处理器会先写入队列中剩余的数据
+//	@receiver BlockChain 实例
+//	@param ctx - 停止的超时 context
+//	@return error - 超时时返回错误
+func (bc *BlockChain) Stop(ctx context.Context) error {
+	bc.runLock.Lock()
+	defer bc.runLock.Unlock()
+
+	if bc.cancel == nil {
+		return nil
+	}
+	bc.cancel()
+
+	if bc.buffer != nil {
+		if err := bc.buffer.Stop(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := utils.WaitGroupContext(ctx, &bc.wg); err != nil {
+		return err
+	}
+
+	if calculator := bc.Calculator(); calculator != nil {
+		if err := calculator.Stop(ctx); err != nil {
+			return err
+		}
+	}
+
+	return bc.dp.Stop(ctx)
 }
 
 // BlockProcessRoutine
 //
 //	@Description: 接收对应了缓冲区弹出的 channel中区块的协程，从 chan 中读取区块并且调用 insertBlock 进行处理
 //	@receiver BlockChain 实例
-func (bc *BlockChain) BlockProcessRoutine() {
+//	@param ctx - 区块处理协程的 context，结束时协程退出
+func (bc *BlockChain) BlockProcessRoutine(ctx context.Context) {
+	defer bc.wg.Done()
+
 	for {
 		select {
 		case block := <-bc.bufferChan:
 			bc.insertBlock(block)
+		case <-ctx.Done():
+			return
 		}
 	}
 }
@@ -150,34 +251,51 @@ func (bc *BlockChain) BlockProcessRoutine() {
 //	@param txs - 选择好的交易数组
 //	@param timestamp - 当前的逻辑时间戳
 //	@param params - 当前的 VDF 计算信息
-//	@param packageInterval - 打包的间隔
 //	@return *common.Block - 完成打包的区块，如果出错返回为 nil
 //	@return error - 错误信息
-func (bc *BlockChain) PackageNewBlock(txs []common.Transaction, timestamp int64, params *common.GeneralParams, packageInterval int64) (*common.Block, error) {
+func (bc *BlockChain) PackageNewBlock(txs []common.Transaction, timestamp int64, params *common.GeneralParams) (*common.Block, error) {
 	packageStart := time.Now()
 
-	// 对传入的区块参数进行序列化
 	log.Traceln("Start package new block.")
-	paramsBytes, err := utils.SerializeGeneralParams(params)
-
-	if err != nil {
-		log.WithField("error", err).Errorln("Serialize params failed while package block.")
-		return nil, err
+	rules := bc.SlotRules()
+	if rules == nil {
+		return nil, errSlotRulesNotInit
 	}
 
 	// 从区块缓冲视图中找到当前视图下最优的区块，具体的选取方法需要查阅文档 （wiki/区块缓冲视图.md）
-	nowHeight := (timestamp-bc.genesisTime)/1000/packageInterval + 1
+	// 区块高度不能超过时隙对应的高度，否则会被其他节点拒绝
+	nowHeight := rules.SlotHeight(timestamp)
 	bestBlock := bc.buffer.GetPriorityLeaf(nowHeight)
 	log.Infof("Package block height #%d.", bestBlock.Header.Height+1)
-	publicKey, err := hex.DecodeString(config.String("consensus.pub"))
+	prv, err := bc.ConsensusKey()
+	if err != nil {
+		return nil, err
+	}
+	publicKey := crypto.PublicKey2Bytes(&prv.PublicKey)
 
+	// 链使用状态树时，在父区块的状态上执行交易得到状态根，写入区块参数的副本
+	blockParams := *params
+	var trie *stateTrie
+	if bc.DataVersion() != DataVersionNone {
+		blockParams.DataRoot, trie, err = bc.nextDataRoot(bestBlock, txs)
+		if err != nil {
+			log.WithError(err).Errorln("Compute data root failed while package block.")
+			return nil, err
+		}
+	}
+
+	// 对传入的区块参数进行序列化
+	paramsBytes, err := utils.SerializeGeneralParams(&blockParams)
 	if err != nil {
-		log.Errorln("Get public key from config failed.")
+		log.WithField("error", err).Errorln("Serialize params failed while package block.")
 		return nil, err
 	}
 
 	// 对交易列表构建 Merkle 哈希树
-	merkleRoot := BuildMerkleTree(txs)
+	merkleRoot, err := MerkleRoot(bc.MerkleVersion(), txs)
+	if err != nil {
+		return nil, err
+	}
 	// 区块创建
 	block := common.Block{
 		Header: common.BlockHeader{
@@ -193,19 +311,19 @@ func (bc *BlockChain) PackageNewBlock(txs []common.Transaction, timestamp int64,
 		Transactions: txs,
 	}
 
-	// 区块头序列化
-	byteBlockHeaderData, err := utils.SerializeBlockHeader(&block.Header)
-
-	if err != nil {
-		log.WithField("error", err).Errorln("Serialize block header failed.")
+	// 计算区块的哈希值并填入到区块头，然后使用共识私钥对区块头签名
+	if err = sealBlockHeader(&block.Header, prv); err != nil {
+		log.WithField("error", err).Errorln("Seal block header failed.")
 		return nil, err
 	}
 
-	// 计算区块的哈希值并填入到区块头
-	hash := sha256.New()
-	hash.Write(byteBlockHeaderData)
-	blockHash := common.Hash(hash.Sum(nil))
-	block.Header.BlockHash = blockHash
+	// 本地打包的区块不再重复验证状态根
+	if trie != nil {
+		if err = trie.commit(); err != nil {
+			return nil, err
+		}
+		bc.verifiedRoots.Add(block.BlockHash(), nil)
+	}
 
 	// 指标记录，本次区块打包耗时
 	metrics.PackageBlockMetricsSet(float64(time.Since(packageStart).Milliseconds()))
@@ -224,56 +342,21 @@ func (bc *BlockChain) NewGenesisBlock() {
 		return
 	}
 
-	// 读取配置文件中的公钥，并解码
-	publicKey, err := hex.DecodeString(config.String("consensus.pub"))
-	if err != nil {
-		log.Errorln("Get public key from config failed.")
-		return
-	}
-
-	// 生成创世区块内的 VDF 参数
-	genesisParams, err := crypto.GenerateGenesisParams()
-	if err != nil {
-		log.WithField("error", err).Errorln("Generate genesis params failed.")
-		return
-	}
-
-	// 对参数进行序列化为字节数组
-	genesisParamsBytes, err := utils.SerializeGenesisParams(genesisParams)
+	// 根据配置文件生成创世参数并签名，链 ID 未配置时为 0
+	// 多个节点需要使用同一个创世区块时，使用 generate 子命令生成创世文件
+	g, err := NewGenesis(config.Int64("consensus.chain_id", 0), time.Now().UnixMilli(), nil)
 	if err != nil {
-		log.WithField("error", err).Errorln("Genesis params serialize error.")
+		log.WithError(err).Errorln("Create genesis failed.")
 		return
 	}
 
-	nullHash := common.Hash{}
-	genesisBlock := common.Block{
-		Header: common.BlockHeader{
-			Timestamp: time.Now().UnixMilli(),
-			//Timestamp:     handler.GetLogicClock(),
-			PrevBlockHash: nullHash,
-			BlockHash:     [32]byte{},
-			MerkleRoot:    [32]byte(nullHash),
-			Height:        0,
-			Params:        genesisParamsBytes,
-			PublicKey:     [33]byte(publicKey),
-		},
-		Transactions: []common.Transaction{},
-	}
-
-	// 序列化区块得到字节数组数据
-	byteBlockHeaderData, err := utils.SerializeBlockHeader(&genesisBlock.Header)
+	genesisBlock, err := g.Block()
 	if err != nil {
-		log.WithField("error", err).Errorln("Serialize block header failed.")
+		log.WithError(err).Errorln("Build genesis block failed.")
 		return
 	}
 
-	// 对字节数据进行哈希并将哈希值填充到区块头
-	hash := sha256.New()
-	hash.Write(byteBlockHeaderData)
-	blockHash := common.Hash(hash.Sum(nil))
-	genesisBlock.Header.BlockHash = blockHash
-
-	bc.AppendBlockTask(&genesisBlock)
+	bc.AppendBlockTask(genesisBlock)
 }
 
 // GetLatestBlock
@@ -346,6 +429,47 @@ func (bc *BlockChain) GetBlockByHash(hash *common.Hash) (*common.Block, error) {
 	return block, nil
 }
 
+// GetBlockFromBuffer
+//
+//	@Description: 在缓冲区当前视图的已选定区块中查找区块，用于响应其他节点缺失父区块的请求
+//	@receiver BlockChain 实例
+//	@param hash - 区块哈希值
+//	@return *common.Block - 对应的区块，不在缓冲区中时返回 nil
+func (bc *BlockChain) GetBlockFromBuffer(hash common.Hash) *common.Block {
+	if bc.buffer == nil {
+		return nil
+	}
+	return bc.buffer.GetSelectedBlock(hex.EncodeToString(hash[:]))
+}
+
+// GetBufferedBlockByHeight
+//
+//	@Description: 在缓冲区当前视图中获取某个高度的已选定区块，这部分区块还没有写入数据库，之后仍然可能被替换
+//	@receiver BlockChain 实例
+//	@param height - 区块高度
+//	@return *common.Block - 对应高度的已选定区块，不在缓冲区中时返回 nil
+func (bc *BlockChain) GetBufferedBlockByHeight(height int64) *common.Block {
+	if bc.buffer == nil {
+		return nil
+	}
+	return bc.buffer.GetSelectedBlockByHeight(height)
+}
+
+// HasBlock
+//
+//	@Description: 区块是否已经在数据库中，或者已经进入过缓冲区
+//	@receiver BlockChain 实例
+//	@param hash - 区块哈希值
+//	@return bool - 本地是否已经有该区块
+func (bc *BlockChain) HasBlock(hash common.Hash) bool {
+	if bc.buffer != nil && bc.buffer.Known(hex.EncodeToString(hash[:])) {
+		return true
+	}
+
+	block, err := bc.GetBlockByHash(&hash)
+	return err == nil && block != nil
+}
+
 // GetBlockByHeight
 //
 //	@Description: 根据高度拉取区块，需要考虑一下同步时是否换用其他的函数
@@ -428,7 +552,13 @@ func (bc *BlockChain) insertBlock(block *common.Block) {
 	count := len(block.Transactions)
 
 	blockHash := common.Hash(block.Header.BlockHash)
-	pool := GetTxPoolInst()
+	pool := bc.TxPool()
+
+	// 校验区块头哈希以及打包节点的签名，签名错误的区块不允许写入数据库
+	if !VerifyBlockSignature(block) {
+		log.WithField("hash", block.BlockHash()[:8]).Warning("Block signature verify failed.")
+		return
+	}
 
 	// 获取对应哈希的区块，如果区块存在，说明链上已经存在该区块
 	_, err := bc.GetBlockByHash(&blockHash)
@@ -455,10 +585,41 @@ func (bc *BlockChain) insertBlock(block *common.Block) {
 			}).Errorln("Block error, prev block hash not match.")
 			return
 		}
+
+		// 区块时间戳需要满足创世参数中的时隙规则
+		if rules := bc.SlotRules(); rules != nil {
+			if err = rules.VerifyBlock(block, latestBlock); err != nil {
+				log.WithError(err).WithField("height",
+					block.Header.Height).Warning("Block timestamp verify failed.")
+				return
+			}
+		}
+
+		// 区块参数中的状态根需要与执行交易的结果一致
+		if err = bc.verifyDataRoot(block, latestBlock); err != nil {
+			log.WithError(err).WithField("height",
+				block.Header.Height).Warning("Block data root verify failed.")
+			return
+		}
 	} else {
-		// 插入区块是创世区块，说明 buffer 没有初始化，需要进行初始化
+		// 插入区块是创世区块，如果配置了仪式参数文件，创世区块中的 VDF 参数需要与其一致
+		if !verifyGenesisCeremony(block) {
+			log.WithField("hash", block.BlockHash()[:8]).Errorln("Genesis params not match ceremony params.")
+			return
+		}
+
+		// 无论创世区块来自创世文件还是其他节点，都在插入时写入创世数据和权重快照
+		if err = bc.writeGenesisAlloc(block); err != nil {
+			log.WithError(err).WithField("hash", block.BlockHash()[:8]).Errorln("Write genesis alloc failed.")
+			return
+		}
+
+		// 说明 buffer 没有初始化，需要进行初始化
+		if err = bc.genesisInitialization(block); err != nil {
+			log.WithError(err).WithField("hash", block.BlockHash()[:8]).Errorln("Genesis initialization failed.")
+			return
+		}
 		bc.createBlockBuffer(block)
-		bc.genesisInitialization(block)
 	}
 
 	// 锁定 BlockChain 实例的最新区块，将当前区块写入缓存
@@ -505,7 +666,9 @@ func (bc *BlockChain) insertBlock(block *common.Block) {
 		tx.Body.Index = int64(idx)
 
 		// 从交易池中移除某个交易
-		pool.RemoveTx(tx.Body.Hash)
+		if pool != nil {
+			pool.RemoveTx(tx.Body.Hash)
+		}
 		txWriter := karmem.NewWriter(1024)
 		// 交易的索引：tx#{hash}
 		keys[idx+transactionStartIndex] = append([]byte("tx#"), tx.Body.Hash[:]...)
@@ -582,8 +745,9 @@ func (bc *BlockChain) insertBlock(block *common.Block) {
 		seed.SetBytes(params.Seed[:])
 		proof.SetInt64(0)
 
-		calculator := crypto.GetCalculatorInstance()
-		calculator.AppendNewSeed(seed, proof)
+		if calculator := bc.Calculator(); calculator != nil {
+			calculator.AppendNewSeed(seed, proof)
+		}
 	}
 	//	params, _ := utils.DeserializeGeneralParams(block.Header.Params)
 	//	// todo: 将编码转换的过程放入到VRF代码中
@@ -698,36 +862,162 @@ func (bc *BlockChain) createBlockBuffer(latest *common.Block) {
 	// todo: 需要处理报错
 	log.Traceln("Create new block buffer.")
 	var err error
-	bc.buffer, err = NewBlockBuffer(latest, bc.bufferChan)
+	bc.buffer, err = NewBlockBuffer(latest, bc.bufferChan, bc.Calculator(), bc.SlotRules(), bc.clock)
 
 	if err != nil {
 		log.WithError(err).Errorln("Create new block buffer failed.")
+		return
 	}
+	bc.buffer.verifyState = bc.verifyDataRoot
+
+	// 区块链已经启动时，缓冲区创建后立即启动
+	if bc.ctx != nil {
+		_ = bc.buffer.Start(bc.ctx)
+	}
+}
+
+// Calculator
+//
+//	@Description: 获取由创世参数初始化的 VDF 计算实例
+//	@receiver BlockChain 实例
+//	@return *crypto.Calculator - 计算实例，还没有创世区块时返回 nil
+func (bc *BlockChain) Calculator() *crypto.Calculator {
+	bc.paramsLock.RLock()
+	defer bc.paramsLock.RUnlock()
+
+	return bc.calculator
+}
+
+// SlotRules
+//
+//	@Description: 获取由创世参数确定的区块时间规则
+//	@receiver BlockChain 实例
+//	@return *SlotRules - 区块时间规则，还没有创世区块时返回 nil
+func (bc *BlockChain) SlotRules() *SlotRules {
+	bc.paramsLock.RLock()
+	defer bc.paramsLock.RUnlock()
+
+	return bc.slotRules
+}
+
+// ChainID
+//
+//	@Description: 获取本地节点所在链的链 ID，交易签名和验证时使用
+//	@receiver BlockChain 实例
+//	@return int64 - 链 ID
+func (bc *BlockChain) ChainID() int64 {
+	bc.paramsLock.RLock()
+	defer bc.paramsLock.RUnlock()
+
+	return bc.chainID
+}
+
+// SetChainID
+//
+//	@Description: 设置配置的链 ID，还没有创世区块时使用，插入创世区块时会被创世参数中的链 ID 覆盖
+//	@receiver BlockChain 实例
+//	@param id - 链 ID
+func (bc *BlockChain) SetChainID(id int64) {
+	bc.paramsLock.Lock()
+	defer bc.paramsLock.Unlock()
+
+	bc.chainID = id
+}
+
+// TxCapacity
+//
+//	@Description: 链每秒最多可以打包的交易数量，由每个区块最多打包的交易数量和出块时隙长度决定，
+//	还没有创世参数时使用默认的时隙长度
+//	@receiver BlockChain 实例
+//	@return int64 - 每秒最多打包的交易数量
+func (bc *BlockChain) TxCapacity() int64 {
+	interval := int64(DefaultSlotInterval)
+	if rules := bc.SlotRules(); rules != nil && rules.SlotInterval > 0 {
+		interval = rules.SlotInterval
+	}
+	return maxTxPackageCount * 1000 / interval
+}
+
+// SetTxPool
+//
+//	@Description: 设置区块链使用的交易池，区块写入数据库时从交易池中移除区块中的交易
+//	@receiver BlockChain 实例
+//	@param pool - 交易池实例
+func (bc *BlockChain) SetTxPool(pool *TxPool) {
+	bc.paramsLock.Lock()
+	defer bc.paramsLock.Unlock()
+
+	bc.txPool = pool
+}
+
+// TxPool
+//
+//	@Description: 获取区块链使用的交易池
+//	@receiver BlockChain 实例
+//	@return *TxPool - 交易池实例，没有设置时返回 nil
+func (bc *BlockChain) TxPool() *TxPool {
+	bc.paramsLock.RLock()
+	defer bc.paramsLock.RUnlock()
+
+	return bc.txPool
 }
 
 // genesisInitialization
 //
-//	@Description: 传入创世区块，读取区块数据并初始化 VDF 计算
+//	@Description: 传入创世区块，读取区块数据并初始化 VDF 计算，创世参数在 paramsLock 下一次性写入
 //	@receiver BlockChain 实例
 //	@param block - 创世区块
-func (bc *BlockChain) genesisInitialization(block *common.Block) {
-	if block != nil {
-		// todo: 错误处理
-		// 对区块中的参数进行反序列化
-		genesisParams, _ := utils.DeserializeGenesisParams(block.Header.Params)
-		bc.genesisParams = genesisParams
-		bc.genesisTime = block.Header.Timestamp
+//	@return error - 创世参数解析失败或者 VDF 初始化失败时返回错误，此时不修改任何创世参数
+func (bc *BlockChain) genesisInitialization(block *common.Block) error {
+	if block == nil {
+		return errGenesisNotFound
+	}
 
-		pp := new(big.Int)
-		order := new(big.Int)
-		// 设置大整数的值
-		pp.SetBytes(genesisParams.VerifyParam[:])
-		order.SetBytes(genesisParams.Order[:])
+	// 对区块中的参数进行反序列化
+	genesisParams, err := utils.DeserializeGenesisParams(block.Header.Params)
+	if err != nil {
+		log.WithError(err).Errorln("Deserialize genesis params failed.")
+		return err
+	}
+	rules := NewSlotRules(block.Header.Timestamp, genesisParams)
+
+	// 根据创世参数选择 VDF 方案并初始化
+	vdf, err := crypto.NewVDF(genesisParams)
+	if err != nil {
+		log.WithError(err).Errorln("Create VDF from genesis params failed.")
+		return err
+	}
+	calculator := crypto.NewCalculator(vdf)
+	calculator.SetCheckpointStore(&vdfCheckpointStore{db: bc.db})
+	weights := bc.loadGenesisWeights()
+
+	bc.paramsLock.Lock()
+	defer bc.paramsLock.Unlock()
 
-		// 初始化
-		crypto.CalculatorInitialization(pp, order, genesisParams.TimeParam)
-		log.Infoln("Genesis params initialization.")
+	bc.genesisParams = genesisParams
+	bc.genesisTime = block.Header.Timestamp
+	if bc.calculator == nil {
+		bc.calculator = calculator
 	}
+	if bc.slotRules == nil {
+		bc.slotRules = rules
+	}
+	bc.weights = weights
+	bc.chainID = genesisParams.ChainID
+	log.Infoln("Genesis params initialization.")
+	return nil
+}
+
+// currentGenesisParams
+//
+//	@Description: 获取创世参数，创世参数在插入创世区块时写入，读取时需要加锁
+//	@receiver BlockChain 实例
+//	@return *common.GenesisParams - 创世参数，还没有创世区块时返回 nil
+func (bc *BlockChain) currentGenesisParams() *common.GenesisParams {
+	bc.paramsLock.RLock()
+	defer bc.paramsLock.RUnlock()
+
+	return bc.genesisParams
 }
 
 // ReadAddressData
@@ -771,3 +1061,32 @@ func isPrevBlock(prev *common.Block, block *common.Block) bool {
 	blockPrevHash := block.Header.PrevBlockHash[:]
 	return bytes.Compare(prevBlockHash, blockPrevHash) == 0
 }
+
+// SetConsensusKey
+//
+//	@Description: 设置打包区块使用的共识私钥，同一个进程中运行多个节点时每个节点使用不同的私钥
+//	@receiver BlockChain 实例
+//	@param prv - 共识私钥
+func (bc *BlockChain) SetConsensusKey(prv *ecdsa.PrivateKey) {
+	bc.paramsLock.Lock()
+	defer bc.paramsLock.Unlock()
+
+	bc.consensusKey = prv
+}
+
+// ConsensusKey
+//
+//	@Description: 获取打包区块使用的共识私钥，没有设置时读取配置文件中的 consensus.prv
+//	@receiver BlockChain 实例
+//	@return *ecdsa.PrivateKey - 共识私钥
+//	@return error - 读取配置文件中的私钥失败时返回错误
+func (bc *BlockChain) ConsensusKey() (*ecdsa.PrivateKey, error) {
+	bc.paramsLock.RLock()
+	prv := bc.consensusKey
+	bc.paramsLock.RUnlock()
+
+	if prv != nil {
+		return prv, nil
+	}
+	return loadConsensusKey()
+}
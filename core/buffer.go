@@ -7,6 +7,8 @@
 package core
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/crypto"
@@ -14,6 +16,7 @@ import (
 	"github.com/chain-lab/go-norn/utils"
 	lru "github.com/hashicorp/golang-lru"
 	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
 	"math/big"
 	"sync"
 	"time"
@@ -26,7 +29,11 @@ const (
 	maxKnownBlock       = 2048                   // lru 缓冲下最多存放多少区块
 	maxProcessedBlock   = 2048                   // lru 缓冲下最多存放多少区块
 	maxQueueBlock       = 1024                   // 区块处理第二队列最多存放多少区块
-	maxBufferSize       = 12                     // buffer 缓冲多少高度时弹出一个区块
+	MaxBufferSize       = 12                     // buffer 缓冲多少高度时弹出一个区块
+)
+
+var (
+	errCalculatorNotInit = errors.New("vdf calculator not initialized")
 )
 
 // BlockBuffer 维护一个树形结构的缓冲区，保存当前视图下的区块信息
@@ -45,12 +52,30 @@ type BlockBuffer struct {
 	latestBlockHeight int64         // 当前 db 中存储的最新区块的高度
 	latestBlock       *common.Block // 当前 db 中存储的最新区块
 	bufferedHeight    int64         // 缓存视图的最新高度
-	bufferFull        bool          // 缓存高度是否到达 maxBufferSize
+	bufferFull        bool          // 缓存高度是否到达 MaxBufferSize
 
 	updateLock sync.RWMutex // 视图更新的读写锁
+
+	calculator *crypto.Calculator // 区块链的 VDF 计算实例，用于验证区块的 VDF 参数
+	rules      *SlotRules         // 区块时间规则，为 nil 时不检查区块时间戳
+	clock      utils.Clock        // 第二队列处理使用的时钟
+
+	// 检查区块参数中的状态根，为 nil 时不检查，由区块链在创建缓冲区后设置
+	verifyState func(block *common.Block, parent *common.Block) error
+
+	cancel context.CancelFunc // 停止缓冲区的处理协程
+	wg     sync.WaitGroup
 }
 
-func NewBlockBuffer(latest *common.Block, popChan chan *common.Block) (*BlockBuffer, error) {
+func NewBlockBuffer(latest *common.Block, popChan chan *common.Block,
+	calculator *crypto.Calculator, rules *SlotRules, clock utils.Clock) (*BlockBuffer, error) {
+	if calculator == nil {
+		return nil, errCalculatorNotInit
+	}
+	if clock == nil {
+		clock = utils.NewRealClock()
+	}
+
 	knownBlock, err := lru.New(maxKnownBlock)
 	if err != nil {
 		log.WithField("error", err).Debug("Create known block cache failed.")
@@ -79,19 +104,49 @@ func NewBlockBuffer(latest *common.Block, popChan chan *common.Block) (*BlockBuf
 		latestBlock:       latest,
 		bufferedHeight:    latest.Header.Height,
 		bufferFull:        false,
+
+		calculator: calculator,
+		rules:      rules,
+		clock:      clock,
 	}
 
+	return buffer, nil
+}
+
+// Start 启动缓冲区的两个处理协程
+func (b *BlockBuffer) Start(ctx context.Context) error {
+	ctx, b.cancel = context.WithCancel(ctx)
+
 	metrics.RoutineCreateCounterObserve(8)
-	go buffer.Process()
-	go buffer.secondProcess()
+	b.wg.Add(2)
+	go func() {
+		defer b.wg.Done()
+		b.Process(ctx)
+	}()
+	go func() {
+		defer b.wg.Done()
+		b.secondProcess(ctx)
+	}()
+
+	return nil
+}
 
-	return buffer, nil
+// Stop 停止缓冲区的处理协程，并等待协程退出
+func (b *BlockBuffer) Stop(ctx context.Context) error {
+	if b.cancel == nil {
+		return nil
+	}
+
+	b.cancel()
+	return utils.WaitGroupContext(ctx, &b.wg)
 }
 
-// Process 是 BlockBuffer 的线程函数，它依次接收区块进行处理
-func (b *BlockBuffer) Process() {
+// Process 是 BlockBuffer 的线程函数，它依次接收区块进行处理，ctx 结束时退出
+func (b *BlockBuffer) Process(ctx context.Context) {
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case block := <-b.blockChan:
 			// 取得区块和其前一个区块的哈希值
 			prevBlockHash := block.PrevBlockHash()
@@ -140,8 +195,23 @@ func (b *BlockBuffer) Process() {
 
 			b.processedBlocks.Add(blockHash, nil)
 
+			// 父区块为最新区块或者前一个高度的选定区块，区块时间戳需要满足时隙规则
+			parent := b.latestBlock
+			if prevBlockHash != b.latestBlock.BlockHash() {
+				parent = prevHeightBlock
+			}
+			if b.rules != nil {
+				if err := b.rules.VerifyBlock(block, parent); err != nil {
+					log.WithError(err).WithField("height",
+						blockHeight).Debugln("Verify block timestamp failed.")
+
+					b.updateLock.Unlock()
+					break
+				}
+			}
+
 			// 区块的 VDF 验证过程，如果满足对比条件则需要进行 VDF 验证
-			calculator := crypto.GetCalculatorInstance()
+			calculator := b.calculator
 			seed := new(big.Int)
 			proof := new(big.Int)
 
@@ -161,6 +231,16 @@ func (b *BlockBuffer) Process() {
 			}
 			log.Debugf("seed after verify: %s", hex.EncodeToString(seed.Bytes()))
 
+			if b.verifyState != nil {
+				if err := b.verifyState(block, parent); err != nil {
+					log.WithError(err).WithField("height",
+						blockHeight).Debugln("Verify block data root failed.")
+
+					b.updateLock.Unlock()
+					break
+				}
+			}
+
 			// 获取这个区块高度下已经选定的区块
 			selected, _ := b.selectedBlock[blockHeight]
 			replaced := false
@@ -181,7 +261,7 @@ func (b *BlockBuffer) Process() {
 				b.updateTreeView(blockHeight)
 			}
 
-			if block.Header.Height-b.latestBlockHeight > maxBufferSize {
+			if block.Header.Height-b.latestBlockHeight > MaxBufferSize {
 				b.popChan <- b.PopSelectedBlock()
 				b.bufferFull = true
 			}
@@ -192,13 +272,22 @@ func (b *BlockBuffer) Process() {
 	}
 }
 
-func (b *BlockBuffer) secondProcess() {
-	timer := time.NewTicker(secondQueueInterval)
+func (b *BlockBuffer) secondProcess(ctx context.Context) {
+	timer := b.clock.NewTicker(secondQueueInterval)
+	defer timer.Stop()
+
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		// 接收计时器到期事件
-		case <-timer.C:
-			block := <-b.secondChan
+		case <-timer.C():
+			var block *common.Block
+			select {
+			case block = <-b.secondChan:
+			case <-ctx.Done():
+				return
+			}
 			metrics.SecondBufferDec()
 			log.WithField("height", block.Header.Height).Debugln("Pop block from second channel.")
 
@@ -234,7 +323,7 @@ func (b *BlockBuffer) secondProcess() {
 			}
 			b.processedBlocks.Add(blockHash, nil)
 
-			calculator := crypto.GetCalculatorInstance()
+			calculator := b.calculator
 			seed := new(big.Int)
 			proof := new(big.Int)
 
@@ -248,6 +337,18 @@ func (b *BlockBuffer) secondProcess() {
 				break
 			}
 
+			// 父区块为最新区块或者前一个高度的选定区块，状态根在父区块的状态上验证
+			if b.verifyState != nil {
+				parent := b.latestBlock
+				if prevBlockHash != b.latestBlock.BlockHash() {
+					parent = prevHeightBlock
+				}
+				if err := b.verifyState(block, parent); err != nil {
+					b.updateLock.Unlock()
+					break
+				}
+			}
+
 			log.WithField("height", blockHeight).Debugln("[Second channel] Create block map.")
 
 			replaced := false
@@ -265,7 +366,7 @@ func (b *BlockBuffer) secondProcess() {
 				b.updateTreeView(blockHeight)
 			}
 
-			if block.Header.Height-b.latestBlockHeight > maxBufferSize {
+			if block.Header.Height-b.latestBlockHeight > MaxBufferSize {
 				b.bufferFull = true
 				b.popChan <- b.PopSelectedBlock()
 			}
@@ -306,6 +407,37 @@ func (b *BlockBuffer) AppendBlock(block *common.Block) {
 	b.blockChan <- block
 }
 
+// Known 区块是否已经进入过缓冲区，包括还在队列中等待父区块的区块
+func (b *BlockBuffer) Known(hash string) bool {
+	return b.knownBlocks.Contains(hash)
+}
+
+// GetSelectedBlock
+//
+//	@Description: 在当前视图的已选定区块中查找对应哈希值的区块
+//	@receiver b
+//	@param hash - hex 格式的区块哈希值
+//	@return *common.Block - 对应的区块，不在视图中时返回 nil
+func (b *BlockBuffer) GetSelectedBlock(hash string) *common.Block {
+	b.updateLock.RLock()
+	defer b.updateLock.RUnlock()
+
+	for _, block := range b.selectedBlock {
+		if block != nil && block.BlockHash() == hash {
+			return block
+		}
+	}
+	return nil
+}
+
+// GetSelectedBlockByHeight 当前视图中某个高度的已选定区块，没有选定区块时返回 nil
+func (b *BlockBuffer) GetSelectedBlockByHeight(height int64) *common.Block {
+	b.updateLock.RLock()
+	defer b.updateLock.RUnlock()
+
+	return b.selectedBlock[height]
+}
+
 // GetPriorityLeaf 获取当前视图下的最优树叶
 func (b *BlockBuffer) GetPriorityLeaf(nowHeight int64) *common.Block {
 	log.Traceln("Start get priority leaf.")
@@ -378,6 +510,12 @@ func compareBlock(origin *common.Block, block *common.Block) (*common.Block, boo
 		if origin.Header.Timestamp < block.Header.Timestamp {
 			return origin, false
 		}
+
+		// 时间戳相同时按照区块哈希选择，保证所有节点不论收到区块的顺序如何都选出同一个区块
+		if origin.Header.Timestamp == block.Header.Timestamp &&
+			bytes.Compare(origin.Header.BlockHash[:], block.Header.BlockHash[:]) <= 0 {
+			return origin, false
+		}
 		return block, true
 	}
 
@@ -7,6 +7,7 @@
 package core
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -75,7 +76,7 @@ func testCreateTransactionList() []common.Transaction {
 	res := make([]common.Transaction, 0, count)
 
 	for i := 0; i < count; i++ {
-		tx := buildTransaction(privateKey)
+		tx := buildTransaction(privateKey, 0)
 		res = append(res, *tx)
 	}
 	return res
@@ -119,13 +120,12 @@ func TestBlockBuffer(t *testing.T) {
 	// upd(2023/3/23): 这里修改了推出区块的逻辑，测试代码未修改，可能无法通过测试
 	log.SetLevel(log.TraceLevel)
 	genesisBlock := testCreateBlock(nil, nil)
-	buffer, err := NewBlockBuffer(genesisBlock, nil)
-	go buffer.Process()
-	go buffer.secondProcess()
-
+	buffer, err := NewBlockBuffer(genesisBlock, nil, nil, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
+	_ = buffer.Start(context.Background())
+	defer buffer.Stop(context.Background())
 
 	testRandomCreateLayer(buffer)
 
@@ -152,3 +152,17 @@ func TestBlockBuffer(t *testing.T) {
 		t.Fatal("Pop block from view failed.")
 	}
 }
+
+func TestCompareBlockSameTimestamp(t *testing.T) {
+	genesisBlock := testCreateBlock(nil, nil)
+	a := testCreateBlock(genesisBlock, nil)
+	b := *a
+	b.Header.BlockHash[0] = ^a.Header.BlockHash[0]
+
+	// 时间戳相同时，不论区块到达的顺序如何都选出同一个区块
+	first, _ := compareBlock(a, &b)
+	second, _ := compareBlock(&b, a)
+	if first != second {
+		t.Fatal("block selection depends on arrival order")
+	}
+}
@@ -0,0 +1,58 @@
+// Package core
+// @Description: 读取配置中的创世仪式参数文件，创建和接收创世区块时使用
+package core
+
+import (
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/gookit/config/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+// loadGenesisCeremony
+//
+//	@Description: 读取 consensus.ceremony 配置的仪式参数文件并进行校验
+//	@return *crypto.CeremonyParams - 仪式参数，未配置时返回 nil
+//	@return error - 文件读取失败或校验失败时返回错误
+func loadGenesisCeremony() (*crypto.CeremonyParams, error) {
+	path := config.String("consensus.ceremony")
+	if path == "" {
+		return nil, nil
+	}
+
+	params, err := crypto.LoadCeremonyParams(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = params.Validate(); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// verifyGenesisCeremony
+//
+//	@Description: 检查创世区块中的 VDF 参数是否由本地配置的仪式生成，未配置仪式参数时不做检查
+//	@param block - 创世区块
+//	@return bool - 是否通过检查
+func verifyGenesisCeremony(block *common.Block) bool {
+	ceremony, err := loadGenesisCeremony()
+	if err != nil {
+		log.WithError(err).Errorln("Load genesis ceremony params failed.")
+		return false
+	}
+
+	if ceremony == nil {
+		return true
+	}
+
+	genesisParams, err := utils.DeserializeGenesisParams(block.Header.Params)
+	if err != nil {
+		return false
+	}
+
+	return ceremony.MatchGenesisParams(genesisParams)
+}
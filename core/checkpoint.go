@@ -0,0 +1,43 @@
+// Package core
+// @Description: VDF 计算检查点在数据库中的存储，节点重启后计算器可以从检查点继续计算
+package core
+
+import (
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/interfaces"
+	"github.com/chain-lab/go-norn/utils"
+)
+
+type vdfCheckpointStore struct {
+	db interfaces.DBInterface
+}
+
+// SaveVDFCheckpoint
+//
+//	@Description: 保存 VDF 计算检查点，只保留最新的一个检查点
+//	@receiver s - 检查点存储实例
+//	@param cp - 检查点
+//	@return error - 错误信息
+func (s *vdfCheckpointStore) SaveVDFCheckpoint(cp *common.VDFCheckpoint) error {
+	data, err := utils.SerializeVDFCheckpoint(cp)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Insert(utils.VDFCheckpointDBKey(), data)
+}
+
+// LoadVDFCheckpoint
+//
+//	@Description: 读取数据库中的 VDF 计算检查点
+//	@receiver s - 检查点存储实例
+//	@return *common.VDFCheckpoint - 检查点
+//	@return error - 检查点不存在时返回数据库的错误
+func (s *vdfCheckpointStore) LoadVDFCheckpoint() (*common.VDFCheckpoint, error) {
+	data, err := s.db.Get(utils.VDFCheckpointDBKey())
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.DeserializeVDFCheckpoint(data)
+}
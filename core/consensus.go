@@ -0,0 +1,154 @@
+// Package core
+// @Description: VRF 共识阈值的计算，阈值由创世参数中的期望出块数量、总权重以及链上登记的节点权重决定
+package core
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	log "github.com/sirupsen/logrus"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ConsensusThreshold
+//
+//	@Description: 获取某个打包节点的 VRF 共识阈值，本地打包和验证远端区块时都使用该阈值
+//	@receiver BlockChain 实例
+//	@param publicKey - 打包节点的公钥
+//	@return *big.Int - VRF 输出需要满足的阈值，旧版本的链返回 nil
+func (bc *BlockChain) ConsensusThreshold(publicKey [33]byte) *big.Int {
+	params := bc.currentGenesisParams()
+	if params == nil {
+		return nil
+	}
+
+	weight := bc.ProducerWeight(publicKey)
+	return crypto.VRFConsensusThreshold(params.ExpectedProducers, weight,
+		params.TotalWeight)
+}
+
+// ProducerWeight
+//
+//	@Description: 读取打包节点在创世时登记的权重。创世参数中的 WeightRegistry 为权重登记的数据地址，
+//	登记的数据 key 为公钥的 16 进制编码，value 为十进制的权重值；未设置登记地址或未登记的节点使用默认权重。
+//	权重取自创世数据的快照，而不是当前的 data# 状态，各个节点无论提交到哪个高度，验证同一个区块时得到的阈值都相同
+//	@receiver BlockChain 实例
+//	@param publicKey - 打包节点的公钥
+//	@return int64 - 节点的权重
+func (bc *BlockChain) ProducerWeight(publicKey [33]byte) int64 {
+	bc.paramsLock.RLock()
+	defer bc.paramsLock.RUnlock()
+
+	weight, ok := bc.weights[hex.EncodeToString(publicKey[:])]
+	if !ok {
+		return crypto.DefaultProducerWeight
+	}
+
+	return weight
+}
+
+// genesisWeights
+//
+//	@Description: 从创世数据中取出登记地址下的打包节点权重，权重格式不合法的数据被忽略
+//	@param alloc - 创世数据
+//	@param registry - 权重登记的数据地址，16 进制编码
+//	@return map[string]int64 - 公钥的 16 进制编码到权重的映射
+func genesisWeights(alloc []GenesisAlloc, registry string) map[string]int64 {
+	weights := make(map[string]int64)
+	if registry == "" {
+		return weights
+	}
+
+	for _, a := range alloc {
+		if !strings.EqualFold(a.Address, registry) {
+			continue
+		}
+
+		weight, err := strconv.ParseInt(strings.TrimSpace(a.Value), 10, 64)
+		if err != nil || weight < 0 {
+			log.WithField("weight", a.Value).Debugln("Parse producer weight failed.")
+			continue
+		}
+		weights[a.Key] = weight
+	}
+
+	return weights
+}
+
+// writeGenesisWeights
+//
+//	@Description: 将创世数据中登记的打包节点权重快照写入数据库，与创世数据一同写入
+//	@receiver bc - BlockChain 实例
+//	@param alloc - 创世数据
+//	@param registry - 权重登记的数据地址，16 进制编码，为空时快照为空
+//	@return error - 写入数据库失败时返回错误
+func (bc *BlockChain) writeGenesisWeights(alloc []GenesisAlloc, registry string) error {
+	weights := genesisWeights(alloc, registry)
+	data, err := json.Marshal(weights)
+	if err != nil {
+		return err
+	}
+
+	return bc.db.Insert(utils.GenesisWeightsDBKey(), data)
+}
+
+// loadGenesisWeights
+//
+//	@Description: 从数据库中读取创世时的权重快照，没有快照时所有节点使用默认权重
+//	@receiver bc - BlockChain 实例
+//	@return map[string]int64 - 公钥的 16 进制编码到权重的映射
+func (bc *BlockChain) loadGenesisWeights() map[string]int64 {
+	weights := make(map[string]int64)
+	data, err := bc.db.Get(utils.GenesisWeightsDBKey())
+	if err != nil || len(data) == 0 {
+		return weights
+	}
+
+	if err = json.Unmarshal(data, &weights); err != nil {
+		log.WithError(err).Errorln("Decode genesis weights failed.")
+	}
+	return weights
+}
+
+// VRFVersion
+//
+//	@Description: 获取当前链所使用的 VRF 版本，旧版本的链创世参数中该字段为 0
+//	@receiver BlockChain 实例
+//	@return uint8 - VRF 版本
+func (bc *BlockChain) VRFVersion() uint8 {
+	params := bc.currentGenesisParams()
+	if params == nil {
+		return crypto.VRFVersionLegacy
+	}
+
+	return params.VRFVersion
+}
+
+// VerifyHeaderVRF
+//
+//	@Description: 验证区块头中的 VRF 证明，并检查 VRF 输出是否满足打包节点的共识阈值
+//	@receiver bc
+//	@param header - 需要验证的区块头
+//	@return bool - 验证结果
+func (bc *BlockChain) VerifyHeaderVRF(header *common.BlockHeader) bool {
+	params, err := utils.DeserializeGeneralParams(header.Params)
+	if err != nil {
+		log.WithField("error", err).Warning("Deserialize params failed.")
+		return false
+	}
+
+	publicKey := crypto.Bytes2PublicKey(header.PublicKey[:])
+	if publicKey.X == nil || publicKey.Y == nil {
+		return false
+	}
+
+	verified, err := crypto.VRFCheckRemoteConsensus(bc.VRFVersion(), publicKey,
+		params.Result, params.S, params.T, params.RandomNumber[:],
+		bc.ConsensusThreshold(header.PublicKey))
+
+	return err == nil && verified
+}
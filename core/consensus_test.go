@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	"testing"
+)
+
+func TestProducerWeightGenesisSnapshot(t *testing.T) {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	producer := [33]byte(crypto.PublicKey2Bytes(&prv.PublicKey))
+	producerKey := hex.EncodeToString(producer[:])
+	registry := "0a0f870f81376f77db1981f94f39b719f5eb3f7c"
+
+	params, err := crypto.GenerateGenesisParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TimeParam = 1000
+	registryBytes, _ := hex.DecodeString(registry)
+	params.WeightRegistry = [20]byte(registryBytes)
+
+	alloc := []GenesisAlloc{
+		{Address: registry, Key: producerKey, Value: "5"},
+		{Address: registry, Key: "invalid", Value: "-1"},
+	}
+	g, err := SealGenesis(7, 1700000000000, params, alloc, prv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := utils.NewMemoryLevelDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := NewBlockchain(db, nil, nil)
+	if err = chain.InitGenesis(g); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = chain.Calculator().Stop(context.Background()) })
+
+	if weight := chain.ProducerWeight(producer); weight != 5 {
+		t.Fatalf("Unexpected genesis weight %d.", weight)
+	}
+
+	// 创世之后对登记地址的写入不影响共识阈值
+	dbKey := utils.DataAddressKey2DBKey(registryBytes, []byte(producerKey))
+	if err = db.Insert(dbKey, []byte("100")); err != nil {
+		t.Fatal(err)
+	}
+	if weight := chain.ProducerWeight(producer); weight != 5 {
+		t.Fatalf("Producer weight follows current state, got %d.", weight)
+	}
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if weight := chain.ProducerWeight([33]byte(crypto.PublicKey2Bytes(&other.PublicKey))); weight != crypto.DefaultProducerWeight {
+		t.Fatalf("Unregistered producer weight %d.", weight)
+	}
+
+	// 重启后从数据库中读取快照
+	restarted := NewBlockchain(db, nil, nil)
+	block, _ := chain.GetBlockByHeight(0)
+	if err := restarted.genesisInitialization(block); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = restarted.Calculator().Stop(context.Background()) })
+	if weight := restarted.ProducerWeight(producer); weight != 5 {
+		t.Fatalf("Genesis weights lost after restart, got %d.", weight)
+	}
+}
@@ -3,6 +3,7 @@
 package core
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"github.com/chain-lab/go-norn/common"
@@ -11,6 +12,7 @@ import (
 	"github.com/chain-lab/go-norn/utils"
 	log "github.com/sirupsen/logrus"
 	"strconv"
+	"sync"
 )
 
 const (
@@ -33,6 +35,10 @@ type DataTask struct {
 type DataProcessor struct {
 	taskChannel chan *DataTask         // processor 的任务接收队列
 	db          interfaces.DBInterface // 数据库实例
+	router      *pubsub.EventRouter    // 数据变更事件的发布路由
+
+	cancel context.CancelFunc // 停止处理协程
+	wg     sync.WaitGroup
 }
 
 // NewDataProcessor
@@ -47,34 +53,85 @@ func NewDataProcessor() *DataProcessor {
 	}
 }
 
+// Start
+//
+//	@Description: 启动数据处理协程
+//	@receiver DataProcessor 实例
+//	@param ctx - 父 context
+//	@return error
+func (dp *DataProcessor) Start(ctx context.Context) error {
+	ctx, dp.cancel = context.WithCancel(ctx)
+
+	dp.wg.Add(1)
+	go dp.Run(ctx)
+	return nil
+}
+
+// Stop
+//
+//	@Description: 停止数据处理协程，队列中剩余的任务会在协程退出前处理完成
+//	@receiver DataProcessor 实例
+//	@param ctx - 停止的超时 context
+//	@return error - 超时时返回错误
+func (dp *DataProcessor) Stop(ctx context.Context) error {
+	if dp.cancel == nil {
+		return nil
+	}
+
+	dp.cancel()
+	return utils.WaitGroupContext(ctx, &dp.wg)
+}
+
 // Run
 //
-//	@Description: 数据处理器的运行函数
+//	@Description: 数据处理器的运行函数，ctx 结束时处理完队列中剩余的任务后退出
 //	@receiver DataProcessor 实例
-func (dp *DataProcessor) Run() {
+//	@param ctx - 处理协程的 context
+func (dp *DataProcessor) Run(ctx context.Context) {
+	defer dp.wg.Done()
+
 	// 无限循环处理任务
 	for {
 		select {
 		// todo: batch insert with a buffer
 		// 如果 channel 中存在任务待处理，则取出
 		case task := <-dp.taskChannel:
-			if task.Type == setCommandString {
-				dp.setData(task)
-			} else if task.Type == appendCommandString {
-				taskValue := task.Value
-				mapValue := map[string]string{}
-				err := json.Unmarshal(taskValue, &mapValue)
-
-				if err != nil {
-					log.WithError(err).Errorln("Receive append task failed.")
-					continue
+			dp.process(task)
+		case <-ctx.Done():
+			for {
+				select {
+				case task := <-dp.taskChannel:
+					dp.process(task)
+				default:
+					log.Infoln("Data processor exit.")
+					return
 				}
-				dp.appendData(task)
 			}
 		}
 	}
 }
 
+// process
+//
+//	@Description: 处理单个数据任务
+//	@receiver DataProcessor 实例
+//	@param task - 数据任务
+func (dp *DataProcessor) process(task *DataTask) {
+	if task.Type == setCommandString {
+		dp.setData(task)
+	} else if task.Type == appendCommandString {
+		taskValue := task.Value
+		mapValue := map[string]string{}
+		err := json.Unmarshal(taskValue, &mapValue)
+
+		if err != nil {
+			log.WithError(err).Errorln("Receive append task failed.")
+			return
+		}
+		dp.appendData(task)
+	}
+}
+
 // setData
 //
 //	@Description: 设置数据任务，它会覆盖相同 address、 key 下的数据
@@ -111,7 +168,6 @@ func (dp *DataProcessor) setData(task *DataTask) {
 	}
 
 	// 触发数据变更事件
-	router := pubsub.CreateNewEventRouter()
 	event := pubsub.Event{
 		Type:    "data",
 		Hash:    hex.EncodeToString(task.Hash[:]),
@@ -120,8 +176,7 @@ func (dp *DataProcessor) setData(task *DataTask) {
 		Params:  params,
 	}
 
-	log.Infof("Append task to router.")
-	router.AppendEvent(event)
+	dp.publishEvent(event)
 }
 
 // appendData
@@ -174,7 +229,6 @@ func (dp *DataProcessor) appendData(task *DataTask) {
 	}
 
 	// 触发数据变更事件
-	router := pubsub.CreateNewEventRouter()
 	event := pubsub.Event{
 		Type:    "data",
 		Hash:    hex.EncodeToString(task.Hash[:]),
@@ -183,6 +237,19 @@ func (dp *DataProcessor) appendData(task *DataTask) {
 		Params:  params,
 	}
 
+	dp.publishEvent(event)
+}
+
+// publishEvent
+//
+//	@Description: 将数据变更事件发送到事件路由，没有设置路由时不发布
+//	@receiver DataProcessor 实例
+//	@param event - 数据变更事件
+func (dp *DataProcessor) publishEvent(event pubsub.Event) {
+	if dp.router == nil {
+		return
+	}
+
 	log.Infof("Append task to router.")
-	router.AppendEvent(event)
+	dp.router.AppendEvent(event)
 }
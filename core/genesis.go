@@ -0,0 +1,522 @@
+// Package core
+// @Description: 创世文件的生成与加载。创世区块由 generate 子命令生成到 genesis.json，所有节点从同一个文件加载，
+// 创世区块的哈希由文件内容确定，节点之间在握手时比较创世哈希，避免不同的创世节点生成互不兼容的链
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/gookit/config/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"os"
+)
+
+var (
+	errGenesisHash     = errors.New("genesis hash mismatch")
+	errGenesisSign     = errors.New("genesis signature verify failed")
+	errGenesisMismatch = errors.New("local genesis block not match genesis file")
+	errGenesisAlloc    = errors.New("genesis alloc not available, start with the genesis file")
+	errGenesisNotFound = errors.New("genesis block not found")
+)
+
+// GenesisVDF
+//
+//	@Description: 创世文件中的 VDF 参数，字节数组均使用 16 进制编码
+type GenesisVDF struct {
+	Scheme       uint8  `json:"scheme"`
+	TimeParam    int64  `json:"time_param"`
+	Seed         string `json:"seed"`
+	VerifyParam  string `json:"verify_param"`
+	Order        string `json:"order,omitempty"`
+	Modulus      string `json:"modulus,omitempty"`
+	Discriminant string `json:"discriminant,omitempty"`
+}
+
+// GenesisConsensus
+//
+//	@Description: 创世文件中的共识参数
+type GenesisConsensus struct {
+	ExpectedProducers int64  `json:"expected_producers"`
+	TotalWeight       int64  `json:"total_weight"`
+	WeightRegistry    string `json:"weight_registry,omitempty"`
+	VRFVersion        uint8  `json:"vrf_version"`
+	SlotInterval      int64  `json:"slot_interval"`    // 出块时隙长度，单位 ms
+	MaxFutureDrift    int64  `json:"max_future_drift"` // 区块时间戳允许超前本地逻辑时钟的范围，单位 ms
+	MerkleVersion     uint8  `json:"merkle_version"`   // 交易 Merkle 树的版本，旧的创世文件中没有该字段，为 0
+	DataVersion       uint8  `json:"data_version"`     // data# 状态树的版本，旧的创世文件中没有该字段，为 0
+}
+
+// GenesisAlloc
+//
+//	@Description: 创世时写入的 data# 数据，address 为 20 字节地址的 16 进制编码
+type GenesisAlloc struct {
+	Address string `json:"address"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+}
+
+// Genesis
+//
+//	@Description: 创世文件，Hash 和 Signature 由 generate 子命令填写，加载时会重新计算并校验
+type Genesis struct {
+	ChainID   int64            `json:"chain_id"`
+	Timestamp int64            `json:"timestamp"`
+	PublicKey string           `json:"public_key"`
+	VDF       GenesisVDF       `json:"vdf"`
+	Consensus GenesisConsensus `json:"consensus"`
+	Alloc     []GenesisAlloc   `json:"alloc,omitempty"`
+	Hash      string           `json:"hash"`
+	Signature string           `json:"signature"`
+}
+
+// NewGenesis
+//
+//	@Description: 根据配置文件中的共识参数生成创世文件，并使用 consensus.prv 对创世区块进行签名
+//	@param chainID - 链 ID
+//	@param timestamp - 创世区块的时间戳
+//	@param alloc - 创世时写入的数据
+//	@return *Genesis - 创世文件
+//	@return error - 错误信息
+func NewGenesis(chainID int64, timestamp int64, alloc []GenesisAlloc) (*Genesis, error) {
+	genesisParams, err := genesisParamsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	prv, err := loadConsensusKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return SealGenesis(chainID, timestamp, genesisParams, alloc, prv)
+}
+
+// SealGenesis
+//
+//	@Description: 使用给定的创世参数和私钥生成创世文件，模拟测试中使用较小的 VDF 时间参数时直接调用
+//	@param chainID - 链 ID
+//	@param timestamp - 创世区块的时间戳
+//	@param genesisParams - 创世参数，链 ID 和创世数据的根哈希由本函数填写，未设置的时隙参数使用默认值
+//	@param alloc - 创世时写入的数据
+//	@param prv - 创世区块的签名私钥
+//	@return *Genesis - 创世文件
+//	@return error - 错误信息
+func SealGenesis(chainID int64, timestamp int64, genesisParams *common.GenesisParams,
+	alloc []GenesisAlloc, prv *ecdsa.PrivateKey) (*Genesis, error) {
+	var err error
+	genesisParams.ChainID = chainID
+	if genesisParams.SlotInterval <= 0 {
+		genesisParams.SlotInterval = DefaultSlotInterval
+	}
+	if genesisParams.MaxFutureDrift <= 0 {
+		genesisParams.MaxFutureDrift = DefaultMaxFutureDrift
+	}
+	genesisParams.AllocRoot, err = genesisAllocRoot(alloc)
+	if err != nil {
+		return nil, err
+	}
+	genesisParams.DataRoot = [32]byte{}
+	if genesisParams.DataVersion != DataVersionNone {
+		if genesisParams.DataRoot, err = genesisDataRoot(nil, alloc); err != nil {
+			return nil, err
+		}
+	}
+
+	// 创世区块中的公钥由签名私钥得到，保证签名可以通过验证
+	g := &Genesis{
+		ChainID:   chainID,
+		Timestamp: timestamp,
+		PublicKey: hex.EncodeToString(crypto.PublicKey2Bytes(&prv.PublicKey)),
+		Alloc:     alloc,
+	}
+	g.setParams(genesisParams)
+
+	block, err := g.buildBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// 计算区块头哈希并填充到区块头，同时对区块头进行签名
+	if err = sealBlockHeader(&block.Header, prv); err != nil {
+		return nil, err
+	}
+
+	g.Hash = hex.EncodeToString(block.Header.BlockHash[:])
+	g.Signature = hex.EncodeToString(block.Header.Signature)
+	return g, nil
+}
+
+// LoadGenesis
+//
+//	@Description: 读取创世文件
+//	@param path - 文件路径
+//	@return *Genesis - 创世文件
+//	@return error - 错误信息
+func LoadGenesis(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	g := new(Genesis)
+	if err = json.Unmarshal(data, g); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// Save
+//
+//	@Description: 将创世文件写入磁盘
+//	@receiver g - 创世文件
+//	@param path - 文件路径
+//	@return error - 错误信息
+func (g *Genesis) Save(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// Block
+//
+//	@Description: 由创世文件构建创世区块，并校验区块哈希、签名以及创世数据的根哈希
+//	@receiver g - 创世文件
+//	@return *common.Block - 创世区块
+//	@return error - 校验失败时返回错误
+func (g *Genesis) Block() (*common.Block, error) {
+	block, err := g.buildBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash, err := BlockHeaderHash(&block.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	if hex.EncodeToString(blockHash[:]) != g.Hash {
+		return nil, errGenesisHash
+	}
+
+	signature, err := hex.DecodeString(g.Signature)
+	if err != nil {
+		return nil, errGenesisSign
+	}
+
+	block.Header.BlockHash = blockHash
+	block.Header.Signature = signature
+	if !VerifyBlockSignature(block) {
+		return nil, errGenesisSign
+	}
+
+	return block, nil
+}
+
+// InitGenesis
+//
+//	@Description: 使用创世文件初始化区块链。本地没有创世区块时记录文件中的创世数据并插入创世区块，
+//	已经存在时检查本地的创世区块是否与文件一致
+//	@receiver bc - BlockChain 实例
+//	@param g - 创世文件
+//	@return error - 创世文件不合法或与本地不一致时返回错误
+func (bc *BlockChain) InitGenesis(g *Genesis) error {
+	block, err := g.Block()
+	if err != nil {
+		return err
+	}
+
+	local, _ := bc.GetBlockByHeight(0)
+	if local != nil {
+		if local.Header.BlockHash != block.Header.BlockHash {
+			return errGenesisMismatch
+		}
+		return nil
+	}
+
+	// 创世数据在插入创世区块时写入
+	bc.paramsLock.Lock()
+	bc.genesisAlloc = g.Alloc
+	bc.paramsLock.Unlock()
+
+	log.WithField("hash", g.Hash[:8]).Infoln("Init chain with genesis file.")
+	bc.AppendBlockTask(block)
+	return nil
+}
+
+// writeGenesisAlloc
+//
+//	@Description: 插入创世区块时写入创世数据、创世数据的状态树以及创世时的权重快照。创世数据来自 InitGenesis 记录的创世文件，
+//	需要与创世区块中的根哈希一致；从其他节点收到的创世区块没有创世数据时，只有区块中的根哈希为空才能插入
+//	@receiver bc - BlockChain 实例
+//	@param block - 创世区块
+//	@return error - 没有与创世区块一致的创世数据或者写入数据库失败时返回错误
+func (bc *BlockChain) writeGenesisAlloc(block *common.Block) error {
+	genesisParams, err := utils.DeserializeGenesisParams(block.Header.Params)
+	if err != nil {
+		return err
+	}
+
+	bc.paramsLock.RLock()
+	alloc := bc.genesisAlloc
+	bc.paramsLock.RUnlock()
+
+	root, err := genesisAllocRoot(alloc)
+	if err != nil {
+		return err
+	}
+	if root != genesisParams.AllocRoot {
+		return errGenesisAlloc
+	}
+
+	for _, a := range alloc {
+		address, _ := hex.DecodeString(a.Address)
+		dbKey := utils.DataAddressKey2DBKey(address, []byte(a.Key))
+		if err = bc.db.Insert(dbKey, []byte(a.Value)); err != nil {
+			return err
+		}
+	}
+
+	// 使用状态树的链同时写入创世数据的状态树，之后的区块在此基础上计算状态根
+	if genesisParams.DataVersion != DataVersionNone {
+		trie := newStateTrie(bc.db)
+		dataRoot, err := genesisDataRoot(trie, alloc)
+		if err != nil {
+			return err
+		}
+		if dataRoot != genesisParams.DataRoot {
+			return errGenesisAlloc
+		}
+		if err = trie.commit(); err != nil {
+			return err
+		}
+	}
+
+	var registry string
+	if genesisParams.WeightRegistry != [20]byte{} {
+		registry = hex.EncodeToString(genesisParams.WeightRegistry[:])
+	}
+	return bc.writeGenesisWeights(alloc, registry)
+}
+
+// GenesisHash
+//
+//	@Description: 获取本地创世区块的哈希值
+//	@receiver bc - BlockChain 实例
+//	@return common.Hash - 创世区块哈希，本地没有创世区块时为空哈希
+func (bc *BlockChain) GenesisHash() common.Hash {
+	block, err := bc.GetBlockByHeight(0)
+	if err != nil || block == nil {
+		return common.Hash{}
+	}
+
+	return block.Header.BlockHash
+}
+
+// buildBlock
+//
+//	@Description: 构建未签名的创世区块，区块哈希与签名为空
+func (g *Genesis) buildBlock() (*common.Block, error) {
+	genesisParams, err := g.params()
+	if err != nil {
+		return nil, err
+	}
+
+	genesisParamsBytes, err := utils.SerializeGenesisParams(genesisParams)
+	if err != nil {
+		log.WithField("error", err).Errorln("Genesis params serialize error.")
+		return nil, err
+	}
+
+	publicKey, err := hex.DecodeString(g.PublicKey)
+	if err != nil || len(publicKey) != 33 {
+		return nil, errors.New("invalid genesis public key")
+	}
+
+	nullHash := common.Hash{}
+	return &common.Block{
+		Header: common.BlockHeader{
+			Timestamp:     g.Timestamp,
+			PrevBlockHash: nullHash,
+			BlockHash:     [32]byte{},
+			MerkleRoot:    [32]byte(nullHash),
+			Height:        0,
+			Params:        genesisParamsBytes,
+			PublicKey:     [33]byte(publicKey),
+		},
+		Transactions: []common.Transaction{},
+	}, nil
+}
+
+// params
+//
+//	@Description: 将创世文件中的参数转换为区块中的创世参数
+func (g *Genesis) params() (*common.GenesisParams, error) {
+	genesisParams := new(common.GenesisParams)
+	genesisParams.ChainID = g.ChainID
+	genesisParams.TimeParam = g.VDF.TimeParam
+	genesisParams.VDFScheme = g.VDF.Scheme
+	genesisParams.ExpectedProducers = g.Consensus.ExpectedProducers
+	genesisParams.TotalWeight = g.Consensus.TotalWeight
+	genesisParams.VRFVersion = g.Consensus.VRFVersion
+	genesisParams.SlotInterval = g.Consensus.SlotInterval
+	genesisParams.MaxFutureDrift = g.Consensus.MaxFutureDrift
+	genesisParams.MerkleVersion = g.Consensus.MerkleVersion
+	genesisParams.DataVersion = g.Consensus.DataVersion
+
+	fixed := []struct {
+		src string
+		dst []byte
+	}{
+		{g.VDF.Seed, genesisParams.Seed[:]},
+		{g.VDF.VerifyParam, genesisParams.VerifyParam[:]},
+		{g.VDF.Order, genesisParams.Order[:]},
+		{g.Consensus.WeightRegistry, genesisParams.WeightRegistry[:]},
+	}
+	for _, f := range fixed {
+		b, err := hex.DecodeString(f.src)
+		if err != nil || (len(b) != 0 && len(b) != len(f.dst)) {
+			return nil, errors.New("invalid genesis params")
+		}
+		copy(f.dst, b)
+	}
+
+	var err error
+	if genesisParams.Modulus, err = hex.DecodeString(g.VDF.Modulus); err != nil {
+		return nil, err
+	}
+	if genesisParams.Discriminant, err = hex.DecodeString(g.VDF.Discriminant); err != nil {
+		return nil, err
+	}
+
+	genesisParams.AllocRoot, err = genesisAllocRoot(g.Alloc)
+	if err != nil {
+		return nil, err
+	}
+	if genesisParams.DataVersion != DataVersionNone {
+		if genesisParams.DataRoot, err = genesisDataRoot(nil, g.Alloc); err != nil {
+			return nil, err
+		}
+	}
+
+	return genesisParams, nil
+}
+
+// setParams
+//
+//	@Description: 将区块中的创世参数写入创世文件
+func (g *Genesis) setParams(genesisParams *common.GenesisParams) {
+	g.VDF = GenesisVDF{
+		Scheme:       genesisParams.VDFScheme,
+		TimeParam:    genesisParams.TimeParam,
+		Seed:         hex.EncodeToString(genesisParams.Seed[:]),
+		VerifyParam:  hex.EncodeToString(genesisParams.VerifyParam[:]),
+		Modulus:      hex.EncodeToString(genesisParams.Modulus),
+		Discriminant: hex.EncodeToString(genesisParams.Discriminant),
+	}
+	if genesisParams.Order != [128]byte{} {
+		g.VDF.Order = hex.EncodeToString(genesisParams.Order[:])
+	}
+
+	g.Consensus = GenesisConsensus{
+		ExpectedProducers: genesisParams.ExpectedProducers,
+		TotalWeight:       genesisParams.TotalWeight,
+		VRFVersion:        genesisParams.VRFVersion,
+		SlotInterval:      genesisParams.SlotInterval,
+		MaxFutureDrift:    genesisParams.MaxFutureDrift,
+		MerkleVersion:     genesisParams.MerkleVersion,
+		DataVersion:       genesisParams.DataVersion,
+	}
+	if genesisParams.WeightRegistry != [20]byte{} {
+		g.Consensus.WeightRegistry = hex.EncodeToString(genesisParams.WeightRegistry[:])
+	}
+}
+
+// genesisParamsFromConfig
+//
+//	@Description: 根据配置文件生成创世参数，配置了仪式参数文件时使用仪式生成的参数，创世节点不知道群的阶
+//	@return *common.GenesisParams - 创世参数
+//	@return error - 错误信息
+func genesisParamsFromConfig() (*common.GenesisParams, error) {
+	ceremony, err := loadGenesisCeremony()
+	if err != nil {
+		log.WithError(err).Errorln("Load genesis ceremony params failed.")
+		return nil, err
+	}
+
+	var genesisParams *common.GenesisParams
+	if ceremony != nil {
+		genesisParams, err = ceremony.GenesisParams()
+	} else {
+		genesisParams, err = crypto.GenerateGenesisParams()
+	}
+	if err != nil {
+		log.WithField("error", err).Errorln("Generate genesis params failed.")
+		return nil, err
+	}
+
+	// 读取配置文件中的共识阈值和出块时隙参数，未配置时使用默认值
+	genesisParams.ExpectedProducers = config.Int64("consensus.expected",
+		genesisParams.ExpectedProducers)
+	genesisParams.TotalWeight = config.Int64("consensus.weight",
+		genesisParams.TotalWeight)
+	genesisParams.SlotInterval = config.Int64("consensus.slot_interval", DefaultSlotInterval)
+	genesisParams.MaxFutureDrift = config.Int64("consensus.max_future_drift", DefaultMaxFutureDrift)
+	// 新创建的链使用完整的二叉 Merkle 树，交易都可以生成 Merkle 证明
+	genesisParams.MerkleVersion = MerkleVersionBinary
+	// 新创建的链在区块参数中记录状态根，轻节点可以验证 data# 数据
+	genesisParams.DataVersion = DataVersionTrie
+	if registry, err := hex.DecodeString(config.String("consensus.registry")); err == nil && len(registry) == 20 {
+		genesisParams.WeightRegistry = [20]byte(registry)
+	}
+
+	// 配置为 classgroup 时使用类群上的 Wesolowski VDF，判别式由创世参数中的种子生成
+	if ceremony == nil && config.String("consensus.vdf") == "classgroup" {
+		genesisParams.VDFScheme = crypto.VDFSchemeClassGroup
+		genesisParams.TimeParam = config.Int64("consensus.vdf_time", crypto.DefaultClassGroupTimeParam)
+		genesisParams.Discriminant = crypto.GenerateClassGroupParams(genesisParams.Seed[:],
+			crypto.DefaultDiscriminantBits)
+	}
+
+	return genesisParams, nil
+}
+
+// genesisAllocRoot
+//
+//	@Description: 计算创世数据的哈希，按照文件中的顺序对每条数据的地址、key、value 进行长度前缀编码后哈希
+//	@param alloc - 创世数据
+//	@return [32]byte - 创世数据的根哈希，没有数据时为空哈希
+//	@return error - 地址格式错误时返回错误
+func genesisAllocRoot(alloc []GenesisAlloc) ([32]byte, error) {
+	if len(alloc) == 0 {
+		return [32]byte{}, nil
+	}
+
+	hash := sha256.New()
+	lengthBytes := make([]byte, 8)
+	for _, a := range alloc {
+		address, err := hex.DecodeString(a.Address)
+		if err != nil || len(address) != 20 {
+			return [32]byte{}, errors.New("invalid genesis alloc address")
+		}
+
+		hash.Write(address)
+		for _, field := range [][]byte{[]byte(a.Key), []byte(a.Value)} {
+			binary.LittleEndian.PutUint64(lengthBytes, uint64(len(field)))
+			hash.Write(lengthBytes)
+			hash.Write(field)
+		}
+	}
+
+	return [32]byte(hash.Sum(nil)), nil
+}
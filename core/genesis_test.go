@@ -0,0 +1,200 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/gookit/config/v2"
+	"path/filepath"
+	"testing"
+)
+
+func testGenesis(t *testing.T) *Genesis {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err := config.Set("consensus.prv", hex.EncodeToString(prv.D.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	alloc := []GenesisAlloc{
+		{Address: "0a0f870f81376f77db1981f94f39b719f5eb3f7c", Key: "weight", Value: "1"},
+	}
+
+	g, err := NewGenesis(7, 1700000000000, alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestGenesisFile(t *testing.T) {
+	g := testGenesis(t)
+
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	if err := g.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadGenesis(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 从文件构建的创世区块与生成时的哈希一致
+	block, err := loaded.Block()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hex.EncodeToString(block.Header.BlockHash[:]) != g.Hash {
+		t.Fatal("Genesis hash not deterministic.")
+	}
+
+	if !block.IsGenesisBlock() {
+		t.Fatal("Block from genesis file is not genesis block.")
+	}
+}
+
+func TestGenesisFileTampered(t *testing.T) {
+	g := testGenesis(t)
+
+	tampered := *g
+	tampered.Alloc = []GenesisAlloc{
+		{Address: "0a0f870f81376f77db1981f94f39b719f5eb3f7c", Key: "weight", Value: "100"},
+	}
+	if _, err := tampered.Block(); err == nil {
+		t.Fatal("Tampered genesis alloc passed verification.")
+	}
+
+	tampered = *g
+	tampered.ChainID = 8
+	if _, err := tampered.Block(); err == nil {
+		t.Fatal("Tampered genesis chain id passed verification.")
+	}
+
+	tampered = *g
+	tampered.Consensus.SlotInterval = 1000
+	if _, err := tampered.Block(); err == nil {
+		t.Fatal("Tampered genesis slot interval passed verification.")
+	}
+
+	tampered = *g
+	tampered.Signature = hex.EncodeToString([]byte{0x30, 0x00})
+	if _, err := tampered.Block(); err == nil {
+		t.Fatal("Tampered genesis signature passed verification.")
+	}
+}
+
+// testGenesisChain 创建没有创世区块的区块链
+func testGenesisChain(t *testing.T) (*BlockChain, *utils.LevelDB) {
+	db, err := utils.NewMemoryLevelDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := NewBlockchain(db, nil, nil)
+	t.Cleanup(func() {
+		if calculator := chain.Calculator(); calculator != nil {
+			_ = calculator.Stop(context.Background())
+		}
+	})
+	return chain, db
+}
+
+func TestGenesisAllocInsert(t *testing.T) {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	params, err := crypto.GenerateGenesisParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TimeParam = 1000
+	registry := "0a0f870f81376f77db1981f94f39b719f5eb3f7c"
+	registryBytes, _ := hex.DecodeString(registry)
+	params.WeightRegistry = [20]byte(registryBytes)
+
+	producer := [33]byte(crypto.PublicKey2Bytes(&prv.PublicKey))
+	producerKey := hex.EncodeToString(producer[:])
+	g, err := SealGenesis(7, 1700000000000, params,
+		[]GenesisAlloc{{Address: registry, Key: producerKey, Value: "5"}}, prv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := g.Block()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 从其他节点收到的创世区块，本地没有与之一致的创世数据时不插入
+	chain, _ := testGenesisChain(t)
+	chain.AppendBlockTask(block)
+	if local, _ := chain.GetBlockByHeight(0); local != nil {
+		t.Fatal("genesis block inserted without alloc")
+	}
+
+	// 从创世文件插入时写入创世数据和权重快照
+	chain, db := testGenesisChain(t)
+	if err = chain.InitGenesis(g); err != nil {
+		t.Fatal(err)
+	}
+	if local, _ := chain.GetBlockByHeight(0); local == nil {
+		t.Fatal("genesis block not inserted")
+	}
+	value, err := db.Get(utils.DataAddressKey2DBKey(registryBytes, []byte(producerKey)))
+	if err != nil || string(value) != "5" {
+		t.Fatalf("genesis alloc not written, value %q", value)
+	}
+	if weight := chain.ProducerWeight(producer); weight != 5 {
+		t.Fatalf("unexpected genesis weight %d", weight)
+	}
+}
+
+func TestGenesisAllocEmpty(t *testing.T) {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	params, err := crypto.GenerateGenesisParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TimeParam = 1000
+	g, err := SealGenesis(7, 1700000000000, params, nil, prv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := g.Block()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有创世数据的创世区块可以从其他节点收到，插入时同样写入权重快照
+	chain, db := testGenesisChain(t)
+	chain.AppendBlockTask(block)
+	if local, _ := chain.GetBlockByHeight(0); local == nil {
+		t.Fatal("genesis block from peer not inserted")
+	}
+	if data, err := db.Get(utils.GenesisWeightsDBKey()); err != nil || len(data) == 0 {
+		t.Fatal("genesis weights snapshot not written")
+	}
+}
+
+func TestGenesisInitializationError(t *testing.T) {
+	chain, _ := testGenesisChain(t)
+
+	// 创世区块不存在或者创世参数无法初始化 VDF 时返回错误，不修改创世参数
+	if err := chain.genesisInitialization(nil); err != errGenesisNotFound {
+		t.Fatalf("unexpected error %v", err)
+	}
+	params, err := utils.SerializeGenesisParams(&common.GenesisParams{VDFScheme: crypto.VDFSchemeClassGroup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &common.Block{Header: common.BlockHeader{Params: params}}
+	if err = chain.genesisInitialization(block); err == nil {
+		t.Fatal("genesis with invalid vdf params initialized")
+	}
+	if chain.currentGenesisParams() != nil || chain.Calculator() != nil ||
+		chain.VRFVersion() != crypto.VRFVersionLegacy {
+		t.Fatal("genesis params set after failed initialization")
+	}
+}
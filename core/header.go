@@ -0,0 +1,113 @@
+// Package core
+// @Description: 区块头的哈希计算、签名以及签名验证
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/gookit/config/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+// BlockHeaderHash
+//
+//	@Description: 计算区块头的哈希值，计算时区块头中的 BlockHash 和 Signature 字段置空
+//	@param header - 区块头，函数内不会修改该区块头
+//	@return common.Hash - 区块头哈希值
+//	@return error - 序列化错误信息
+func BlockHeaderHash(header *common.BlockHeader) (common.Hash, error) {
+	// 对区块头进行拷贝，避免修改原有的区块数据
+	headerCopy := *header
+	headerCopy.BlockHash = [32]byte{}
+	headerCopy.Signature = []byte{}
+
+	byteBlockHeaderData, err := utils.SerializeBlockHeader(&headerCopy)
+	if err != nil {
+		log.WithField("error", err).Errorln("Serialize block header failed.")
+		return common.Hash{}, err
+	}
+
+	hash := sha256.New()
+	hash.Write(byteBlockHeaderData)
+	return common.Hash(hash.Sum(nil)), nil
+}
+
+// sealBlockHeader
+//
+//	@Description: 计算区块头哈希并填入，然后使用打包节点的私钥对区块哈希进行签名
+//	@param header - 需要填充哈希和签名的区块头
+//	@param prv - 打包节点私钥，对应区块头中的 PublicKey
+//	@return error - 错误信息
+func sealBlockHeader(header *common.BlockHeader, prv *ecdsa.PrivateKey) error {
+	blockHash, err := BlockHeaderHash(header)
+	if err != nil {
+		return err
+	}
+
+	signature, err := ecdsa.SignASN1(rand.Reader, prv, blockHash[:])
+	if err != nil {
+		log.WithError(err).Errorln("Sign block header failed.")
+		return err
+	}
+
+	header.BlockHash = blockHash
+	header.Signature = signature
+	return nil
+}
+
+// loadConsensusKey
+//
+//	@Description: 读取配置文件中的共识私钥 consensus.prv
+//	@return *ecdsa.PrivateKey - 共识私钥
+//	@return error - 错误信息
+func loadConsensusKey() (*ecdsa.PrivateKey, error) {
+	prv, err := crypto.DecodePrivateKeyFromHexString(config.String("consensus.prv"))
+	if err != nil {
+		log.WithError(err).Errorln("Get private key from config failed.")
+		return nil, err
+	}
+
+	if prv.D.Sign() == 0 {
+		return nil, errors.New("consensus private key is empty")
+	}
+
+	return prv, nil
+}
+
+// VerifyBlockSignature
+//
+//	@Description: 验证区块头的哈希值以及打包节点对区块哈希的签名，VRF 的验证与该函数相互独立
+//	@param block - 需要验证的区块
+//	@return bool - 哈希值和签名均正确时返回 true
+func VerifyBlockSignature(block *common.Block) bool {
+	header := &block.Header
+	if len(header.Signature) == 0 {
+		log.WithField("height", header.Height).Debugln("Block header signature is empty.")
+		return false
+	}
+
+	// 重新计算区块头哈希，检查区块头内容是否被修改
+	blockHash, err := BlockHeaderHash(header)
+	if err != nil {
+		return false
+	}
+
+	if !bytes.Equal(blockHash[:], header.BlockHash[:]) {
+		log.WithField("height", header.Height).Debugln("Block hash not match.")
+		return false
+	}
+
+	publicKey := crypto.Bytes2PublicKey(header.PublicKey[:])
+	if publicKey.X == nil || publicKey.Y == nil {
+		log.WithField("height", header.Height).Debugln("Unmarshal block public key failed.")
+		return false
+	}
+
+	return ecdsa.VerifyASN1(publicKey, blockHash[:], header.Signature)
+}
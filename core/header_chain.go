@@ -0,0 +1,454 @@
+// Package core
+// @Description: 区块头链，用于先同步区块头再下载区块体的同步方式。区块头链从本地最新区块开始，
+// 每次追加一段区块头之前检查哈希链接、时隙规则、签名、VRF 证明以及 VDF 证明，只有全部通过验证才会追加；
+// 下载的区块体需要与已经验证的区块头一致，并且交易列表的 Merkle 根与区块头相同。
+//
+// 节点的 VDF 计算实例会接受分叉区块中的 seed，规范链上的下一个 seed 可能是由一个只出现在分叉区块中的 seed 计算得到的，
+// 仅凭规范链无法验证这次更新。区块头链允许这样的更新，但是要求下一次更新能够证明是由它计算得到的，
+// 也就是不允许连续两次无法验证的更新，伪造的 seed 至少需要一轮 VDF 计算才能被后续的区块头使用
+package core
+
+import (
+	"bytes"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	errHeaderNotLinked   = errors.New("header not linked to parent")
+	errHeaderSignature   = errors.New("invalid header signature")
+	errHeaderVRF         = errors.New("invalid header vrf proof")
+	errHeaderVDF         = errors.New("invalid header vdf proof")
+	errHeaderParams      = errors.New("malformed header params")
+	errHeaderNotFound    = errors.New("header not found")
+	errBodyHashNotMatch  = errors.New("block hash not match header")
+	errMerkleRootInvalid = errors.New("merkle root not match transactions")
+)
+
+// InvalidHeaderProof 错误是否由区块头的签名、VRF 或者 VDF 证明验证失败导致，只有这类错误能够说明区块头是伪造的，
+// 区块头无法链接等错误可能是区块头链在验证期间被修改或者对端处于分叉上
+func InvalidHeaderProof(err error) bool {
+	return err == errHeaderSignature || err == errHeaderVRF || err == errHeaderVDF
+}
+
+// vdfTransition 区块头链中 VDF seed 的一次更新，需要验证 result 是 seed 的计算结果
+type vdfTransition struct {
+	seed   *big.Int
+	proof  *big.Int
+	result *big.Int
+}
+
+// vdfState 区块头链某个位置处的 VDF 状态
+type vdfState struct {
+	seed       *big.Int // 当前的 seed
+	prevSeed   *big.Int // seed 之前的一个 seed
+	unanchored bool     // seed 无法由 prevSeed 验证，下一次更新需要证明由 seed 计算得到
+}
+
+// HeaderChain 从本地最新区块开始的区块头链
+type HeaderChain struct {
+	chain *BlockChain
+
+	headers map[int64]*common.BlockHeader // 已经验证的区块头，height -> header
+	base    int64                         // 区块头链的起点高度，即创建时本地最新区块的高度
+	tip     *common.BlockHeader           // 区块头链的最新区块头
+	vdf     vdfState                      // tip 处的 VDF 状态
+	store   *HeaderStore                  // 轻节点的区块头存储，不为 nil 时区块头写入存储而不是保留在内存中
+
+	lock sync.RWMutex
+}
+
+// NewHeaderChain
+//
+//	@Description: 以本地最新区块为起点创建区块头链，并从最新区块向前找到 VDF 的当前 seed 和上一个 seed
+//	@receiver bc
+//	@return *HeaderChain - 区块头链
+//	@return error - 本地还没有区块时返回错误
+func (bc *BlockChain) NewHeaderChain() (*HeaderChain, error) {
+	latest, err := bc.GetLatestBlock()
+	if err != nil || latest == nil {
+		return nil, errors.New("latest block not found")
+	}
+
+	return bc.newHeaderChain(&latest.Header, func(height int64) *common.BlockHeader {
+		block, err := bc.GetBlockByHeight(height)
+		if err != nil || block == nil {
+			return nil
+		}
+		return &block.Header
+	})
+}
+
+// NewLightHeaderChain
+//
+//	@Description: 轻节点以区块头存储中的最新区块头为起点创建区块头链，存储为空时从创世区块开始，
+//	追加的区块头写入区块头存储，不在内存中保留
+//	@receiver bc
+//	@param store - 区块头存储
+//	@return *HeaderChain - 区块头链
+//	@return error - 本地还没有创世区块时返回错误
+func (bc *BlockChain) NewLightHeaderChain(store *HeaderStore) (*HeaderChain, error) {
+	base := store.Latest()
+	if base == nil {
+		genesis, err := bc.GetBlockByHeight(0)
+		if err != nil || genesis == nil {
+			return nil, errGenesisNotFound
+		}
+
+		base = &genesis.Header
+		if err = store.Append([]*common.BlockHeader{base}); err != nil {
+			return nil, err
+		}
+	}
+
+	hc, err := bc.newHeaderChain(base, func(height int64) *common.BlockHeader {
+		header, err := store.GetHeaderByHeight(height)
+		if err != nil {
+			return nil
+		}
+		return header
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hc.store = store
+	return hc, nil
+}
+
+// newHeaderChain
+//
+//	@Description: 以 base 为起点创建区块头链，从 base 向前找到 VDF 的当前 seed 和上一个 seed
+//	@receiver bc
+//	@param base - 区块头链的起点
+//	@param lookup - 获取本地某个高度的区块头，不存在时返回 nil
+//	@return *HeaderChain - 区块头链
+//	@return error - 起点的参数无法解析时返回错误
+func (bc *BlockChain) newHeaderChain(base *common.BlockHeader,
+	lookup func(height int64) *common.BlockHeader) (*HeaderChain, error) {
+	seed, _, err := headerSeed(base)
+	if err != nil {
+		return nil, err
+	}
+
+	// VDF 的 seed 可能在连续的多个区块中保持不变，向前找到第一个不同的 seed
+	prevSeed := big.NewInt(0)
+	for height := base.Height - 1; height >= 0; height-- {
+		header := lookup(height)
+		if header == nil {
+			break
+		}
+
+		s, _, err := headerSeed(header)
+		if err != nil {
+			break
+		}
+		if s.Cmp(seed) != 0 {
+			prevSeed = s
+			break
+		}
+	}
+
+	return &HeaderChain{
+		chain:   bc,
+		headers: make(map[int64]*common.BlockHeader),
+		base:    base.Height,
+		tip:     base,
+		vdf: vdfState{
+			seed:     seed,
+			prevSeed: prevSeed,
+		},
+	}, nil
+}
+
+// Height 区块头链最新区块头的高度
+func (hc *HeaderChain) Height() int64 {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.tip.Height
+}
+
+// Tip 区块头链的最新区块头
+func (hc *HeaderChain) Tip() *common.BlockHeader {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.tip
+}
+
+// Header 获取区块头链中某个高度的区块头，不存在时返回 nil
+func (hc *HeaderChain) Header(height int64) *common.BlockHeader {
+	if hc.store != nil {
+		header, err := hc.store.GetHeaderByHeight(height)
+		if err != nil {
+			return nil
+		}
+		return header
+	}
+
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.headers[height]
+}
+
+// Prune 删除高度不超过 height 的区块头，区块写入数据库后不再需要对应的区块头
+func (hc *HeaderChain) Prune(height int64) {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+
+	for h := hc.base + 1; h <= height; h++ {
+		delete(hc.headers, h)
+	}
+	if height > hc.base {
+		hc.base = height
+	}
+}
+
+// Verify
+//
+//	@Description: 检查一段区块头能否追加到区块头链的末尾，不修改区块头链
+//	@receiver hc
+//	@param headers - 按高度排列的区块头，第一个区块头的父区块为区块头链的最新区块头
+//	@return error - 验证失败的原因
+func (hc *HeaderChain) Verify(headers []*common.BlockHeader) error {
+	hc.lock.RLock()
+	tip, state := hc.tip, hc.vdf
+	hc.lock.RUnlock()
+
+	_, err := hc.verify(tip, state, headers)
+	return err
+}
+
+// Append
+//
+//	@Description: 验证一段区块头并追加到区块头链的末尾，验证失败时区块头链不变
+//	@receiver hc
+//	@param headers - 按高度排列的区块头
+//	@return error - 验证失败的原因，或者区块头链在验证期间被修改
+func (hc *HeaderChain) Append(headers []*common.BlockHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	hc.lock.RLock()
+	tip, state := hc.tip, hc.vdf
+	hc.lock.RUnlock()
+
+	state, err := hc.verify(tip, state, headers)
+	if err != nil {
+		return err
+	}
+
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+
+	// 验证期间区块头链已经追加了其他区块头
+	if hc.tip != tip {
+		return errHeaderNotLinked
+	}
+
+	if hc.store != nil {
+		if err = hc.store.Append(headers); err != nil {
+			return err
+		}
+	} else {
+		for _, header := range headers {
+			hc.headers[header.Height] = header
+		}
+	}
+	hc.tip = headers[len(headers)-1]
+	hc.vdf = state
+	return nil
+}
+
+// VerifyBody
+//
+//	@Description: 检查下载的区块是否与区块头链中对应高度的区块头一致，并且交易列表的 Merkle 根与区块头相同
+//	@receiver hc
+//	@param block - 下载的区块
+//	@return error - 不一致时返回错误
+func (hc *HeaderChain) VerifyBody(block *common.Block) error {
+	header := hc.Header(block.Header.Height)
+	if header == nil {
+		return errHeaderNotFound
+	}
+
+	// 重新计算区块头哈希，区块头中的 BlockHash 字段可能被篡改
+	blockHash, err := BlockHeaderHash(&block.Header)
+	if err != nil {
+		return err
+	}
+	if blockHash != header.BlockHash || block.Header.BlockHash != header.BlockHash {
+		return errBodyHashNotMatch
+	}
+
+	merkleRoot, err := MerkleRoot(hc.chain.MerkleVersion(), block.Transactions)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(merkleRoot, header.MerkleRoot[:]) {
+		return errMerkleRootInvalid
+	}
+	return nil
+}
+
+// verify
+//
+//	@Description: 依次检查区块头之间的链接关系和时隙规则，并记录 VDF seed 的更新；
+//	然后并行验证每个区块头的签名、VRF 证明以及每次 VDF seed 更新的证明，最后检查没有连续两次无法验证的更新
+//	@receiver hc
+//	@param parent - 第一个区块头的父区块头
+//	@param state - 父区块头处的 VDF 状态
+//	@param headers - 按高度排列的区块头
+//	@return vdfState - 最后一个区块头处的 VDF 状态
+//	@return error - 验证失败的原因
+func (hc *HeaderChain) verify(parent *common.BlockHeader, state vdfState,
+	headers []*common.BlockHeader) (vdfState, error) {
+	rules := hc.chain.SlotRules()
+	if rules == nil {
+		return state, errSlotRulesNotInit
+	}
+	calculator := hc.chain.Calculator()
+	if calculator == nil {
+		return state, errCalculatorNotInit
+	}
+
+	// 与 VDF 计算实例的规则一致，区块中的 seed 等于当前或上一个 seed 时不需要验证，否则需要证明是当前 seed 的计算结果
+	seed, prevSeed := state.seed, state.prevSeed
+	transitions := make([]vdfTransition, 0)
+	for _, header := range headers {
+		if header.Height != parent.Height+1 || header.PrevBlockHash != parent.BlockHash {
+			return state, errHeaderNotLinked
+		}
+
+		if err := rules.VerifyHeader(header, parent); err != nil {
+			return state, err
+		}
+
+		result, proof, err := headerSeed(header)
+		if err != nil {
+			return state, err
+		}
+		if result.Cmp(seed) != 0 && result.Cmp(prevSeed) != 0 {
+			transitions = append(transitions, vdfTransition{
+				seed:   seed,
+				proof:  proof,
+				result: result,
+			})
+			prevSeed, seed = seed, result
+		}
+		parent = header
+	}
+
+	verified := make([]bool, len(transitions))
+	err := verifyParallel(len(headers)+len(transitions), func(idx int) error {
+		if idx >= len(headers) {
+			t := transitions[idx-len(headers)]
+			verified[idx-len(headers)] = calculator.Verify(t.seed, t.proof, t.result)
+			return nil
+		}
+
+		header := headers[idx]
+		if !VerifyBlockSignature(&common.Block{Header: *header}) {
+			return errHeaderSignature
+		}
+		if !hc.chain.VerifyHeaderVRF(header) {
+			return errHeaderVRF
+		}
+		return nil
+	})
+	if err != nil {
+		return state, err
+	}
+
+	unanchored := state.unanchored
+	for _, ok := range verified {
+		if !ok && unanchored {
+			return state, errHeaderVDF
+		}
+		unanchored = !ok
+	}
+
+	return vdfState{
+		seed:       seed,
+		prevSeed:   prevSeed,
+		unanchored: unanchored,
+	}, nil
+}
+
+// headerSeed
+//
+//	@Description: 读取区块头中的 VDF seed 和证明，创世区块使用创世参数中的 seed
+//	@param header - 区块头
+//	@return *big.Int - VDF seed
+//	@return *big.Int - VDF 证明
+//	@return error - 区块参数不完整时返回错误
+func headerSeed(header *common.BlockHeader) (*big.Int, *big.Int, error) {
+	if header.Height == 0 {
+		params, err := utils.DeserializeGenesisParams(header.Params)
+		if err != nil {
+			return nil, nil, err
+		}
+		return new(big.Int).SetBytes(params.Seed[:]), big.NewInt(0), nil
+	}
+
+	params, err := utils.DeserializeGeneralParams(header.Params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(params.Result) == 0 {
+		return nil, nil, errHeaderParams
+	}
+
+	return new(big.Int).SetBytes(params.Result), new(big.Int).SetBytes(params.Proof), nil
+}
+
+// verifyParallel
+//
+//	@Description: 使用与 CPU 数量相同的协程并行执行 n 个验证任务，任意一个任务失败后不再执行剩余的任务
+//	@param n - 任务数量
+//	@param fn - 验证任务，参数为任务序号
+//	@return error - 第一个失败的任务返回的错误
+func verifyParallel(n int, fn func(idx int) error) error {
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+
+	var (
+		next     atomic.Int64
+		failed   atomic.Bool
+		firstErr error
+		once     sync.Once
+		wg       sync.WaitGroup
+	)
+
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				idx := int(next.Add(1) - 1)
+				if idx >= n {
+					return
+				}
+
+				if err := fn(idx); err != nil {
+					once.Do(func() { firstErr = err })
+					failed.Store(true)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,257 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	"math/big"
+	"testing"
+)
+
+// testHeaderChain 创建只有创世区块的区块链，以及一个从创世区块开始的区块头链
+func testHeaderChain(t *testing.T) (*BlockChain, *ecdsa.PrivateKey) {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	params, err := crypto.GenerateGenesisParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TimeParam = 1000
+	params.ExpectedProducers = 1
+	params.TotalWeight = 1
+
+	g, err := SealGenesis(7, 1700000000000, params, nil, prv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := utils.NewMemoryLevelDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := NewBlockchain(db, nil, nil)
+	if err = chain.InitGenesis(g); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = chain.Calculator().Stop(context.Background()) })
+	return chain, prv
+}
+
+// testBuildHeaders 从 parent 开始构建 n 个签名的区块头，每隔 interval 个区块更新一次 VDF seed
+func testBuildHeaders(t *testing.T, chain *BlockChain, prv *ecdsa.PrivateKey,
+	parent *common.BlockHeader, seed *big.Int, n, interval int) []*common.Block {
+	rules := chain.SlotRules()
+	blocks := make([]*common.Block, 0, n)
+
+	for i := 0; i < n; i++ {
+		if interval > 0 && i%interval == interval-1 {
+			result, err := chain.Calculator().Evaluate(context.Background(), seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seed = result.Result
+
+			// 更新 seed 的区块携带计算结果和证明
+			blocks = append(blocks, testHeaderBlock(t, chain, prv, parent, rules, result.Result, result.Proof))
+		} else {
+			blocks = append(blocks, testHeaderBlock(t, chain, prv, parent, rules, seed, big.NewInt(0)))
+		}
+		parent = &blocks[len(blocks)-1].Header
+	}
+	return blocks
+}
+
+func testHeaderBlock(t *testing.T, chain *BlockChain, prv *ecdsa.PrivateKey, parent *common.BlockHeader,
+	rules *SlotRules, seed, proof *big.Int) *common.Block {
+	randNumber, s, tt, err := crypto.VRFProve(chain.VRFVersion(), prv, seed.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paramsBytes, err := utils.SerializeGeneralParams(&common.GeneralParams{
+		Result:       seed.Bytes(),
+		Proof:        proof.Bytes(),
+		RandomNumber: [33]byte(randNumber),
+		S:            s,
+		T:            tt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txs := []common.Transaction{*buildTransaction(prv, chain.ChainID())}
+	block := &common.Block{
+		Header: common.BlockHeader{
+			Timestamp:     parent.Timestamp + rules.SlotInterval,
+			PrevBlockHash: parent.BlockHash,
+			MerkleRoot:    [32]byte(BuildMerkleTree(txs)),
+			Height:        parent.Height + 1,
+			PublicKey:     [33]byte(crypto.PublicKey2Bytes(&prv.PublicKey)),
+			Params:        paramsBytes,
+		},
+		Transactions: txs,
+	}
+	if err = sealBlockHeader(&block.Header, prv); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func testHeaders(blocks []*common.Block) []*common.BlockHeader {
+	headers := make([]*common.BlockHeader, len(blocks))
+	for idx := range blocks {
+		header := blocks[idx].Header
+		headers[idx] = &header
+	}
+	return headers
+}
+
+func testGenesisSeed(t *testing.T, chain *BlockChain) (*common.BlockHeader, *big.Int) {
+	genesis, err := chain.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, _, err := headerSeed(&genesis.Header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &genesis.Header, seed
+}
+
+func TestHeaderChainAppend(t *testing.T) {
+	chain, prv := testHeaderChain(t)
+	genesis, seed := testGenesisSeed(t, chain)
+
+	hc, err := chain.NewHeaderChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := testBuildHeaders(t, chain, prv, genesis, seed, 8, 3)
+	if err = hc.Append(testHeaders(blocks[:4])); err != nil {
+		t.Fatal(err)
+	}
+	// 分批追加时 VDF seed 的状态延续到下一批
+	if err = hc.Append(testHeaders(blocks[4:])); err != nil {
+		t.Fatal(err)
+	}
+	if hc.Height() != 8 {
+		t.Fatalf("unexpected header chain height %d", hc.Height())
+	}
+
+	for _, block := range blocks {
+		if err = hc.VerifyBody(block); err != nil {
+			t.Fatalf("verify body #%d failed: %v", block.Header.Height, err)
+		}
+	}
+
+	// 交易列表与区块头中的 Merkle 根不一致
+	tampered := *blocks[2]
+	tampered.Transactions = []common.Transaction{*buildTransaction(prv, chain.ChainID())}
+	if err = hc.VerifyBody(&tampered); err != errMerkleRootInvalid {
+		t.Fatalf("tampered body accepted, error %v", err)
+	}
+
+	hc.Prune(4)
+	if hc.Header(4) != nil || hc.Header(5) == nil {
+		t.Fatal("headers not pruned")
+	}
+}
+
+func TestHeaderChainReject(t *testing.T) {
+	chain, prv := testHeaderChain(t)
+	genesis, seed := testGenesisSeed(t, chain)
+
+	hc, err := chain.NewHeaderChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := testBuildHeaders(t, chain, prv, genesis, seed, 4, 2)
+
+	// 缺少中间的区块头
+	headers := testHeaders(blocks)
+	if err = hc.Verify([]*common.BlockHeader{headers[0], headers[2]}); err != errHeaderNotLinked {
+		t.Fatalf("unlinked headers accepted, error %v", err)
+	}
+	if InvalidHeaderProof(err) {
+		t.Fatal("unlinked headers reported as invalid proof")
+	}
+
+	// 区块头内容被修改后签名不正确
+	headers = testHeaders(blocks)
+	headers[1].GasLimit = 1
+	if err = hc.Verify(headers); err != errHeaderSignature || !InvalidHeaderProof(err) {
+		t.Fatalf("tampered header accepted, error %v", err)
+	}
+
+	// 连续两次伪造的 VDF 结果，重新签名后哈希和签名正确
+	forged := testHeaderBlock(t, chain, prv, genesis, chain.SlotRules(),
+		new(big.Int).Add(seed, big.NewInt(1)), big.NewInt(1))
+	next := testHeaderBlock(t, chain, prv, &forged.Header, chain.SlotRules(),
+		new(big.Int).Add(seed, big.NewInt(2)), big.NewInt(1))
+	if err = hc.Verify(testHeaders([]*common.Block{forged, next})); err != errHeaderVDF || !InvalidHeaderProof(err) {
+		t.Fatalf("forged vdf accepted, error %v", err)
+	}
+
+	// 其他节点签名的区块不满足该节点的 VRF 证明
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	header := blocks[0].Header
+	header.PublicKey = [33]byte(crypto.PublicKey2Bytes(&other.PublicKey))
+	if err = sealBlockHeader(&header, other); err != nil {
+		t.Fatal(err)
+	}
+	if err = hc.Verify([]*common.BlockHeader{&header}); err != errHeaderVRF || !InvalidHeaderProof(err) {
+		t.Fatalf("header with foreign vrf proof accepted, error %v", err)
+	}
+
+	if err = hc.Append(headers); err == nil || hc.Height() != 0 {
+		t.Fatal("header chain changed after failed append")
+	}
+}
+
+func TestHeaderChainSkippedSeed(t *testing.T) {
+	chain, prv := testHeaderChain(t)
+	genesis, seed := testGenesisSeed(t, chain)
+	rules := chain.SlotRules()
+
+	evaluate := func(seed *big.Int) (*big.Int, *big.Int) {
+		result, err := chain.Calculator().Evaluate(context.Background(), seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Result, result.Proof
+	}
+
+	// 第一次更新的结果只出现在分叉区块中，规范链上的区块携带由它计算得到的 seed
+	skipped, _ := evaluate(seed)
+	second, secondProof := evaluate(skipped)
+	third, thirdProof := evaluate(second)
+
+	b1 := testHeaderBlock(t, chain, prv, genesis, rules, second, secondProof)
+	b2 := testHeaderBlock(t, chain, prv, &b1.Header, rules, second, big.NewInt(0))
+	b3 := testHeaderBlock(t, chain, prv, &b2.Header, rules, third, thirdProof)
+
+	hc, err := chain.NewHeaderChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 无法验证的更新可以出现在批次末尾，下一批的第一次更新需要由它计算得到
+	if err = hc.Append(testHeaders([]*common.Block{b1, b2})); err != nil {
+		t.Fatal(err)
+	}
+	forged := testHeaderBlock(t, chain, prv, &b2.Header, rules,
+		new(big.Int).Add(second, big.NewInt(1)), big.NewInt(1))
+	if err = hc.Verify(testHeaders([]*common.Block{forged})); err != errHeaderVDF {
+		t.Fatalf("unanchored seed followed by forged vdf accepted, error %v", err)
+	}
+	if err = hc.Append(testHeaders([]*common.Block{b3})); err != nil {
+		t.Fatal(err)
+	}
+	if hc.Height() != 3 {
+		t.Fatalf("unexpected header chain height %d", hc.Height())
+	}
+}
@@ -0,0 +1,139 @@
+// Package core
+// @Description: 轻节点的区块头存储，轻节点只保存已经验证的区块头，区块头按照哈希值存放，高度索引和最新区块头索引指向区块头的哈希值，
+// 与完整节点存放区块的方式相同
+package core
+
+import (
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/interfaces"
+	"github.com/chain-lab/go-norn/utils"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// HeaderStore 区块头存储
+type HeaderStore struct {
+	db interfaces.DBInterface
+
+	latest *common.BlockHeader // 最新的区块头，为 nil 时从数据库中读取
+	lock   sync.RWMutex
+}
+
+// NewHeaderStore
+//
+//	@Description: 使用数据库实例创建区块头存储
+//	@param db - 数据库实例
+//	@return *HeaderStore
+func NewHeaderStore(db interfaces.DBInterface) *HeaderStore {
+	return &HeaderStore{db: db}
+}
+
+// Append
+//
+//	@Description: 批量写入已经验证的区块头，并将最新区块头索引指向最后一个区块头
+//	@receiver hs
+//	@param headers - 按高度排列的区块头
+//	@return error - 序列化或者写入数据库失败时返回错误
+func (hs *HeaderStore) Append(headers []*common.BlockHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	keys := make([][]byte, 0, 2*len(headers)+1)
+	values := make([][]byte, 0, 2*len(headers)+1)
+	for _, header := range headers {
+		headerBytes, err := utils.SerializeBlockHeader(header)
+		if err != nil {
+			return err
+		}
+
+		hash := common.Hash(header.BlockHash)
+		keys = append(keys, utils.HeaderHash2DBKey(hash), utils.HeaderHeight2DBKey(header.Height))
+		values = append(values, headerBytes, header.BlockHash[:])
+	}
+
+	tip := headers[len(headers)-1]
+	keys = append(keys, utils.LatestHeaderDBKey())
+	values = append(values, tip.BlockHash[:])
+
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+
+	if err := hs.db.BatchInsert(keys, values); err != nil {
+		log.WithError(err).Errorln("Insert headers failed.")
+		return err
+	}
+	hs.latest = tip
+	return nil
+}
+
+// Latest 获取最新的区块头，还没有存储区块头时返回 nil
+func (hs *HeaderStore) Latest() *common.BlockHeader {
+	hs.lock.RLock()
+	latest := hs.latest
+	hs.lock.RUnlock()
+	if latest != nil {
+		return latest
+	}
+
+	hash, err := hs.db.Get(utils.LatestHeaderDBKey())
+	if err != nil || len(hash) != 32 {
+		return nil
+	}
+
+	header, err := hs.GetHeaderByHash(common.Hash(hash))
+	if err != nil {
+		return nil
+	}
+
+	hs.lock.Lock()
+	if hs.latest == nil {
+		hs.latest = header
+	}
+	hs.lock.Unlock()
+	return header
+}
+
+// Height 最新区块头的高度，还没有存储区块头时返回 -1
+func (hs *HeaderStore) Height() int64 {
+	latest := hs.Latest()
+	if latest == nil {
+		return -1
+	}
+	return latest.Height
+}
+
+// GetHeaderByHash
+//
+//	@Description: 通过区块哈希获取区块头
+//	@receiver hs
+//	@param hash - 区块哈希
+//	@return *common.BlockHeader - 区块头
+//	@return error - 区块头不存在时返回错误
+func (hs *HeaderStore) GetHeaderByHash(hash common.Hash) (*common.BlockHeader, error) {
+	headerBytes, err := hs.db.Get(utils.HeaderHash2DBKey(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.DeserializeBlockHeader(headerBytes)
+}
+
+// GetHeaderByHeight
+//
+//	@Description: 通过高度获取区块头
+//	@receiver hs
+//	@param height - 区块高度
+//	@return *common.BlockHeader - 区块头
+//	@return error - 区块头不存在时返回错误
+func (hs *HeaderStore) GetHeaderByHeight(height int64) (*common.BlockHeader, error) {
+	hash, err := hs.db.Get(utils.HeaderHeight2DBKey(height))
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != 32 {
+		return nil, errHeaderNotFound
+	}
+
+	return hs.GetHeaderByHash(common.Hash(hash))
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/crypto"
+	"testing"
+	"time"
+)
+
+func testSealedBlock(t *testing.T, prv *ecdsa.PrivateKey) *common.Block {
+	block := &common.Block{
+		Header: common.BlockHeader{
+			Timestamp: time.Now().UnixMilli(),
+			Height:    1,
+			PublicKey: [33]byte(crypto.PublicKey2Bytes(&prv.PublicKey)),
+			Params:    []byte{},
+		},
+		Transactions: []common.Transaction{},
+	}
+
+	if err := sealBlockHeader(&block.Header, prv); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func TestVerifyBlockSignature(t *testing.T) {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	block := testSealedBlock(t, prv)
+
+	if !VerifyBlockSignature(block) {
+		t.Fatal("Sealed block signature verify failed.")
+	}
+
+	// 修改区块头内容后哈希不再匹配
+	block.Header.Height = 2
+	if VerifyBlockSignature(block) {
+		t.Fatal("Tampered block header passed verification.")
+	}
+}
+
+func TestVerifyBlockSignatureWrongKey(t *testing.T) {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	block := testSealedBlock(t, prv)
+
+	// 替换公钥并重新计算哈希，签名者与区块头中的公钥不一致
+	block.Header.PublicKey = [33]byte(crypto.PublicKey2Bytes(&other.PublicKey))
+	hash, err := BlockHeaderHash(&block.Header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Header.BlockHash = hash
+
+	if VerifyBlockSignature(block) {
+		t.Fatal("Block signed by another key passed verification.")
+	}
+
+	block.Header.Signature = []byte{}
+	if VerifyBlockSignature(block) {
+		t.Fatal("Block without signature passed verification.")
+	}
+}
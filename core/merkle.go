@@ -1,13 +1,36 @@
 // Package core
-// @Description: Merkle 树处理逻辑
+// @Description: Merkle 树处理逻辑。旧版本的链使用的 Merkle 树每一层只合并前一半的节点，根哈希只覆盖部分交易，
+// 为了兼容已有的链，根哈希的计算方式由创世参数中的 MerkleVersion 决定；Merkle 证明按照对应版本的树结构生成，
+// 旧版本的链上没有被根哈希覆盖的交易无法生成证明
 package core
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"github.com/chain-lab/go-norn/common"
+	"github.com/syndtr/goleveldb/leveldb/errors"
 	"math"
 )
 
+const (
+	MerkleVersionLegacy uint8 = 0 // 旧版本链使用的 Merkle 树，即 BuildMerkleTree
+	MerkleVersionBinary uint8 = 1 // 完整的二叉 Merkle 树，每一层两两合并，剩余的一个节点与空节点合并
+)
+
+const (
+	merkleSiblingRight uint8 = 0  // 兄弟节点在右侧
+	merkleSiblingLeft  uint8 = 1  // 兄弟节点在左侧
+	merkleSiblingNull  uint8 = 2  // 兄弟节点为空节点，只对当前节点计算哈希
+	merkleStepSize           = 33 // 证明中每一层的长度，1 字节的位置和 32 字节的兄弟节点哈希
+)
+
+var (
+	errMerkleVersion    = errors.New("unknown merkle version")
+	errMerkleIndex      = errors.New("transaction index out of range")
+	errTxNotCommitted   = errors.New("transaction not committed by merkle root")
+	errMerkleProofBytes = errors.New("malformed merkle proof")
+)
+
 type node struct {
 	data  []byte
 	left  *node
@@ -33,23 +56,30 @@ func mergeHash(leftNode *node, rightNode *node) []byte {
 //	@param txs - 需要构建的交易列表
 //	@return []byte - Merkle 树的根哈希值
 func BuildMerkleTree(txs []common.Transaction) []byte {
-	// 获取交易数量
-	length := len(txs)
-
-	if length <= 0 {
+	if len(txs) <= 0 {
 		return make([]byte, 32)
 	}
 
+	_, root := buildLegacyMerkleTree(txs)
+	return root.data
+}
+
+// buildLegacyMerkleTree 构建旧版本的 Merkle 树，返回叶子节点和根节点
+func buildLegacyMerkleTree(txs []common.Transaction) ([]node, *node) {
+	// 获取交易数量
+	length := len(txs)
+
 	// 最底层一共有对应交易数量的节点
-	nodes := make([]node, length)
+	leaves := make([]node, length)
 	for idx := range txs {
 		// 初始化节点信息
-		nodes[idx] = node{
+		leaves[idx] = node{
 			data:  txs[idx].Body.Hash[:],
 			left:  nil,
 			right: nil,
 		}
 	}
+	nodes := leaves
 
 	// 二叉树的结构，其高度为 log2(length)
 	height := int(math.Log2(float64(length))) + 1
@@ -83,5 +113,172 @@ func BuildMerkleTree(txs []common.Transaction) []byte {
 			break
 		}
 	}
-	return nodes[0].data
+	return leaves, &nodes[0]
+}
+
+// buildBinaryMerkleTree 构建完整的二叉 Merkle 树，返回叶子节点和根节点，只有一个交易时根节点就是叶子节点
+func buildBinaryMerkleTree(txs []common.Transaction) ([]node, *node) {
+	leaves := make([]node, len(txs))
+	for idx := range txs {
+		leaves[idx] = node{data: txs[idx].Body.Hash[:]}
+	}
+
+	nodes := leaves
+	for len(nodes) > 1 {
+		level := make([]node, (len(nodes)+1)/2)
+		for j := 0; j < len(nodes); j += 2 {
+			right := &node{}
+			if j+1 < len(nodes) {
+				right = &nodes[j+1]
+			}
+			level[j/2] = node{
+				data:  mergeHash(&nodes[j], right),
+				left:  &nodes[j],
+				right: right,
+			}
+		}
+		nodes = level
+	}
+	return leaves, &nodes[0]
+}
+
+// buildMerkleTree 按照 Merkle 版本构建 Merkle 树
+func buildMerkleTree(version uint8, txs []common.Transaction) ([]node, *node, error) {
+	switch version {
+	case MerkleVersionLegacy:
+		leaves, root := buildLegacyMerkleTree(txs)
+		return leaves, root, nil
+	case MerkleVersionBinary:
+		leaves, root := buildBinaryMerkleTree(txs)
+		return leaves, root, nil
+	}
+	return nil, nil, errMerkleVersion
+}
+
+// MerkleRoot
+//
+//	@Description: 按照 Merkle 版本计算交易列表的根哈希，没有交易时为空哈希
+//	@param version - 创世参数中的 Merkle 版本
+//	@param txs - 交易列表
+//	@return []byte - 根哈希
+//	@return error - 版本未知时返回错误
+func MerkleRoot(version uint8, txs []common.Transaction) ([]byte, error) {
+	if len(txs) == 0 {
+		if version != MerkleVersionLegacy && version != MerkleVersionBinary {
+			return nil, errMerkleVersion
+		}
+		return make([]byte, 32), nil
+	}
+
+	_, root, err := buildMerkleTree(version, txs)
+	if err != nil {
+		return nil, err
+	}
+	return root.data, nil
+}
+
+// MerkleProof
+//
+//	@Description: 生成交易列表中第 index 个交易的 Merkle 证明，证明由叶子节点到根节点每一层的兄弟节点组成
+//	@param version - 创世参数中的 Merkle 版本
+//	@param txs - 区块中的交易列表
+//	@param index - 交易在区块中的位置
+//	@return []byte - Merkle 证明
+//	@return error - 位置越界、版本未知或者交易没有被根哈希覆盖时返回错误
+func MerkleProof(version uint8, txs []common.Transaction, index int) ([]byte, error) {
+	if index < 0 || index >= len(txs) {
+		return nil, errMerkleIndex
+	}
+
+	leaves, root, err := buildMerkleTree(version, txs)
+	if err != nil {
+		return nil, err
+	}
+
+	proof, ok := merklePath(root, &leaves[index])
+	if !ok {
+		return nil, errTxNotCommitted
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof
+//
+//	@Description: 使用 Merkle 证明由交易哈希逐层计算到根节点，并与区块头中的根哈希比较
+//	@param root - 区块头中的 Merkle 根哈希
+//	@param leaf - 交易哈希
+//	@param proof - Merkle 证明
+//	@return error - 证明格式错误或者根哈希不一致时返回错误
+func VerifyMerkleProof(root [32]byte, leaf common.Hash, proof []byte) error {
+	if len(proof)%merkleStepSize != 0 {
+		return errMerkleProofBytes
+	}
+
+	current := &node{data: leaf[:]}
+	for offset := 0; offset < len(proof); offset += merkleStepSize {
+		sibling := &node{data: proof[offset+1 : offset+merkleStepSize]}
+
+		switch proof[offset] {
+		case merkleSiblingRight:
+			current = &node{data: mergeHash(current, sibling)}
+		case merkleSiblingLeft:
+			current = &node{data: mergeHash(sibling, current)}
+		case merkleSiblingNull:
+			current = &node{data: mergeHash(current, &node{})}
+		default:
+			return errMerkleProofBytes
+		}
+	}
+
+	if !bytes.Equal(current.data, root[:]) {
+		return errMerkleRootInvalid
+	}
+	return nil
+}
+
+// merklePath 在以 n 为根的子树中查找叶子节点，返回由叶子节点到 n 的各层兄弟节点
+func merklePath(n, leaf *node) ([]byte, bool) {
+	if n == leaf {
+		return make([]byte, 0, merkleStepSize*8), true
+	}
+	if n.left == nil {
+		return nil, false
+	}
+
+	if proof, ok := merklePath(n.left, leaf); ok {
+		return append(proof, merkleStep(merkleSiblingRight, n.right)...), true
+	}
+	if n.right != nil {
+		if proof, ok := merklePath(n.right, leaf); ok {
+			return append(proof, merkleStep(merkleSiblingLeft, n.left)...), true
+		}
+	}
+	return nil, false
+}
+
+// merkleStep 编码证明中的一层，兄弟节点为空节点时只记录位置
+func merkleStep(side uint8, sibling *node) []byte {
+	step := make([]byte, merkleStepSize)
+	if sibling == nil || sibling.data == nil {
+		step[0] = merkleSiblingNull
+		return step
+	}
+
+	step[0] = side
+	copy(step[1:], sibling.data)
+	return step
+}
+
+// MerkleVersion
+//
+//	@Description: 获取当前链所使用的 Merkle 版本，旧版本的链创世参数中该字段为 0
+//	@receiver BlockChain 实例
+//	@return uint8 - Merkle 版本
+func (bc *BlockChain) MerkleVersion() uint8 {
+	params := bc.currentGenesisParams()
+	if params == nil {
+		return MerkleVersionLegacy
+	}
+
+	return params.MerkleVersion
 }
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -21,7 +22,7 @@ func TestBuildMerkleTree(t *testing.T) {
 	txs := make([]common.Transaction, 0, 3000)
 
 	for i := 0; i < 3000; i++ {
-		tx := buildTransaction(privateKey)
+		tx := buildTransaction(privateKey, 0)
 		txs = append(txs, *tx)
 	}
 
@@ -32,3 +33,94 @@ func TestBuildMerkleTree(t *testing.T) {
 	t.Logf("Build merkle tree use %d μs.", usedTime.Microseconds())
 	t.Logf("Merkle tree root is %s.", hex.EncodeToString(merkleRoot))
 }
+
+// TestMerkleRootLegacy 旧版本的根哈希需要与 BuildMerkleTree 一致，保证已有链上的区块依旧可以验证
+func TestMerkleRootLegacy(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txs := make([]common.Transaction, 0, 9)
+	for i := 0; i <= 9; i++ {
+		root, err := MerkleRoot(MerkleVersionLegacy, txs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(root, BuildMerkleTree(txs)) {
+			t.Fatalf("legacy root mismatch with %d transactions", len(txs))
+		}
+		txs = append(txs, *buildTransaction(privateKey, 0))
+	}
+
+	if _, err := MerkleRoot(3, txs); err != errMerkleVersion {
+		t.Fatalf("expected unknown merkle version error, got %v", err)
+	}
+}
+
+// TestMerkleProof 对不同数量的交易生成每个交易的证明，证明需要能够验证到根哈希，篡改后验证失败
+func TestMerkleProof(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txs := make([]common.Transaction, 0, 17)
+	for i := 1; i <= 17; i++ {
+		txs = append(txs, *buildTransaction(privateKey, 0))
+
+		root, err := MerkleRoot(MerkleVersionBinary, txs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for idx := range txs {
+			proof, err := MerkleProof(MerkleVersionBinary, txs, idx)
+			if err != nil {
+				t.Fatalf("build proof %d/%d failed: %v", idx, len(txs), err)
+			}
+			if err := VerifyMerkleProof([32]byte(root), txs[idx].Body.Hash, proof); err != nil {
+				t.Fatalf("verify proof %d/%d failed: %v", idx, len(txs), err)
+			}
+
+			other := txs[(idx+1)%len(txs)].Body.Hash
+			if len(txs) > 1 && VerifyMerkleProof([32]byte(root), other, proof) == nil {
+				t.Fatalf("proof %d/%d verified with other transaction", idx, len(txs))
+			}
+		}
+	}
+
+	if _, err := MerkleProof(MerkleVersionBinary, txs, len(txs)); err != errMerkleIndex {
+		t.Fatalf("expected index error, got %v", err)
+	}
+}
+
+// TestMerkleProofLegacy 旧版本的树只能为根哈希覆盖的交易生成证明
+func TestMerkleProofLegacy(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txs := make([]common.Transaction, 0, 4)
+	for i := 0; i < 4; i++ {
+		txs = append(txs, *buildTransaction(privateKey, 0))
+	}
+	root := BuildMerkleTree(txs)
+
+	for idx := range txs {
+		proof, err := MerkleProof(MerkleVersionLegacy, txs, idx)
+		if idx < 2 {
+			if err != nil {
+				t.Fatalf("build legacy proof %d failed: %v", idx, err)
+			}
+			if err := VerifyMerkleProof([32]byte(root), txs[idx].Body.Hash, proof); err != nil {
+				t.Fatalf("verify legacy proof %d failed: %v", idx, err)
+			}
+			continue
+		}
+		if err != errTxNotCommitted {
+			t.Fatalf("expected transaction %d not committed, got %v", idx, err)
+		}
+	}
+}
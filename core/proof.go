@@ -0,0 +1,71 @@
+// Package core
+// @Description: 轻节点查询使用的 Merkle 证明。完整节点为交易生成到区块头 Merkle 根的证明，轻节点使用本地已经验证的区块头检查证明；
+// data# 数据的证明由区块参数中的状态根提供，见 state.go
+package core
+
+import (
+	"github.com/chain-lab/go-norn/common"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+var (
+	errProofTxNotInBlock = errors.New("transaction not found in block")
+	errProofTxInvalid    = errors.New("invalid proof transaction")
+	errProofHeader       = errors.New("transaction not match header")
+)
+
+// TransactionProof
+//
+//	@Description: 获取数据库中的交易以及交易到所在区块 Merkle 根的证明
+//	@receiver BlockChain 实例
+//	@param hash - 交易哈希
+//	@return *common.Transaction - 交易，包含所在区块的高度、哈希和交易在区块中的位置
+//	@return []byte - Merkle 证明
+//	@return error - 交易或者区块不存在，或者交易没有被 Merkle 根覆盖时返回错误
+func (bc *BlockChain) TransactionProof(hash common.Hash) (*common.Transaction, []byte, error) {
+	tx, err := bc.GetTransactionByHash(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blockHash := common.Hash(tx.Body.BlockHash)
+	block, err := bc.GetBlockByHash(&blockHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := int(tx.Body.Index)
+	if index < 0 || index >= len(block.Transactions) || block.Transactions[index].Body.Hash != tx.Body.Hash {
+		return nil, nil, errProofTxNotInBlock
+	}
+
+	proof, err := MerkleProof(bc.MerkleVersion(), block.Transactions, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, proof, nil
+}
+
+// VerifyTransactionProof
+//
+//	@Description: 使用本地的区块头验证完整节点响应的交易和 Merkle 证明。数据库中的交易在写入时填入了区块高度、哈希和位置，
+//	这些字段不在交易哈希的范围内，验证签名前需要清空
+//	@param header - 本地已经验证的区块头
+//	@param tx - 完整节点响应的交易
+//	@param proof - Merkle 证明
+//	@param chainID - 本地节点所在链的链 ID
+//	@return error - 验证失败的原因
+func VerifyTransactionProof(header *common.BlockHeader, tx *common.Transaction, proof []byte, chainID int64) error {
+	if tx.Body.Height != header.Height || tx.Body.BlockHash != header.BlockHash {
+		return errProofHeader
+	}
+
+	body := tx.Body
+	body.Height, body.BlockHash, body.Index = 0, [32]byte{}, 0
+	unsealed := common.Transaction{Body: body}
+	if !unsealed.Verify(chainID) {
+		return errProofTxInvalid
+	}
+
+	return VerifyMerkleProof(header.MerkleRoot, tx.Body.Hash, proof)
+}
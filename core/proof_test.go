@@ -0,0 +1,64 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/chain-lab/go-norn/common"
+	"testing"
+)
+
+// TestVerifyTransactionProof 模拟数据库中的交易，证明需要能够通过区块头验证，修改交易或者区块头后验证失败
+func TestVerifyTransactionProof(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txs := make([]common.Transaction, 0, 5)
+	for i := 0; i < 5; i++ {
+		txs = append(txs, *buildTransaction(privateKey, 7))
+	}
+	root, err := MerkleRoot(MerkleVersionBinary, txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &common.BlockHeader{
+		Height:     12,
+		BlockHash:  [32]byte{1, 2, 3},
+		MerkleRoot: [32]byte(root),
+	}
+
+	for idx := range txs {
+		proof, err := MerkleProof(MerkleVersionBinary, txs, idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 写入数据库时填入的区块信息
+		stored := txs[idx]
+		stored.Body.Height = header.Height
+		stored.Body.BlockHash = header.BlockHash
+		stored.Body.Index = int64(idx)
+
+		if err := VerifyTransactionProof(header, &stored, proof, 7); err != nil {
+			t.Fatalf("verify transaction %d failed: %v", idx, err)
+		}
+
+		tampered := stored
+		tampered.Body.Timestamp++
+		if VerifyTransactionProof(header, &tampered, proof, 7) == nil {
+			t.Fatalf("tampered transaction %d verified", idx)
+		}
+
+		other := *header
+		other.BlockHash = [32]byte{4, 5, 6}
+		if VerifyTransactionProof(&other, &stored, proof, 7) == nil {
+			t.Fatalf("transaction %d verified with other header", idx)
+		}
+
+		if VerifyTransactionProof(header, &stored, proof, 8) == nil {
+			t.Fatalf("transaction %d verified on other chain", idx)
+		}
+	}
+}
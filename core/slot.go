@@ -0,0 +1,107 @@
+// Package core
+// @Description: 区块时间戳与时隙规则。时隙从创世区块的时间戳开始，每 SlotInterval 毫秒为一个时隙，
+// 打包节点在时隙 n（从 0 开始）内打包的区块高度不超过 n+1；区块时间戳不能早于父区块，也不能超前本地逻辑时钟
+// MaxFutureDrift 以上。时隙长度和容忍范围由创世参数确定，所有节点使用相同的规则
+package core
+
+import (
+	"github.com/chain-lab/go-norn/common"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+const (
+	DefaultSlotInterval   = 2000 // 默认出块时隙长度，单位 ms
+	DefaultMaxFutureDrift = 1000 // 默认区块时间戳允许超前本地逻辑时钟的范围，单位 ms
+)
+
+var (
+	errBlockBeforeParent  = errors.New("block timestamp before parent")
+	errBlockBeforeGenesis = errors.New("block timestamp before genesis")
+	errBlockFromFuture    = errors.New("block timestamp too far in the future")
+	errBlockSlotHeight    = errors.New("block height ahead of slot")
+	errSlotRulesNotInit   = errors.New("slot rules not initialized")
+)
+
+// SlotRules 由创世参数确定的区块时间规则
+type SlotRules struct {
+	GenesisTime    int64 // 创世区块时间戳
+	SlotInterval   int64 // 时隙长度，单位 ms
+	MaxFutureDrift int64 // 区块时间戳允许超前本地逻辑时钟的范围，单位 ms
+}
+
+// NewSlotRules
+//
+//	@Description: 根据创世区块的时间戳和创世参数创建区块时间规则，旧的创世参数中没有时隙参数时使用默认值
+//	@param genesisTime - 创世区块时间戳
+//	@param params - 创世参数
+//	@return *SlotRules - 区块时间规则
+func NewSlotRules(genesisTime int64, params *common.GenesisParams) *SlotRules {
+	rules := &SlotRules{
+		GenesisTime:    genesisTime,
+		SlotInterval:   DefaultSlotInterval,
+		MaxFutureDrift: DefaultMaxFutureDrift,
+	}
+
+	if params != nil && params.SlotInterval > 0 {
+		rules.SlotInterval = params.SlotInterval
+	}
+	if params != nil && params.MaxFutureDrift > 0 {
+		rules.MaxFutureDrift = params.MaxFutureDrift
+	}
+	return rules
+}
+
+// Slot 计算时间戳所在的时隙，创世区块所在的时隙为 0
+func (r *SlotRules) Slot(timestamp int64) int64 {
+	return (timestamp - r.GenesisTime) / r.SlotInterval
+}
+
+// SlotHeight 时间戳所在的时隙内允许打包的最大区块高度
+func (r *SlotRules) SlotHeight(timestamp int64) int64 {
+	return r.Slot(timestamp) + 1
+}
+
+// VerifyBlock
+//
+//	@Description: 检查区块时间戳与父区块和时隙的关系，不依赖本地时钟，同步历史区块时同样适用
+//	@receiver r
+//	@param block - 需要检查的区块
+//	@param parent - 父区块，为 nil 时不检查与父区块的关系
+//	@return error - 不满足规则时返回对应的错误
+func (r *SlotRules) VerifyBlock(block *common.Block, parent *common.Block) error {
+	if parent == nil {
+		return r.VerifyHeader(&block.Header, nil)
+	}
+	return r.VerifyHeader(&block.Header, &parent.Header)
+}
+
+// VerifyHeader 与 VerifyBlock 相同，用于只有区块头的情况
+func (r *SlotRules) VerifyHeader(header *common.BlockHeader, parent *common.BlockHeader) error {
+	timestamp := header.Timestamp
+	if timestamp < r.GenesisTime {
+		return errBlockBeforeGenesis
+	}
+
+	if parent != nil && timestamp < parent.Timestamp {
+		return errBlockBeforeParent
+	}
+
+	if header.Height > r.SlotHeight(timestamp) {
+		return errBlockSlotHeight
+	}
+	return nil
+}
+
+// VerifyDrift
+//
+//	@Description: 检查区块时间戳是否超前本地逻辑时钟 MaxFutureDrift 以上
+//	@receiver r
+//	@param block - 需要检查的区块
+//	@param now - 本地逻辑时钟
+//	@return error - 超前时返回 errBlockFromFuture
+func (r *SlotRules) VerifyDrift(block *common.Block, now int64) error {
+	if block.Header.Timestamp > now+r.MaxFutureDrift {
+		return errBlockFromFuture
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"github.com/chain-lab/go-norn/common"
+	"testing"
+)
+
+func testSlotBlock(height int64, timestamp int64) *common.Block {
+	return &common.Block{
+		Header: common.BlockHeader{
+			Height:    height,
+			Timestamp: timestamp,
+		},
+	}
+}
+
+func TestSlotRules(t *testing.T) {
+	genesisTime := int64(1700000000000)
+	rules := NewSlotRules(genesisTime, &common.GenesisParams{
+		SlotInterval:   1000,
+		MaxFutureDrift: 500,
+	})
+
+	parent := testSlotBlock(1, genesisTime+500)
+	if err := rules.VerifyBlock(testSlotBlock(2, genesisTime+1200), parent); err != nil {
+		t.Fatal(err)
+	}
+
+	// 时间戳早于父区块
+	if err := rules.VerifyBlock(testSlotBlock(2, genesisTime+400), parent); err != errBlockBeforeParent {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// 时隙 1 内最多打包高度为 2 的区块
+	if err := rules.VerifyBlock(testSlotBlock(3, genesisTime+1999), parent); err != errBlockSlotHeight {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := rules.VerifyBlock(testSlotBlock(1, genesisTime-1), nil); err != errBlockBeforeGenesis {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	block := testSlotBlock(2, genesisTime+1200)
+	if err := rules.VerifyDrift(block, genesisTime+700); err != nil {
+		t.Fatal(err)
+	}
+	if err := rules.VerifyDrift(block, genesisTime+699); err != errBlockFromFuture {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestSlotRulesDefault(t *testing.T) {
+	// 旧的创世参数中没有时隙参数
+	rules := NewSlotRules(0, &common.GenesisParams{})
+	if rules.SlotInterval != DefaultSlotInterval || rules.MaxFutureDrift != DefaultMaxFutureDrift {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+	if rules.SlotHeight(DefaultSlotInterval-1) != 1 || rules.SlotHeight(DefaultSlotInterval) != 2 {
+		t.Fatal("unexpected slot height")
+	}
+}
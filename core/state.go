@@ -0,0 +1,551 @@
+// Package core
+// @Description: data# 数据的状态树。为了让轻节点验证 data# 数据，区块参数中记录执行区块交易之后的状态根，
+// 状态树是以 sha256(data#<address>#<key>) 为路径的压缩稀疏 Merkle 树，只有一个叶子的子树直接由叶子节点表示。
+// 为了兼容已有的链，是否计算状态根由创世参数中的 DataVersion 决定，旧的链不计算状态根，轻节点也不提供 data# 数据查询
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/interfaces"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+const (
+	DataVersionNone uint8 = 0 // 旧版本的链，区块中没有状态根
+	DataVersionTrie uint8 = 1 // 区块参数中记录 data# 数据状态树的根哈希
+)
+
+const (
+	stateLeafNode     byte = 0  // 叶子节点，保存完整路径和数据的哈希
+	stateInternalNode byte = 1  // 内部节点，保存左右子节点的哈希
+	stateNodeSize          = 65 // 节点编码的长度，1 字节类型和两个 32 字节哈希
+	statePathBits          = 256
+)
+
+var (
+	errDataRootInvalid = errors.New("data root not match block data")
+	errStateNode       = errors.New("malformed state node")
+	errDataProofBytes  = errors.New("malformed data proof")
+	errDataNotProved   = errors.New("data not committed by data root")
+)
+
+// DataProof
+//
+//	@Description: 某个高度的区块中 data# 数据到状态根的证明，Found 为 false 时证明该数据不存在
+type DataProof struct {
+	Height int64
+	Found  bool
+	Value  []byte
+	Proof  []byte
+}
+
+// stateTrie
+//
+//	@Description: 状态树的读写，新生成的节点和数据先保存在内存中，验证通过后由 commit 写入数据库
+type stateTrie struct {
+	db      interfaces.DBInterface // 为 nil 时只在内存中计算，用于计算创世数据的状态根
+	pending map[common.Hash][]byte
+	values  map[common.Hash][]byte
+}
+
+func newStateTrie(db interfaces.DBInterface) *stateTrie {
+	return &stateTrie{
+		db:      db,
+		pending: make(map[common.Hash][]byte),
+		values:  make(map[common.Hash][]byte),
+	}
+}
+
+// stateDataPath 数据在状态树中的路径
+func stateDataPath(address, key []byte) common.Hash {
+	return sha256.Sum256(utils.DataAddressKey2DBKey(address, key))
+}
+
+// statePathBit 路径第 depth 位，0 为左子树，1 为右子树
+func statePathBit(path common.Hash, depth int) byte {
+	return (path[depth/8] >> (7 - depth%8)) & 1
+}
+
+// stateNodeHash 节点编码的哈希
+func stateNodeHash(kind byte, a, b common.Hash) (common.Hash, []byte) {
+	encoded := make([]byte, 0, stateNodeSize)
+	encoded = append(encoded, kind)
+	encoded = append(encoded, a[:]...)
+	encoded = append(encoded, b[:]...)
+	return sha256.Sum256(encoded), encoded
+}
+
+// putNode 将新生成的节点加入待写入的节点
+func (st *stateTrie) putNode(kind byte, a, b common.Hash) common.Hash {
+	hash, encoded := stateNodeHash(kind, a, b)
+	st.pending[hash] = encoded
+	return hash
+}
+
+// node 读取节点，返回节点类型和两个哈希
+func (st *stateTrie) node(hash common.Hash) (byte, common.Hash, common.Hash, error) {
+	encoded, ok := st.pending[hash]
+	if !ok {
+		if st.db == nil {
+			return 0, common.Hash{}, common.Hash{}, errStateNode
+		}
+		var err error
+		if encoded, err = st.db.Get(utils.StateNode2DBKey(hash)); err != nil {
+			return 0, common.Hash{}, common.Hash{}, err
+		}
+	}
+
+	if len(encoded) != stateNodeSize || encoded[0] > stateInternalNode {
+		return 0, common.Hash{}, common.Hash{}, errStateNode
+	}
+	return encoded[0], common.Hash(encoded[1:33]), common.Hash(encoded[33:]), nil
+}
+
+// value 根据数据的哈希读取数据
+func (st *stateTrie) value(hash common.Hash) ([]byte, error) {
+	if value, ok := st.values[hash]; ok {
+		return value, nil
+	}
+	if st.db == nil {
+		return nil, errStateNode
+	}
+	return st.db.Get(utils.StateValue2DBKey(hash))
+}
+
+// walk
+//
+//	@Description: 由根节点沿着路径向下查找，直到空节点或者叶子节点
+//	@receiver st
+//	@param root - 状态根
+//	@param path - 数据路径
+//	@return []common.Hash - 由上到下各层的兄弟节点
+//	@return common.Hash - 查找结束的节点，空节点时为空哈希
+//	@return error - 节点不存在或者格式错误时返回错误
+func (st *stateTrie) walk(root, path common.Hash) ([]common.Hash, common.Hash, error) {
+	siblings := make([]common.Hash, 0, 16)
+	current := root
+	for depth := 0; current != (common.Hash{}); depth++ {
+		kind, left, right, err := st.node(current)
+		if err != nil {
+			return nil, common.Hash{}, err
+		}
+		if kind == stateLeafNode {
+			break
+		}
+		if depth >= statePathBits {
+			return nil, common.Hash{}, errStateNode
+		}
+
+		if statePathBit(path, depth) == 0 {
+			siblings = append(siblings, right)
+			current = left
+		} else {
+			siblings = append(siblings, left)
+			current = right
+		}
+	}
+	return siblings, current, nil
+}
+
+// get 读取数据，数据不存在时 found 为 false
+func (st *stateTrie) get(root, path common.Hash) (value []byte, found bool, err error) {
+	_, terminal, err := st.walk(root, path)
+	if err != nil || terminal == (common.Hash{}) {
+		return nil, false, err
+	}
+
+	_, leafPath, valueHash, err := st.node(terminal)
+	if err != nil || leafPath != path {
+		return nil, false, err
+	}
+	value, err = st.value(valueHash)
+	return value, err == nil, err
+}
+
+// update
+//
+//	@Description: 写入数据，返回新的状态根，旧的状态根中的节点保持不变
+//	@receiver st
+//	@param root - 状态根
+//	@param path - 数据路径
+//	@param value - 数据
+//	@return common.Hash - 新的状态根
+//	@return error - 节点不存在或者格式错误时返回错误
+func (st *stateTrie) update(root, path common.Hash, value []byte) (common.Hash, error) {
+	siblings, terminal, err := st.walk(root, path)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	valueHash := common.Hash(sha256.Sum256(value))
+	st.values[valueHash] = append([]byte{}, value...)
+	current := st.putNode(stateLeafNode, path, valueHash)
+
+	// 路径上是其他数据的叶子节点时，在两条路径第一个不同的位置分叉
+	depth := len(siblings)
+	if terminal != (common.Hash{}) {
+		_, leafPath, _, err := st.node(terminal)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if leafPath != path {
+			split := depth
+			for statePathBit(path, split) == statePathBit(leafPath, split) {
+				split++
+			}
+			if statePathBit(path, split) == 0 {
+				current = st.putNode(stateInternalNode, current, terminal)
+			} else {
+				current = st.putNode(stateInternalNode, terminal, current)
+			}
+			for d := split - 1; d >= depth; d-- {
+				current = st.foldNode(path, d, current, common.Hash{})
+			}
+		}
+	}
+
+	for d := depth - 1; d >= 0; d-- {
+		current = st.foldNode(path, d, current, siblings[d])
+	}
+	return current, nil
+}
+
+// foldNode 将第 depth 层路径上的节点与兄弟节点合并为上一层的内部节点
+func (st *stateTrie) foldNode(path common.Hash, depth int, current, sibling common.Hash) common.Hash {
+	if statePathBit(path, depth) == 0 {
+		return st.putNode(stateInternalNode, current, sibling)
+	}
+	return st.putNode(stateInternalNode, sibling, current)
+}
+
+// applyTransactions
+//
+//	@Description: 按照 DataProcessor 的规则执行交易中的 set、append 指令，返回执行后的状态根
+//	@receiver st
+//	@param root - 执行前的状态根
+//	@param txs - 区块中的交易
+//	@return common.Hash - 执行后的状态根
+//	@return error - 状态树节点缺失时返回错误
+func (st *stateTrie) applyTransactions(root common.Hash, txs []common.Transaction) (common.Hash, error) {
+	for idx := range txs {
+		tx := &txs[idx]
+		if tx.Body.Data == nil {
+			continue
+		}
+		dc, err := utils.DeserializeDataCommand(tx.Body.Data)
+		if err != nil {
+			continue
+		}
+
+		path := stateDataPath(tx.Body.Receiver[:], dc.Key)
+		value := dc.Value
+		switch string(dc.Opt) {
+		case setCommandString:
+		case appendCommandString:
+			// append 的值需要是 json 对象，追加到已有的 json 数组末尾，已有的数据不是数组时不处理
+			mapValue := map[string]string{}
+			if json.Unmarshal(dc.Value, &mapValue) != nil {
+				continue
+			}
+			var mapArray []map[string]string
+			existing, found, err := st.get(root, path)
+			if err != nil {
+				return common.Hash{}, err
+			}
+			if found && json.Unmarshal(existing, &mapArray) != nil {
+				continue
+			}
+			if value, err = json.Marshal(append(mapArray, mapValue)); err != nil {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if root, err = st.update(root, path, value); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	return root, nil
+}
+
+// prove
+//
+//	@Description: 生成数据到状态根的证明，格式为 1 字节的结束节点类型（0 为空节点，1 为叶子节点并附带路径和数据哈希），
+//	之后是由上到下各层的兄弟节点哈希
+//	@receiver st
+//	@param root - 状态根
+//	@param path - 数据路径
+//	@return []byte - 证明
+//	@return *DataProof - 证明以及数据，不包含高度
+//	@return error - 状态树节点缺失时返回错误
+func (st *stateTrie) prove(root, path common.Hash) (*DataProof, error) {
+	siblings, terminal, err := st.walk(root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DataProof{Proof: make([]byte, 0, 65+32*len(siblings))}
+	if terminal == (common.Hash{}) {
+		result.Proof = append(result.Proof, 0)
+	} else {
+		_, leafPath, valueHash, err := st.node(terminal)
+		if err != nil {
+			return nil, err
+		}
+		result.Proof = append(result.Proof, 1)
+		result.Proof = append(result.Proof, leafPath[:]...)
+		result.Proof = append(result.Proof, valueHash[:]...)
+		if leafPath == path {
+			if result.Value, err = st.value(valueHash); err != nil {
+				return nil, err
+			}
+			result.Found = true
+		}
+	}
+
+	for _, sibling := range siblings {
+		result.Proof = append(result.Proof, sibling[:]...)
+	}
+	return result, nil
+}
+
+// commit 将新生成的节点和数据写入数据库
+func (st *stateTrie) commit() error {
+	if st.db == nil || len(st.pending) == 0 {
+		return nil
+	}
+
+	keys := make([][]byte, 0, len(st.pending)+len(st.values))
+	values := make([][]byte, 0, len(st.pending)+len(st.values))
+	for hash, encoded := range st.pending {
+		keys = append(keys, utils.StateNode2DBKey(hash))
+		values = append(values, encoded)
+	}
+	for hash, value := range st.values {
+		keys = append(keys, utils.StateValue2DBKey(hash))
+		values = append(values, value)
+	}
+
+	err := st.db.BatchInsert(keys, values)
+	st.pending = make(map[common.Hash][]byte)
+	st.values = make(map[common.Hash][]byte)
+	return err
+}
+
+// VerifyDataProof
+//
+//	@Description: 使用区块头中的状态根验证 data# 数据的证明
+//	@param root - 状态根，由 BlockDataRoot 得到
+//	@param address - 数据的地址
+//	@param key - 数据的 key
+//	@param value - 完整节点响应的数据，不存在时为 nil
+//	@param proof - 证明
+//	@return bool - 数据是否存在
+//	@return error - 证明格式错误或者与状态根不一致时返回错误
+func VerifyDataProof(root common.Hash, address, key, value, proof []byte) (bool, error) {
+	if len(proof) == 0 {
+		return false, errDataProofBytes
+	}
+
+	path := stateDataPath(address, key)
+	var current common.Hash
+	var leafPath common.Hash
+	rest := proof[1:]
+	switch proof[0] {
+	case 0:
+	case 1:
+		if len(rest) < 64 {
+			return false, errDataProofBytes
+		}
+		leafPath = common.Hash(rest[:32])
+		current, _ = stateNodeHash(stateLeafNode, leafPath, common.Hash(rest[32:64]))
+		rest = rest[64:]
+	default:
+		return false, errDataProofBytes
+	}
+	if len(rest)%32 != 0 || len(rest)/32 > statePathBits {
+		return false, errDataProofBytes
+	}
+
+	depth := len(rest) / 32
+	found := proof[0] == 1 && leafPath == path
+	if proof[0] == 1 && !found {
+		// 其他数据的叶子节点需要在同一条路径上，才能说明该数据不存在
+		for d := 0; d < depth; d++ {
+			if statePathBit(path, d) != statePathBit(leafPath, d) {
+				return false, errDataNotProved
+			}
+		}
+	}
+	if found && sha256.Sum256(value) != [32]byte(proof[33:65]) {
+		return false, errDataNotProved
+	}
+
+	for d := depth - 1; d >= 0; d-- {
+		sibling := common.Hash(rest[d*32 : d*32+32])
+		if statePathBit(path, d) == 0 {
+			current, _ = stateNodeHash(stateInternalNode, current, sibling)
+		} else {
+			current, _ = stateNodeHash(stateInternalNode, sibling, current)
+		}
+	}
+	if current != root {
+		return false, errDataNotProved
+	}
+	return found, nil
+}
+
+// BlockDataRoot
+//
+//	@Description: 获取区块头中的状态根，创世区块的状态根在创世参数中，其他区块在区块参数中
+//	@param header - 区块头
+//	@return common.Hash - 状态根
+//	@return error - 区块参数解析失败时返回错误
+func BlockDataRoot(header *common.BlockHeader) (common.Hash, error) {
+	if header.Height == 0 {
+		params, err := utils.DeserializeGenesisParams(header.Params)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return params.DataRoot, nil
+	}
+
+	params, err := utils.DeserializeGeneralParams(header.Params)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return params.DataRoot, nil
+}
+
+// genesisDataRoot
+//
+//	@Description: 按照创世文件中的顺序写入创世数据，计算创世区块的状态根
+//	@param trie - 状态树，为 nil 时只计算状态根
+//	@param alloc - 创世数据
+//	@return common.Hash - 状态根
+//	@return error - 地址格式错误时返回错误
+func genesisDataRoot(trie *stateTrie, alloc []GenesisAlloc) (common.Hash, error) {
+	if trie == nil {
+		trie = newStateTrie(nil)
+	}
+
+	var root common.Hash
+	for _, a := range alloc {
+		address, err := hex.DecodeString(a.Address)
+		if err != nil || len(address) != 20 {
+			return common.Hash{}, errors.New("invalid genesis alloc address")
+		}
+		if root, err = trie.update(root, stateDataPath(address, []byte(a.Key)), []byte(a.Value)); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	return root, nil
+}
+
+// DataVersion 创世参数中的状态树版本，还没有创世区块时为 DataVersionNone
+func (bc *BlockChain) DataVersion() uint8 {
+	genesisParams := bc.currentGenesisParams()
+	if genesisParams == nil {
+		return DataVersionNone
+	}
+	return genesisParams.DataVersion
+}
+
+// nextDataRoot
+//
+//	@Description: 在父区块的状态上执行交易，计算区块的状态根
+//	@receiver bc
+//	@param parent - 父区块
+//	@param txs - 区块中的交易
+//	@return common.Hash - 状态根
+//	@return *stateTrie - 保存新节点的状态树，需要在区块通过验证后 commit
+//	@return error - 父区块的状态不存在时返回错误
+func (bc *BlockChain) nextDataRoot(parent *common.Block, txs []common.Transaction) (common.Hash, *stateTrie, error) {
+	parentRoot, err := BlockDataRoot(&parent.Header)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+
+	trie := newStateTrie(bc.db)
+	root, err := trie.applyTransactions(parentRoot, txs)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	return root, trie, nil
+}
+
+// verifyDataRoot
+//
+//	@Description: 检查区块参数中的状态根与在父区块状态上执行交易的结果一致，一致时写入新的状态树节点。
+//	状态树节点按照哈希存储，分叉上的区块写入的节点不会影响其他区块
+//	@receiver bc
+//	@param block - 需要验证的区块
+//	@param parent - 父区块
+//	@return error - 状态根不一致时返回 errDataRootInvalid
+func (bc *BlockChain) verifyDataRoot(block *common.Block, parent *common.Block) error {
+	if bc.DataVersion() == DataVersionNone {
+		return nil
+	}
+
+	blockHash := block.BlockHash()
+	if bc.verifiedRoots.Contains(blockHash) {
+		return nil
+	}
+
+	params, err := utils.DeserializeGeneralParams(block.Header.Params)
+	if err != nil {
+		return err
+	}
+	root, trie, err := bc.nextDataRoot(parent, block.Transactions)
+	if err != nil {
+		return err
+	}
+	if root != params.DataRoot {
+		return errDataRootInvalid
+	}
+
+	if err = trie.commit(); err != nil {
+		return err
+	}
+	bc.verifiedRoots.Add(blockHash, nil)
+	return nil
+}
+
+// ProveData
+//
+//	@Description: 生成 data# 数据到某个高度区块状态根的证明，请求的高度超过本地高度时使用本地的最新区块
+//	@receiver bc
+//	@param address - 数据的地址
+//	@param key - 数据的 key
+//	@param height - 轻节点已经验证的区块头高度
+//	@return *DataProof - 数据和证明
+//	@return error - 链没有状态根或者区块不存在时返回错误
+func (bc *BlockChain) ProveData(address, key []byte, height int64) (*DataProof, error) {
+	if bc.DataVersion() == DataVersionNone {
+		return nil, errDataRootInvalid
+	}
+
+	if latest := bc.Height(); height > latest {
+		height = latest
+	}
+	block, err := bc.GetBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	root, err := BlockDataRoot(&block.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := newStateTrie(bc.db).prove(root, stateDataPath(address, key))
+	if err != nil {
+		return nil, err
+	}
+	proof.Height = height
+	return proof, nil
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	"testing"
+)
+
+// testDataTransaction 构建 data 字段为数据指令的交易，只用于执行指令，没有签名
+func testDataTransaction(t *testing.T, receiver [20]byte, opt, key, value string) common.Transaction {
+	data, err := utils.SerializeDataCommand(&common.DataCommand{
+		Opt:   []byte(opt),
+		Key:   []byte(key),
+		Value: []byte(value),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return common.Transaction{Body: common.TransactionBody{Receiver: receiver, Data: data}}
+}
+
+func TestStateTrieProof(t *testing.T) {
+	db, err := utils.NewMemoryLevelDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	trie := newStateTrie(db)
+	address := [20]byte{0xaa}
+
+	var root common.Hash
+	for i := 0; i < 64; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if root, err = trie.update(root, stateDataPath(address[:], []byte(key)), []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = trie.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 修改一个数据得到新的状态根，旧的状态根仍然可以生成证明
+	oldRoot := root
+	path := stateDataPath(address[:], []byte("key0"))
+	if root, err = trie.update(root, path, []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	if err = trie.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := newStateTrie(db)
+	for i := 0; i < 64; i++ {
+		key := []byte(fmt.Sprintf("key%d", i))
+		proof, err := reader.prove(oldRoot, stateDataPath(address[:], key))
+		if err != nil || !proof.Found {
+			t.Fatalf("key%d not found, error %v", i, err)
+		}
+		if found, err := VerifyDataProof(oldRoot, address[:], key, proof.Value, proof.Proof); err != nil || !found {
+			t.Fatalf("key%d proof rejected: %v", i, err)
+		}
+	}
+
+	proof, err := reader.prove(root, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, err := VerifyDataProof(root, address[:], []byte("key0"), []byte("changed"), proof.Proof); err != nil || !found {
+		t.Fatalf("updated value rejected: %v", err)
+	}
+
+	// 数据被修改、状态根不一致或者证明被截断时验证失败
+	if _, err = VerifyDataProof(root, address[:], []byte("key0"), []byte("key0"), proof.Proof); err == nil {
+		t.Fatal("old value verified with new root")
+	}
+	if _, err = VerifyDataProof(oldRoot, address[:], []byte("key0"), []byte("changed"), proof.Proof); err == nil {
+		t.Fatal("new value verified with old root")
+	}
+	if _, err = VerifyDataProof(root, address[:], []byte("key0"), []byte("changed"),
+		proof.Proof[:len(proof.Proof)-32]); err == nil {
+		t.Fatal("truncated proof verified")
+	}
+
+	// 不存在的数据可以证明不存在，但是不能被证明为存在
+	for _, key := range []string{"missing", "key64", "other"} {
+		proof, err = reader.prove(root, stateDataPath(address[:], []byte(key)))
+		if err != nil || proof.Found {
+			t.Fatalf("%s found, error %v", key, err)
+		}
+		if found, err := VerifyDataProof(root, address[:], []byte(key), nil, proof.Proof); err != nil || found {
+			t.Fatalf("%s absence rejected: %v", key, err)
+		}
+		if found, _ := VerifyDataProof(root, address[:], []byte(key), []byte("key1"), proof.Proof); found {
+			t.Fatalf("%s proved with absence proof", key)
+		}
+	}
+
+	// 其他数据的证明不能用来证明该数据不存在
+	proof, _ = reader.prove(root, stateDataPath(address[:], []byte("key1")))
+	if _, err = VerifyDataProof(root, address[:], []byte("key2"), nil, proof.Proof); err == nil {
+		t.Fatal("absence proved with proof of other data")
+	}
+}
+
+func TestStateApplyTransactions(t *testing.T) {
+	receiver := [20]byte{0xbb}
+	trie := newStateTrie(nil)
+
+	// 与 DataProcessor 的规则一致：set 直接写入，append 向 json 数组追加，数据不是数组或者追加的值不是 json 对象时跳过
+	txs := []common.Transaction{
+		testDataTransaction(t, receiver, "set", "name", "norn"),
+		testDataTransaction(t, receiver, "append", "log", `{"a":"1"}`),
+		testDataTransaction(t, receiver, "append", "log", `{"a":"2"}`),
+		testDataTransaction(t, receiver, "append", "log", "not json"),
+		testDataTransaction(t, receiver, "append", "name", `{"a":"1"}`),
+		testDataTransaction(t, receiver, "delete", "name", ""),
+		{Body: common.TransactionBody{Receiver: receiver, Data: []byte("random")}},
+	}
+	root, err := trie.applyTransactions(common.Hash{}, txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"name": "norn",
+		"log":  `[{"a":"1"},{"a":"2"}]`,
+	}
+	for key, value := range expected {
+		got, found, err := trie.get(root, stateDataPath(receiver[:], []byte(key)))
+		if err != nil || !found || string(got) != value {
+			t.Fatalf("%s is %q, error %v", key, got, err)
+		}
+	}
+
+	// 相同的交易在任意节点上得到相同的状态根
+	again, err := newStateTrie(nil).applyTransactions(common.Hash{}, txs)
+	if err != nil || again != root {
+		t.Fatal("data root not deterministic")
+	}
+}
+
+func TestDataRootVerify(t *testing.T) {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	params, err := crypto.GenerateGenesisParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TimeParam = 1000
+	params.DataVersion = DataVersionTrie
+	address := "0a0f870f81376f77db1981f94f39b719f5eb3f7c"
+	addressBytes, _ := hex.DecodeString(address)
+	g, err := SealGenesis(7, 1700000000000, params,
+		[]GenesisAlloc{{Address: address, Key: "name", Value: "genesis"}}, prv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Consensus.DataVersion != DataVersionTrie {
+		t.Fatal("data version not written to genesis file")
+	}
+
+	chain, _ := testGenesisChain(t)
+	if err = chain.InitGenesis(g); err != nil {
+		t.Fatal(err)
+	}
+	genesis, err := chain.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 创世数据可以由创世区块的状态根证明
+	genesisRoot, err := BlockDataRoot(&genesis.Header)
+	if err != nil || genesisRoot == (common.Hash{}) {
+		t.Fatalf("genesis data root missing, error %v", err)
+	}
+	proof, err := chain.ProveData(addressBytes, []byte("name"), 10)
+	if err != nil || proof.Height != 0 || !proof.Found {
+		t.Fatalf("genesis data not proved, error %v", err)
+	}
+	if found, err := VerifyDataProof(genesisRoot, addressBytes, []byte("name"), proof.Value, proof.Proof); err != nil ||
+		!found || string(proof.Value) != "genesis" {
+		t.Fatalf("genesis data proof rejected: %v", err)
+	}
+
+	// 区块参数中的状态根需要与在父区块状态上执行交易的结果一致
+	txs := []common.Transaction{testDataTransaction(t, [20]byte(addressBytes), "set", "name", "block")}
+	root, _, err := chain.nextDataRoot(genesis, txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildBlock := func(hash byte, dataRoot common.Hash) *common.Block {
+		paramsBytes, err := utils.SerializeGeneralParams(&common.GeneralParams{DataRoot: dataRoot})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &common.Block{
+			Header:       common.BlockHeader{Height: 1, BlockHash: [32]byte{hash}, Params: paramsBytes},
+			Transactions: txs,
+		}
+	}
+
+	if err = chain.verifyDataRoot(buildBlock(1, genesisRoot), genesis); err != errDataRootInvalid {
+		t.Fatalf("stale data root accepted, error %v", err)
+	}
+	block := buildBlock(2, root)
+	if err = chain.verifyDataRoot(block, genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	// 验证通过后写入状态树节点，可以生成新状态根的证明
+	proof, err = newStateTrie(chain.db).prove(root, stateDataPath(addressBytes, []byte("name")))
+	if err != nil || string(proof.Value) != "block" {
+		t.Fatalf("block state not committed, error %v", err)
+	}
+}
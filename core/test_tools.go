@@ -3,7 +3,6 @@ package core
 import (
 	"crypto/ecdsa"
 	"crypto/rand"
-	"crypto/sha256"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/crypto"
 	log "github.com/sirupsen/logrus"
@@ -12,7 +11,7 @@ import (
 )
 
 // buildTransaction 仅用于测试，也是一个构建交易的例子
-func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
+func buildTransaction(key *ecdsa.PrivateKey, chainID int64) *common.Transaction {
 	data := make([]byte, 32)
 	rand.Read(data)
 	timestamp := time.Now().UnixMilli()
@@ -30,9 +29,7 @@ func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
 	txBody.WriteAsRoot(writer)
 	txBodyBytes := writer.Bytes()
 
-	hash := sha256.New()
-	hash.Write(txBodyBytes)
-	txHashBytes := hash.Sum(nil)
+	txHashBytes := common.TransactionSigningHash(txBodyBytes, chainID)
 	txSignatureBytes, err := ecdsa.SignASN1(rand.Reader, key, txHashBytes)
 
 	if err != nil {
@@ -3,11 +3,13 @@
 package core
 
 import (
+	"context"
 	"encoding/hex"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/metrics"
 	log "github.com/sirupsen/logrus"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -15,11 +17,6 @@ const (
 	maxTxPoolSize     = 20480 // 交易池存放的的最多交易数量
 )
 
-var (
-	txOnce     sync.Once       // 只实例化一次交易池，golang 下的单例模式
-	txPoolInst *TxPool   = nil // 交易池实例，单例模式
-)
-
 type TxPool struct {
 	chain        *BlockChain              // 区块链实例，用于查询交易是否存在
 	txQueue      chan string              // 交易打包等待队列，存放哈希值
@@ -29,31 +26,44 @@ type TxPool struct {
 
 	flags  sync.Map
 	height int
+
+	closed atomic.Bool // 交易池停止后不再接收新的交易
 }
 
 // NewTxPool
 //
-//	@Description: 获取一个交易池单例，如果有则直接返回
+//	@Description: 创建一个交易池实例
 //	@param chain - 区块链实例
 //	@return *TxPool - 交易池实例
 func NewTxPool(chain *BlockChain) *TxPool {
-	txOnce.Do(func() {
-		txPoolInst = &TxPool{
-			chain:   chain,
-			txQueue: make(chan string, 10240),
+	return &TxPool{
+		chain:   chain,
+		txQueue: make(chan string, 10240),
 
-			count: 0,
-		}
-	})
-	return txPoolInst
+		count: 0,
+	}
 }
 
-// GetTxPoolInst
+// Start
 //
-//	@Description: 获取交易池实例
-//	@return *TxPool
-func GetTxPoolInst() *TxPool {
-	return txPoolInst
+//	@Description: 启动交易池，交易池没有后台协程，只恢复接收交易
+//	@receiver pool - 交易池实例
+//	@param ctx - 父 context
+//	@return error
+func (pool *TxPool) Start(ctx context.Context) error {
+	pool.closed.Store(false)
+	return nil
+}
+
+// Stop
+//
+//	@Description: 停止交易池，停止后不再接收新的交易
+//	@receiver pool - 交易池实例
+//	@param ctx - 停止的超时 context
+//	@return error
+func (pool *TxPool) Stop(ctx context.Context) error {
+	pool.closed.Store(true)
+	return nil
 }
 
 func (pool *TxPool) rangeFunc(key, value interface{}) bool {
@@ -133,7 +143,7 @@ func (pool *TxPool) Package() []common.Transaction {
 //	@receiver pool - 交易池实例
 //	@param transaction - 一笔交易的实例
 func (pool *TxPool) Add(transaction *common.Transaction) {
-	if pool.count > maxTxPoolSize {
+	if pool.closed.Load() || pool.count > maxTxPoolSize {
 		return
 	}
 
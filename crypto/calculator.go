@@ -1,8 +1,10 @@
 // Package crypto
-// @Description: VDF 计算协程，从 channel 中接收任务，计算后再放入 channel
+// @Description: VDF 计算器，每个 seed 对应一个可以取消的计算任务，计算过程中记录进度并定期保存检查点
 package crypto
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"github.com/chain-lab/go-norn/common"
@@ -10,108 +12,151 @@ import (
 	log "github.com/sirupsen/logrus"
 	"math/big"
 	"sync"
+	"time"
+)
+
+const (
+	progressRounds   = 10000   // 每计算多少轮检查一次取消信号并更新计算进度
+	checkpointRounds = 1000000 // 每计算多少轮保存一次检查点
 )
 
 var (
-	calculatorOnce sync.Once                   // 单例，实例化一次 calculator
-	calculatorInst *Calculator                 // calculator 的实例
-	zero           *big.Int    = big.NewInt(0) // 常量，大整数下的 0
+	zero *big.Int = big.NewInt(0) // 常量，大整数下的 0
 )
 
+// VDFResult
+//
+//	@Description: 一轮 VDF 计算的结果，计算结果和证明总是和输入的 seed 成对保存
+type VDFResult struct {
+	Seed   *big.Int // 本轮计算的输入
+	Result *big.Int // 计算结果 seed^(2^t) mod n
+	Proof  *big.Int // 证明 pi
+}
+
+// VDFCheckpointStore
+//
+//	@Description: VDF 检查点的持久化接口，节点重启后可以从检查点继续未完成的计算
+type VDFCheckpointStore interface {
+	SaveVDFCheckpoint(cp *common.VDFCheckpoint) error
+	LoadVDFCheckpoint() (*common.VDFCheckpoint, error)
+}
+
 type Calculator struct {
-	// 接收传入的新参数
-	seedChannel      chan *big.Int
-	prevProofChannel chan *big.Int
-	// 传出计算结果
-	resultChannel chan *big.Int
-	proofChannel  chan *big.Int
-
-	// 这三个参数区块存储在创世区块上
-	proofParam *big.Int
-	order      *big.Int
-	timeParam  int64
+	// 计算所使用的 VDF 方案，方案和参数存储在创世区块上
+	vdf VDF
 
+	// 上一轮的 seed，当前轮的 seed 以及证明当前 seed 正确的 proof
 	prevSeed *big.Int
 	seed     *big.Int
 	proof    *big.Int
-
-	changed    bool
-	changeLock sync.RWMutex
+	// 当前 seed 的计算结果，计算完成之前为 nil
+	result *VDFResult
+
+	// 计算器的根 context，以及当前计算任务的取消函数和编号
+	ctx       context.Context
+	stop      context.CancelFunc
+	jobCancel context.CancelFunc
+	jobID     uint64
+	jobs      sync.WaitGroup
+
+	// 检查点存储、保存检查点的间隔轮数以及当前任务的计算进度
+	store              VDFCheckpointStore
+	checkpointInterval int64
+	progress           float64
+	eta                time.Duration
+
+	lock sync.RWMutex
 }
 
-// GetCalculatorInstance 所有的对 VDF 计算类的操作必须从这里获取实例
-func GetCalculatorInstance() *Calculator {
-	if calculatorInst == nil {
-		log.Fatal("Calculator not init.")
-		return nil
+// NewCalculator
+//
+//	@Description: 创建一个 VDF 计算实例，在调用 AppendNewSeed 之前不会开始计算
+//	@param vdf - 计算所使用的 VDF 方案
+//	@return *Calculator - 计算实例
+func NewCalculator(vdf VDF) *Calculator {
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &Calculator{
+		vdf: vdf,
+
+		// 当前阶段的输入和证明
+		prevSeed: big.NewInt(0),
+		seed:     big.NewInt(0),
+		proof:    big.NewInt(0),
+
+		ctx:  ctx,
+		stop: stop,
+
+		checkpointInterval: checkpointRounds,
 	}
-	return calculatorInst
 }
 
-// CalculatorInitialization
+// SetCheckpointStore
 //
-//	@Description: 初始化 VDF 计算实例
-//	@param pp - 证明所用到的安全参数，对应论文中的 l
-//	@param order - 计算所在的群的阶
-//	@param t - 计算的时间参数 t，需要计算的为 m^{2^{t}}
-func CalculatorInitialization(pp *big.Int, order *big.Int, t int64) {
-	calculatorOnce.Do(func() {
-		calculatorInst = &Calculator{
-			// 传递消息的管道，外界 -> calculator
-			seedChannel:      make(chan *big.Int, 32),
-			prevProofChannel: make(chan *big.Int, 32),
-			// 传递消息的管道， calculator -> 外界
-			resultChannel: make(chan *big.Int, 32),
-			proofChannel:  make(chan *big.Int, 32),
-
-			// 证明参数，阶数，时间参数
-			proofParam: pp,
-			order:      order,
-			timeParam:  t,
-
-			// 当前阶段的输出和证明
-			prevSeed: big.NewInt(0),
-			seed:     big.NewInt(0),
-			proof:    big.NewInt(0),
-
-			// 输入是否出现变化（是否需要直接进入下一轮）
-			changed: false,
-		}
+//	@Description: 设置检查点的存储，设置后计算任务会定期保存检查点，并在 seed 相同时从检查点继续计算
+//	@receiver c - 计算实例
+//	@param store - 检查点存储
+func (c *Calculator) SetCheckpointStore(store VDFCheckpointStore) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.store = store
+}
 
-		metrics.RoutineCreateCounterObserve(10)
-		go calculatorInst.run()
-		//go func(c *Calculator) {
-		//	for {
-		//		time.Sleep(100 * time.Millisecond)
-		//		log.Infof("VDF Seed: %s", hex.EncodeToString(c.seed.Bytes()))
-		//	}
-		//}(calculatorInst)
-	})
+// Stop
+//
+//	@Description: 取消当前的计算任务并等待任务退出，任务退出前会保存检查点，停止后不会再开始新的计算
+//	@receiver c - 计算实例
+//	@param ctx - 等待的超时 context
+//	@return error - 等待超时时返回 ctx 的错误
+func (c *Calculator) Stop(ctx context.Context) error {
+	c.stop()
+
+	done := make(chan struct{})
+	go func() {
+		c.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // GetSeedParams
 //
-//	@Description: 读取计算信息，如果channel中有数据则优先获取
+//	@Description: 读取计算信息，如果当前 seed 已经完成计算则返回计算结果和证明，否则返回当前的 seed 和它的证明。
+//	计算结果不再从通道中取出，在新的 seed 到来之前每次调用都返回相同的结果，调用方不能用返回值是否变化判断是否有新的计算结果，
+//	同一个结果在多个时隙被用于出块时由时隙规则限制
 //	@receiver c - 计算实例
 //	@return *big.Int - 计算结果
 //	@return *big.Int - 证明 pi
 func (c *Calculator) GetSeedParams() (*big.Int, *big.Int) {
-	seed := new(big.Int)
-	proof := new(big.Int)
-
-	log.Debugf("Channel length: %d", len(c.resultChannel))
-	if len(c.resultChannel) != 0 {
-		seed = <-c.resultChannel
-		proof = <-c.proofChannel
-		return seed, proof
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	// 结果和证明在同一个结构体中保存，不会出现结果和证明不匹配的情况
+	if c.result != nil {
+		return new(big.Int).Set(c.result.Result), new(big.Int).Set(c.result.Proof)
 	}
 
-	c.changeLock.RLock()
-	seed.SetBytes(c.seed.Bytes())
-	proof.SetBytes(c.proof.Bytes())
-	c.changeLock.RUnlock()
+	return new(big.Int).Set(c.seed), new(big.Int).Set(c.proof)
+}
 
-	return seed, proof
+// Progress
+//
+//	@Description: 获取当前计算任务的进度
+//	@receiver c - 计算实例
+//	@return float64 - 计算进度百分比
+//	@return time.Duration - 预计剩余时间
+func (c *Calculator) Progress() (float64, time.Duration) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.progress, c.eta
 }
 
 // VerifyBlockVDF
@@ -122,16 +167,13 @@ func (c *Calculator) GetSeedParams() (*big.Int, *big.Int) {
 //	@param proof - 证明参数
 //	@return bool - 是否正确
 func (c *Calculator) VerifyBlockVDF(seed *big.Int, proof *big.Int) bool {
-	c.changeLock.Lock()
-	defer c.changeLock.Unlock()
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 
 	// 如果区块的 seed 和上一个参数相同或 seed 与当前的 seed 相同，或者在不为 0 的情况下验证成功
 	// 返回参数正确
 	if c.prevSeed.Cmp(seed) == 0 || c.seed.Cmp(seed) == 0 || (c.seed.Cmp(
-		zero) != 0 && c.
-		Verify(c.
-			seed,
-			proof, seed)) {
+		zero) != 0 && c.Verify(c.seed, proof, seed)) {
 		return true
 	}
 
@@ -145,25 +187,39 @@ func (c *Calculator) VerifyBlockVDF(seed *big.Int, proof *big.Int) bool {
 //	@param seed - 区块携带的 seed
 //	@param proof - 证明 pi
 func (c *Calculator) AppendNewSeed(seed *big.Int, proof *big.Int) {
-	c.changeLock.Lock()
-	defer c.changeLock.Unlock()
+	// 先复制当前的 seed，在锁外验证证明，验证期间不阻塞读取计算参数和验证区块
+	c.lock.RLock()
+	prevSeed, current := new(big.Int).Set(c.prevSeed), new(big.Int).Set(c.seed)
+	c.lock.RUnlock()
 
-	log.Debugln("Now VDF seed: %s", hex.EncodeToString(c.seed.Bytes()))
+	log.Debugf("Now VDF seed: %s", hex.EncodeToString(current.Bytes()))
 
 	// 检查如果当前的 seed 没有变化就直接返回 或者
 	// 如果当前的 seed 不是初始的0，并且输入无法通过验证则不更新
-	if c.prevSeed.Cmp(seed) == 0 || c.seed.Cmp(seed) == 0 || (c.seed.Cmp(zero) != 0 && !c.Verify(c.seed, proof, seed)) {
-		log.Debugln("Block VDF verify failed seed: %s, result: %s",
-			hex.EncodeToString(c.seed.Bytes()), hex.EncodeToString(seed.
+	if prevSeed.Cmp(seed) == 0 || current.Cmp(seed) == 0 || (current.Cmp(zero) != 0 && !c.Verify(current, proof, seed)) {
+		log.Debugf("Block VDF verify failed seed: %s, result: %s",
+			hex.EncodeToString(current.Bytes()), hex.EncodeToString(seed.
 				Bytes()))
 		return
 	}
 
-	c.changed = true
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// 验证期间 seed 已经被其他区块更新，证明是针对旧的 seed 的，不再更新
+	if c.seed.Cmp(current) != 0 {
+		log.Debugf("VDF seed changed during verify, skip seed: %s", hex.EncodeToString(seed.Bytes()))
+		return
+	}
+
 	log.Debugf("New Seed: %s, Proof: %s", hex.EncodeToString(seed.Bytes()),
 		hex.EncodeToString(proof.Bytes()))
-	c.seedChannel <- seed
-	c.prevProofChannel <- proof
+
+	c.prevSeed.Set(c.seed)
+	c.seed = new(big.Int).Set(seed)
+	c.proof = new(big.Int).Set(proof)
+	c.result = nil
+	c.startJob()
 }
 
 // GenerateParams
@@ -198,105 +254,210 @@ func GenerateParams() (*big.Int, *big.Int, error) {
 	return n, pp, nil
 }
 
-// run
+// startJob
 //
-//	@Description: VDF 计算运行主程序，如果其它节点传入了正确的参数，会中断当前计算并且开始新一轮的计算
-//	@receiver c
-func (c *Calculator) run() {
-	for {
-		select {
-		case seed := <-c.seedChannel:
-			c.changeLock.RLock()
-			log.Infoln("Start new VDF calculate.")
-
-			c.changed = false
-			c.prevSeed.SetBytes(c.seed.Bytes())
-			c.seed.SetBytes(seed.Bytes())
-			log.Infof("Set seed to %s", hex.EncodeToString(c.seed.Bytes()))
-			c.proof = <-c.prevProofChannel
-			c.changeLock.RUnlock()
-
-			result, pi := c.calculate(seed)
-
-			if pi != nil {
-				log.Debugf("Calculate result: %s", hex.EncodeToString(result.
-					Bytes()))
-				log.Debugf("Calculate proof: %s", hex.EncodeToString(pi.Bytes()))
-				c.resultChannel <- result
-				c.proofChannel <- pi
-			}
-		}
+//	@Description: 取消正在进行的计算任务，并对当前的 seed 开始新的计算任务，调用时需要持有写锁
+//	@receiver c - 计算实例
+func (c *Calculator) startJob() {
+	if c.jobCancel != nil {
+		c.jobCancel()
+	}
+
+	// 计算器已经停止，不再开始新的计算
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.jobCancel = cancel
+	c.jobID++
+	c.progress, c.eta = 0, 0
+
+	metrics.RoutineCreateCounterObserve(10)
+	c.jobs.Add(1)
+	go c.runJob(ctx, c.jobID, new(big.Int).Set(c.seed))
+}
+
+// runJob
+//
+//	@Description: VDF 计算任务，如果其它节点传入了正确的参数，任务会被取消并且开始新一轮的计算
+//	@receiver c - 计算实例
+//	@param ctx - 任务的 context
+//	@param id - 任务编号，用于丢弃已经被替换的任务的结果
+//	@param seed - 当前轮计算的 seed
+func (c *Calculator) runJob(ctx context.Context, id uint64, seed *big.Int) {
+	defer c.jobs.Done()
+	log.Infof("Start new VDF calculate with seed %s", hex.EncodeToString(seed.Bytes()))
+
+	result, err := c.evaluate(ctx, seed, c.loadCheckpoint(seed), id)
+	if err != nil {
+		log.Infoln("VDF seed changed")
+		return
 	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// 计算期间 seed 被替换，丢弃计算结果
+	if c.jobID != id {
+		return
+	}
+
+	log.Debugf("Calculate result: %s", hex.EncodeToString(result.Result.Bytes()))
+	log.Debugf("Calculate proof: %s", hex.EncodeToString(result.Proof.Bytes()))
+	c.result = result
 }
 
-// calculate
+// Evaluate
 //
-//	@Description: 输入参数 seed，根据对象的参数来计算 VDF，参考论文：A survey of two verifiable delay functions
+//	@Description: 同步地计算 seed 的 VDF 结果和证明，不保存检查点，也不影响计算器当前的任务
+//	@receiver c - 计算实例
+//	@param ctx - 取消计算使用的 context
+//	@param seed - 计算的输入
+//	@return *VDFResult - 计算结果
+//	@return error - 计算被取消时返回 ctx 的错误
+func (c *Calculator) Evaluate(ctx context.Context, seed *big.Int) (*VDFResult, error) {
+	return c.evaluate(ctx, seed, nil, 0)
+}
+
+// evaluate
+//
+//	@Description: 输入参数 seed，使用计算器的 VDF 方案逐轮进行计算
 //	@receiver c
+//	@param ctx - 取消计算使用的 context
 //	@param seed - 当前轮计算的 seed
-//	@return *big.Int - VDF 计算结果
-//	@return *big.Int - VDF 证明 pi
-func (c *Calculator) calculate(seed *big.Int) (*big.Int, *big.Int) {
-	pi, r := big.NewInt(1), big.NewInt(1)
-	g := new(big.Int)
-	result := new(big.Int)
-	result.SetBytes(seed.Bytes())
-
-	// result = g^(2^t)
-	// 注： 这里如果直接计算 m^a mod n 的时间复杂度是接近 O(t) 的
-	// 所以在 t 足够大的情况下是可以抵御攻击的
-	for round := int64(0); round < c.timeParam; round++ {
-		//log.Infoln(round)
-		if c.changed {
-			log.Infoln("VDF seed changed")
-			return nil, nil
+//	@param cp - 从该检查点继续计算，为 nil 时从头开始
+//	@param job - 计算任务编号，不为 0 时记录计算进度并保存检查点
+//	@return *VDFResult - VDF 计算结果和证明 pi
+//	@return error - 计算被取消时返回 ctx 的错误
+func (c *Calculator) evaluate(ctx context.Context, seed *big.Int, cp *common.VDFCheckpoint, job uint64) (*VDFResult, error) {
+	state := c.vdf.NewState(seed, cp)
+	rounds := c.vdf.Rounds()
+	start := state.Round()
+	if cp != nil {
+		log.Infof("Resume VDF calculate from round %d.", start)
+	}
+
+	begin := time.Now()
+	for round := start; round < rounds; round = state.Round() {
+		if round%progressRounds == 0 {
+			select {
+			case <-ctx.Done():
+				// 计算器停止时保存当前进度，seed 被替换时不需要保存
+				if job != 0 && c.ctx.Err() != nil && round != start {
+					c.saveCheckpoint(state.Checkpoint())
+				}
+				return nil, ctx.Err()
+			default:
+			}
+
+			if job != 0 {
+				c.reportProgress(job, round, start, begin)
+			}
 		}
 
-		// result = result^2 mod n
-		result.Mul(result, result)
-		result.Mod(result, c.order)
+		if job != 0 && round != start && round%c.checkpointInterval == 0 {
+			c.saveCheckpoint(state.Checkpoint())
+		}
+
+		state.Step()
+	}
 
-		// tmp = 2 * r
-		tmp := big.NewInt(2)
-		b := new(big.Int)
+	if job != 0 {
+		c.reportProgress(job, rounds, start, begin)
+	}
 
-		tmp.Mul(tmp, r)
+	return state.Result(), nil
+}
 
-		b.Div(tmp, c.proofParam) // b = tmp / l
-		r.Mod(tmp, c.proofParam) // r = tmp % l
-		// pi = g^b * pi^2 mod n
-		pi.Mul(pi, pi)
-		g.Exp(seed, b, c.order)
-		pi.Mul(pi, g)
-		pi.Mod(pi, c.order)
+// reportProgress
+//
+//	@Description: 记录当前任务的计算进度和预计剩余时间，并更新指标
+//	@receiver c - 计算实例
+//	@param job - 计算任务编号，已经被替换的任务不再更新进度
+//	@param round - 当前的计算轮数
+//	@param start - 本次任务开始时的轮数，从检查点恢复时不为 0
+//	@param begin - 本次任务开始的时间
+func (c *Calculator) reportProgress(job uint64, round, start int64, begin time.Time) {
+	rounds := c.vdf.Rounds()
+	progress := float64(round) / float64(rounds) * 100
+
+	eta := time.Duration(0)
+	if done := round - start; done > 0 {
+		eta = time.Duration(float64(time.Since(begin)) / float64(done) * float64(rounds-round))
 	}
 
-	return result, pi
+	c.lock.Lock()
+	if c.jobID != job {
+		c.lock.Unlock()
+		return
+	}
+	c.progress, c.eta = progress, eta
+	c.lock.Unlock()
+
+	metrics.VDFProgressSet(progress)
+	metrics.VDFETASet(eta.Seconds())
 }
 
-// Verify 验证 VDF 计算结果 result == pi^l * seed^s
-// 具体细节见论文 - Simple Verifiable Delay Functions
-func (c *Calculator) Verify(seed *big.Int, pi *big.Int, result *big.Int) bool {
-	tSeed := big.NewInt(0)
-	tSeed.SetBytes(seed.Bytes())
-	tPi := big.NewInt(0)
-	tPi.SetBytes(pi.Bytes())
+// saveCheckpoint
+//
+//	@Description: 保存计算的中间状态
+//	@receiver c - 计算实例
+//	@param cp - 计算状态导出的检查点
+func (c *Calculator) saveCheckpoint(cp *common.VDFCheckpoint) {
+	c.lock.RLock()
+	store := c.store
+	c.lock.RUnlock()
+
+	if store == nil {
+		return
+	}
+
+	if err := store.SaveVDFCheckpoint(cp); err != nil {
+		log.WithError(err).Warning("Save VDF checkpoint failed.")
+	}
+}
+
+// loadCheckpoint
+//
+//	@Description: 读取与 seed 对应的检查点，如果不存在或者参数不匹配返回 nil
+//	@receiver c - 计算实例
+//	@param seed - 当前轮计算的 seed
+//	@return *common.VDFCheckpoint - 检查点
+func (c *Calculator) loadCheckpoint(seed *big.Int) *common.VDFCheckpoint {
+	c.lock.RLock()
+	store := c.store
+	c.lock.RUnlock()
 
-	r := big.NewInt(2)
-	t := big.NewInt(c.timeParam)
+	if store == nil {
+		return nil
+	}
 
-	// r = r^t mod pp
-	r = r.Exp(r, t, c.proofParam)
+	cp, err := store.LoadVDFCheckpoint()
+	if err != nil || cp == nil {
+		return nil
+	}
 
-	// h = pi^pp
-	h := tPi.Exp(tPi, c.proofParam, c.order)
-	// s = seed
-	s := tSeed.Exp(tSeed, r, c.order)
+	// 检查点中的 TimeParam 记录的是计算的总轮数
+	rounds := c.vdf.Rounds()
+	if !bytes.Equal(cp.Seed, seed.Bytes()) || cp.TimeParam != rounds ||
+		cp.Round <= 0 || cp.Round >= rounds {
+		return nil
+	}
 
-	h = h.Mul(h, s)
-	h = h.Mod(h, c.order)
+	return cp
+}
 
-	return result.Cmp(h) == 0
+// Verify
+//
+//	@Description: 使用计算器的 VDF 方案验证 result 是否为 seed 的计算结果
+//	@receiver c - 计算实例
+//	@param seed - 计算的输入
+//	@param pi - 证明
+//	@param result - 计算结果
+//	@return bool - 验证结果
+func (c *Calculator) Verify(seed *big.Int, pi *big.Int, result *big.Int) bool {
+	return c.vdf.Verify(seed, pi, result)
 }
 
 // GenerateGenesisParams 打包 VDF 计算参数
@@ -320,6 +481,9 @@ func GenerateGenesisParams() (*common.GenesisParams, error) {
 	//genesisParams.TimeParam = 1000
 	genesisParams.VerifyParam = [32]byte(pp.Bytes())
 	genesisParams.Seed = [32]byte(seed.Bytes())
+	genesisParams.ExpectedProducers = DefaultExpectedProducers
+	genesisParams.TotalWeight = DefaultTotalWeight
+	genesisParams.VRFVersion = VRFVersionECVRF
 
 	return genesisParams, nil
 }
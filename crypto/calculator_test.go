@@ -1,10 +1,14 @@
 package crypto
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"github.com/chain-lab/go-norn/common"
 	log "github.com/sirupsen/logrus"
+	"math/big"
 	"testing"
+	"time"
 )
 
 func TestCalculator(t *testing.T) {
@@ -15,11 +19,7 @@ func TestCalculator(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	calculator := Calculator{
-		order:      n,
-		proofParam: pp,
-		timeParam:  10000,
-	}
+	calculator := NewCalculator(NewRSAVDF(pp, n, 10000))
 
 	msg, err := rand.Prime(rand.Reader, 3)
 
@@ -27,7 +27,11 @@ func TestCalculator(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	result, pi := calculator.calculate(msg)
+	vdf, err := calculator.Evaluate(context.Background(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, pi := vdf.Result, vdf.Proof
 
 	if !calculator.Verify(msg, pi, result) {
 		t.Fatal("Verify failed.")
@@ -36,3 +40,101 @@ func TestCalculator(t *testing.T) {
 	result.Cmp(tt260)
 	log.Info(hex.EncodeToString(result.Bytes()))
 }
+
+type memoryCheckpointStore struct {
+	cp *common.VDFCheckpoint
+}
+
+func (s *memoryCheckpointStore) SaveVDFCheckpoint(cp *common.VDFCheckpoint) error {
+	s.cp = cp
+	return nil
+}
+
+func (s *memoryCheckpointStore) LoadVDFCheckpoint() (*common.VDFCheckpoint, error) {
+	return s.cp, nil
+}
+
+func TestCalculatorCancel(t *testing.T) {
+	n, pp, _ := GenerateParams()
+	calculator := NewCalculator(NewRSAVDF(pp, n, 1<<40))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := calculator.Evaluate(ctx, big.NewInt(7)); err == nil {
+		t.Fatal("Cancelled calculate returned result.")
+	}
+}
+
+func TestCalculatorCheckpointResume(t *testing.T) {
+	n, pp, _ := GenerateParams()
+	seed := big.NewInt(11)
+	calculator := NewCalculator(NewRSAVDF(pp, n, 3*progressRounds))
+	calculator.checkpointInterval = 2 * progressRounds
+	store := &memoryCheckpointStore{}
+	calculator.SetCheckpointStore(store)
+
+	full, err := calculator.evaluate(context.Background(), seed, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store.cp == nil || store.cp.Round != 2*progressRounds {
+		t.Fatal("Checkpoint not saved.")
+	}
+
+	cp := calculator.loadCheckpoint(seed)
+	if cp == nil {
+		t.Fatal("Checkpoint not loaded.")
+	}
+
+	if calculator.loadCheckpoint(big.NewInt(13)) != nil {
+		t.Fatal("Checkpoint loaded for another seed.")
+	}
+
+	resumed, err := calculator.evaluate(context.Background(), seed, cp, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resumed.Result.Cmp(full.Result) != 0 || resumed.Proof.Cmp(full.Proof) != 0 {
+		t.Fatal("Resumed calculate result mismatch.")
+	}
+
+	if !calculator.Verify(seed, resumed.Proof, resumed.Result) {
+		t.Fatal("Verify resumed result failed.")
+	}
+}
+
+func TestCalculatorSeedParams(t *testing.T) {
+	n, pp, _ := GenerateParams()
+	calculator := NewCalculator(NewRSAVDF(pp, n, 1000))
+	defer calculator.Stop(context.Background())
+
+	seed := big.NewInt(17)
+	calculator.AppendNewSeed(seed, big.NewInt(0))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, proof := calculator.GetSeedParams()
+		if result.Cmp(seed) != 0 {
+			if !calculator.Verify(seed, proof, result) {
+				t.Fatal("Result and proof mismatch.")
+			}
+
+			if !calculator.VerifyBlockVDF(result, proof) {
+				t.Fatal("Verify block VDF failed.")
+			}
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("Calculate timeout.")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if progress, _ := calculator.Progress(); progress != 100 {
+		t.Fatalf("Progress %f not finished.", progress)
+	}
+}
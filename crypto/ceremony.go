@@ -0,0 +1,435 @@
+// Package crypto
+// @Description: 创世参数生成仪式。GenerateParams 在本地生成 p、q，创世节点知道群的阶，可以绕过 VDF 的计算。
+// 仪式提供三种方式，信任假设各不相同：
+//   - classgroup：类群的阶无法计算，不存在陷门，判别式由所有参与者贡献的随机数派生，不需要信任任何参与者，推荐使用
+//   - rsa-challenge：需要信任 RSA Laboratories 在生成 RSA-2048 之后销毁了素因子
+//   - multiparty：每个参与者都知道自己贡献的模数的分解，可以在乘积模数的群中构造已知阶的元素并伪造 Wesolowski 证明，
+//     需要信任所有参与者都销毁了素因子，只用于仍然使用 RSA 群的链
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"math/big"
+	"os"
+)
+
+const (
+	CeremonyModeClassGroup   = "classgroup"    // 多个参与者贡献随机数，派生类群 VDF 的判别式
+	CeremonyModeRSAChallenge = "rsa-challenge" // 使用 RSA Factoring Challenge 中未被分解的 RSA-2048
+	CeremonyModeMultiParty   = "multiparty"    // 多个参与者各自贡献模数，需要信任所有参与者
+
+	CeremonyContributionBits = 1024    // 每个参与者贡献的模数位数
+	CeremonyTimeParam        = 2500000 // 仪式生成参数的默认时间参数，模数更长时每轮平方更慢
+
+	ceremonySmallPrimeBound = 100000 // 检查贡献模数时试除的素数上界
+	ceremonyEntropyBytes    = 32     // 每个参与者贡献的随机数字节数
+)
+
+// rsa2048 RSA Factoring Challenge 中的 RSA-2048，至今没有公开的分解
+const rsa2048 = "25195908475657893494027183240048398571429282126204032027777137836043662020707595556264018525880784406918290641249515082189298559149176184502808489120072844992687392807287776735971418347270261896375014971824691165077613379859095700097330459748808428401797429100642458691817195118746121515172654632282216869987549182422433637259085141865462043576798423387184774447920739934236584823824281198163815010674810451660377306056201619676256133844143603833904414952634432190114657544454178424020924616515723350778707749817125772467962926386356373289912154831438167899885040445364023527381951378636564391212010397122822120720357"
+
+var (
+	errCeremonyMode         = errors.New("unknown ceremony mode")
+	errCeremonyModulus      = errors.New("ceremony modulus mismatch")
+	errCeremonyContribution = errors.New("invalid ceremony contribution")
+	errCeremonyDerived      = errors.New("ceremony derived params mismatch")
+)
+
+// CeremonyContribution
+//
+//	@Description: 一个参与者的贡献，classgroup 方式下为随机数，multiparty 方式下为模数，参与者在生成后需要销毁 p、q
+type CeremonyContribution struct {
+	Participant string `json:"participant"`
+	Entropy     string `json:"entropy,omitempty"`
+	Modulus     string `json:"modulus,omitempty"`
+}
+
+// CeremonyParams
+//
+//	@Description: 仪式生成的创世参数文件，其他节点可以通过 Validate 重新计算并检查所有字段
+type CeremonyParams struct {
+	Mode          string                 `json:"mode"`
+	Contributions []CeremonyContribution `json:"contributions,omitempty"`
+	Modulus       string                 `json:"modulus,omitempty"`
+	ProofParam    string                 `json:"proof_param,omitempty"`
+	Discriminant  string                 `json:"discriminant,omitempty"`
+	Seed          string                 `json:"seed"`
+	TimeParam     int64                  `json:"time_param"`
+}
+
+// NewCeremonyEntropy
+//
+//	@Description: 生成一个参与者在 classgroup 方式下贡献的随机数
+//	@param participant - 参与者名称
+//	@return *CeremonyContribution - 参与者的贡献
+//	@return error - 错误信息
+func NewCeremonyEntropy(participant string) (*CeremonyContribution, error) {
+	entropy := make([]byte, ceremonyEntropyBytes)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+
+	return &CeremonyContribution{
+		Participant: participant,
+		Entropy:     hex.EncodeToString(entropy),
+	}, nil
+}
+
+// NewCeremonyContribution
+//
+//	@Description: 生成一个参与者在 multiparty 方式下贡献的模数，函数返回后 p、q 不再被引用
+//	@param participant - 参与者名称
+//	@param bits - 模数的位数
+//	@return *CeremonyContribution - 参与者的贡献
+//	@return error - 错误信息
+func NewCeremonyContribution(participant string, bits int) (*CeremonyContribution, error) {
+	p, err := rand.Prime(rand.Reader, bits/2)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := rand.Prime(rand.Reader, bits-bits/2)
+	if err != nil {
+		return nil, err
+	}
+
+	n := new(big.Int).Mul(p, q)
+	return &CeremonyContribution{
+		Participant: participant,
+		Modulus:     hex.EncodeToString(n.Bytes()),
+	}, nil
+}
+
+// RSAChallengeCeremony
+//
+//	@Description: 使用 RSA-2048 作为模数生成仪式参数
+//	@param timeParam - VDF 的时间参数
+//	@return *CeremonyParams - 仪式参数
+func RSAChallengeCeremony(timeParam int64) *CeremonyParams {
+	n, _ := new(big.Int).SetString(rsa2048, 10)
+	return newCeremonyParams(CeremonyModeRSAChallenge, nil, n, timeParam)
+}
+
+// CombineCeremonyEntropy
+//
+//	@Description: 将所有参与者贡献的随机数哈希为种子，由种子派生类群的判别式，任何一个参与者的随机数保密时都无法预先选择判别式
+//	@param contributions - 参与者的贡献
+//	@param timeParam - VDF 的时间参数
+//	@return *CeremonyParams - 仪式参数
+//	@return error - 存在不合法的贡献时返回错误
+func CombineCeremonyEntropy(contributions []CeremonyContribution, timeParam int64) (*CeremonyParams, error) {
+	if len(contributions) == 0 {
+		return nil, errCeremonyContribution
+	}
+
+	seen := make(map[string]bool)
+	hash := sha256.New()
+	for _, c := range contributions {
+		entropy, err := hex.DecodeString(c.Entropy)
+		if err != nil || len(entropy) != ceremonyEntropyBytes || c.Modulus != "" || seen[c.Entropy] {
+			return nil, errCeremonyContribution
+		}
+		seen[c.Entropy] = true
+		hash.Write(entropy)
+	}
+
+	seed := ceremonyHash("seed", hash.Sum(nil))
+	return &CeremonyParams{
+		Mode:          CeremonyModeClassGroup,
+		Contributions: contributions,
+		Discriminant:  hex.EncodeToString(GenerateClassGroupParams(seed, DefaultDiscriminantBits)),
+		Seed:          hex.EncodeToString(seed),
+		TimeParam:     timeParam,
+	}, nil
+}
+
+// CombineCeremonyContributions
+//
+//	@Description: 检查所有参与者的贡献并将模数相乘得到仪式参数，参数的安全性依赖于所有参与者都销毁了素因子
+//	@param contributions - 参与者的贡献
+//	@param timeParam - VDF 的时间参数
+//	@return *CeremonyParams - 仪式参数
+//	@return error - 存在不合法的贡献时返回错误
+func CombineCeremonyContributions(contributions []CeremonyContribution, timeParam int64) (*CeremonyParams, error) {
+	n, err := multiplyContributions(contributions)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCeremonyParams(CeremonyModeMultiParty, contributions, n, timeParam), nil
+}
+
+// Validate
+//
+//	@Description: 重新计算模数、证明参数以及种子，检查参数文件是否由仪式生成
+//	@receiver p - 仪式参数
+//	@return error - 参数不一致时返回错误
+func (p *CeremonyParams) Validate() error {
+	if p.TimeParam <= 0 {
+		return errCeremonyDerived
+	}
+
+	var n *big.Int
+	switch p.Mode {
+	case CeremonyModeClassGroup:
+		expected, err := CombineCeremonyEntropy(p.Contributions, p.TimeParam)
+		if err != nil {
+			return err
+		}
+		if p.Seed != expected.Seed || p.Discriminant != expected.Discriminant ||
+			p.Modulus != "" || p.ProofParam != "" {
+			return errCeremonyDerived
+		}
+		return nil
+	case CeremonyModeRSAChallenge:
+		n, _ = new(big.Int).SetString(rsa2048, 10)
+	case CeremonyModeMultiParty:
+		var err error
+		n, err = multiplyContributions(p.Contributions)
+		if err != nil {
+			return err
+		}
+	default:
+		return errCeremonyMode
+	}
+
+	if p.Modulus != hex.EncodeToString(n.Bytes()) {
+		return errCeremonyModulus
+	}
+
+	expected := newCeremonyParams(p.Mode, p.Contributions, n, p.TimeParam)
+	if p.ProofParam != expected.ProofParam || p.Seed != expected.Seed || p.Discriminant != "" {
+		return errCeremonyDerived
+	}
+
+	return nil
+}
+
+// GenesisParams
+//
+//	@Description: 将仪式参数转换为创世区块中的 VDF 参数
+//	@receiver p - 仪式参数
+//	@return *common.GenesisParams - 创世参数，共识相关的字段使用默认值
+//	@return error - 参数解码失败时返回错误
+func (p *CeremonyParams) GenesisParams() (*common.GenesisParams, error) {
+	seed, err := hex.DecodeString(p.Seed)
+	if err != nil || len(seed) != 32 {
+		return nil, errCeremonyDerived
+	}
+
+	genesisParams := new(common.GenesisParams)
+	genesisParams.TimeParam = p.TimeParam
+	genesisParams.Seed = [32]byte(seed)
+	genesisParams.ExpectedProducers = DefaultExpectedProducers
+	genesisParams.TotalWeight = DefaultTotalWeight
+	genesisParams.VRFVersion = VRFVersionECVRF
+
+	if p.Mode == CeremonyModeClassGroup {
+		discriminant, err := hex.DecodeString(p.Discriminant)
+		if err != nil || len(discriminant) == 0 {
+			return nil, errCeremonyDerived
+		}
+
+		genesisParams.Discriminant = discriminant
+		genesisParams.VDFScheme = VDFSchemeClassGroup
+		return genesisParams, nil
+	}
+
+	modulus, err := hex.DecodeString(p.Modulus)
+	if err != nil {
+		return nil, err
+	}
+
+	pp, err := hex.DecodeString(p.ProofParam)
+	if err != nil || len(pp) != 32 {
+		return nil, errCeremonyDerived
+	}
+
+	genesisParams.Modulus = modulus
+	genesisParams.VerifyParam = [32]byte(pp)
+	genesisParams.VDFScheme = VDFSchemeRSA
+
+	return genesisParams, nil
+}
+
+// Save
+//
+//	@Description: 将仪式参数写入 JSON 文件
+//	@receiver p - 仪式参数
+//	@param path - 文件路径
+//	@return error - 错误信息
+func (p *CeremonyParams) Save(path string) error {
+	return saveJSON(path, p)
+}
+
+// Save
+//
+//	@Description: 将参与者的贡献写入 JSON 文件，用于线下交换
+//	@receiver c - 参与者的贡献
+//	@param path - 文件路径
+//	@return error - 错误信息
+func (c *CeremonyContribution) Save(path string) error {
+	return saveJSON(path, c)
+}
+
+// LoadCeremonyParams
+//
+//	@Description: 读取仪式参数文件
+//	@param path - 文件路径
+//	@return *CeremonyParams - 仪式参数
+//	@return error - 错误信息
+func LoadCeremonyParams(path string) (*CeremonyParams, error) {
+	p := new(CeremonyParams)
+	if err := loadJSON(path, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadCeremonyContribution
+//
+//	@Description: 读取参与者的贡献文件
+//	@param path - 文件路径
+//	@return *CeremonyContribution - 参与者的贡献
+//	@return error - 错误信息
+func LoadCeremonyContribution(path string) (*CeremonyContribution, error) {
+	c := new(CeremonyContribution)
+	if err := loadJSON(path, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// MatchGenesisParams
+//
+//	@Description: 检查创世区块中的 VDF 参数是否与仪式参数一致
+//	@receiver p - 仪式参数
+//	@param params - 创世区块参数
+//	@return bool - 是否一致
+func (p *CeremonyParams) MatchGenesisParams(params *common.GenesisParams) bool {
+	expected, err := p.GenesisParams()
+	if err != nil {
+		return false
+	}
+
+	if expected.VDFScheme == VDFSchemeClassGroup {
+		return params.VDFScheme == VDFSchemeClassGroup &&
+			bytes.Equal(params.Discriminant, expected.Discriminant) &&
+			params.Seed == expected.Seed &&
+			params.TimeParam == expected.TimeParam
+	}
+
+	return params.VDFScheme == VDFSchemeRSA &&
+		hex.EncodeToString(params.Modulus) == hex.EncodeToString(expected.Modulus) &&
+		params.VerifyParam == expected.VerifyParam &&
+		params.Seed == expected.Seed &&
+		params.TimeParam == expected.TimeParam
+}
+
+// newCeremonyParams
+//
+//	@Description: 由模数确定性地派生证明参数 l 和初始种子，没有任何一方可以选择这两个值
+func newCeremonyParams(mode string, contributions []CeremonyContribution, n *big.Int, timeParam int64) *CeremonyParams {
+	modulus := n.Bytes()
+
+	// l 为 256 位的素数
+	l := new(big.Int).SetBytes(ceremonyHash("proof-param", modulus))
+	l.SetBit(l, 255, 1)
+	l.SetBit(l, 0, 1)
+	for !l.ProbablyPrime(20) {
+		l.Add(l, two)
+	}
+
+	return &CeremonyParams{
+		Mode:          mode,
+		Contributions: contributions,
+		Modulus:       hex.EncodeToString(modulus),
+		ProofParam:    hex.EncodeToString(l.Bytes()),
+		Seed:          hex.EncodeToString(ceremonyHash("seed", modulus)),
+		TimeParam:     timeParam,
+	}
+}
+
+// multiplyContributions
+//
+//	@Description: 检查每个贡献并计算模数的乘积，贡献的模数不能过短、不能有小素因子、不能重复
+func multiplyContributions(contributions []CeremonyContribution) (*big.Int, error) {
+	if len(contributions) == 0 {
+		return nil, errCeremonyContribution
+	}
+
+	small := smallPrimes(ceremonySmallPrimeBound)
+	seen := make(map[string]bool)
+	n := big.NewInt(1)
+	m := new(big.Int)
+
+	for _, c := range contributions {
+		bytesModulus, err := hex.DecodeString(c.Modulus)
+		if err != nil || c.Entropy != "" || seen[c.Modulus] {
+			return nil, errCeremonyContribution
+		}
+		seen[c.Modulus] = true
+
+		ni := new(big.Int).SetBytes(bytesModulus)
+		if ni.BitLen() < CeremonyContributionBits || ni.ProbablyPrime(20) {
+			return nil, errCeremonyContribution
+		}
+
+		for _, sp := range small {
+			if m.Mod(ni, sp).Sign() == 0 {
+				return nil, errCeremonyContribution
+			}
+		}
+
+		n.Mul(n, ni)
+	}
+
+	return n, nil
+}
+
+// smallPrimes
+//
+//	@Description: 埃氏筛得到小于 bound 的素数
+func smallPrimes(bound int) []*big.Int {
+	composite := make([]bool, bound)
+	var primes []*big.Int
+	for i := 2; i < bound; i++ {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, big.NewInt(int64(i)))
+		for j := i * i; j < bound; j += i {
+			composite[j] = true
+		}
+	}
+	return primes
+}
+
+func ceremonyHash(domain string, data []byte) []byte {
+	hash := sha256.New()
+	hash.Write([]byte("norn-genesis-ceremony/" + domain))
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+func saveJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func loadJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,209 @@
+package crypto
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+func TestRSAChallengeCeremony(t *testing.T) {
+	params := RSAChallengeCeremony(CeremonyTimeParam)
+	if err := params.Validate(); err != nil {
+		t.Fatalf("validate rsa challenge params failed: %v", err)
+	}
+
+	genesisParams, err := params.GenesisParams()
+	if err != nil {
+		t.Fatalf("convert genesis params failed: %v", err)
+	}
+
+	if new(big.Int).SetBytes(genesisParams.Modulus).BitLen() != 2048 {
+		t.Fatalf("rsa challenge modulus is not 2048 bits")
+	}
+
+	l := new(big.Int).SetBytes(genesisParams.VerifyParam[:])
+	if l.BitLen() != 256 || !l.ProbablyPrime(20) {
+		t.Fatalf("proof param is not a 256 bits prime")
+	}
+
+	if !params.MatchGenesisParams(genesisParams) {
+		t.Fatalf("genesis params not match ceremony params")
+	}
+}
+
+func TestCombineCeremonyContributions(t *testing.T) {
+	var contributions []CeremonyContribution
+	for _, name := range []string{"alice", "bob"} {
+		c, err := NewCeremonyContribution(name, CeremonyContributionBits)
+		if err != nil {
+			t.Fatalf("create contribution failed: %v", err)
+		}
+		contributions = append(contributions, *c)
+	}
+
+	params, err := CombineCeremonyContributions(contributions, 1000)
+	if err != nil {
+		t.Fatalf("combine contributions failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ceremony.json")
+	if err = params.Save(path); err != nil {
+		t.Fatalf("save ceremony params failed: %v", err)
+	}
+
+	loaded, err := LoadCeremonyParams(path)
+	if err != nil {
+		t.Fatalf("load ceremony params failed: %v", err)
+	}
+
+	if err = loaded.Validate(); err != nil {
+		t.Fatalf("validate ceremony params failed: %v", err)
+	}
+
+	genesisParams, err := loaded.GenesisParams()
+	if err != nil {
+		t.Fatalf("convert genesis params failed: %v", err)
+	}
+
+	// 使用仪式参数计算并验证 VDF
+	vdf, err := NewVDF(genesisParams)
+	if err != nil {
+		t.Fatalf("create vdf failed: %v", err)
+	}
+
+	seed := new(big.Int).SetBytes(genesisParams.Seed[:])
+	result, err := NewCalculator(vdf).Evaluate(context.Background(), seed)
+	if err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+
+	if !vdf.Verify(seed, result.Proof, result.Result) {
+		t.Fatalf("verify vdf with ceremony params failed")
+	}
+}
+
+func TestCeremonyTampered(t *testing.T) {
+	c, err := NewCeremonyContribution("alice", CeremonyContributionBits)
+	if err != nil {
+		t.Fatalf("create contribution failed: %v", err)
+	}
+
+	params, err := CombineCeremonyContributions([]CeremonyContribution{*c}, 1000)
+	if err != nil {
+		t.Fatalf("combine contributions failed: %v", err)
+	}
+
+	// 修改种子
+	tampered := *params
+	tampered.Seed = RSAChallengeCeremony(1000).Seed
+	if tampered.Validate() == nil {
+		t.Fatalf("tampered seed passed validation")
+	}
+
+	// 替换模数，但贡献列表不变
+	tampered = *params
+	tampered.Modulus = RSAChallengeCeremony(1000).Modulus
+	if tampered.Validate() == nil {
+		t.Fatalf("tampered modulus passed validation")
+	}
+
+	// 重复的贡献
+	if _, err = CombineCeremonyContributions([]CeremonyContribution{*c, *c}, 1000); err == nil {
+		t.Fatalf("duplicate contributions passed validation")
+	}
+
+	// 含有小素因子的贡献
+	n, _ := new(big.Int).SetString(params.Modulus, 16)
+	n.Mul(n, big.NewInt(3))
+	weak := CeremonyContribution{Participant: "mallory", Modulus: n.Text(16)}
+	if _, err = CombineCeremonyContributions([]CeremonyContribution{weak}, 1000); err == nil {
+		t.Fatalf("contribution with small factor passed validation")
+	}
+}
+
+func TestClassGroupCeremony(t *testing.T) {
+	var contributions []CeremonyContribution
+	for _, name := range []string{"alice", "bob", "carol"} {
+		c, err := NewCeremonyEntropy(name)
+		if err != nil {
+			t.Fatalf("create contribution failed: %v", err)
+		}
+		contributions = append(contributions, *c)
+	}
+
+	params, err := CombineCeremonyEntropy(contributions, 1000)
+	if err != nil {
+		t.Fatalf("combine contributions failed: %v", err)
+	}
+
+	if err = params.Validate(); err != nil {
+		t.Fatalf("validate ceremony params failed: %v", err)
+	}
+
+	genesisParams, err := params.GenesisParams()
+	if err != nil {
+		t.Fatalf("convert genesis params failed: %v", err)
+	}
+
+	if genesisParams.VDFScheme != VDFSchemeClassGroup || len(genesisParams.Modulus) != 0 {
+		t.Fatalf("ceremony params not use class group vdf")
+	}
+
+	if !params.MatchGenesisParams(genesisParams) {
+		t.Fatalf("genesis params not match ceremony params")
+	}
+
+	// 使用仪式参数计算并验证 VDF
+	vdf, err := NewVDF(genesisParams)
+	if err != nil {
+		t.Fatalf("create vdf failed: %v", err)
+	}
+
+	seed := new(big.Int).SetBytes(genesisParams.Seed[:])
+	result, err := NewCalculator(vdf).Evaluate(context.Background(), seed)
+	if err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+
+	if !vdf.Verify(seed, result.Proof, result.Result) {
+		t.Fatalf("verify vdf with ceremony params failed")
+	}
+}
+
+func TestClassGroupCeremonyTampered(t *testing.T) {
+	alice, _ := NewCeremonyEntropy("alice")
+	bob, _ := NewCeremonyEntropy("bob")
+
+	params, err := CombineCeremonyEntropy([]CeremonyContribution{*alice, *bob}, 1000)
+	if err != nil {
+		t.Fatalf("combine contributions failed: %v", err)
+	}
+
+	// 替换参与者的随机数，判别式不变
+	tampered := *params
+	tampered.Contributions = []CeremonyContribution{*alice, *alice}
+	if tampered.Validate() == nil {
+		t.Fatalf("duplicate entropy passed validation")
+	}
+
+	carol, _ := NewCeremonyEntropy("carol")
+	tampered.Contributions = []CeremonyContribution{*alice, *carol}
+	if tampered.Validate() == nil {
+		t.Fatalf("tampered contributions passed validation")
+	}
+
+	// 使用自己选择的判别式
+	tampered = *params
+	tampered.Discriminant = hex.EncodeToString(GenerateClassGroupParams([]byte("chosen"), DefaultDiscriminantBits))
+	if tampered.Validate() == nil {
+		t.Fatalf("tampered discriminant passed validation")
+	}
+
+	// 混入 RSA 模数的贡献
+	c, _ := NewCeremonyContribution("mallory", CeremonyContributionBits)
+	if _, err = CombineCeremonyEntropy([]CeremonyContribution{*alice, *c}, 1000); err == nil {
+		t.Fatalf("modulus contribution passed validation")
+	}
+}
@@ -0,0 +1,368 @@
+// Package crypto
+// @Description: 虚二次域类群上的二元二次型运算，类群的阶未知且不需要可信设置，用于 Wesolowski VDF
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"math/big"
+)
+
+var (
+	one  = big.NewInt(1)
+	two  = big.NewInt(2)
+	four = big.NewInt(4)
+
+	errInvalidForm         = errors.New("invalid class group form")
+	errInvalidDiscriminant = errors.New("invalid class group discriminant")
+)
+
+// ClassGroupForm
+//
+//	@Description: 判别式为 D = b^2 - 4ac < 0 的正定二元二次型 (a, b, c)，运算结果总是约化的
+type ClassGroupForm struct {
+	A *big.Int
+	B *big.Int
+	C *big.Int
+}
+
+// GenerateDiscriminant
+//
+//	@Description: 从种子确定性地生成 bits 位的负素数判别式 D = -p，p ≡ 3 (mod 4)，任何节点都可以复现
+//	@param seed - 生成判别式的种子
+//	@param bits - 判别式的位数
+//	@return *big.Int - 判别式 D
+func GenerateDiscriminant(seed []byte, bits int) *big.Int {
+	p := hashToInt(seed, bits)
+	p.SetBit(p, bits-1, 1)
+
+	// 调整为 p ≡ 3 (mod 4)，随后以 4 为步长搜索素数
+	p.SetBit(p, 0, 1)
+	p.SetBit(p, 1, 1)
+	for !p.ProbablyPrime(20) {
+		p.Add(p, four)
+	}
+
+	return p.Neg(p)
+}
+
+// ValidateDiscriminant
+//
+//	@Description: 检查判别式是否为 D < 0，D ≡ 1 (mod 4) 并且 -D 为素数
+//	@param d - 判别式
+//	@return error - 判别式不合法时返回错误
+func ValidateDiscriminant(d *big.Int) error {
+	if d == nil || d.Sign() >= 0 {
+		return errInvalidDiscriminant
+	}
+
+	p := new(big.Int).Neg(d)
+	if new(big.Int).Mod(d, four).Cmp(one) != 0 || !p.ProbablyPrime(20) {
+		return errInvalidDiscriminant
+	}
+
+	return nil
+}
+
+// IdentityForm
+//
+//	@Description: 类群的单位元 (1, 1, (1 - D) / 4)
+//	@param d - 判别式
+//	@return *ClassGroupForm - 单位元
+func IdentityForm(d *big.Int) *ClassGroupForm {
+	c := new(big.Int).Sub(one, d)
+	c.Rsh(c, 2)
+
+	return &ClassGroupForm{A: big.NewInt(1), B: big.NewInt(1), C: c}
+}
+
+// HashToForm
+//
+//	@Description: 将消息映射为类群中的元素，a 为满足 D 是模 a 二次剩余的素数，b 为 D 模 a 的平方根
+//	@param d - 判别式
+//	@param msg - 需要映射的消息
+//	@return *ClassGroupForm - 约化后的二次型
+func HashToForm(d *big.Int, msg []byte) *ClassGroupForm {
+	bits := d.BitLen() / 4
+	if bits < 16 {
+		bits = 16
+	}
+
+	a := hashToInt(msg, bits)
+	a.SetBit(a, bits-1, 1)
+	a.SetBit(a, 0, 1)
+
+	for {
+		if a.ProbablyPrime(20) && big.Jacobi(d, a) == 1 {
+			break
+		}
+		a.Add(a, two)
+	}
+
+	dMod := new(big.Int).Mod(d, a)
+	b := new(big.Int).ModSqrt(dMod, a)
+	// b 需要与 D 同奇偶，这样 b^2 - D 才能被 4a 整除
+	if b.Bit(0) == 0 {
+		b.Sub(a, b)
+	}
+
+	c := new(big.Int).Mul(b, b)
+	c.Sub(c, d)
+	c.Div(c, new(big.Int).Mul(four, a))
+
+	f := &ClassGroupForm{A: a, B: b, C: c}
+	return f.reduce()
+}
+
+// Discriminant
+//
+//	@Description: 计算二次型的判别式 b^2 - 4ac
+func (f *ClassGroupForm) Discriminant() *big.Int {
+	d := new(big.Int).Mul(f.B, f.B)
+	ac := new(big.Int).Mul(f.A, f.C)
+	ac.Mul(ac, four)
+	return d.Sub(d, ac)
+}
+
+// Equal
+//
+//	@Description: 比较两个约化的二次型是否相同
+func (f *ClassGroupForm) Equal(g *ClassGroupForm) bool {
+	return f.A.Cmp(g.A) == 0 && f.B.Cmp(g.B) == 0 && f.C.Cmp(g.C) == 0
+}
+
+// Inverse
+//
+//	@Description: 二次型的逆元 (a, -b, c)
+func (f *ClassGroupForm) Inverse() *ClassGroupForm {
+	g := &ClassGroupForm{
+		A: new(big.Int).Set(f.A),
+		B: new(big.Int).Neg(f.B),
+		C: new(big.Int).Set(f.C),
+	}
+	return g.reduce()
+}
+
+// Compose
+//
+//	@Description: 二次型的合成（类群中的乘法），参考 Cohen, A Course in Computational Algebraic Number Theory, 算法 5.4.7
+//	@param g - 另一个判别式相同的二次型
+//	@return *ClassGroupForm - 约化后的合成结果
+func (f *ClassGroupForm) Compose(g *ClassGroupForm) *ClassGroupForm {
+	f1, f2 := f, g
+	if f1.A.Cmp(f2.A) > 0 {
+		f1, f2 = f2, f1
+	}
+
+	a1, b1 := f1.A, f1.B
+	a2, b2, c2 := f2.A, f2.B, f2.C
+
+	// s = (b1 + b2) / 2, n = b2 - s
+	s := new(big.Int).Add(b1, b2)
+	s.Rsh(s, 1)
+	n := new(big.Int).Sub(b2, s)
+
+	// d = gcd(a1, a2) = u * a2 + v * a1, y1 = u
+	y1 := new(big.Int)
+	d := new(big.Int)
+	if new(big.Int).Mod(a2, a1).Sign() == 0 {
+		d.Set(a1)
+	} else {
+		d.GCD(y1, nil, a2, a1)
+	}
+
+	// d1 = gcd(s, d) = x2 * s - y2 * d
+	x2 := new(big.Int)
+	y2 := big.NewInt(-1)
+	d1 := new(big.Int)
+	if new(big.Int).Mod(s, d).Sign() == 0 {
+		d1.Set(d)
+	} else {
+		v := new(big.Int)
+		d1.GCD(x2, v, s, d)
+		y2.Neg(v)
+	}
+
+	v1 := new(big.Int).Div(a1, d1)
+	v2 := new(big.Int).Div(a2, d1)
+
+	// r = (y1 * y2 * n - x2 * c2) mod v1
+	r := new(big.Int).Mul(y1, y2)
+	r.Mul(r, n)
+	r.Sub(r, new(big.Int).Mul(x2, c2))
+	r.Mod(r, v1)
+
+	// b3 = b2 + 2 * v2 * r, a3 = v1 * v2, c3 = (c2 * d1 + r * (b2 + v2 * r)) / v1
+	v2r := new(big.Int).Mul(v2, r)
+	b3 := new(big.Int).Lsh(v2r, 1)
+	b3.Add(b3, b2)
+	a3 := new(big.Int).Mul(v1, v2)
+	c3 := new(big.Int).Add(b2, v2r)
+	c3.Mul(c3, r)
+	c3.Add(c3, new(big.Int).Mul(c2, d1))
+	c3.Div(c3, v1)
+
+	h := &ClassGroupForm{A: a3, B: b3, C: c3}
+	return h.reduce()
+}
+
+// Square
+//
+//	@Description: 二次型的平方
+func (f *ClassGroupForm) Square() *ClassGroupForm {
+	return f.Compose(f)
+}
+
+// Pow
+//
+//	@Description: 计算 f^e，e 为非负整数
+//	@param e - 指数
+//	@return *ClassGroupForm - 约化后的结果
+func (f *ClassGroupForm) Pow(e *big.Int) *ClassGroupForm {
+	result := IdentityForm(f.Discriminant())
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		result = result.Square()
+		if e.Bit(i) == 1 {
+			result = result.Compose(f)
+		}
+	}
+	return result
+}
+
+// Bytes
+//
+//	@Description: 二次型的编码 0x01 || sign(b) || a || |b|，a 和 b 按照判别式的长度补齐，
+//	开头的 0x01 保证编码转换为大整数后不会丢失前导零
+//	@param d - 判别式
+//	@return []byte - 编码结果
+func (f *ClassGroupForm) Bytes(d *big.Int) []byte {
+	size := formElementSize(d)
+	buf := make([]byte, 2+2*size)
+	buf[0] = 0x01
+	if f.B.Sign() < 0 {
+		buf[1] = 0x01
+	}
+
+	f.A.FillBytes(buf[2 : 2+size])
+	new(big.Int).Abs(f.B).FillBytes(buf[2+size:])
+	return buf
+}
+
+// DecodeForm
+//
+//	@Description: 从编码中恢复二次型，只接受判别式为 d 的约化二次型
+//	@param d - 判别式
+//	@param data - Bytes 得到的编码
+//	@return *ClassGroupForm - 二次型
+//	@return error - 编码不合法时返回错误
+func DecodeForm(d *big.Int, data []byte) (*ClassGroupForm, error) {
+	size := formElementSize(d)
+	if len(data) != 2+2*size || data[0] != 0x01 || data[1] > 0x01 {
+		return nil, errInvalidForm
+	}
+
+	a := new(big.Int).SetBytes(data[2 : 2+size])
+	b := new(big.Int).SetBytes(data[2+size:])
+	if data[1] == 0x01 {
+		b.Neg(b)
+	}
+
+	if a.Sign() <= 0 {
+		return nil, errInvalidForm
+	}
+
+	// c = (b^2 - D) / 4a，要求整除
+	num := new(big.Int).Mul(b, b)
+	num.Sub(num, d)
+	c, m := new(big.Int).DivMod(num, new(big.Int).Mul(four, a), new(big.Int))
+	if m.Sign() != 0 {
+		return nil, errInvalidForm
+	}
+
+	f := &ClassGroupForm{A: a, B: b, C: c}
+	if !f.isReduced() {
+		return nil, errInvalidForm
+	}
+
+	return f, nil
+}
+
+// normalize
+//
+//	@Description: 将 b 调整到 (-a, a] 的范围内
+func (f *ClassGroupForm) normalize() {
+	if f.B.Cmp(new(big.Int).Neg(f.A)) > 0 && f.B.Cmp(f.A) <= 0 {
+		return
+	}
+
+	// r = floor((a - b) / 2a)，除数为正数时 big.Int 的 Div 即为向下取整
+	twoA := new(big.Int).Lsh(f.A, 1)
+	r := new(big.Int).Sub(f.A, f.B)
+	r.Div(r, twoA)
+
+	// c = a * r^2 + b * r + c, b = b + 2ra
+	c := new(big.Int).Mul(f.A, r)
+	c.Add(c, f.B)
+	c.Mul(c, r)
+	f.C.Add(f.C, c)
+	f.B.Add(f.B, r.Mul(r, twoA))
+}
+
+// reduce
+//
+//	@Description: 约化二次型，约化后满足 |b| <= a <= c，并且 |b| = a 或 a = c 时 b >= 0
+func (f *ClassGroupForm) reduce() *ClassGroupForm {
+	f.normalize()
+	for f.A.Cmp(f.C) > 0 || (f.A.Cmp(f.C) == 0 && f.B.Sign() < 0) {
+		f.A, f.C = f.C, f.A
+		f.B.Neg(f.B)
+		f.normalize()
+	}
+	return f
+}
+
+// isReduced
+//
+//	@Description: 检查二次型是否已经约化
+func (f *ClassGroupForm) isReduced() bool {
+	if f.A.Sign() <= 0 || f.A.Cmp(f.C) > 0 {
+		return false
+	}
+
+	if f.B.Cmp(f.A) > 0 || f.B.Cmp(new(big.Int).Neg(f.A)) <= 0 {
+		return false
+	}
+
+	return f.A.Cmp(f.C) != 0 || f.B.Sign() >= 0
+}
+
+// formElementSize
+//
+//	@Description: 约化二次型中 a 和 |b| 不超过 sqrt(|D| / 3)，按照判别式长度的一半计算编码长度
+func formElementSize(d *big.Int) int {
+	return (d.BitLen()/2)/8 + 1
+}
+
+// hashToInt
+//
+//	@Description: 使用 SHA256 计数器模式将消息扩展为 bits 位的整数
+func hashToInt(msg []byte, bits int) *big.Int {
+	size := (bits + 7) / 8
+	buf := make([]byte, 0, size+sha256.Size)
+	counter := make([]byte, 4)
+
+	for i := uint32(0); len(buf) < size; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		hash := sha256.New()
+		hash.Write(counter)
+		hash.Write(msg)
+		buf = hash.Sum(buf)
+	}
+
+	x := new(big.Int).SetBytes(buf[:size])
+	if extra := size*8 - bits; extra > 0 {
+		x.Rsh(x, uint(extra))
+	}
+	return x
+}
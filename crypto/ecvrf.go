@@ -0,0 +1,265 @@
+// Package crypto
+// @Description: RFC 9381 ECVRF-P256-SHA256-TAI 的实现，使用 try-and-increment 方法将消息映射到曲线上，
+// 使用 RFC 6979 生成确定性的 nonce
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"math/big"
+)
+
+const (
+	ecvrfSuiteString = 0x01 // ECVRF-P256-SHA256-TAI 的 suite_string
+	ecvrfPtLen       = 33   // 压缩点的编码长度
+	ecvrfCLen        = 16   // challenge 的长度
+	ecvrfQLen        = 32   // 标量的长度
+
+	ECVRFProofSize  = ecvrfPtLen + ecvrfCLen + ecvrfQLen // 证明 pi 的长度
+	ECVRFOutputSize = sha256.Size                        // 输出 beta 的长度
+)
+
+var (
+	errECVRFInvalidProof = errors.New("invalid ECVRF proof")
+	errECVRFEncodeFailed = errors.New("ECVRF encode to curve failed")
+)
+
+// ECVRFProve
+//
+//	@Description: 使用私钥 prv 对消息 alpha 计算 ECVRF 证明 pi = Gamma || c || s
+//	@param prv - P256 曲线上的私钥
+//	@param alpha - 需要计算的消息
+//	@return []byte - 证明 pi，长度为 ECVRFProofSize
+//	@return error - 错误信息
+func ECVRFProve(prv *ecdsa.PrivateKey, alpha []byte) ([]byte, error) {
+	curve := elliptic.P256()
+	N := curve.Params().N
+
+	// H = encode_to_curve(Y, alpha)，Gamma = x * H
+	xH, yH, err := ecvrfEncodeToCurve(curve, &prv.PublicKey, alpha)
+	if err != nil {
+		return nil, err
+	}
+	hString := elliptic.MarshalCompressed(curve, xH, yH)
+	xGamma, yGamma := curve.ScalarMult(xH, yH, prv.D.Bytes())
+
+	// k 为 RFC 6979 生成的 nonce，U = k * B，V = k * H
+	k := ecvrfNonce(N, prv.D, hString)
+	xU, yU := curve.ScalarBaseMult(k.Bytes())
+	xV, yV := curve.ScalarMult(xH, yH, k.Bytes())
+
+	c := ecvrfChallenge(curve, &prv.PublicKey, xH, yH, xGamma, yGamma, xU, yU, xV, yV)
+
+	// s = (k + c * x) mod q
+	s := new(big.Int).Mul(c, prv.D)
+	s = s.Add(s, k)
+	s = s.Mod(s, N)
+
+	pi := make([]byte, 0, ECVRFProofSize)
+	pi = append(pi, elliptic.MarshalCompressed(curve, xGamma, yGamma)...)
+	pi = append(pi, c.FillBytes(make([]byte, ecvrfCLen))...)
+	pi = append(pi, s.FillBytes(make([]byte, ecvrfQLen))...)
+	return pi, nil
+}
+
+// ECVRFVerify
+//
+//	@Description: 使用公钥验证消息 alpha 的 ECVRF 证明，验证通过时返回 VRF 输出 beta
+//	@param pub - P256 曲线上的公钥
+//	@param alpha - VRF 计算的消息
+//	@param pi - ECVRF 证明
+//	@return []byte - VRF 输出 beta
+//	@return error - 证明不合法时返回错误
+func ECVRFVerify(pub *ecdsa.PublicKey, alpha []byte, pi []byte) ([]byte, error) {
+	curve := elliptic.P256()
+	if pub == nil || pub.X == nil || pub.Y == nil || !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errECVRFInvalidProof
+	}
+
+	xGamma, yGamma, c, s, err := ecvrfDecodeProof(curve, pi)
+	if err != nil {
+		return nil, err
+	}
+
+	xH, yH, err := ecvrfEncodeToCurve(curve, pub, alpha)
+	if err != nil {
+		return nil, err
+	}
+
+	// U = s * B - c * Y，V = s * H - c * Gamma
+	xU, yU := ecvrfSubMult(curve, nil, nil, s, pub.X, pub.Y, c)
+	xV, yV := ecvrfSubMult(curve, xH, yH, s, xGamma, yGamma, c)
+
+	cPrime := ecvrfChallenge(curve, pub, xH, yH, xGamma, yGamma, xU, yU, xV, yV)
+	if c.Cmp(cPrime) != 0 {
+		return nil, errECVRFInvalidProof
+	}
+
+	return ecvrfGammaToHash(curve, xGamma, yGamma), nil
+}
+
+// ECVRFProofToHash
+//
+//	@Description: 从证明 pi 中计算 VRF 输出 beta，该函数不验证证明，只应在验证通过后使用
+//	@param pi - ECVRF 证明
+//	@return []byte - VRF 输出 beta
+//	@return error - 证明格式错误时返回错误
+func ECVRFProofToHash(pi []byte) ([]byte, error) {
+	curve := elliptic.P256()
+	xGamma, yGamma, _, _, err := ecvrfDecodeProof(curve, pi)
+	if err != nil {
+		return nil, err
+	}
+
+	return ecvrfGammaToHash(curve, xGamma, yGamma), nil
+}
+
+// ecvrfEncodeToCurve
+//
+//	@Description: try-and-increment 方法，将公钥与消息哈希后映射到曲线上的点 H
+//	@param curve - P256 曲线
+//	@param pub - 公钥，作为 encode_to_curve_salt
+//	@param alpha - 消息
+//	@return *big.Int - 点 H 的 x 坐标
+//	@return *big.Int - 点 H 的 y 坐标
+//	@return error - 256 次尝试均失败时返回错误
+func ecvrfEncodeToCurve(curve elliptic.Curve, pub *ecdsa.PublicKey, alpha []byte) (*big.Int, *big.Int, error) {
+	salt := elliptic.MarshalCompressed(curve, pub.X, pub.Y)
+
+	for ctr := 0; ctr < 256; ctr++ {
+		hash := sha256.New()
+		hash.Write([]byte{ecvrfSuiteString, 0x01})
+		hash.Write(salt)
+		hash.Write(alpha)
+		hash.Write([]byte{byte(ctr), 0x00})
+
+		// interpret_hash_value_as_a_point：在哈希值前添加 0x02 作为压缩点解码
+		point := append([]byte{0x02}, hash.Sum(nil)...)
+		x, y := elliptic.UnmarshalCompressed(curve, point)
+		if x != nil {
+			return x, y, nil
+		}
+	}
+
+	return nil, nil, errECVRFEncodeFailed
+}
+
+// ecvrfNonce
+//
+//	@Description: RFC 6979 第 3.2 节的确定性 nonce 生成，消息为 point_to_string(H)
+//	@param q - 曲线的阶
+//	@param x - 私钥
+//	@param hString - 点 H 的压缩编码
+//	@return *big.Int - nonce k
+func ecvrfNonce(q *big.Int, x *big.Int, hString []byte) *big.Int {
+	h1 := sha256.Sum256(hString)
+
+	// bits2octets(h1)，P256 的 qlen 与哈希长度相同，只需要对 q 取模
+	z := new(big.Int).SetBytes(h1[:])
+	z = z.Mod(z, q)
+
+	xOctets := x.FillBytes(make([]byte, ecvrfQLen))
+	hOctets := z.FillBytes(make([]byte, ecvrfQLen))
+
+	V := bytes.Repeat([]byte{0x01}, sha256.Size)
+	K := make([]byte, sha256.Size)
+
+	K = ecvrfHmac(K, V, []byte{0x00}, xOctets, hOctets)
+	V = ecvrfHmac(K, V)
+	K = ecvrfHmac(K, V, []byte{0x01}, xOctets, hOctets)
+	V = ecvrfHmac(K, V)
+
+	for {
+		V = ecvrfHmac(K, V)
+		k := new(big.Int).SetBytes(V)
+		if k.Sign() > 0 && k.Cmp(q) < 0 {
+			return k
+		}
+
+		K = ecvrfHmac(K, V, []byte{0x00})
+		V = ecvrfHmac(K, V)
+	}
+}
+
+// ecvrfHmac
+//
+//	@Description: 计算 HMAC-SHA256(key, data[0] || data[1] || ...)
+func ecvrfHmac(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// ecvrfChallenge
+//
+//	@Description: challenge 生成，c = SHA256(suite || 0x02 || Y || H || Gamma || U || V || 0x00) 的前 cLen 字节
+//	@return *big.Int - challenge c
+func ecvrfChallenge(curve elliptic.Curve, pub *ecdsa.PublicKey, xH, yH, xGamma, yGamma, xU, yU, xV, yV *big.Int) *big.Int {
+	hash := sha256.New()
+	hash.Write([]byte{ecvrfSuiteString, 0x02})
+	hash.Write(elliptic.MarshalCompressed(curve, pub.X, pub.Y))
+	hash.Write(elliptic.MarshalCompressed(curve, xH, yH))
+	hash.Write(elliptic.MarshalCompressed(curve, xGamma, yGamma))
+	hash.Write(elliptic.MarshalCompressed(curve, xU, yU))
+	hash.Write(elliptic.MarshalCompressed(curve, xV, yV))
+	hash.Write([]byte{0x00})
+
+	return new(big.Int).SetBytes(hash.Sum(nil)[:ecvrfCLen])
+}
+
+// ecvrfDecodeProof
+//
+//	@Description: 将证明 pi 解码为 Gamma、c、s
+//	@return error - 证明长度错误、Gamma 不在曲线上或 s 超出范围时返回错误
+func ecvrfDecodeProof(curve elliptic.Curve, pi []byte) (*big.Int, *big.Int, *big.Int, *big.Int, error) {
+	if len(pi) != ECVRFProofSize {
+		return nil, nil, nil, nil, errECVRFInvalidProof
+	}
+
+	xGamma, yGamma := elliptic.UnmarshalCompressed(curve, pi[:ecvrfPtLen])
+	if xGamma == nil {
+		return nil, nil, nil, nil, errECVRFInvalidProof
+	}
+
+	c := new(big.Int).SetBytes(pi[ecvrfPtLen : ecvrfPtLen+ecvrfCLen])
+	s := new(big.Int).SetBytes(pi[ecvrfPtLen+ecvrfCLen:])
+	if s.Cmp(curve.Params().N) >= 0 {
+		return nil, nil, nil, nil, errECVRFInvalidProof
+	}
+
+	return xGamma, yGamma, c, s, nil
+}
+
+// ecvrfSubMult
+//
+//	@Description: 计算 s * P - c * Q，P 为 nil 时使用基点
+func ecvrfSubMult(curve elliptic.Curve, xP, yP *big.Int, s *big.Int, xQ, yQ *big.Int, c *big.Int) (*big.Int, *big.Int) {
+	var xSP, ySP *big.Int
+	if xP == nil {
+		xSP, ySP = curve.ScalarBaseMult(s.Bytes())
+	} else {
+		xSP, ySP = curve.ScalarMult(xP, yP, s.Bytes())
+	}
+
+	// -Q = (x, p - y)
+	yNegQ := new(big.Int).Sub(curve.Params().P, yQ)
+	xCQ, yCQ := curve.ScalarMult(xQ, yNegQ, c.Bytes())
+	return curve.Add(xSP, ySP, xCQ, yCQ)
+}
+
+// ecvrfGammaToHash
+//
+//	@Description: proof_to_hash，beta = SHA256(suite || 0x03 || point_to_string(Gamma) || 0x00)，P256 的 cofactor 为 1
+func ecvrfGammaToHash(curve elliptic.Curve, xGamma, yGamma *big.Int) []byte {
+	hash := sha256.New()
+	hash.Write([]byte{ecvrfSuiteString, 0x03})
+	hash.Write(elliptic.MarshalCompressed(curve, xGamma, yGamma))
+	hash.Write([]byte{0x00})
+	return hash.Sum(nil)
+}
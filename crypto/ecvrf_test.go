@@ -0,0 +1,95 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// RFC 9381 附录 B.1 中 ECVRF-P256-SHA256-TAI 的测试向量
+var ecvrfTestVectors = []struct {
+	sk    string
+	pk    string
+	alpha string
+	pi    string
+	beta  string
+}{
+	{
+		sk:    "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+		pk:    "0360fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6",
+		alpha: "73616d706c65",
+		pi:    "035b5c726e8c0e2c488a107c600578ee75cb702343c153cb1eb8dec77f4b5071b4a53f0a46f018bc2c56e58d383f2305e0975972c26feea0eb122fe7893c15af376b33edf7de17c6ea056d4d82de6bc02f",
+		beta:  "a3ad7b0ef73d8fc6655053ea22f9bede8c743f08bbed3d38821f0e16474b505e",
+	},
+	{
+		sk:    "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721",
+		pk:    "0360fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6",
+		alpha: "74657374",
+		pi:    "034dac60aba508ba0c01aa9be80377ebd7562c4a52d74722e0abae7dc3080ddb56c19e067b15a8a8174905b13617804534214f935b94c2287f797e393eb0816969d864f37625b443f30f1a5a33f2b3c854",
+		beta:  "a284f94ceec2ff4b3794629da7cbafa49121972671b466cab4ce170aa365f26d",
+	},
+}
+
+func TestECVRFTestVectors(t *testing.T) {
+	for i, v := range ecvrfTestVectors {
+		d, _ := new(big.Int).SetString(v.sk, 16)
+		prv := new(ecdsa.PrivateKey)
+		prv.Curve = elliptic.P256()
+		prv.D = d
+		prv.X, prv.Y = prv.Curve.ScalarBaseMult(d.Bytes())
+
+		pk := elliptic.MarshalCompressed(prv.Curve, prv.X, prv.Y)
+		if hex.EncodeToString(pk) != v.pk {
+			t.Fatalf("Vector %d: public key mismatch.", i)
+		}
+
+		alpha, _ := hex.DecodeString(v.alpha)
+		pi, err := ECVRFProve(prv, alpha)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hex.EncodeToString(pi) != v.pi {
+			t.Fatalf("Vector %d: proof mismatch, got %x.", i, pi)
+		}
+
+		beta, err := ECVRFVerify(&prv.PublicKey, alpha, pi)
+		if err != nil {
+			t.Fatalf("Vector %d: verify failed: %v", i, err)
+		}
+
+		if hex.EncodeToString(beta) != v.beta {
+			t.Fatalf("Vector %d: output mismatch, got %x.", i, beta)
+		}
+
+		hash, _ := ECVRFProofToHash(pi)
+		if !bytes.Equal(hash, beta) {
+			t.Fatalf("Vector %d: proof to hash mismatch.", i)
+		}
+	}
+}
+
+func TestECVRFVerifyReject(t *testing.T) {
+	v := ecvrfTestVectors[0]
+	pk, _ := hex.DecodeString(v.pk)
+	pub := Bytes2PublicKey(pk)
+	alpha, _ := hex.DecodeString(v.alpha)
+	pi, _ := hex.DecodeString(v.pi)
+
+	if _, err := ECVRFVerify(pub, []byte("other"), pi); err == nil {
+		t.Fatal("Proof verified with another message.")
+	}
+
+	tampered := append([]byte{}, pi...)
+	tampered[len(tampered)-1] ^= 0x01
+	if _, err := ECVRFVerify(pub, alpha, tampered); err == nil {
+		t.Fatal("Tampered proof verified.")
+	}
+
+	if _, err := ECVRFVerify(pub, alpha, pi[:ECVRFProofSize-1]); err == nil {
+		t.Fatal("Short proof verified.")
+	}
+}
@@ -0,0 +1,399 @@
+// Package crypto
+// @Description: 可替换的 VDF 方案，包括 RSA 群上的 VDF（旧版本链使用）以及类群上的 Wesolowski VDF
+package crypto
+
+import (
+	"crypto/sha256"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"math/big"
+)
+
+const (
+	VDFSchemeRSA        uint8 = 0 // RSA 群上的 VDF，证明参数 l 固定在创世区块中
+	VDFSchemeClassGroup uint8 = 1 // 虚二次域类群上的 Wesolowski VDF，不需要可信设置
+
+	DefaultDiscriminantBits    = 1024   // 类群判别式的默认位数
+	DefaultClassGroupTimeParam = 200000 // 类群 VDF 的默认时间参数，类群上的平方比 RSA 群慢得多
+
+	wesolowskiChallengeBits = 128 // Wesolowski 证明中挑战素数 l 的位数
+)
+
+var (
+	errUnknownVDFScheme = errors.New("unknown VDF scheme")
+)
+
+// VDF
+//
+//	@Description: VDF 方案的接口，计算被拆分为若干轮迭代，便于计算器取消计算、记录进度以及保存检查点
+type VDF interface {
+	// Scheme 返回 VDF 方案的编号
+	Scheme() uint8
+	// Rounds 返回一次完整计算需要的迭代轮数
+	Rounds() int64
+	// NewState 创建 seed 的计算状态，cp 不为 nil 时从检查点恢复
+	NewState(seed *big.Int, cp *common.VDFCheckpoint) VDFState
+	// Verify 验证 result 是否为 seed 的 VDF 计算结果
+	Verify(seed *big.Int, proof *big.Int, result *big.Int) bool
+}
+
+// VDFState
+//
+//	@Description: 一次 VDF 计算的中间状态
+type VDFState interface {
+	// Step 执行一轮迭代
+	Step()
+	// Round 返回已经完成的迭代轮数
+	Round() int64
+	// Checkpoint 将当前状态导出为检查点
+	Checkpoint() *common.VDFCheckpoint
+	// Result 在完成所有迭代后返回计算结果
+	Result() *VDFResult
+}
+
+// NewVDF
+//
+//	@Description: 根据创世区块参数中的 VDFScheme 创建对应的 VDF 方案
+//	@param params - 创世区块参数
+//	@return VDF - VDF 方案
+//	@return error - 方案未知或者参数不合法时返回错误
+func NewVDF(params *common.GenesisParams) (VDF, error) {
+	switch params.VDFScheme {
+	case VDFSchemeRSA:
+		pp := new(big.Int).SetBytes(params.VerifyParam[:])
+		order := new(big.Int).SetBytes(params.Order[:])
+		// 由仪式生成的参数中模数可能超过 Order 的长度，此时使用 Modulus
+		if len(params.Modulus) > 0 {
+			order = new(big.Int).SetBytes(params.Modulus)
+		}
+		return NewRSAVDF(pp, order, params.TimeParam), nil
+	case VDFSchemeClassGroup:
+		d := new(big.Int).SetBytes(params.Discriminant)
+		d.Neg(d)
+		if err := ValidateDiscriminant(d); err != nil {
+			return nil, err
+		}
+		return NewClassGroupVDF(d, params.TimeParam), nil
+	}
+
+	return nil, errUnknownVDFScheme
+}
+
+// rsaVDF
+//
+//	@Description: RSA 群上的 VDF，计算 seed^(2^t) mod n，并在计算过程中同时得到证明 pi
+type rsaVDF struct {
+	proofParam *big.Int
+	order      *big.Int
+	timeParam  int64
+}
+
+// NewRSAVDF
+//
+//	@Description: 创建 RSA 群上的 VDF
+//	@param pp - 证明所用到的安全参数，对应论文中的 l
+//	@param order - 计算所在的群的阶
+//	@param t - 计算的时间参数 t，需要计算的为 m^{2^{t}}
+//	@return VDF - VDF 方案
+func NewRSAVDF(pp *big.Int, order *big.Int, t int64) VDF {
+	return &rsaVDF{
+		proofParam: pp,
+		order:      order,
+		timeParam:  t,
+	}
+}
+
+func (v *rsaVDF) Scheme() uint8 {
+	return VDFSchemeRSA
+}
+
+func (v *rsaVDF) Rounds() int64 {
+	return v.timeParam
+}
+
+func (v *rsaVDF) NewState(seed *big.Int, cp *common.VDFCheckpoint) VDFState {
+	s := &rsaVDFState{
+		vdf:    v,
+		seed:   new(big.Int).Set(seed),
+		result: new(big.Int).Set(seed),
+		pi:     big.NewInt(1),
+		r:      big.NewInt(1),
+		g:      new(big.Int),
+	}
+
+	if cp != nil {
+		s.round = cp.Round
+		s.result.SetBytes(cp.Result)
+		s.pi.SetBytes(cp.Proof)
+		s.r.SetBytes(cp.Remainder)
+	}
+	return s
+}
+
+// Verify 验证 VDF 计算结果 result == pi^l * seed^s
+// 具体细节见论文 - Simple Verifiable Delay Functions
+func (v *rsaVDF) Verify(seed *big.Int, pi *big.Int, result *big.Int) bool {
+	tSeed := big.NewInt(0)
+	tSeed.SetBytes(seed.Bytes())
+	tPi := big.NewInt(0)
+	tPi.SetBytes(pi.Bytes())
+
+	r := big.NewInt(2)
+	t := big.NewInt(v.timeParam)
+
+	// r = r^t mod pp
+	r = r.Exp(r, t, v.proofParam)
+
+	// h = pi^pp
+	h := tPi.Exp(tPi, v.proofParam, v.order)
+	// s = seed
+	s := tSeed.Exp(tSeed, r, v.order)
+
+	h = h.Mul(h, s)
+	h = h.Mod(h, v.order)
+
+	return result.Cmp(h) == 0
+}
+
+type rsaVDFState struct {
+	vdf   *rsaVDF
+	seed  *big.Int
+	round int64
+
+	result *big.Int
+	pi     *big.Int
+	r      *big.Int
+	g      *big.Int
+}
+
+// Step
+//
+//	@Description: 一轮迭代，参考论文：A survey of two verifiable delay functions
+//	注： 这里如果直接计算 m^a mod n 的时间复杂度是接近 O(t) 的，所以在 t 足够大的情况下是可以抵御攻击的
+func (s *rsaVDFState) Step() {
+	v := s.vdf
+
+	// result = result^2 mod n
+	s.result.Mul(s.result, s.result)
+	s.result.Mod(s.result, v.order)
+
+	// tmp = 2 * r
+	tmp := big.NewInt(2)
+	b := new(big.Int)
+
+	tmp.Mul(tmp, s.r)
+
+	b.Div(tmp, v.proofParam)   // b = tmp / l
+	s.r.Mod(tmp, v.proofParam) // r = tmp % l
+	// pi = g^b * pi^2 mod n
+	s.pi.Mul(s.pi, s.pi)
+	s.g.Exp(s.seed, b, v.order)
+	s.pi.Mul(s.pi, s.g)
+	s.pi.Mod(s.pi, v.order)
+
+	s.round++
+}
+
+func (s *rsaVDFState) Round() int64 {
+	return s.round
+}
+
+func (s *rsaVDFState) Checkpoint() *common.VDFCheckpoint {
+	return &common.VDFCheckpoint{
+		Seed:      s.seed.Bytes(),
+		Round:     s.round,
+		TimeParam: s.vdf.Rounds(),
+		Result:    s.result.Bytes(),
+		Proof:     s.pi.Bytes(),
+		Remainder: s.r.Bytes(),
+	}
+}
+
+func (s *rsaVDFState) Result() *VDFResult {
+	return &VDFResult{
+		Seed:   new(big.Int).Set(s.seed),
+		Result: new(big.Int).Set(s.result),
+		Proof:  new(big.Int).Set(s.pi),
+	}
+}
+
+// classGroupVDF
+//
+//	@Description: 类群上的 Wesolowski VDF。输入 x = HashToForm(seed)，输出 y = x^(2^t)，
+//	挑战素数 l = HashPrime(x, y)，证明 pi = x^floor(2^t / l)，验证 pi^l * x^(2^t mod l) == y
+type classGroupVDF struct {
+	discriminant *big.Int
+	timeParam    int64
+}
+
+// NewClassGroupVDF
+//
+//	@Description: 创建类群上的 Wesolowski VDF
+//	@param d - 类群的判别式，需要通过 ValidateDiscriminant 的检查
+//	@param t - 计算的时间参数 t
+//	@return VDF - VDF 方案
+func NewClassGroupVDF(d *big.Int, t int64) VDF {
+	return &classGroupVDF{
+		discriminant: d,
+		timeParam:    t,
+	}
+}
+
+func (v *classGroupVDF) Scheme() uint8 {
+	return VDFSchemeClassGroup
+}
+
+// Rounds
+//
+//	@Description: 前 t 轮计算 y = x^(2^t)，后 t 轮在得到挑战素数 l 后计算证明 pi
+func (v *classGroupVDF) Rounds() int64 {
+	return 2 * v.timeParam
+}
+
+func (v *classGroupVDF) NewState(seed *big.Int, cp *common.VDFCheckpoint) VDFState {
+	x := HashToForm(v.discriminant, seed.Bytes())
+	s := &classGroupVDFState{
+		vdf:  v,
+		seed: new(big.Int).Set(seed),
+		x:    x,
+		y:    x,
+		pi:   IdentityForm(v.discriminant),
+		r:    big.NewInt(1),
+	}
+
+	if cp == nil {
+		return s
+	}
+
+	y, err := DecodeForm(v.discriminant, cp.Result)
+	if err != nil {
+		return s
+	}
+	s.round, s.y = cp.Round, y
+
+	if cp.Round >= v.timeParam {
+		pi, err := DecodeForm(v.discriminant, cp.Proof)
+		if err != nil {
+			s.round = v.timeParam
+			s.l = wesolowskiChallenge(v.discriminant, s.x, s.y)
+			return s
+		}
+		s.pi = pi
+		s.r.SetBytes(cp.Remainder)
+		s.l = wesolowskiChallenge(v.discriminant, s.x, s.y)
+	}
+	return s
+}
+
+func (v *classGroupVDF) Verify(seed *big.Int, proof *big.Int, result *big.Int) bool {
+	y, err := DecodeForm(v.discriminant, result.Bytes())
+	if err != nil {
+		return false
+	}
+
+	pi, err := DecodeForm(v.discriminant, proof.Bytes())
+	if err != nil {
+		return false
+	}
+
+	x := HashToForm(v.discriminant, seed.Bytes())
+	l := wesolowskiChallenge(v.discriminant, x, y)
+
+	// r = 2^t mod l
+	r := new(big.Int).Exp(two, big.NewInt(v.timeParam), l)
+
+	return pi.Pow(l).Compose(x.Pow(r)).Equal(y)
+}
+
+type classGroupVDFState struct {
+	vdf   *classGroupVDF
+	seed  *big.Int
+	round int64
+
+	x  *ClassGroupForm
+	y  *ClassGroupForm
+	pi *ClassGroupForm
+	l  *big.Int
+	r  *big.Int
+}
+
+// Step
+//
+//	@Description: 前 t 轮对 y 进行平方；完成后计算挑战素数 l，后 t 轮按照长除法逐位计算 pi = x^floor(2^t / l)
+func (s *classGroupVDFState) Step() {
+	v := s.vdf
+
+	if s.round < v.timeParam {
+		s.y = s.y.Square()
+		s.round++
+
+		if s.round == v.timeParam {
+			s.l = wesolowskiChallenge(v.discriminant, s.x, s.y)
+		}
+		return
+	}
+
+	// b = floor(2r / l), r = 2r mod l, pi = pi^2 * x^b
+	tmp := new(big.Int).Lsh(s.r, 1)
+	b := new(big.Int)
+	b.DivMod(tmp, s.l, s.r)
+
+	s.pi = s.pi.Square()
+	if b.Sign() != 0 {
+		s.pi = s.pi.Compose(s.x)
+	}
+	s.round++
+}
+
+func (s *classGroupVDFState) Round() int64 {
+	return s.round
+}
+
+func (s *classGroupVDFState) Checkpoint() *common.VDFCheckpoint {
+	d := s.vdf.discriminant
+	return &common.VDFCheckpoint{
+		Seed:      s.seed.Bytes(),
+		Round:     s.round,
+		TimeParam: s.vdf.Rounds(),
+		Result:    s.y.Bytes(d),
+		Proof:     s.pi.Bytes(d),
+		Remainder: s.r.Bytes(),
+	}
+}
+
+func (s *classGroupVDFState) Result() *VDFResult {
+	d := s.vdf.discriminant
+	return &VDFResult{
+		Seed:   new(big.Int).Set(s.seed),
+		Result: new(big.Int).SetBytes(s.y.Bytes(d)),
+		Proof:  new(big.Int).SetBytes(s.pi.Bytes(d)),
+	}
+}
+
+// wesolowskiChallenge
+//
+//	@Description: Fiat-Shamir 挑战，从输入 x 和输出 y 的哈希中得到 wesolowskiChallengeBits 位的素数 l
+func wesolowskiChallenge(d *big.Int, x, y *ClassGroupForm) *big.Int {
+	hash := sha256.New()
+	hash.Write(x.Bytes(d))
+	hash.Write(y.Bytes(d))
+
+	l := hashToInt(hash.Sum(nil), wesolowskiChallengeBits)
+	l.SetBit(l, wesolowskiChallengeBits-1, 1)
+	l.SetBit(l, 0, 1)
+	for !l.ProbablyPrime(20) {
+		l.Add(l, two)
+	}
+	return l
+}
+
+// GenerateClassGroupParams
+//
+//	@Description: 从种子生成类群 VDF 的判别式，返回 |D| 的字节编码，用于写入创世区块参数
+//	@param seed - 种子，通常为创世参数中的 Seed
+//	@param bits - 判别式的位数
+//	@return []byte - |D| 的字节编码
+func GenerateClassGroupParams(seed []byte, bits int) []byte {
+	d := GenerateDiscriminant(seed, bits)
+	return new(big.Int).Neg(d).Bytes()
+}
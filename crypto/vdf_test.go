@@ -0,0 +1,169 @@
+package crypto
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+func TestClassGroupForm(t *testing.T) {
+	d := GenerateDiscriminant([]byte("class-group"), 256)
+	if err := ValidateDiscriminant(d); err != nil {
+		t.Fatal(err)
+	}
+
+	f := HashToForm(d, []byte("f"))
+	g := HashToForm(d, []byte("g"))
+	h := HashToForm(d, []byte("h"))
+
+	if f.Discriminant().Cmp(d) != 0 || f.Compose(g).Discriminant().Cmp(d) != 0 {
+		t.Fatal("Discriminant changed after compose.")
+	}
+
+	if !f.Compose(f.Inverse()).Equal(IdentityForm(d)) {
+		t.Fatal("Compose with inverse is not identity.")
+	}
+
+	if !f.Compose(g).Compose(h).Equal(f.Compose(g.Compose(h))) {
+		t.Fatal("Compose is not associative.")
+	}
+
+	if !f.Pow(big.NewInt(12)).Equal(f.Pow(big.NewInt(5)).Compose(f.Pow(big.NewInt(7)))) {
+		t.Fatal("Pow mismatch.")
+	}
+
+	decoded, err := DecodeForm(d, f.Bytes(d))
+	if err != nil || !decoded.Equal(f) {
+		t.Fatal("Decode form failed.")
+	}
+
+	if _, err := DecodeForm(d, f.Bytes(d)[1:]); err == nil {
+		t.Fatal("Decode truncated form succeeded.")
+	}
+}
+
+func TestClassGroupVDF(t *testing.T) {
+	d := GenerateDiscriminant([]byte("class-group"), 256)
+	vdf := NewClassGroupVDF(d, 200)
+	calculator := NewCalculator(vdf)
+
+	seed := big.NewInt(23)
+	res, err := calculator.Evaluate(context.Background(), seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !vdf.Verify(seed, res.Proof, res.Result) {
+		t.Fatal("Verify class group VDF failed.")
+	}
+
+	if vdf.Verify(big.NewInt(29), res.Proof, res.Result) {
+		t.Fatal("Verify with another seed succeeded.")
+	}
+
+	x := HashToForm(d, seed.Bytes())
+	if vdf.Verify(seed, res.Proof, new(big.Int).SetBytes(x.Bytes(d))) {
+		t.Fatal("Verify with wrong result succeeded.")
+	}
+}
+
+func TestClassGroupVDFCheckpointResume(t *testing.T) {
+	d := GenerateDiscriminant([]byte("class-group"), 256)
+	vdf := NewClassGroupVDF(d, 100)
+	seed := big.NewInt(31)
+
+	full := vdf.NewState(seed, nil)
+	for full.Round() < vdf.Rounds() {
+		full.Step()
+	}
+
+	// 分别在计算输出和计算证明的阶段保存检查点并恢复
+	for _, stop := range []int64{50, 150} {
+		state := vdf.NewState(seed, nil)
+		for state.Round() < stop {
+			state.Step()
+		}
+
+		resumed := vdf.NewState(seed, state.Checkpoint())
+		for resumed.Round() < vdf.Rounds() {
+			resumed.Step()
+		}
+
+		if resumed.Result().Result.Cmp(full.Result().Result) != 0 ||
+			resumed.Result().Proof.Cmp(full.Result().Proof) != 0 {
+			t.Fatalf("Resume from round %d mismatch.", stop)
+		}
+	}
+}
+
+func TestNewVDF(t *testing.T) {
+	params, err := GenerateGenesisParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vdf, err := NewVDF(params)
+	if err != nil || vdf.Scheme() != VDFSchemeRSA {
+		t.Fatal("Create RSA VDF failed.")
+	}
+
+	params.VDFScheme = VDFSchemeClassGroup
+	params.Discriminant = GenerateClassGroupParams(params.Seed[:], 256)
+	vdf, err = NewVDF(params)
+	if err != nil || vdf.Scheme() != VDFSchemeClassGroup {
+		t.Fatal("Create class group VDF failed.")
+	}
+
+	params.Discriminant = big.NewInt(15).Bytes()
+	if _, err = NewVDF(params); err == nil {
+		t.Fatal("Invalid discriminant accepted.")
+	}
+}
+
+const benchmarkTimeParam = 2000
+
+func benchmarkEvaluate(b *testing.B, vdf VDF) {
+	calculator := NewCalculator(vdf)
+	for i := 0; i < b.N; i++ {
+		_, _ = calculator.Evaluate(context.Background(), big.NewInt(int64(i+2)))
+	}
+}
+
+func benchmarkVerify(b *testing.B, vdf VDF) {
+	calculator := NewCalculator(vdf)
+	seed := big.NewInt(7)
+	res, _ := calculator.Evaluate(context.Background(), seed)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !vdf.Verify(seed, res.Proof, res.Result) {
+			b.Fatal("Verify failed.")
+		}
+	}
+}
+
+func benchmarkRSAVDF() VDF {
+	n, pp, _ := GenerateParams()
+	return NewRSAVDF(pp, n, benchmarkTimeParam)
+}
+
+func benchmarkClassGroupVDF() VDF {
+	d := GenerateDiscriminant([]byte("benchmark"), DefaultDiscriminantBits)
+	return NewClassGroupVDF(d, benchmarkTimeParam)
+}
+
+func BenchmarkRSAVDFEvaluate(b *testing.B) {
+	benchmarkEvaluate(b, benchmarkRSAVDF())
+}
+
+func BenchmarkRSAVDFVerify(b *testing.B) {
+	benchmarkVerify(b, benchmarkRSAVDF())
+}
+
+func BenchmarkClassGroupVDFEvaluate(b *testing.B) {
+	benchmarkEvaluate(b, benchmarkClassGroupVDF())
+}
+
+func BenchmarkClassGroupVDFVerify(b *testing.B) {
+	benchmarkVerify(b, benchmarkClassGroupVDF())
+}
@@ -3,21 +3,32 @@
 package crypto
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
 	"github.com/gookit/config/v2"
 	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
 	"math/big"
 )
 
 const (
-	// todo: 作为参数写入到创世区块
+	// ConsensusFloor 旧版本链（创世参数中未设置期望出块数量）所使用的最低概率
 	ConsensusFloor = 0.0 // 共识要求的最低概率
+
+	DefaultExpectedProducers = 2  // 每个出块间隔中期望的出块节点数量
+	DefaultTotalWeight       = 10 // 全网的总权重，未登记权重的节点权重为 1
+	DefaultProducerWeight    = 1  // 未在链上登记权重的节点的默认权重
+
+	VRFVersionLegacy uint8 = 0 // 旧版本链使用的 VRF 构造，即 VRFCalculate 和 VRFVerify
+	VRFVersionECVRF  uint8 = 1 // RFC 9381 ECVRF-P256-SHA256-TAI
 )
 
 var (
+	errUnknownVRFVersion = errors.New("unknown VRF version")
+
 	tt260 = BigPow(2, 260) // 2 ^ 260
 	tt256 = BigPow(2, 256) // 2 ^ 256
 )
@@ -32,7 +43,6 @@ var (
 //	@return *big.Int - 证明参数 t
 //	@return error - 错误信息
 func VRFCalculate(curve elliptic.Curve, msg []byte) ([]byte, *big.Int, *big.Int, error) {
-	N := curve.Params().N
 	// 读取本地的私钥
 	prvHex := config.String("consensus.prv")
 	//prvHex := "f8bc37201dfa59c1b62ce77a168c168e2a525ebad8e18c131be8ab4be6b5a5cb"
@@ -42,6 +52,22 @@ func VRFCalculate(curve elliptic.Curve, msg []byte) ([]byte, *big.Int, *big.Int,
 		return nil, nil, nil, err
 	}
 
+	return VRFCalculateWithKey(curve, prv, msg)
+}
+
+// VRFCalculateWithKey
+//
+//	@Description: 使用给定的私钥对消息 m 进行 VRF 计算，并返回证明参数 (s,t)
+//	@param curve - 计算所在的曲线
+//	@param prv - 计算使用的私钥
+//	@param msg - 需要计算的消息
+//	@return []byte - VRF 计算结果
+//	@return *big.Int - 证明参数 s
+//	@return *big.Int - 证明参数 t
+//	@return error - 错误信息
+func VRFCalculateWithKey(curve elliptic.Curve, prv *ecdsa.PrivateKey, msg []byte) ([]byte, *big.Int, *big.Int, error) {
+	N := curve.Params().N
+
 	// 对消息进行哈希，避免消息过短
 	sha2 := sha256.New()
 	sha2.Write(msg)
@@ -82,13 +108,42 @@ func VRFCalculate(curve elliptic.Curve, msg []byte) ([]byte, *big.Int, *big.Int,
 	return rBytes, s, t, nil
 }
 
+// VRFConsensusThreshold
+//
+//	@Description: 计算 VRF 输出需要满足的阈值，使得每个出块间隔内满足共识的节点数量期望为 expectedProducers
+//	节点满足共识的概率为 expectedProducers * weight / totalWeight，阈值为 2^256 乘以该概率
+//	@param expectedProducers - 每个出块间隔期望的出块节点数量
+//	@param weight - 节点的权重
+//	@param totalWeight - 全网总权重
+//	@return *big.Int - VRF 输出哈希需要小于的阈值，如果参数未设置（旧版本的链）返回 nil
+func VRFConsensusThreshold(expectedProducers, weight, totalWeight int64) *big.Int {
+	if expectedProducers <= 0 || totalWeight <= 0 {
+		return nil
+	}
+
+	if weight <= 0 {
+		return big.NewInt(0)
+	}
+
+	threshold := new(big.Int).Mul(tt256, big.NewInt(expectedProducers))
+	threshold = threshold.Mul(threshold, big.NewInt(weight))
+	threshold = threshold.Div(threshold, big.NewInt(totalWeight))
+
+	// 概率大于 1 时，所有的输出都满足共识
+	if threshold.Cmp(tt256) > 0 {
+		threshold.Set(tt256)
+	}
+	return threshold
+}
+
 // VRFCheckOutputConsensus
 //
 //	@Description: 检查一个 VRF 的输出是否满足共识
 //	@param randomOutput
+//	@param threshold - VRFConsensusThreshold 计算得到的阈值，为 nil 时使用 ConsensusFloor 进行判断
 //	@param local - 是否为本地的验证计算
 //	@return bool - VRF 是否满足共识条件
-func VRFCheckOutputConsensus(randomOutput []byte, local bool) bool {
+func VRFCheckOutputConsensus(randomOutput []byte, threshold *big.Int, local bool) bool {
 	sha2 := sha256.New()
 	sha2.Write(randomOutput)
 	digest := sha2.Sum(nil)
@@ -96,49 +151,118 @@ func VRFCheckOutputConsensus(randomOutput []byte, local bool) bool {
 	r := new(big.Int)
 	r.SetBytes(digest)
 
-	base := new(big.Int)
-	base.SetInt64(1000)
-	r = r.Mul(r, base)
-	r = r.Div(r, tt256)
+	// 旧版本的链不包含阈值参数，沿用最低概率的判断
+	if threshold == nil {
+		base := new(big.Int)
+		base.SetInt64(1000)
+		r = r.Mul(r, base)
+		r = r.Div(r, tt256)
+
+		prob := float64(r.Int64()) / 1000.0
+		if local {
+			log.Infof("Local VRF consensus prob = %f", prob)
+		}
+		return prob > ConsensusFloor
+	}
 
-	prob := float64(r.Int64()) / 1000.0
 	if local {
-		log.Infof("Local VRF consensus prob = %f", prob)
+		log.Debugf("Local VRF output = %s, threshold = %s",
+			r.Text(16), threshold.Text(16))
 	}
-	return prob > ConsensusFloor
+	return r.Cmp(threshold) < 0
+}
+
+// VRFProve
+//
+//	@Description: 按照 VRF 版本使用本地的私钥对消息计算 VRF，返回的三个字段依次填入区块参数的 RandomNumber、S、T
+//	@param version - 创世参数中的 VRF 版本
+//	@param prv - 本地节点的共识私钥
+//	@param msg - 需要计算的消息
+//	@return []byte - 旧版本为 VRF 输出点，ECVRF 为证明中的 Gamma
+//	@return []byte - 旧版本为证明参数 s，ECVRF 为证明中的 challenge c
+//	@return []byte - 旧版本为证明参数 t，ECVRF 为证明中的 s
+//	@return error - 错误信息
+func VRFProve(version uint8, prv *ecdsa.PrivateKey, msg []byte) ([]byte, []byte, []byte, error) {
+	if version == VRFVersionLegacy {
+		rBytes, s, t, err := VRFCalculateWithKey(elliptic.P256(), prv, msg)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return rBytes, s.Bytes(), t.Bytes(), nil
+	}
+
+	if version != VRFVersionECVRF {
+		return nil, nil, nil, errUnknownVRFVersion
+	}
+
+	pi, err := ECVRFProve(prv, msg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pi[:ecvrfPtLen], pi[ecvrfPtLen : ecvrfPtLen+ecvrfCLen], pi[ecvrfPtLen+ecvrfCLen:], nil
 }
 
 // VRFCheckLocalConsensus
 //
 //	@Description: 检查当前节点是否是一个共识节点
+//	@param version - 创世参数中的 VRF 版本
+//	@param prv - 本地节点的共识私钥
 //	@param vdfOutput - 当前的 VDF 轮的输出结果
+//	@param threshold - 本地节点的共识阈值
 //	@return bool - 当前节点是否是共识节点
 //	@return error - 报错信息
-func VRFCheckLocalConsensus(vdfOutput []byte) (bool, error) {
-	rBytes, _, _, _ := VRFCalculate(elliptic.P256(), vdfOutput)
+func VRFCheckLocalConsensus(version uint8, prv *ecdsa.PrivateKey, vdfOutput []byte, threshold *big.Int) (bool, error) {
+	rBytes, s, t, err := VRFProve(version, prv, vdfOutput)
+	if err != nil {
+		return false, err
+	}
 
-	return VRFCheckOutputConsensus(rBytes, true), nil
+	output := rBytes
+	if version == VRFVersionECVRF {
+		output, err = ECVRFProofToHash(bytes.Join([][]byte{rBytes, s, t}, nil))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return VRFCheckOutputConsensus(output, threshold, true), nil
 }
 
 // VRFCheckRemoteConsensus
 //
 //	@Description: 检查一个其他节点的输出是否满足共识条件
+//	@param version - 创世参数中的 VRF 版本
 //	@param key - 节点公钥
 //	@param vdfMsg - 区块中包含的 VDF 信息
-//	@param s - VRF 证明参数 s
-//	@param t - VRF 证明参数 t
-//	@param value - 对端节点的 VRF 输出结果
+//	@param s - 区块参数中的 S
+//	@param t - 区块参数中的 T
+//	@param value - 区块参数中的 RandomNumber
+//	@param threshold - 对端节点的共识阈值
 //	@return bool - 是否共识节点
 //	@return error - 报错信息
-func VRFCheckRemoteConsensus(key *ecdsa.PublicKey, vdfMsg []byte, s *big.Int, t *big.Int, value []byte) (bool, error) {
-	verified, err := VRFVerify(elliptic.P256(), key, vdfMsg, s, t, value)
+func VRFCheckRemoteConsensus(version uint8, key *ecdsa.PublicKey, vdfMsg []byte, s []byte, t []byte, value []byte, threshold *big.Int) (bool, error) {
+	if version == VRFVersionECVRF {
+		beta, err := ECVRFVerify(key, vdfMsg, bytes.Join([][]byte{value, s, t}, nil))
+		if err != nil {
+			return false, nil
+		}
+		return VRFCheckOutputConsensus(beta, threshold, false), nil
+	}
+
+	if version != VRFVersionLegacy {
+		return false, errUnknownVRFVersion
+	}
+
+	verified, err := VRFVerify(elliptic.P256(), key, vdfMsg,
+		new(big.Int).SetBytes(s), new(big.Int).SetBytes(t), value)
 	if !verified || err != nil {
 		// 验证过程中出错， 认为验证失败
 		//log.WithError(err).Warning("Verify remote consensus failed.")
 		return false, nil
 	}
 
-	return VRFCheckOutputConsensus(value, false), nil
+	return VRFCheckOutputConsensus(value, threshold, false), nil
 }
 
 // VRFVerify
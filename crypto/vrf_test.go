@@ -8,7 +8,9 @@ package crypto
 
 import (
 	"crypto/elliptic"
+	"encoding/binary"
 	"encoding/hex"
+	"math/big"
 	"testing"
 )
 
@@ -36,3 +38,47 @@ func TestVRFCalculateAndVerify(t *testing.T) {
 		t.Fatal("Verify random failed.")
 	}
 }
+
+func TestVRFConsensusThreshold(t *testing.T) {
+	if VRFConsensusThreshold(0, 1, 10) != nil {
+		t.Fatal("Threshold of legacy params should be nil.")
+	}
+
+	if VRFConsensusThreshold(2, 0, 10).Sign() != 0 {
+		t.Fatal("Threshold of zero weight should be zero.")
+	}
+
+	if VRFConsensusThreshold(20, 1, 10).Cmp(tt256) != 0 {
+		t.Fatal("Threshold should not exceed 2^256.")
+	}
+
+	// 2 * 1 / 8 = 1/4
+	quarter := new(big.Int).Div(tt256, big.NewInt(4))
+	if VRFConsensusThreshold(2, 1, 8).Cmp(quarter) != 0 {
+		t.Fatal("Threshold calculate failed.")
+	}
+}
+
+func TestVRFCheckOutputConsensusRate(t *testing.T) {
+	const rounds = 4000
+	threshold := VRFConsensusThreshold(2, 1, 10)
+
+	passed := 0
+	output := make([]byte, 8)
+	for i := 0; i < rounds; i++ {
+		binary.BigEndian.PutUint64(output, uint64(i))
+		if VRFCheckOutputConsensus(output, threshold, false) {
+			passed++
+		}
+	}
+
+	// 期望通过率为 0.2
+	rate := float64(passed) / rounds
+	if rate < 0.17 || rate > 0.23 {
+		t.Fatalf("Consensus rate %f out of range.", rate)
+	}
+
+	if !VRFCheckOutputConsensus(output, nil, false) {
+		t.Fatal("Legacy consensus check failed.")
+	}
+}
@@ -0,0 +1,10 @@
+package interfaces
+
+import "context"
+
+// Service 带有后台协程的子系统。Start 传入的 ctx 作为子系统内部协程 context 的父 context，
+// Stop 通知所有协程退出，并等待退出完成或者 ctx 超时
+type Service interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
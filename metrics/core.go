@@ -28,6 +28,16 @@ var (
 		Name: "core_buffer_second_queue",
 		Help: "Core buffer second blocks",
 	})
+	// 当前 VDF 计算的进度百分比
+	vdfProgressMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "core_vdf_progress",
+		Help: "VDF calculate progress in percentage.",
+	})
+	// 当前 VDF 计算的预计剩余时间（秒）
+	vdfETAMetric = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "core_vdf_eta_seconds",
+		Help: "VDF calculate estimated remaining time in seconds.",
+	})
 )
 
 func TxPoolMetricsInc() {
@@ -53,3 +63,11 @@ func SecondBufferInc() {
 func SecondBufferDec() {
 	coreBufferSecondQueueMetrices.Dec()
 }
+
+func VDFProgressSet(progress float64) {
+	vdfProgressMetric.Set(progress)
+}
+
+func VDFETASet(seconds float64) {
+	vdfETAMetric.Set(seconds)
+}
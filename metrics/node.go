@@ -18,6 +18,31 @@ var (
 		Name: "node_time_sync_delta",
 		Help: "Time sync delta per round sync.",
 	})
+	// 时间同步每轮按往返时延加权得到的偏移
+	timeSyncOffset = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "node_time_sync_offset",
+		Help: "Weighted clock offset of the latest time sync round.",
+	})
+	// 时间同步每轮有效样本的离散度
+	timeSyncDispersion = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "node_time_sync_dispersion",
+		Help: "Dispersion of the latest time sync round.",
+	})
+	// 时间同步每轮有效样本的平均往返时延
+	timeSyncRTT = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "node_time_sync_rtt",
+		Help: "Mean round trip time of the latest time sync round.",
+	})
+	// 时间同步每轮的有效样本数量
+	timeSyncSamples = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "node_time_sync_samples",
+		Help: "Accepted samples in the latest time sync round.",
+	})
+	// 时间同步被剔除的异常样本数量
+	timeSyncRejectedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "node_time_sync_rejected_samples",
+		Help: "The counter for rejected time sync samples.",
+	})
 	// 插入数据库的交易数量
 	transactionInsertCount = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "node_transaction_insert_count",
@@ -58,6 +83,41 @@ var (
 		Name: "block_syncer_status",
 		Help: "The status in time syncer.",
 	})
+	// 区块同步的插入速率
+	blockSyncRate = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "block_sync_rate",
+		Help: "Blocks inserted per second during block sync.",
+	})
+	// 区块同步预计剩余时间
+	blockSyncETA = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "block_sync_eta_seconds",
+		Help: "Estimated seconds until block sync reaches the remote height.",
+	})
+	// 区块同步剩余的区块数量
+	blockSyncRemaining = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "block_sync_remaining",
+		Help: "Blocks remaining until block sync reaches the remote height.",
+	})
+	// 区块同步的目标高度
+	blockSyncTarget = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "block_sync_target_height",
+		Help: "Height reached by the weighted majority of peers selected as block sync target.",
+	})
+	// 支持区块同步目标的节点数量
+	blockSyncSelectedPeers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "block_sync_selected_peers",
+		Help: "Number of peers supporting the block sync target.",
+	})
+	// 同步完成后落后过多、重新进入区块同步的次数
+	blockSyncCatchUpCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "block_sync_catch_up",
+		Help: "The counter for falling behind peers after sync and restarting block sync.",
+	})
+	// 为缺少父区块的广播区块补齐的父区块数量
+	parentFetchCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "block_parent_fetch",
+		Help: "The counter for missing parent blocks fetched from peers.",
+	})
 	transactionInsertCounter = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "transaction_insert_counter",
 		Help: "The counter for inserted txs.",
@@ -68,6 +128,33 @@ func TimeSyncDeltaSet(value float64) {
 	timeSyncDelta.Set(value)
 }
 
+func TimeSyncRoundObserve(offset, dispersion, rtt float64, accepted, rejected int) {
+	timeSyncOffset.Set(offset)
+	timeSyncDispersion.Set(dispersion)
+	timeSyncRTT.Set(rtt)
+	timeSyncSamples.Set(float64(accepted))
+	timeSyncRejectedCounter.Add(float64(rejected))
+}
+
+func BlockSyncProgressSet(rate, eta, remaining float64) {
+	blockSyncRate.Set(rate)
+	blockSyncETA.Set(eta)
+	blockSyncRemaining.Set(remaining)
+}
+
+func BlockSyncTargetSet(height, peers float64) {
+	blockSyncTarget.Set(height)
+	blockSyncSelectedPeers.Set(peers)
+}
+
+func BlockSyncCatchUpInc() {
+	blockSyncCatchUpCounter.Inc()
+}
+
+func ParentFetchInc() {
+	parentFetchCounter.Inc()
+}
+
 func TransactionInsertAdd(value float64) {
 	transactionInsertCount.Add(value)
 }
@@ -33,13 +33,13 @@ var (
 		Name: "gossip_block_broadcast_counter",
 		Help: "P2P network broadcast topic send blocks counter.",
 	})
-	gossipUDPSendCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "gossip_upd_send_counter",
-		Help: "Gossip UDP send counter",
+	gossipTxSendCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gossip_tx_send_counter",
+		Help: "Gossip transaction send counter",
 	})
-	gossipUDPRecvCounter = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "gossip_upd_recv_counter",
-		Help: "Gossip UDP receive counter",
+	gossipTxRecvCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gossip_tx_recv_counter",
+		Help: "Gossip transaction receive counter",
 	})
 )
 
@@ -63,10 +63,10 @@ func GossipBroadcastBlocksCountInc() {
 	gossipBlockBroadcastCounter.Inc()
 }
 
-func GossipUDPSendCountInc() {
-	gossipUDPSendCounter.Inc()
+func GossipTxSendCountAdd(count int) {
+	gossipTxSendCounter.Add(float64(count))
 }
 
-func GossipUDPRecvCountInc() {
-	gossipUDPRecvCounter.Inc()
+func GossipTxRecvCountAdd(count int) {
+	gossipTxRecvCounter.Add(float64(count))
 }
@@ -7,6 +7,7 @@
 package metrics
 
 import (
+	"context"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/shirou/gopsutil/cpu"
@@ -25,10 +26,14 @@ func getCpuPercent() float64 {
 	return percent[0]
 }
 
-func RegularMetricsRoutine() {
+// RegularMetricsRoutine 定期记录系统信息，ctx 取消后退出
+func RegularMetricsRoutine(ctx context.Context) {
 	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		// 每 1s 通过 metrics 记录 cpu 占用信息
 		case <-ticker.C:
 			cpuPercentGauge.Set(getCpuPercent())
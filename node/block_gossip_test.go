@@ -0,0 +1,136 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"testing"
+	"time"
+)
+
+// testGossipBlock 构建高度为 1 的签名区块，区块中没有 VRF 参数
+func testGossipBlock(t *testing.T) *common.Block {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	block := &common.Block{
+		Header: common.BlockHeader{
+			Timestamp: time.Now().UnixMilli(),
+			Height:    1,
+			PublicKey: [33]byte(crypto.PublicKey2Bytes(&prv.PublicKey)),
+			Params:    []byte{},
+		},
+		Transactions: []common.Transaction{},
+	}
+
+	blockHash, err := core.BlockHeaderHash(&block.Header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Header.BlockHash = blockHash
+	block.Header.Signature, err = ecdsa.SignASN1(rand.Reader, prv, blockHash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+// testGossipMsg 将区块序列化为广播消息
+func testGossipMsg(t *testing.T, block *common.Block) *pubsub.Message {
+	data, err := utils.SerializeBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pubsub.Message{Message: &pb.Message{Data: data}}
+}
+
+func TestValidateGossipBlock(t *testing.T) {
+	pm := testManager(t)
+	pm.blockSyncer.setSynced()
+	ctx := context.Background()
+	from := peer.ID("relay")
+
+	genesis, err := pm.chain.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := testGossipMsg(t, genesis)
+	if result := pm.validateGossipBlock(ctx, from, msg); result != pubsub.ValidationAccept {
+		t.Fatalf("valid block not accepted, result %d", result)
+	}
+	if block, ok := msg.ValidatorData.(*common.Block); !ok || block.Header.BlockHash != genesis.Header.BlockHash {
+		t.Fatal("validated block not attached to message")
+	}
+
+	// 未同步完成时不处理也不转发，不扣分
+	pm.blockSyncer.status = blockSyncing
+	if result := pm.validateGossipBlock(ctx, from, testGossipMsg(t, testGossipBlock(t))); result != pubsub.ValidationIgnore {
+		t.Fatalf("block validated while syncing, result %d", result)
+	}
+	if score := pm.reputation.Score(from); score != 0 {
+		t.Fatalf("peer penalized while syncing, score %f", score)
+	}
+}
+
+func TestValidateGossipBlockReject(t *testing.T) {
+	pm := testManager(t)
+	pm.blockSyncer.setSynced()
+	ctx := context.Background()
+
+	malformed := &pubsub.Message{Message: &pb.Message{Data: []byte("block")}}
+	signature := testGossipBlock(t)
+	signature.Header.Signature[len(signature.Header.Signature)-1] ^= 0xff
+	cases := map[string]*pubsub.Message{
+		"malformed": malformed,
+		"signature": testGossipMsg(t, signature),
+		"vrf":       testGossipMsg(t, testGossipBlock(t)),
+	}
+
+	for name, msg := range cases {
+		from := peer.ID(name)
+		if result := pm.validateGossipBlock(ctx, from, msg); result != pubsub.ValidationReject {
+			t.Fatalf("%s block not rejected, result %d", name, result)
+		}
+		if score := pm.reputation.Score(from); score >= 0 {
+			t.Fatalf("%s block relay not penalized", name)
+		}
+	}
+
+	// 本地发布的区块不再验证
+	if result := pm.validateGossipBlock(ctx, pm.id, testGossipMsg(t, testGossipBlock(t))); result != pubsub.ValidationAccept {
+		t.Fatalf("local block not accepted, result %d", result)
+	}
+}
+
+func TestFetchParentsRelease(t *testing.T) {
+	pm := testManager(t)
+	pm.ctx = context.Background()
+	p, rp, received := testRequestPeer(t, pm)
+	p.status.Capabilities = CapabilityBlockByHash
+
+	block := &common.Block{Header: common.BlockHeader{Height: pm.chain.Height() + 3, PrevBlockHash: [32]byte{1}}}
+	prevHash := block.PrevBlockHash()
+
+	// 对端没有父区块时移除请求记录，之后收到的区块可以再次触发补齐
+	for round := 0; round < 2; round++ {
+		pm.fetchMissingParents(block, p)
+		req := testReceiveRequest(t, received, p2p.StatusCodeGetBlockBodiesMsg)
+		rp.SendMessage(&p2p.Message{Code: p2p.StatusCodeBlockBodiesMsg, ReplyTo: req.RequestID})
+
+		deadline := time.Now().Add(5 * time.Second)
+		for pm.parentRequests.Contains(prevHash) {
+			if time.Now().After(deadline) {
+				t.Fatal("failed parent request not released")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
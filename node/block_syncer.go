@@ -3,12 +3,16 @@
 package node
 
 import (
+	"context"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/core"
 	"github.com/chain-lab/go-norn/metrics"
 	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
 	"github.com/libp2p/go-libp2p/core/peer"
 	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"sort"
 	"sync"
 	"time"
 )
@@ -23,28 +27,72 @@ const (
 const (
 	checkInterval        = 100 * time.Millisecond
 	requestBlockInterval = 3 * time.Second
+	syncResponseTimeout  = 15 * time.Second // 区块同步请求的响应超时时间
+
+	maxBlockRange      = 64                   // 单次区间请求的最大区块数量
+	maxBlockRangeBytes = p2p.MaxFrameSize / 2 // 区间响应数据包的最大长度
+	minSyncBatch       = 4                    // 区间请求的最小区块数量
+	syncWindowSize     = 1024                 // 下载窗口，只请求已插入高度之上窗口内的区块
+	syncBatchLatency   = 2 * time.Second      // 根据对端速率计算批量大小时，期望单次请求的耗时
+	defaultSyncRate    = 8.0                  // 新节点的初始速率估计，单位 区块/s
+	syncRateWeight     = 0.3                  // 速率指数平均中新样本的权重
+	progressInterval   = time.Second          // 更新同步进度的间隔
 )
 
+var errBlockNotMatchHeader = errors.New("block not match verified header")
+
+// syncRequest 发送给对端的区块请求
+type syncRequest struct {
+	height int64     // 请求的区块高度
+	sent   time.Time // 请求发送的时间
+}
+
+// syncProgress 区块同步的进度统计
+type syncProgress struct {
+	lastTime   time.Time // 上一次统计的时间
+	lastHeight int64     // 上一次统计时已插入的高度
+	rate       float64   // 插入速率的指数平均，单位 区块/s
+}
+
 type BlockSyncerConfig struct {
-	Chain *core.BlockChain
+	Chain       *core.BlockChain
+	Clock       utils.Clock       // 请求超时和重试使用的时钟，为 nil 时使用系统时钟
+	HeaderStore *core.HeaderStore // 轻节点的区块头存储，不为 nil 时只同步区块头
 }
 
 type BlockSyncer struct {
 	peerSet []*Peer
 
-	remoteHeight int64 // 目前其他节点的最高高度
-	targetHeight int64 // 到达 buffer 同步状态后的目标高度
+	remoteHeight int64 // 加权的多数节点已经达到的数据库高度
+	targetHeight int64 // 加权的多数节点已经达到的缓冲区高度，本地数据库到达后同步完成
 	knownHeight  int64 // 目前已知节点中的最新高度
 
-	requestTimestamp map[int64]time.Time     // 区块请求的时间戳, height -> time
-	blockMap         map[int64]*common.Block // height -> block 对应高度的区块
-	peerReqTime      map[peer.ID]time.Time
-
-	chain          *core.BlockChain        // 区块链实例
-	status         uint8                   // 当前同步状态
-	statusMsg      chan *p2p.SyncStatusMsg // 同步器接收到的同步消息
-	lock           sync.RWMutex            // 状态锁
-	peerStatusLock sync.RWMutex            // peerSet 管理锁
+	requestDeadline map[int64]time.Time     // 区块请求的截止时间，超过后允许重新请求, height -> time
+	blockMap        map[int64]*common.Block // height -> block 对应高度的区块
+	peerReqTime     map[peer.ID]time.Time
+	pendingRequests map[peer.ID]*syncRequest // 等待对端响应的区块请求
+	peerRate        map[peer.ID]float64      // 对端响应速率的指数平均，单位 区块/s
+	progress        syncProgress             // 同步进度统计
+	headerFirst     bool                     // 是否先同步区块头，由配置 sync.header_first 决定
+	headers         *core.HeaderChain        // 已经验证的区块头链，为 nil 时直接下载区块
+	store           *core.HeaderStore        // 轻节点的区块头存储
+	light           bool                     // 是否为轻节点，轻节点只同步区块头，不下载区块
+	heads           map[peer.ID]*peerHead    // 节点在状态消息中声明的链头
+	selected        []peer.ID                // 支持当前同步目标的节点，按 ID 排序
+	excluded        map[peer.ID]time.Time    // 同步停滞时被排除的节点，节点 ID -> 排除的截止时间
+	delivered       map[peer.ID]time.Time    // 节点最近一次提供区块或区块头的时间
+	lastProgress    time.Time                // 最近一次插入区块或者重新选择同步目标的时间
+
+	chain          *core.BlockChain // 区块链实例
+	clock          utils.Clock      // 请求超时和重试使用的时钟
+	status         uint8            // 当前同步状态
+	statusMsg      chan *peerStatus // 同步器接收到的同步消息
+	lock           sync.RWMutex     // 状态锁
+	peerStatusLock sync.RWMutex     // peerSet 管理锁
+
+	ctx    context.Context    // 同步协程的 context，停止时取消
+	cancel context.CancelFunc // 取消同步协程
+	wg     sync.WaitGroup     // 等待同步协程退出
 
 	//cond *sync.Cond
 }
@@ -55,19 +103,33 @@ type BlockSyncer struct {
 //	@param config - 同步器配置，包含了区块链实例
 //	@return *BlockSyncer
 func NewBlockSyncer(config *BlockSyncerConfig) *BlockSyncer {
+	clock := config.Clock
+	if clock == nil {
+		clock = utils.NewRealClock()
+	}
+
 	syncer := BlockSyncer{
 		peerSet: make([]*Peer, 0, 40),
 
-		remoteHeight:     -3,
-		targetHeight:     -2,
-		knownHeight:      -1,
-		requestTimestamp: make(map[int64]time.Time),
-		blockMap:         make(map[int64]*common.Block),
-		peerReqTime:      make(map[peer.ID]time.Time),
+		remoteHeight:    -3,
+		targetHeight:    -2,
+		knownHeight:     -1,
+		requestDeadline: make(map[int64]time.Time),
+		blockMap:        make(map[int64]*common.Block),
+		peerReqTime:     make(map[peer.ID]time.Time),
+		pendingRequests: make(map[peer.ID]*syncRequest),
+		peerRate:        make(map[peer.ID]float64),
+		headerFirst:     headerFirstEnabled() || config.HeaderStore != nil,
+		store:           config.HeaderStore,
+		light:           config.HeaderStore != nil,
+		heads:           make(map[peer.ID]*peerHead),
+		excluded:        make(map[peer.ID]time.Time),
+		delivered:       make(map[peer.ID]time.Time),
 
 		chain:     config.Chain,
+		clock:     clock,
 		status:    syncPaused,
-		statusMsg: make(chan *p2p.SyncStatusMsg, maxSyncerStatusChannel),
+		statusMsg: make(chan *peerStatus, maxSyncerStatusChannel),
 	}
 	metrics.BlockSyncerStatusSet(int8(syncPaused))
 
@@ -78,158 +140,232 @@ func NewBlockSyncer(config *BlockSyncerConfig) *BlockSyncer {
 //
 //	@Description: 启动同步器实例
 //	@receiver bs
-func (bs *BlockSyncer) Start() {
+//	@param ctx - 父 context，取消后同步协程退出
+//	@return error
+func (bs *BlockSyncer) Start(ctx context.Context) error {
+	bs.ctx, bs.cancel = context.WithCancel(ctx)
+
 	metrics.RoutineCreateCounterObserve(11)
-	go bs.run()                 // 启动同步协程
-	go bs.statusMsgRoutine()    // 启动同步消息管理协程
-	go bs.blockProcessRoutine() // 启动区块处理协程
+	bs.wg.Add(4)
+	go bs.run(bs.ctx)                 // 启动同步协程
+	go bs.statusMsgRoutine(bs.ctx)    // 启动同步消息管理协程
+	go bs.blockProcessRoutine(bs.ctx) // 启动区块处理协程
+	go bs.headerRoutine(bs.ctx)       // 启动区块头同步协程
 
+	// 启动前已经被设置为同步完成时（如创世节点），不再修改同步状态
 	bs.lock.Lock()
-	metrics.BlockSyncerStatusSet(int8(blockSyncing))
-	bs.status = blockSyncing // 设置同步状态为 syncing
+	bs.lastProgress = bs.clock.Now()
+	if bs.status == syncPaused {
+		metrics.BlockSyncerStatusSet(int8(blockSyncing))
+		bs.status = blockSyncing // 设置同步状态为 syncing
+	}
 	bs.lock.Unlock()
 	log.Infoln("Start process block syncer.")
+	return nil
+}
+
+// Stop
+//
+//	@Description: 停止同步器，等待同步协程退出
+//	@receiver bs
+//	@param ctx - 停止的超时 context
+//	@return error - 超时时返回错误
+func (bs *BlockSyncer) Stop(ctx context.Context) error {
+	if bs.cancel == nil {
+		return nil
+	}
+	bs.cancel()
+
+	return utils.WaitGroupContext(ctx, &bs.wg)
 }
 
 // run
 //
-//	@Description: 同步协程，每秒触发检查是否有空闲的 peer，如果有，就由该 peer 去拉取区块
+//	@Description: 同步协程，定时检查空闲的 peer，按照速率从高到低为空闲的 peer 分配下载窗口内最低的未请求区间，
+//	支持区间请求的节点批量拉取，其余节点每次拉取一个区块，同步完成后只清理已经断开的节点
 //	@receiver bs
-func (bs *BlockSyncer) run() {
-	ticker := time.NewTicker(500 * time.Millisecond)
+//	@param ctx - 同步协程的 context
+func (bs *BlockSyncer) run(ctx context.Context) {
+	defer bs.wg.Done()
+
+	ticker := bs.clock.NewTicker(checkInterval)
+	defer ticker.Stop()
 	log.Traceln("Start block syncer routine.")
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		// 每隔 100 ms 检查一次是否存在空闲的 peer，如果有则进行区块的拉取
-		case <-ticker.C:
+		case <-ticker.C():
 			available := make([]*Peer, 0, len(bs.peerSet))
+			idle := make([]*Peer, 0, len(bs.peerSet))
 			bs.peerStatusLock.Lock()
 			for idx := range bs.peerSet {
 				p := bs.peerSet[idx]
 
 				// todo: 这里目前不考虑大量节点的情况， 后续需要对这部分内存进行回收
 				if p.Stopped() {
+					delete(bs.peerRate, p.peerID)
 					continue
 				}
 				available = append(available, p)
 
-				id := p.peerID
-				if p.MarkSynced() || time.Since(bs.
-					peerReqTime[id]) < requestBlockInterval {
-					log.Traceln("Peer just send msg, loop continue.")
+				// 只向保存完整区块的节点请求区块
+				if !p.FullNode() {
 					continue
 				}
 
-				height := bs.selectBlockHeight()
-				if height < 0 {
+				id := p.peerID
+				if p.MarkSynced() && bs.checkRequestTimeout(p) {
 					continue
 				}
-				log.Infof("Select to get block #%d", height)
 
-				metrics.RoutineCreateCounterObserve(14)
-				go requestSyncGetBlock(height, p)
-				p.SetMarkSynced(true)
+				if p.MarkSynced() || bs.clock.Since(bs.
+					peerReqTime[id]) < requestBlockInterval {
+					log.Traceln("Peer just send msg, loop continue.")
+					continue
+				}
+				idle = append(idle, p)
 			}
 			bs.peerSet = available
+
+			// 同步完成后不再主动拉取，落后过多时由状态协程切换回同步区块；轻节点不下载区块
+			if bs.light || bs.getStatus() == synced {
+				bs.peerStatusLock.Unlock()
+				continue
+			}
+
+			// 速率高的节点优先获取高度更低的区间，尽快填补插入位置之后的空缺
+			sort.SliceStable(idle, func(i, j int) bool {
+				return bs.rateOf(idle[i].peerID) > bs.rateOf(idle[j].peerID)
+			})
+
+			for _, p := range idle {
+				if !bs.dispatch(p) {
+					break
+				}
+			}
 			bs.peerStatusLock.Unlock()
 		}
+	}
+}
 
-		// 关闭，不再主动拉取
-		bs.lock.RLock()
-		if bs.status == synced {
-			bs.lock.RUnlock()
-			log.Infoln("Block syncer exit.")
-			break
+// dispatch
+//
+//	@Description: 为空闲的节点分配需要拉取的区块，调用时需要持有 peerStatusLock
+//	@receiver bs
+//	@param p - 节点实例
+//	@return bool - 是否分配了区块，窗口内没有可以请求的区块时返回 false
+func (bs *BlockSyncer) dispatch(p *Peer) bool {
+	id := p.peerID
+
+	switch {
+	case p.SupportBlockRange():
+		start, count := bs.selectBlockRange(bs.batchSize(id), syncResponseTimeout)
+		if count == 0 {
+			return false
+		}
+		log.Debugf("Select to get block #%d-#%d", start, start+count-1)
+
+		metrics.RoutineCreateCounterObserve(14)
+		p.SetMarkSynced(true)
+		go bs.fetchBlockRange(start, count, p)
+	case p.SupportRequest():
+		height, count := bs.selectBlockRange(1, syncResponseTimeout)
+		if count == 0 {
+			return false
+		}
+		log.Infof("Select to get block #%d", height)
+
+		metrics.RoutineCreateCounterObserve(14)
+		p.SetMarkSynced(true)
+		go bs.fetchBlock(height, p)
+	default:
+		// 旧版本的节点不支持请求 ID，由 handler 接收响应，在这里记录请求用于检查超时
+		height, count := bs.selectBlockRange(1, requestBlockInterval)
+		if count == 0 {
+			return false
+		}
+		log.Infof("Select to get block #%d", height)
+
+		p.SetMarkSynced(true)
+		go requestSyncGetBlock(height, p)
+		bs.pendingRequests[id] = &syncRequest{
+			height: height,
+			sent:   bs.clock.Now(),
 		}
-		bs.lock.RUnlock()
 	}
+
+	return true
 }
 
 // statusMsgRoutine
 //
-//	@Description: 状态信息处理协程，获取状态信息队列中的信息， 然后计算对端最高高度
+//	@Description: 状态信息处理协程，记录各个节点声明的链头并重新计算同步目标，没有收到状态信息时定时重新计算，
+//	使过期的链头不再影响同步目标。同步完成后继续跟踪各个节点的链头，落后过多时重新进入同步
 //	@receiver bs
-func (bs *BlockSyncer) statusMsgRoutine() {
+//	@param ctx - 同步协程的 context
+func (bs *BlockSyncer) statusMsgRoutine(ctx context.Context) {
+	defer bs.wg.Done()
+
+	ticker := bs.clock.NewTicker(syncTargetInterval)
+	defer ticker.Stop()
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		// 从 channel 中得到一个状态消息
-		case msg := <-bs.statusMsg:
-			height := msg.LatestHeight
-			bufferHeight := msg.BufferedEndHeight
-			bs.lock.Lock()
-
-			if height == -1 {
-				bs.lock.Unlock()
-				continue
-			}
-
-			// 计算当前所见到的其它节点的最高高度
-			bs.remoteHeight = max(height, bs.remoteHeight)
-			log.WithField("Remote height", height).Traceln("Sync remote height.")
-
-			// 如果缓冲高度为 -1 且正在同步状态，设定 target 高度
-			// 当前还未同步到其它节点的缓冲区高度
-			if bufferHeight != -1 && bs.status == blockSyncing {
-				bs.targetHeight = max(bufferHeight, bs.targetHeight)
-				log.WithField("Buffer height", bufferHeight).Traceln("Sync buffer height.")
-			}
-			dbLatestHeight := bs.chain.Height()
-
-			if bs.remoteHeight == bs.knownHeight {
-				metrics.BlockSyncerStatusSet(int8(bufferSyncing))
-				bs.status = bufferSyncing
-			}
-
-			if dbLatestHeight == bs.targetHeight {
-				log.Infoln("Reach target block height.")
-
-				metrics.BlockSyncerStatusSet(int8(synced))
-				bs.status = synced
-			}
+		case status := <-bs.statusMsg:
+			log.WithFields(log.Fields{
+				"peer":   status.peer.peerID,
+				"height": status.msg.LatestHeight,
+				"buffer": status.msg.BufferedEndHeight,
+			}).Traceln("Sync remote height.")
 
+			bs.lock.Lock()
+			bs.updatePeerHead(status)
 			bs.lock.Unlock()
+		case <-ticker.C():
 		}
 
-		bs.lock.RLock()
-		if bs.status == synced {
-			bs.lock.RUnlock()
-			break
-		}
-		bs.lock.RUnlock()
+		bs.updateSyncStatus()
 	}
 }
 
 // blockProcessRoutine
 //
-//	@Description: 区块处理协程， 每 1s 取出map中的区块加入到 chain 中
+//	@Description: 区块处理协程，定时按高度顺序取出已经收到的连续区块加入到 chain 中，乱序到达的区块等待前面的区块，
+//	同时统计同步的速率和剩余时间，同步完成后等待重新进入同步
 //	@receiver bs
-func (bs *BlockSyncer) blockProcessRoutine() {
-	ticker := time.NewTicker(checkInterval)
+//	@param ctx - 同步协程的 context
+func (bs *BlockSyncer) blockProcessRoutine(ctx context.Context) {
+	defer bs.wg.Done()
+
+	ticker := bs.clock.NewTicker(checkInterval)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
 			bs.lock.RLock()
 			knownHeight := bs.knownHeight + 1
 			remoteHeight := bs.remoteHeight
+			status := bs.status
 			bs.lock.RUnlock()
 
+			if status == synced || bs.light {
+				continue
+			}
+
 			//  遍历当前高度到对端最高高度，依次取出区块并插入数据库
 			for height := knownHeight; height <= remoteHeight; height++ {
-				if bs.blockMap[height] != nil {
-					bs.insertBlock(height)
-				} else {
+				if !bs.insertBlock(height) {
 					break
 				}
 			}
+			bs.updateProgress()
 		}
-
-		bs.lock.RLock()
-		if bs.status == synced {
-			log.Infoln("Block sync finished, exit block process routine.")
-			bs.lock.RUnlock()
-			break
-		}
-		bs.lock.RUnlock()
 	}
 }
 
@@ -246,54 +382,295 @@ func (bs *BlockSyncer) AddPeer(p *Peer) {
 	bs.peerReqTime[p.peerID] = time.UnixMilli(0)
 }
 
+// checkRequestTimeout
+//
+//	@Description: 检查对端是否在超时时间内响应了区块请求，超时后允许重新向该节点请求；
+//	对端在握手时声明的高度不低于请求高度却没有响应时扣除对端的分数，调用时需要持有 peerStatusLock
+//	@receiver bs
+//	@param p - 节点实例
+//	@return bool - 请求是否仍在等待响应
+func (bs *BlockSyncer) checkRequestTimeout(p *Peer) bool {
+	req, ok := bs.pendingRequests[p.peerID]
+	if !ok || bs.clock.Since(req.sent) < syncResponseTimeout {
+		return true
+	}
+
+	delete(bs.pendingRequests, p.peerID)
+	if status := p.Status(); status != nil && status.Height >= req.height {
+		log.WithFields(log.Fields{
+			"peer":   p.peerID,
+			"height": req.height,
+		}).Debugln("Sync block request timeout.")
+		p.handler.penalizePeer(p.peerID, offenseSyncTimeout)
+	}
+
+	p.SetMarkSynced(false)
+	bs.peerReqTime[p.peerID] = bs.clock.Now()
+	return false
+}
+
+// fetchBlock
+//
+//	@Description: 通过请求/响应向对端获取区块，超时或者响应错误时扣除对端的分数，并允许其他节点立即重新拉取该高度
+//	@receiver bs
+//	@param height - 区块高度
+//	@param p - 节点实例
+func (bs *BlockSyncer) fetchBlock(height int64, p *Peer) {
+	defer p.SetMarkSynced(false)
+
+	ctx, cancel := context.WithTimeout(bs.ctx, syncResponseTimeout)
+	defer cancel()
+
+	block, err := requestSyncBlock(ctx, height, p)
+	if err == nil {
+		if err = bs.appendBlock(block); err == nil {
+			bs.markDelivered(p.peerID)
+			return
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"peer":   p.peerID,
+		"height": height,
+		"error":  err,
+	}).Debugln("Sync block request failed.")
+
+	switch {
+	case err == context.Canceled:
+		// 同步器停止，不扣除对端的分数
+		return
+	case err == context.DeadlineExceeded:
+		if status := p.Status(); status != nil && status.Height >= height {
+			p.handler.penalizePeer(p.peerID, offenseSyncTimeout)
+		}
+	case err == errBlockNotMatchHeader:
+		p.handler.penalizePeer(p.peerID, offenseInvalidHeader)
+	case err != errPeerStopped:
+		p.handler.penalizePeer(p.peerID, offenseMalformedMessage)
+	}
+
+	bs.releaseHeights(height, height+1)
+
+	bs.peerStatusLock.Lock()
+	bs.peerReqTime[p.peerID] = bs.clock.Now()
+	bs.peerStatusLock.Unlock()
+}
+
+// fetchBlockRange
+//
+//	@Description: 通过区间请求向对端批量获取区块，根据响应的耗时更新对端的速率；对端没有返回的高度允许其他节点立即重新拉取，
+//	超时或者响应错误时与 fetchBlock 一样扣除对端的分数
+//	@receiver bs
+//	@param start - 起始高度
+//	@param count - 区块数量
+//	@param p - 节点实例
+func (bs *BlockSyncer) fetchBlockRange(start, count int64, p *Peer) {
+	defer p.SetMarkSynced(false)
+
+	ctx, cancel := context.WithTimeout(bs.ctx, syncResponseTimeout)
+	defer cancel()
+
+	sent := bs.clock.Now()
+	blocks, err := requestSyncBlockRange(ctx, start, count, p)
+	for idx := 0; err == nil && idx < len(blocks); idx++ {
+		err = bs.appendBlock(blocks[idx])
+	}
+	if err == nil {
+		bs.releaseHeights(start+int64(len(blocks)), start+count)
+		bs.observeRate(p.peerID, len(blocks), bs.clock.Since(sent))
+
+		if len(blocks) > 0 {
+			bs.markDelivered(p.peerID)
+		}
+		// 对端没有可以返回的区块，等待一段时间后再向它请求
+		if len(blocks) == 0 {
+			bs.peerStatusLock.Lock()
+			bs.peerReqTime[p.peerID] = bs.clock.Now()
+			bs.peerStatusLock.Unlock()
+		}
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"peer":  p.peerID,
+		"start": start,
+		"count": count,
+		"error": err,
+	}).Debugln("Sync block range request failed.")
+
+	switch {
+	case err == context.Canceled:
+		// 同步器停止，不扣除对端的分数
+		return
+	case err == context.DeadlineExceeded:
+		if status := p.Status(); status != nil && status.Height >= start {
+			p.handler.penalizePeer(p.peerID, offenseSyncTimeout)
+		}
+	case err == errBlockNotMatchHeader:
+		p.handler.penalizePeer(p.peerID, offenseInvalidHeader)
+	case err != errPeerStopped:
+		p.handler.penalizePeer(p.peerID, offenseMalformedMessage)
+	}
+
+	bs.releaseHeights(start, start+count)
+	bs.observeRate(p.peerID, 0, syncResponseTimeout)
+
+	bs.peerStatusLock.Lock()
+	bs.peerReqTime[p.peerID] = bs.clock.Now()
+	bs.peerStatusLock.Unlock()
+}
+
+// releaseHeights
+//
+//	@Description: 清除区间 [start, end) 内还没有收到的区块的请求记录，允许其他节点立即重新拉取
+//	@receiver bs
+//	@param start - 起始高度
+//	@param end - 结束高度，不包含
+func (bs *BlockSyncer) releaseHeights(start, end int64) {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+
+	for height := start; height < end; height++ {
+		if bs.blockMap[height] == nil {
+			delete(bs.requestDeadline, height)
+		}
+	}
+}
+
+// observeRate
+//
+//	@Description: 根据一次请求返回的区块数量和耗时更新对端速率的指数平均
+//	@receiver bs
+//	@param id - 节点 ID
+//	@param blocks - 返回的区块数量
+//	@param elapsed - 请求耗时
+func (bs *BlockSyncer) observeRate(id peer.ID, blocks int, elapsed time.Duration) {
+	if elapsed < time.Millisecond {
+		elapsed = time.Millisecond
+	}
+	sample := float64(blocks) / elapsed.Seconds()
+
+	bs.peerStatusLock.Lock()
+	defer bs.peerStatusLock.Unlock()
+
+	bs.peerRate[id] = syncRateWeight*sample + (1-syncRateWeight)*bs.rateOf(id)
+}
+
+// rateOf 对端的速率估计，没有记录时使用初始速率，调用时需要持有 peerStatusLock
+func (bs *BlockSyncer) rateOf(id peer.ID) float64 {
+	rate, ok := bs.peerRate[id]
+	if !ok {
+		return defaultSyncRate
+	}
+	return rate
+}
+
+// batchSize
+//
+//	@Description: 根据对端的速率计算单次区间请求的区块数量，使请求耗时接近 syncBatchLatency，调用时需要持有 peerStatusLock
+//	@receiver bs
+//	@param id - 节点 ID
+//	@return int64 - 区块数量
+func (bs *BlockSyncer) batchSize(id peer.ID) int64 {
+	size := int64(bs.rateOf(id) * syncBatchLatency.Seconds())
+
+	if size < minSyncBatch {
+		return minSyncBatch
+	}
+	if size > maxBlockRange {
+		return maxBlockRange
+	}
+	return size
+}
+
 // appendStatusMsg
 //
 //	@Description: 添加同步状态消息到 channel
 //	@receiver bs
+//	@param p - 发送消息的节点
 //	@param msg - 其它节点发来的同步状态消息
-func (bs *BlockSyncer) appendStatusMsg(msg *p2p.SyncStatusMsg) {
-	bs.statusMsg <- msg
+func (bs *BlockSyncer) appendStatusMsg(p *Peer, msg *p2p.SyncStatusMsg) {
+	bs.statusMsg <- &peerStatus{
+		peer: p,
+		msg:  msg,
+	}
 }
 
-// selectBlockHeight
+// selectBlockRange
 //
-//	@Description: 选取一个最近的未同步的区块进行拉取，即最近没有拉取过，且高度最低
+//	@Description: 在下载窗口内选取高度最低的一段连续的、没有收到且没有在等待响应的区块进行拉取
 //	@receiver bs
-//	@return int64 - 所需要拉取的区块高度
-func (bs *BlockSyncer) selectBlockHeight() int64 {
-	bs.lock.RLock()
-	defer bs.lock.RUnlock()
+//	@param maxCount - 最多选取的区块数量
+//	@param timeout - 请求的截止时间，超过后允许其他节点重新拉取这些区块
+//	@return int64 - 起始高度
+//	@return int64 - 选取的区块数量，没有可以拉取的区块时为 0
+func (bs *BlockSyncer) selectBlockRange(maxCount int64, timeout time.Duration) (int64, int64) {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
 
-	for height := bs.knownHeight + 1; height <= bs.remoteHeight; height++ {
-		if bs.requestTimestamp[height].IsZero() {
-			log.Traceln("Set map time to zero")
-			bs.requestTimestamp[height] = time.UnixMilli(0)
+	now := bs.clock.Now()
+	selectable := func(height int64) bool {
+		if bs.blockMap[height] != nil {
+			return false
 		}
+		deadline, ok := bs.requestDeadline[height]
+		return !ok || now.After(deadline)
+	}
 
-		if bs.blockMap[height] == nil && time.Since(bs.
-			requestTimestamp[height]) > requestBlockInterval {
-			bs.requestTimestamp[height] = time.Now()
-			log.WithField("height", height).Traceln("Select height to sync.")
-			return height
-		} else {
-			log.WithField("interval", time.Since(bs.requestTimestamp[height])).Traceln("Trace interval value.")
-		}
+	// 区块头优先同步时只下载已经验证的区块头对应的区块
+	end := min(bs.remoteHeight, bs.knownHeight+syncWindowSize)
+	if bs.headers != nil {
+		end = min(end, bs.headers.Height())
+	}
+	start := bs.knownHeight + 1
+	for start <= end && !selectable(start) {
+		start++
+	}
+
+	count := int64(0)
+	for height := start; height <= end && count < maxCount && selectable(height); height++ {
+		bs.requestDeadline[height] = now.Add(timeout)
+		count++
 	}
 
-	return -1
+	if count > 0 {
+		log.WithFields(log.Fields{
+			"start": start,
+			"count": count,
+		}).Traceln("Select block range to sync.")
+	}
+	return start, count
 }
 
 // appendBlock
 //
-//	@Description: 添加区块到同步器
+//	@Description: 添加区块到同步器，区块头优先同步时区块需要与已经验证的区块头一致
 //	@receiver bs
 //	@param block - 区块实例
-func (bs *BlockSyncer) appendBlock(block *common.Block) {
+//	@return error - 区块与已经验证的区块头不一致时返回 errBlockNotMatchHeader
+func (bs *BlockSyncer) appendBlock(block *common.Block) error {
+	blockHeight := block.Header.Height
+
+	// 区块头还没有验证的区块直接丢弃，验证在锁外进行
+	if headers := bs.headerChain(); headers != nil {
+		if headers.Header(blockHeight) == nil {
+			return nil
+		}
+		if err := headers.VerifyBody(block); err != nil {
+			log.WithError(err).WithField("height", blockHeight).Debugln("Block not match verified header.")
+			return errBlockNotMatchHeader
+		}
+	}
+
 	bs.lock.Lock()
 	defer bs.lock.Unlock()
 
-	blockHeight := block.Header.Height
+	// 只接收下载窗口内的区块，避免对端发送的区块无限占用内存；同步完成后到达的响应直接丢弃
+	if bs.status == synced || blockHeight <= bs.knownHeight || blockHeight > bs.knownHeight+syncWindowSize {
+		return nil
+	}
 	bs.blockMap[blockHeight] = block
+	return nil
 }
 
 // insertBlock
@@ -301,23 +678,68 @@ func (bs *BlockSyncer) appendBlock(block *common.Block) {
 //	@Description: 向区块链上插入高度为 height 的区块
 //	@receiver bs
 //	@param height - 所需要插入的区块的高度
-func (bs *BlockSyncer) insertBlock(height int64) {
+//	@return bool - 该高度的区块还没有收到时返回 false
+func (bs *BlockSyncer) insertBlock(height int64) bool {
 	bs.lock.Lock()
 	defer bs.lock.Unlock()
 
 	block, _ := bs.blockMap[height]
 
 	if block == nil {
-		log.Warningln("Block in map is nil.")
-		return
+		return false
 	}
 
 	//bs.chain.InsertBlock(block)
 	bs.chain.AppendBlockTask(block)
 	bs.knownHeight = height
+	bs.lastProgress = bs.clock.Now()
 
-	delete(bs.requestTimestamp, height)
+	delete(bs.requestDeadline, height)
 	delete(bs.blockMap, height)
+	if bs.headers != nil {
+		bs.headers.Prune(height)
+	}
+	return true
+}
+
+// updateProgress
+//
+//	@Description: 每隔 progressInterval 统计一次区块插入的速率，并根据剩余的区块数量估计同步完成的时间
+//	@receiver bs
+func (bs *BlockSyncer) updateProgress() {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+
+	now := bs.clock.Now()
+	if bs.progress.lastTime.IsZero() {
+		bs.progress.lastTime = now
+		bs.progress.lastHeight = bs.knownHeight
+		return
+	}
+
+	elapsed := now.Sub(bs.progress.lastTime)
+	if elapsed < progressInterval {
+		return
+	}
+
+	sample := float64(bs.knownHeight-bs.progress.lastHeight) / elapsed.Seconds()
+	bs.progress.rate = syncRateWeight*sample + (1-syncRateWeight)*bs.progress.rate
+	bs.progress.lastTime = now
+	bs.progress.lastHeight = bs.knownHeight
+
+	remaining := max(bs.remoteHeight-bs.knownHeight, 0)
+	eta := -1.0
+	if bs.progress.rate > 0 {
+		eta = float64(remaining) / bs.progress.rate
+	}
+	metrics.BlockSyncProgressSet(bs.progress.rate, eta, float64(remaining))
+
+	log.WithFields(log.Fields{
+		"height":    bs.knownHeight,
+		"remaining": remaining,
+		"rate":      bs.progress.rate,
+		"eta":       eta,
+	}).Debugln("Block sync progress.")
 }
 
 // getStatus
@@ -349,3 +771,10 @@ func max(h1 int64, h2 int64) int64 {
 		return h2
 	}
 }
+
+func min(h1 int64, h2 int64) int64 {
+	if h1 < h2 {
+		return h1
+	}
+	return h2
+}
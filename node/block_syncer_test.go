@@ -0,0 +1,369 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"testing"
+	"time"
+)
+
+func testBlockSyncer(known, remote int64) (*BlockSyncer, *utils.FakeClock) {
+	clock := utils.NewFakeClock(time.UnixMilli(1700000000000))
+	bs := NewBlockSyncer(&BlockSyncerConfig{Clock: clock})
+	bs.knownHeight = known
+	bs.remoteHeight = remote
+	return bs, clock
+}
+
+func testSyncBlock(height int64) *common.Block {
+	return &common.Block{Header: common.BlockHeader{Height: height}}
+}
+
+func TestSyncBlockRangeSelect(t *testing.T) {
+	bs, _ := testBlockSyncer(9, 100)
+
+	// 相邻的请求分配连续且不重叠的区间
+	if start, count := bs.selectBlockRange(16, syncResponseTimeout); start != 10 || count != 16 {
+		t.Fatalf("unexpected range #%d+%d", start, count)
+	}
+	if start, count := bs.selectBlockRange(16, syncResponseTimeout); start != 26 || count != 16 {
+		t.Fatalf("unexpected range #%d+%d", start, count)
+	}
+
+	// 部分区块乱序到达，未返回的高度释放后优先重新分配
+	bs.appendBlock(testSyncBlock(10))
+	bs.appendBlock(testSyncBlock(11))
+	bs.releaseHeights(10, 26)
+	if start, count := bs.selectBlockRange(64, syncResponseTimeout); start != 12 || count != 14 {
+		t.Fatalf("unexpected range #%d+%d", start, count)
+	}
+
+	// 区间在已经收到的区块处截断
+	bs.appendBlock(testSyncBlock(50))
+	if start, count := bs.selectBlockRange(64, syncResponseTimeout); start != 42 || count != 8 {
+		t.Fatalf("unexpected range #%d+%d", start, count)
+	}
+}
+
+func TestSyncBlockRangeWindow(t *testing.T) {
+	bs, _ := testBlockSyncer(-1, 10*syncWindowSize)
+
+	total := int64(0)
+	for {
+		_, count := bs.selectBlockRange(maxBlockRange, syncResponseTimeout)
+		if count == 0 {
+			break
+		}
+		total += count
+	}
+	if total != syncWindowSize {
+		t.Fatalf("selected %d blocks beyond window %d", total, syncWindowSize)
+	}
+
+	// 窗口外的区块不会被缓存
+	bs.appendBlock(testSyncBlock(syncWindowSize + 1))
+	if len(bs.blockMap) != 0 {
+		t.Fatal("block outside window accepted")
+	}
+}
+
+func TestSyncBlockInsertOrder(t *testing.T) {
+	bs, _ := testBlockSyncer(9, 20)
+
+	bs.appendBlock(testSyncBlock(12))
+	bs.appendBlock(testSyncBlock(11))
+
+	// 区块 #10 没有到达时不插入后面的区块
+	if bs.insertBlock(10) {
+		t.Fatal("missing block inserted")
+	}
+	if bs.knownHeight != 9 || len(bs.blockMap) != 2 {
+		t.Fatalf("unexpected known height %d", bs.knownHeight)
+	}
+}
+
+func TestSyncBatchSize(t *testing.T) {
+	bs, _ := testBlockSyncer(-1, 0)
+	fast, slow := peer.ID("fast"), peer.ID("slow")
+
+	if size := bs.batchSize(fast); size != int64(defaultSyncRate*syncBatchLatency.Seconds()) {
+		t.Fatalf("unexpected default batch size %d", size)
+	}
+
+	for i := 0; i < 20; i++ {
+		bs.observeRate(fast, 64, 200*time.Millisecond)
+		bs.observeRate(slow, 4, 4*time.Second)
+	}
+
+	if size := bs.batchSize(fast); size != maxBlockRange {
+		t.Fatalf("fast peer batch size %d", size)
+	}
+	if size := bs.batchSize(slow); size != minSyncBatch {
+		t.Fatalf("slow peer batch size %d", size)
+	}
+
+	// 请求失败后速率下降
+	rate := bs.rateOf(fast)
+	bs.observeRate(fast, 0, syncResponseTimeout)
+	if bs.rateOf(fast) >= rate {
+		t.Fatal("rate not decreased after failure")
+	}
+}
+
+func TestSelectHeaderCandidates(t *testing.T) {
+	header := func(height int64, tag byte) *common.BlockHeader {
+		return &common.BlockHeader{Height: height, BlockHash: [32]byte{tag, byte(height)}}
+	}
+	chain := func(n int64, tag byte) []*common.BlockHeader {
+		headers := make([]*common.BlockHeader, 0, n)
+		for height := int64(1); height <= n; height++ {
+			headers = append(headers, header(height, tag))
+		}
+		return headers
+	}
+
+	peers := testTimePeers(5)
+	results := [][]*common.BlockHeader{
+		chain(7, 'a'),
+		chain(4, 'b'),
+		chain(6, 'b'),
+		chain(8, 'b'),
+		nil,
+	}
+
+	candidates := selectHeaderCandidates(peers, results)
+	if len(candidates) != 4 {
+		t.Fatalf("unexpected candidates %d", len(candidates))
+	}
+
+	// 单个节点响应的较长序列不优先，认可节点较多的序列优先，认可节点数量相同时较长的序列优先
+	if len(candidates[0].headers) != 4 || len(candidates[0].peers) != 3 {
+		t.Fatal("headers agreed by most peers not selected first")
+	}
+	if len(candidates[1].headers) != 6 || len(candidates[1].peers) != 2 {
+		t.Fatal("headers extended by more peers not preferred")
+	}
+	if candidates[3].headers[0].BlockHash[0] != 'a' || candidates[3].peers[0] != peers[0] {
+		t.Fatal("fork headers of a single peer preferred")
+	}
+}
+
+func testPeerHead(bs *BlockSyncer, clock *utils.FakeClock, id string, height int64) {
+	bs.heads[peer.ID(id)] = &peerHead{
+		id:           peer.ID(id),
+		height:       height,
+		bufferHeight: height + 12,
+		updated:      clock.Now(),
+	}
+}
+
+func TestQuorumHeight(t *testing.T) {
+	cases := []struct {
+		heights []int64
+		weights []float64
+		expect  int64
+	}{
+		{[]int64{100, 10, 11}, []float64{1, 1, 1}, 11},
+		{[]int64{1 << 40, 10}, []float64{1, 1}, 10},
+		{[]int64{100, 10}, []float64{2, 0.5}, 100},
+		{[]int64{100, 10}, []float64{0, 1}, 10},
+		{[]int64{-1, 7}, []float64{1, 1}, 7},
+		{nil, nil, -1},
+	}
+
+	for idx, c := range cases {
+		if height := quorumHeight(c.heights, c.weights); height != c.expect {
+			t.Fatalf("case %d: quorum height %d, expect %d", idx, height, c.expect)
+		}
+	}
+}
+
+func TestSyncTargetLyingPeer(t *testing.T) {
+	bs, clock := testBlockSyncer(-1, -3)
+	bs.status = blockSyncing
+
+	testPeerHead(bs, clock, "a", 20)
+	testPeerHead(bs, clock, "b", 21)
+	testPeerHead(bs, clock, "liar", 1<<40)
+	bs.updateSyncStatus()
+
+	if bs.remoteHeight != 21 || bs.targetHeight != 33 {
+		t.Fatalf("unexpected sync target %d/%d", bs.remoteHeight, bs.targetHeight)
+	}
+	if len(bs.selected) != 2 || bs.selected[0] != "b" || bs.selected[1] != "liar" {
+		t.Fatalf("unexpected selected peers %v", bs.selected)
+	}
+
+	// 到达多数节点的高度后开始同步缓冲区
+	bs.knownHeight = 21
+	bs.updateSyncStatus()
+	if bs.status != bufferSyncing {
+		t.Fatalf("unexpected sync status %d", bs.status)
+	}
+
+	// 过期的链头不再参与选择
+	clock.Advance(peerHeadTTL + time.Second)
+	testPeerHead(bs, clock, "a", 30)
+	bs.updateSyncStatus()
+	if bs.remoteHeight != 30 || len(bs.heads) != 1 {
+		t.Fatalf("stale heads not expired, remote %d", bs.remoteHeight)
+	}
+}
+
+func TestSyncTargetStall(t *testing.T) {
+	bs, clock := testBlockSyncer(20, -3)
+	bs.status = blockSyncing
+	bs.lastProgress = clock.Now()
+
+	refresh := func() {
+		testPeerHead(bs, clock, "honest", 20)
+		testPeerHead(bs, clock, "liar1", 1000)
+		testPeerHead(bs, clock, "liar2", 1000)
+		bs.markDelivered("honest")
+	}
+
+	// 多数节点声明了更高的高度，但是没有提供任何数据
+	refresh()
+	bs.updateSyncStatus()
+	if bs.remoteHeight != 1000 {
+		t.Fatalf("unexpected remote height %d", bs.remoteHeight)
+	}
+
+	for i := 0; i < 4; i++ {
+		clock.Advance(syncStallTimeout / 3)
+		refresh()
+		bs.updateSyncStatus()
+	}
+
+	if bs.remoteHeight != 20 || len(bs.excluded) != 2 {
+		t.Fatalf("stalled peers not excluded, remote %d", bs.remoteHeight)
+	}
+	if bs.status != bufferSyncing {
+		t.Fatalf("unexpected sync status %d", bs.status)
+	}
+
+	// 排除到期后节点重新参与选择
+	clock.Advance(syncStallTimeout)
+	refresh()
+	bs.updateSyncStatus()
+	if len(bs.excluded) != 0 {
+		t.Fatal("excluded peers not released")
+	}
+}
+
+// testSyncChain 创建只有创世区块的区块链，用于检查本地数据库高度
+func testSyncChain(t *testing.T) *core.BlockChain {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	params, err := crypto.GenerateGenesisParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TimeParam = 1000
+
+	g, err := core.SealGenesis(7, 1700000000000, params, nil, prv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := utils.NewMemoryLevelDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := core.NewBlockchain(db, nil, nil)
+	if err = chain.InitGenesis(g); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = chain.Calculator().Stop(context.Background()) })
+	return chain
+}
+
+func TestSyncCatchUp(t *testing.T) {
+	bs, clock := testBlockSyncer(5, -3)
+	bs.chain = testSyncChain(t)
+	bs.status = synced
+
+	// 同步完成后到达的同步响应直接丢弃
+	if err := bs.appendBlock(testSyncBlock(7)); err != nil || len(bs.blockMap) != 0 {
+		t.Fatal("block appended after sync")
+	}
+
+	// 落后不超过缓冲区大小时由广播和补齐父区块追赶
+	testPeerHead(bs, clock, "a", core.MaxBufferSize)
+	testPeerHead(bs, clock, "b", core.MaxBufferSize)
+	bs.updateSyncStatus()
+	if bs.status != synced || bs.remoteHeight != core.MaxBufferSize {
+		t.Fatalf("unexpected sync status %d, remote %d", bs.status, bs.remoteHeight)
+	}
+
+	testPeerHead(bs, clock, "a", 40)
+	testPeerHead(bs, clock, "b", 40)
+	bs.updateSyncStatus()
+	if bs.status != blockSyncing || bs.knownHeight != 0 {
+		t.Fatalf("catch up not started, status %d, known %d", bs.status, bs.knownHeight)
+	}
+
+	// 重新同步时从本地数据库高度开始拉取，并更新缓冲区目标
+	if bs.targetHeight != 52 {
+		t.Fatalf("unexpected target height %d", bs.targetHeight)
+	}
+	if start, count := bs.selectBlockRange(16, syncResponseTimeout); start != 1 || count != 16 {
+		t.Fatalf("unexpected range #%d+%d", start, count)
+	}
+}
+
+func TestRejectHeaderCandidate(t *testing.T) {
+	pm := testManager(t)
+	hc, err := pm.chain.NewHeaderChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := hc.Tip()
+	seed, err := utils.DeserializeGenesisParams(genesis.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := utils.SerializeGeneralParams(&common.GeneralParams{Result: seed.Seed[:]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 高度不连续的区块头无法链接到区块头链，可能是对端处于分叉上，不扣分
+	unlinked := &common.BlockHeader{Height: genesis.Height + 2, Timestamp: genesis.Timestamp}
+	// 链接正确但签名无效的区块头是伪造的
+	forged := &common.BlockHeader{
+		Height:        genesis.Height + 1,
+		Timestamp:     genesis.Timestamp + pm.chain.SlotRules().SlotInterval,
+		PrevBlockHash: genesis.BlockHash,
+		Params:        params,
+		Signature:     []byte("signature"),
+	}
+
+	cases := map[string]struct {
+		header    *common.BlockHeader
+		penalized bool
+	}{
+		"unlinked": {unlinked, false},
+		"forged":   {forged, true},
+	}
+	for name, c := range cases {
+		p := &Peer{peerID: peer.ID(name), handler: pm}
+		err := hc.Append([]*common.BlockHeader{c.header})
+		if err == nil {
+			t.Fatalf("%s header appended", name)
+		}
+
+		rejectHeaderCandidate(&headerCandidate{headers: []*common.BlockHeader{c.header}, peers: []*Peer{p}},
+			c.header.Height, err)
+		if penalized := pm.reputation.Score(p.peerID) < 0; penalized != c.penalized {
+			t.Fatalf("%s header penalized %v, error %v", name, penalized, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package node
+
+import (
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/utils"
+	"testing"
+	"time"
+)
+
+func TestPackageSlot(t *testing.T) {
+	clock := utils.NewFakeClock(time.UnixMilli(1700000000000))
+	ts := NewTimeSyncer(true, 0, clock)
+	rules := core.NewSlotRules(1700000000000, nil)
+
+	slots := 0
+	lastSlot := int64(-1)
+	for i := 0; i < 10; i++ {
+		clock.Advance(time.Second)
+		if timestamp := ts.GetLogicClock(); isPackageSlot(rules, timestamp, lastSlot) {
+			lastSlot = rules.Slot(timestamp)
+			slots++
+		}
+	}
+
+	// 包括创世区块所在的时隙 0
+	if slots != 10*1000/core.DefaultSlotInterval+1 {
+		t.Fatalf("unexpected package slots %d", slots)
+	}
+
+	// 逻辑时钟包含时间同步得到的偏移
+	skewed := NewTimeSyncer(true, core.DefaultSlotInterval, clock)
+	if rules.Slot(ts.GetLogicClock()) == rules.Slot(skewed.GetLogicClock()) {
+		t.Fatal("delta not applied to logic clock")
+	}
+}
+
+func TestTimeSyncConfirm(t *testing.T) {
+	clock := utils.NewFakeClock(time.UnixMilli(1700000000000))
+	ts := NewTimeSyncer(false, 0, clock)
+
+	peers := testTimePeers(defaultSyncQuorum)
+
+	// 对端时钟比本地快 300ms，往返各耗时 20ms
+	respond := func() {
+		for _, p := range peers {
+			testTimeSample(ts, clock, p, 300, 20*time.Millisecond)
+		}
+		clock.Advance(syncInterval)
+		ts.processRound()
+	}
+
+	respond()
+	if ts.synced() {
+		t.Fatal("synced after first sample")
+	}
+
+	for i := 0; i < confirmThreshold; i++ {
+		respond()
+	}
+	if !ts.synced() {
+		t.Fatal("time syncer not synced")
+	}
+
+	delta := ts.GetLogicClock() - clock.Now().UnixMilli()
+	if abs(delta-300) > 1 {
+		t.Fatalf("unexpected delta %d", delta)
+	}
+}
+
+func TestSyncRequestRetry(t *testing.T) {
+	clock := utils.NewFakeClock(time.UnixMilli(1700000000000))
+	bs := NewBlockSyncer(&BlockSyncerConfig{Clock: clock})
+	bs.knownHeight = 9
+	bs.remoteHeight = 10
+
+	if height, count := bs.selectBlockRange(1, requestBlockInterval); height != 10 || count != 1 {
+		t.Fatalf("unexpected height %d", height)
+	}
+
+	// 在重试间隔内不重复请求同一个高度
+	clock.Advance(requestBlockInterval)
+	if height, count := bs.selectBlockRange(1, requestBlockInterval); count != 0 {
+		t.Fatalf("height %d requested again before retry interval", height)
+	}
+
+	clock.Advance(time.Millisecond)
+	if height, count := bs.selectBlockRange(1, requestBlockInterval); height != 10 || count != 1 {
+		t.Fatalf("height not retried, got %d", height)
+	}
+}
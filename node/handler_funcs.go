@@ -10,12 +10,11 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"github.com/chain-lab/go-norn/common"
-	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/core"
 	"github.com/chain-lab/go-norn/metrics"
 	"github.com/chain-lab/go-norn/p2p"
 	"github.com/chain-lab/go-norn/utils"
 	log "github.com/sirupsen/logrus"
-	"math/big"
 )
 
 func handleStatusMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
@@ -41,6 +40,17 @@ func handleNewBlockMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
 
 	if err != nil {
 		log.WithField("error", err).Debugln("Deserialize block from bytes failed.")
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
+		return
+	}
+
+	if !core.VerifyBlockSignature(block) {
+		log.WithField("height", block.Header.Height).Warning("Block signature verify failed.")
+		pm.penalizePeer(p.peerID, offenseInvalidSignature)
+		return
+	}
+
+	if !verifyBlockTimestamp(pm, block, true) {
 		return
 	}
 
@@ -59,13 +69,16 @@ func handleNewBlockMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
 		return
 	}
 
-	if verifyBlockVRF(block) {
+	if verifyBlockVRF(pm.chain, block) {
 		log.WithField("status", status).Debugln("Receive block from p2p.")
+		pm.rewardPeer(p.peerID)
 		pm.chain.AppendBlockTask(block)
 		pm.blockBroadcastQueue <- block
+		pm.fetchMissingParents(block, p)
 	} else {
 		//log.Infoln(hex.EncodeToString(block.Header.PublicKey[:]))
 		log.Warning("Block VRF verify failed.")
+		pm.penalizePeer(p.peerID, offenseInvalidVRF)
 	}
 }
 
@@ -87,9 +100,15 @@ func handleNewBlockMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
 //}
 
 func handleBlockMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
+	// 轻节点不处理区块
+	if pm.light {
+		return
+	}
+
 	status := pm.blockSyncer.getStatus()
 	log.WithField("status", status).Traceln("Receive block.")
-	if status != synced {
+	// 超时后到达的按哈希请求的响应，对端没有该区块时数据为空
+	if status != synced || len(msg.Payload) == 0 {
 		return
 	}
 
@@ -98,6 +117,17 @@ func handleBlockMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
 
 	if err != nil {
 		log.WithField("error", err).Debugln("Deserialize block from bytes failed.")
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
+		return
+	}
+
+	if !core.VerifyBlockSignature(block) {
+		log.WithField("height", block.Header.Height).Warning("Block signature verify failed.")
+		pm.penalizePeer(p.peerID, offenseInvalidSignature)
+		return
+	}
+
+	if !verifyBlockTimestamp(pm, block, false) {
 		return
 	}
 
@@ -114,50 +144,167 @@ func handleBlockMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
 		return
 	}
 
-	if verifyBlockVRF(block) {
+	if verifyBlockVRF(pm.chain, block) {
+		pm.rewardPeer(p.peerID)
 		pm.chain.AppendBlockTask(block)
+		pm.fetchMissingParents(block, p)
+	} else {
+		pm.penalizePeer(p.peerID, offenseInvalidVRF)
 	}
 }
 
-//func handleGetBlockBodiesMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
-//	status := pm.blockSyncer.getStatus()
-//	if status != synced || status != bufferSyncing {
-//		return
-//	}
-//
-//	blockHash := common.Hash(msg.Payload)
-//
-//	block := pm.chain.GetBlockFromBuffer(blockHash)
-//	if block == nil {
-//		log.Debugln("Get block by hash failed")
-//		return
-//	}
-//
-//	metrics.RoutineCreateCounterObserve(30)
-//	respondGetBlockBodies(block, p)
-//}
+// handleGetBlockBodiesMsg 处理按哈希值获取区块，先查询数据库，再查询缓冲区当前视图中的已选定区块
+func handleGetBlockBodiesMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
+	if len(msg.Payload) != common.HashLength {
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
+		return
+	}
+
+	blockHash := common.Hash(msg.Payload)
+	block, err := pm.chain.GetBlockByHash(&blockHash)
+	if err != nil || block == nil {
+		block = pm.chain.GetBlockFromBuffer(blockHash)
+	}
+
+	if block == nil {
+		log.WithField("hash", hex.EncodeToString(blockHash[:])[:8]).Debugln("Get block by hash failed.")
+	}
+
+	metrics.RoutineCreateCounterObserve(30)
+	respondGetBlockBodies(block, msg, p)
+}
+
+// handleGetTxProofMsg 处理轻节点的交易请求，响应交易以及交易到所在区块 Merkle 根的证明
+func handleGetTxProofMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
+	if pm.light {
+		return
+	}
+	if len(msg.Payload) != common.HashLength {
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
+		return
+	}
+
+	txHash := common.Hash(msg.Payload)
+	tx, proof, err := pm.chain.TransactionProof(txHash)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"hash":  hex.EncodeToString(txHash[:])[:8],
+			"error": err,
+		}).Debugln("Build transaction proof failed.")
+		tx, proof = nil, nil
+	}
+
+	respondTxProof(tx, proof, msg, p)
+}
+
+// handleGetDataProofMsg 处理轻节点的 data# 数据请求，响应请求高度（超过本地高度时为本地最新高度）的区块中
+// 数据到状态根的证明，数据不存在时响应不存在的证明；链没有状态根时响应空的数据包
+func handleGetDataProofMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
+	if pm.light {
+		return
+	}
+
+	req, err := utils.DeserializeDataProofReq(msg.Payload)
+	if err != nil || len(req.Address) != 20 || req.Height < 0 {
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
+		return
+	}
+
+	proof, err := pm.chain.ProveData(req.Address, req.Key, req.Height)
+	if err != nil {
+		log.WithError(err).Debugln("Build data proof failed.")
+		proof = nil
+	}
+
+	respondDataProof(proof, msg, p)
+}
+
+// handleTransactionsMsg 处理对端批量广播的交易，超出速率限制的消息直接丢弃，格式或签名错误的交易扣除对端的分数，
+// 新的交易加入交易池并继续广播给其它节点。同步完成之前交易广播协程没有启动，不处理广播的交易
+func handleTransactionsMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
+	if pm.light || !pm.Synced() {
+		return
+	}
+
+	txs, err := utils.DeserializeTransactions(msg.Payload)
+	if err != nil || len(txs) == 0 {
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
+		return
+	}
+
+	// 正常节点在交易池积压时也可能短时间超出限制，超出的交易只丢弃不扣分
+	if !pm.txLimiter.allow(p.peerID, len(txs)) {
+		log.WithField("peer", p.peerID).Debugln("Transaction rate limit exceeded, drop transactions.")
+		return
+	}
+
+	metrics.GossipTxRecvCountAdd(len(txs))
+	for _, tx := range txs {
+		if len(tx.Body.Data) > maxTxDataSize {
+			pm.penalizePeer(p.peerID, offenseMalformedMessage)
+			return
+		}
+
+		txHash := hex.EncodeToString(tx.Body.Hash[:])
+		p.MarkTransaction(txHash)
+		if pm.knownTransaction.Contains(txHash) || pm.txPool.Contain(txHash) {
+			continue
+		}
+
+		if !tx.Verify(pm.chainID) {
+			log.WithField("hash", txHash[:8]).Debugln("Transaction verify failed.")
+			pm.penalizePeer(p.peerID, offenseInvalidSignature)
+			return
+		}
+		pm.AddTransaction(tx)
+	}
+}
 
 func handleSyncStatusReq(pm *P2PManager, msg *p2p.Message, p *Peer) {
 	message := pm.StatusMessage()
 
 	metrics.RoutineCreateCounterObserve(23)
-	respondGetSyncStatus(message, p)
+	respondGetSyncStatus(message, msg, p)
 }
 
 func handleSyncStatusMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
 	payload := msg.Payload
 
-	statusMessage, _ := utils.DeserializeStatusMsg(payload)
-	pm.blockSyncer.appendStatusMsg(statusMessage)
-}
+	statusMessage, err := utils.DeserializeStatusMsg(payload)
+	if err != nil {
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
+		return
+	}
 
-// handleSyncGetBlocksMsg 处理获取某个高度的区块
-func handleSyncGetBlocksMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
-	status := pm.blockSyncer.getStatus()
-	if status != synced {
+	// 链 ID 不一致说明对端在另一个网络中
+	if statusMessage.ChainID != pm.chainID {
+		log.WithFields(log.Fields{
+			"peer":   p.peerID,
+			"local":  pm.chainID,
+			"remote": statusMessage.ChainID,
+		}).Warning("Chain id not match, ignore peer status.")
+		return
+	}
+
+	// 双方都已经有创世区块时，创世哈希不一致说明对端在另一条链上，不使用它的状态进行同步
+	localGenesis := pm.chain.GenesisHash()
+	remoteGenesis := common.Hash(statusMessage.GenesisHash)
+	if localGenesis != (common.Hash{}) && remoteGenesis != (common.Hash{}) &&
+		localGenesis != remoteGenesis {
+		log.WithFields(log.Fields{
+			"peer":   p.peerID,
+			"local":  hex.EncodeToString(localGenesis[:])[:8],
+			"remote": hex.EncodeToString(remoteGenesis[:])[:8],
+		}).Warning("Genesis hash not match, ignore peer status.")
 		return
 	}
 
+	pm.blockSyncer.appendStatusMsg(p, statusMessage)
+}
+
+// handleSyncGetBlocksMsg 处理获取某个高度的区块。数据库中的区块已经提交，不会再被回滚，
+// 本地仍在同步或追赶时同样从数据库中返回
+func handleSyncGetBlocksMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
 	// 从消息中直接转换得到需要的区块高度
 	payload := msg.Payload
 	height := int64(binary.LittleEndian.Uint64(payload))
@@ -170,7 +317,61 @@ func handleSyncGetBlocksMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
 	}
 
 	metrics.RoutineCreateCounterObserve(24)
-	respondSyncGetBlock(block, p)
+	respondSyncGetBlock(block, msg, p)
+}
+
+// handleSyncGetBlockRangeMsg 处理按高度区间获取区块，从起始高度开始连续返回，遇到不存在的区块或者达到数据包大小限制时停止。
+// 不论本地的同步状态都从数据库中返回已经提交的区块，本地没有对应区块时响应空的数据包，避免对端等待超时
+func handleSyncGetBlockRangeMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
+	req, err := utils.DeserializeSyncBlockRangeReq(msg.Payload)
+	if err != nil || req.Start < 0 || req.Count <= 0 {
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
+		return
+	}
+
+	count := req.Count
+	if count > maxBlockRange {
+		count = maxBlockRange
+	}
+
+	blocks := make([]*common.Block, 0, count)
+	for height := req.Start; height < req.Start+count; height++ {
+		block, err := pm.chain.GetBlockByHeight(height)
+		if err != nil || block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+
+	metrics.RoutineCreateCounterObserve(24)
+	respondSyncBlockRange(blocks, msg, p)
+}
+
+// handleSyncGetHeadersMsg 处理按高度区间获取区块头，与区间区块请求一样从起始高度开始连续返回，
+// 同样不论本地的同步状态，没有对应区块时响应空的数据包
+func handleSyncGetHeadersMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
+	req, err := utils.DeserializeSyncBlockRangeReq(msg.Payload)
+	if err != nil || req.Start < 0 || req.Count <= 0 {
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
+		return
+	}
+
+	count := req.Count
+	if count > maxHeaderRange {
+		count = maxHeaderRange
+	}
+
+	headers := make([]*common.BlockHeader, 0, count)
+	for height := req.Start; height < req.Start+count; height++ {
+		block, err := pm.chain.GetBlockByHeight(height)
+		if err != nil || block == nil {
+			break
+		}
+		headers = append(headers, &block.Header)
+	}
+
+	metrics.RoutineCreateCounterObserve(24)
+	respondSyncHeaders(headers, msg, p)
 }
 
 func handleSyncBlockMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
@@ -179,65 +380,75 @@ func handleSyncBlockMsg(pm *P2PManager, msg *p2p.Message, p *Peer) {
 
 	if err != nil {
 		log.WithField("error", err).Debugln("Block deserialize failed.")
+		pm.penalizePeer(p.peerID, offenseMalformedMessage)
 		return
 	}
-	pm.appendBlockToSyncer(block)
+	if err = pm.appendBlockToSyncer(block); err != nil {
+		pm.penalizePeer(p.peerID, offenseInvalidHeader)
+	} else {
+		pm.blockSyncer.markDelivered(p.peerID)
+	}
 	p.SetMarkSynced(false)
 }
 
 func handleTimeSyncReq(pm *P2PManager, msg *p2p.Message, p *Peer) {
-	payload := msg.Payload
-	tMsg, err := utils.DeserializeTimeSyncMsg(payload)
-	tMsg.RecReqTime = pm.timeSyncer.GetLogicClock()
-
+	recReqTime := pm.timeSyncer.GetLogicClock()
+	tMsg, err := utils.DeserializeTimeSyncMsg(msg.Payload)
 	if err != nil {
 		log.WithError(err).Debugln("Time sync message deserialize failed.")
 		return
 	}
 
-	pm.timeSyncer.ProcessSyncRequest(tMsg, p)
+	tMsg.RecReqTime = recReqTime
+	pm.timeSyncer.ProcessSyncRequest(tMsg, msg, p)
 }
 
-func handleTimeSyncRsp(pm *P2PManager, msg *p2p.Message, p *Peer) {
-	payload := msg.Payload
-	tMsg, err := utils.DeserializeTimeSyncMsg(payload)
-	tMsg.RecRspTime = pm.timeSyncer.GetLogicClock()
-
-	if err != nil {
-		log.WithError(err).Warning("Time sync message deserialize failed.")
-		return
+// verifyBlockVRF
+//
+//	@Description: 验证区块中的 VRF 证明，并检查 VRF 输出是否满足打包节点的共识阈值
+//	@param chain - 区块链实例，用于获取打包节点的共识阈值
+//	@param block - 需要验证的区块
+//	@return bool - 验证结果
+func verifyBlockVRF(chain *core.BlockChain, block *common.Block) bool {
+	if !chain.VerifyHeaderVRF(&block.Header) {
+		log.Debugln("Verify VRF failed.")
+		return false
 	}
 
-	pm.timeSyncer.ProcessSyncRespond(tMsg, p)
+	return true
 }
 
-func verifyBlockVRF(block *common.Block) bool {
-	//println(hex.EncodeToString(block.Header.PublicKey[:]))
-	bytesParams := block.Header.Params
-	params, err := utils.DeserializeGeneralParams(bytesParams)
+// verifyBlockTimestamp
+//
+//	@Description: 检查区块时间戳是否处于本地逻辑时钟的窗口内，时间戳超前逻辑时钟超过创世参数中 MaxFutureDrift 的区块被丢弃，
+//	新广播的区块还不能落后逻辑时钟 blockPastTolerance 以上。本地时间同步未完成时逻辑时钟不可信，不进行检查
+//	@param pm - 本地的 manager，用于获取逻辑时钟
+//	@param block - 需要检查的区块
+//	@param fresh - 是否为新广播的区块，同步请求得到的历史区块只检查是否超前
+//	@return bool - 检查结果
+func verifyBlockTimestamp(pm *P2PManager, block *common.Block, fresh bool) bool {
+	rules := pm.chain.SlotRules()
+	if block.Header.Height == 0 || rules == nil || !pm.timeSyncer.synced() {
+		return true
+	}
 
-	// todo: 如果这里的数据不全，导致反序列化出错可能会使得这个区块无法正常添加
-	if err != nil {
-		log.WithField("error", err).Warning("Deserialize params failed.")
+	now := pm.timeSyncer.GetLogicClock()
+	timestamp := block.Header.Timestamp
+	if err := rules.VerifyDrift(block, now); err != nil {
+		log.WithFields(log.Fields{
+			"height":    block.Header.Height,
+			"timestamp": timestamp,
+			"now":       now,
+		}).WithError(err).Warning("Drop block from the future.")
 		return false
 	}
 
-	s := new(big.Int)
-	t := new(big.Int)
-	publicKey := crypto.Bytes2PublicKey(block.Header.PublicKey[:])
-
-	s.SetBytes(params.S)
-	t.SetBytes(params.T)
-
-	verified, err := crypto.VRFCheckRemoteConsensus(publicKey, params.Result, s, t, params.RandomNumber[:])
-
-	if err != nil || !verified {
-		log.Debugln("Verify VRF failed.")
-		//log.Infoln(hex.EncodeToString(s.Bytes()))
-		//log.Infoln(hex.EncodeToString(t.Bytes()))
-		//log.Infoln(hex.EncodeToString(params.Result))
-		//log.Infoln(hex.EncodeToString(params.RandomNumber[:]))
-		//log.Infoln(hex.EncodeToString(block.Header.PublicKey[:]))
+	if fresh && timestamp < now-blockPastTolerance {
+		log.WithFields(log.Fields{
+			"height":    block.Header.Height,
+			"timestamp": timestamp,
+			"now":       now,
+		}).Debugln("Drop stale broadcast block.")
 		return false
 	}
 
@@ -0,0 +1,72 @@
+package node
+
+import (
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	"testing"
+	"time"
+)
+
+// testReceiveReply 等待对端收到 code 类型的响应，跳过其他类型的消息
+func testReceiveReply(t *testing.T, received chan *p2p.Message, code p2p.StatusCode) *p2p.Message {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-received:
+			if msg.Code == code {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("reply %d not received", code)
+		}
+	}
+}
+
+// testRangeReq 构建区间请求消息
+func testRangeReq(t *testing.T, code p2p.StatusCode, start, count int64) *p2p.Message {
+	payload, err := utils.SerializeSyncBlockRangeReq(&p2p.SyncBlockRangeReq{Start: start, Count: count})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &p2p.Message{Code: code, Payload: payload}
+}
+
+func TestServeBlockRangeWhileSyncing(t *testing.T) {
+	pm := testManager(t)
+	pm.blockSyncer.status = blockSyncing
+	p, received := testTxGossipPeer(t, pm)
+
+	// 追赶中的节点同样返回数据库中已经提交的区块
+	handleSyncGetBlockRangeMsg(pm, testRangeReq(t, p2p.StatusCodeSyncGetBlockRangeMsg, 0, 5), p)
+	reply := testReceiveReply(t, received, p2p.StatusCodeSyncBlockRangeMsg)
+	blocks, err := utils.DeserializeBlocks(reply.Payload)
+	if err != nil || len(blocks) != 1 || blocks[0].Header.Height != 0 {
+		t.Fatalf("unexpected block range reply, %d blocks, error %v", len(blocks), err)
+	}
+
+	// 本地没有的区间响应空的数据包
+	handleSyncGetBlockRangeMsg(pm, testRangeReq(t, p2p.StatusCodeSyncGetBlockRangeMsg, 10, 5), p)
+	reply = testReceiveReply(t, received, p2p.StatusCodeSyncBlockRangeMsg)
+	if blocks, err = utils.DeserializeBlocks(reply.Payload); err != nil || len(blocks) != 0 {
+		t.Fatalf("unexpected empty range reply, %d blocks, error %v", len(blocks), err)
+	}
+}
+
+func TestServeHeadersWhileSyncing(t *testing.T) {
+	pm := testManager(t)
+	pm.blockSyncer.status = syncPaused
+	p, received := testTxGossipPeer(t, pm)
+
+	handleSyncGetHeadersMsg(pm, testRangeReq(t, p2p.StatusCodeSyncGetHeadersMsg, 0, 5), p)
+	reply := testReceiveReply(t, received, p2p.StatusCodeSyncHeadersMsg)
+	headers, err := utils.DeserializeBlockHeaders(reply.Payload)
+	if err != nil || len(headers) != 1 || headers[0].Height != 0 {
+		t.Fatalf("unexpected headers reply, %d headers, error %v", len(headers), err)
+	}
+
+	handleSyncGetHeadersMsg(pm, testRangeReq(t, p2p.StatusCodeSyncGetHeadersMsg, 10, 5), p)
+	reply = testReceiveReply(t, received, p2p.StatusCodeSyncHeadersMsg)
+	if headers, err = utils.DeserializeBlockHeaders(reply.Payload); err != nil || len(headers) != 0 {
+		t.Fatalf("unexpected empty headers reply, %d headers, error %v", len(headers), err)
+	}
+}
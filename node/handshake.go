@@ -0,0 +1,228 @@
+// Package node
+// @Description: 节点握手，在建立数据流之后、创建 Peer 之前交换协议版本、创世哈希、链 ID、最新高度以及节点功能，
+// 不在同一个网络或者协议版本不兼容的节点会被断开并在一段时间内禁止连接
+package node
+
+import (
+	"bufio"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/gookit/config/v2"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"time"
+)
+
+const (
+	ProtocolVersion        uint32 = 3 // 当前的协议版本
+	MinProtocolVersion     uint32 = 1 // 兼容的最低协议版本
+	BinaryFrameVersion     uint32 = 2 // 支持二进制分帧的最低协议版本
+	RequestProtocolVersion uint32 = 3 // 支持请求/响应 ID 关联的最低协议版本
+
+	handshakeTimeout     = 5 * time.Second  // 握手超时时间
+	incompatibleBanDelay = 10 * time.Minute // 不兼容节点的禁止连接时长
+)
+
+// 节点功能位，在握手时告知对端
+const (
+	CapabilityFullNode    uint64 = 1 << iota // 存储完整区块，可以响应区块同步请求
+	CapabilityBinaryFrame                    // 支持带长度前缀的二进制分帧
+	CapabilitySnappy                         // 二进制帧支持 snappy 压缩
+	CapabilityZstd                           // 二进制帧支持 zstd 压缩
+	CapabilityBlockRange                     // 支持按高度区间批量请求区块
+	CapabilityHeaderSync                     // 支持按高度区间请求区块头
+	CapabilityBlockByHash                    // 支持按哈希值请求缓冲区或数据库中的区块
+	CapabilityProof                          // 支持为轻节点生成交易的 Merkle 证明
+	CapabilityTxGossip                       // 接收通过数据流批量广播的交易
+	CapabilityDataProof                      // 支持为轻节点生成 data# 数据到区块状态根的证明
+)
+
+var (
+	errProtocolVersion = errors.New("incompatible protocol version")
+	errChainIDMismatch = errors.New("chain id mismatch")
+	errGenesisMismatch = errors.New("genesis hash mismatch")
+	errPeerBanned      = errors.New("peer is banned")
+)
+
+// handshakeMessage
+//
+//	@Description: 生成本地的握手消息
+//	@receiver pm
+//	@return *p2p.HandshakeMsg - 握手消息
+func (pm *P2PManager) handshakeMessage() *p2p.HandshakeMsg {
+	return &p2p.HandshakeMsg{
+		ProtocolVersion: ProtocolVersion,
+		GenesisHash:     pm.chain.GenesisHash(),
+		ChainID:         pm.chainID,
+		Height:          pm.chain.Height(),
+		Capabilities:    localCapabilities(pm.light),
+	}
+}
+
+// localCapabilities
+//
+//	@Description: 本地节点支持的功能，压缩算法由配置 p2p.compression 决定，可选 zstd、snappy 或 none；
+//	轻节点只保存区块头，不响应区块、区块头和证明请求，也不接收广播的交易
+//	@param light - 是否为轻节点
+//	@return uint64 - 功能位
+func localCapabilities(light bool) uint64 {
+	capabilities := CapabilityBinaryFrame
+	if !light {
+		capabilities |= CapabilityFullNode | CapabilityBlockRange | CapabilityHeaderSync |
+			CapabilityBlockByHash | CapabilityProof | CapabilityTxGossip | CapabilityDataProof
+	}
+
+	switch config.String("p2p.compression", "snappy") {
+	case "zstd":
+		capabilities |= CapabilityZstd | CapabilitySnappy
+	case "snappy":
+		capabilities |= CapabilitySnappy
+	}
+
+	return capabilities
+}
+
+// frameConfig
+//
+//	@Description: 根据双方的协议版本和功能位协商分帧方式，任意一方不支持二进制分帧时使用旧的分帧方式，
+//	压缩算法优先选择双方都支持的 zstd，其次是 snappy
+//	@param remote - 对端的握手消息
+//	@return p2p.FrameConfig - 分帧配置
+func frameConfig(remote *p2p.HandshakeMsg) p2p.FrameConfig {
+	if remote == nil || negotiatedVersion(remote.ProtocolVersion) < BinaryFrameVersion {
+		return p2p.FrameConfig{}
+	}
+
+	// 分帧和压缩方式与节点类型无关
+	shared := localCapabilities(false) & remote.Capabilities
+	if shared&CapabilityBinaryFrame == 0 {
+		return p2p.FrameConfig{}
+	}
+
+	frame := p2p.FrameConfig{Framing: p2p.FramingBinary}
+	switch {
+	case shared&CapabilityZstd != 0:
+		frame.Compression = p2p.CompressionZstd
+	case shared&CapabilitySnappy != 0:
+		frame.Compression = p2p.CompressionSnappy
+	}
+
+	return frame
+}
+
+// checkHandshake
+//
+//	@Description: 检查对端的握手消息是否与本地兼容，任意一方还没有创世区块时不比较创世哈希
+//	@receiver pm
+//	@param remote - 对端的握手消息
+//	@return error - 不兼容时返回错误
+func (pm *P2PManager) checkHandshake(remote *p2p.HandshakeMsg) error {
+	if remote.ProtocolVersion < MinProtocolVersion {
+		return errProtocolVersion
+	}
+
+	if remote.ChainID != pm.chainID {
+		return errChainIDMismatch
+	}
+
+	localGenesis := pm.chain.GenesisHash()
+	remoteGenesis := common.Hash(remote.GenesisHash)
+	if localGenesis != (common.Hash{}) && remoteGenesis != (common.Hash{}) &&
+		localGenesis != remoteGenesis {
+		return errGenesisMismatch
+	}
+
+	return nil
+}
+
+// handshake
+//
+//	@Description: 与对端进行握手，握手失败时关闭数据流，对端不兼容时禁止其在一段时间内连接
+//	@receiver pm
+//	@param id - 对端节点 ID
+//	@param s - 数据流
+//	@return *p2p.HandshakeMsg - 对端的握手消息，对端是不支持握手的旧版本节点时为 nil
+//	@return *bufio.ReadWriter - 握手使用的 ReadWriter，需要用于创建 Peer
+//	@return error - 握手失败时返回错误
+func (pm *P2PManager) handshake(id peer.ID, s network.Stream) (*p2p.HandshakeMsg, *bufio.ReadWriter, error) {
+	if pm.isBanned(id) {
+		_ = s.Reset()
+		return nil, nil, errPeerBanned
+	}
+
+	payload, err := utils.SerializeHandshakeMsg(pm.handshakeMessage())
+	if err != nil {
+		_ = s.Reset()
+		return nil, nil, err
+	}
+
+	msg, rw, err := p2p.Handshake(s, payload, handshakeTimeout)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"peer":  id,
+			"error": err,
+		}).Debugln("Handshake with peer failed.")
+		_ = s.Reset()
+		return nil, nil, err
+	}
+
+	// 对端是不支持握手的旧版本节点，使用旧的分帧方式并且没有任何功能位
+	if msg == nil {
+		log.WithField("peer", id).Debugln("Peer not send handshake, fall back to legacy protocol.")
+		return nil, rw, nil
+	}
+
+	remote, err := utils.DeserializeHandshakeMsg(msg.Payload)
+	if err != nil {
+		_ = s.Reset()
+		return nil, nil, err
+	}
+
+	if err = pm.checkHandshake(remote); err != nil {
+		log.WithFields(log.Fields{
+			"peer":    id,
+			"version": remote.ProtocolVersion,
+			"chain":   remote.ChainID,
+			"error":   err,
+		}).Warningln("Incompatible peer, disconnect and ban.")
+		pm.banPeer(id, incompatibleBanDelay)
+		_ = s.Reset()
+		return nil, nil, err
+	}
+
+	return remote, rw, nil
+}
+
+// banPeer
+//
+//	@Description: 禁止节点在一段时间内连接，已经建立连接的节点会被断开
+//	@receiver pm
+//	@param id - 节点 ID
+//	@param duration - 禁止连接的时长
+func (pm *P2PManager) banPeer(id peer.ID, duration time.Duration) {
+	pm.reputation.Ban(id, duration)
+	pm.disconnectPeer(id)
+}
+
+// isBanned
+//
+//	@Description: 检查节点是否处于禁止连接的状态
+//	@receiver pm
+//	@param id - 节点 ID
+//	@return bool - 是否禁止连接
+func (pm *P2PManager) isBanned(id peer.ID) bool {
+	return pm.reputation.IsBanned(id)
+}
+
+// negotiatedVersion
+//
+//	@Description: 双方协议版本中较低的一个，作为与该节点通信使用的版本
+func negotiatedVersion(remote uint32) uint32 {
+	if remote < ProtocolVersion {
+		return remote
+	}
+	return ProtocolVersion
+}
@@ -0,0 +1,247 @@
+package node
+
+import (
+	"context"
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"testing"
+	"time"
+)
+
+// testManager 创建只有创世区块的连接管理器，不启动任何协程
+func testManager(t *testing.T) *P2PManager {
+	chain := testSyncChain(t)
+	pm, err := NewP2PManager(&P2PManagerConfig{
+		Chain:   chain,
+		ChainID: chain.ChainID(),
+		DB:      memoryDB{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pm
+}
+
+func TestCheckHandshake(t *testing.T) {
+	pm := testManager(t)
+	local := pm.handshakeMessage()
+
+	if err := pm.checkHandshake(local); err != nil {
+		t.Fatalf("local handshake rejected: %v", err)
+	}
+
+	// 还没有创世区块的节点不比较创世哈希
+	remote := *local
+	remote.GenesisHash = [32]byte{}
+	if err := pm.checkHandshake(&remote); err != nil {
+		t.Fatalf("peer without genesis rejected: %v", err)
+	}
+
+	remote = *local
+	remote.GenesisHash = [32]byte{1}
+	if err := pm.checkHandshake(&remote); err != errGenesisMismatch {
+		t.Fatalf("unexpected genesis check result %v", err)
+	}
+
+	remote = *local
+	remote.ChainID++
+	if err := pm.checkHandshake(&remote); err != errChainIDMismatch {
+		t.Fatalf("unexpected chain id check result %v", err)
+	}
+
+	remote = *local
+	remote.ProtocolVersion = MinProtocolVersion - 1
+	if err := pm.checkHandshake(&remote); err != errProtocolVersion {
+		t.Fatalf("unexpected version check result %v", err)
+	}
+}
+
+func TestNegotiatedVersion(t *testing.T) {
+	if v := negotiatedVersion(MinProtocolVersion); v != MinProtocolVersion {
+		t.Fatalf("unexpected version %d for old peer", v)
+	}
+	if v := negotiatedVersion(ProtocolVersion + 1); v != ProtocolVersion {
+		t.Fatalf("unexpected version %d for new peer", v)
+	}
+
+	// 旧版本节点使用旧的分帧方式
+	if frame := frameConfig(&p2p.HandshakeMsg{ProtocolVersion: MinProtocolVersion,
+		Capabilities: localCapabilities(false)}); frame.Framing != p2p.FramingLegacy {
+		t.Fatal("binary frame used with legacy peer")
+	}
+	if frame := frameConfig(nil); frame.Framing != p2p.FramingLegacy {
+		t.Fatal("binary frame used without handshake")
+	}
+
+	frame := frameConfig(&p2p.HandshakeMsg{ProtocolVersion: ProtocolVersion,
+		Capabilities: CapabilityBinaryFrame | CapabilitySnappy})
+	if frame.Framing != p2p.FramingBinary || frame.Compression != p2p.CompressionSnappy {
+		t.Fatalf("unexpected frame config %+v", frame)
+	}
+
+	// 本地默认只支持 snappy，只使用双方都支持的压缩算法，没有共同的压缩算法时不压缩
+	frame = frameConfig(&p2p.HandshakeMsg{ProtocolVersion: ProtocolVersion,
+		Capabilities: CapabilityBinaryFrame | CapabilitySnappy | CapabilityZstd})
+	if frame.Framing != p2p.FramingBinary || frame.Compression != p2p.CompressionSnappy {
+		t.Fatalf("unexpected frame config %+v", frame)
+	}
+	frame = frameConfig(&p2p.HandshakeMsg{ProtocolVersion: ProtocolVersion,
+		Capabilities: CapabilityBinaryFrame})
+	if frame.Framing != p2p.FramingBinary || frame.Compression != p2p.CompressionNone {
+		t.Fatalf("unexpected frame config %+v", frame)
+	}
+
+	// 新版本节点没有声明支持二进制分帧时仍然使用旧的分帧方式
+	frame = frameConfig(&p2p.HandshakeMsg{ProtocolVersion: ProtocolVersion,
+		Capabilities: CapabilitySnappy | CapabilityZstd})
+	if frame.Framing != p2p.FramingLegacy {
+		t.Fatalf("unexpected frame config %+v", frame)
+	}
+}
+
+func TestTemporaryBan(t *testing.T) {
+	pm := testManager(t)
+	id := peer.ID("incompatible-peer")
+
+	pm.banPeer(id, 50*time.Millisecond)
+	if !pm.isBanned(id) {
+		t.Fatal("peer not banned")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if pm.isBanned(id) {
+		t.Fatal("temporary ban not expired")
+	}
+}
+
+// TestDialIncompatiblePeer 主动连接链 ID 不同的节点，握手失败后禁止对端连接，并且不能阻塞节点锁
+func TestDialIncompatiblePeer(t *testing.T) {
+	mn := mocknet.New()
+	defer mn.Close()
+
+	local, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 对端发送链 ID 不同的握手消息，并保持数据流直到本地断开
+	localPM := testManager(t)
+	localPM.host = local
+	msg := localPM.handshakeMessage()
+	msg.ChainID++
+	payload, err := utils.SerializeHandshakeMsg(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.SetStreamHandler(ProtocolId, func(s network.Stream) {
+		if _, _, err := p2p.Handshake(s, payload, handshakeTimeout); err == nil {
+			_, _ = s.Read(make([]byte, 1))
+		}
+		_ = s.Reset()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		localPM.CheckAndCreateStream(context.Background(), local, peer.AddrInfo{
+			ID:    remote.ID(),
+			Addrs: remote.Addrs(),
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * handshakeTimeout):
+		t.Fatal("dial incompatible peer blocked")
+	}
+
+	if !localPM.isBanned(remote.ID()) {
+		t.Fatal("incompatible peer not banned")
+	}
+
+	// 节点锁没有被握手占用
+	localPM.peerSetLock.Lock()
+	count := len(localPM.peers)
+	localPM.peerSetLock.Unlock()
+	if count != 0 {
+		t.Fatalf("incompatible peer added, %d peers", count)
+	}
+}
+
+func TestAcceptStream(t *testing.T) {
+	pm := testTxGossipManager(t)
+	p, _, _ := testRequestPeer(t, pm)
+	id := p.peerID
+
+	// 没有连接的节点直接接受
+	if !pm.acceptStream(id, true) {
+		t.Fatal("stream from new peer rejected")
+	}
+
+	// 同一方向的重复数据流
+	pm.peers[id] = p
+	p.outbound = true
+	if pm.acceptStream(id, true) {
+		t.Fatal("duplicate outbound stream accepted")
+	}
+
+	// 本地节点 ID 较大时保留对端发起的数据流
+	pm.id = id + "\xff"
+	if !pm.acceptStream(id, false) {
+		t.Fatal("stream dialed by lower peer id rejected")
+	}
+	if !p.Stopped() {
+		t.Fatal("replaced stream not closed")
+	}
+
+	// 本地节点 ID 较小时保留本地发起的数据流
+	p, _, _ = testRequestPeer(t, pm)
+	id = p.peerID
+	pm.peers[id] = p
+	p.outbound = true
+	pm.id = ""
+	if pm.acceptStream(id, false) {
+		t.Fatal("stream dialed by higher peer id accepted")
+	}
+	if p.Stopped() {
+		t.Fatal("stream dialed by lower peer id closed")
+	}
+
+	// 断开连接的节点可以重新建立连接
+	p.Close()
+	if !pm.acceptStream(id, false) {
+		t.Fatal("stream from stopped peer rejected")
+	}
+}
+
+func TestRemoveStoppedPeer(t *testing.T) {
+	pm := testTxGossipManager(t)
+	stale, _, _ := testRequestPeer(t, pm)
+	current, _, _ := testRequestPeer(t, pm)
+
+	// 断开连接的旧 Peer 已经被同一节点的新连接替换
+	current.peerID = stale.peerID
+	stale.Close()
+	pm.peerSet = append(pm.peerSet, stale, current)
+	pm.peers[stale.peerID] = current
+
+	if !pm.removePeerIfStopped(0) {
+		t.Fatal("stopped peer not removed")
+	}
+	if len(pm.peerSet) != 1 || pm.peers[current.peerID] != current {
+		t.Fatal("replacing peer removed with stopped peer")
+	}
+	if pm.removePeerIfStopped(0) {
+		t.Fatal("running peer removed")
+	}
+}
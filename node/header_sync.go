@@ -0,0 +1,343 @@
+// Package node
+// @Description: 区块头优先的同步方式。同步器先向多个节点请求区块头，验证哈希链接、签名、VRF 以及 VDF 证明后选出最优的区块头链，
+// 区块只在已经验证的区块头范围内下载，并且需要与区块头一致、交易列表的 Merkle 根正确。对端在同步状态中声明的高度只决定是否继续请求区块头，
+// 不会让同步器下载没有经过验证的区块
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/gookit/config/v2"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	maxHeaderRange     = 256                    // 单次区块头请求的最大数量
+	headerSyncPeers    = 3                      // 每轮同时请求区块头的节点数量
+	maxHeaderAhead     = 4 * syncWindowSize     // 区块头链最多超前已插入高度的数量
+	headerSyncInterval = 500 * time.Millisecond // 请求区块头的间隔
+)
+
+// headerCandidate 候选区块头序列以及认可该序列的节点，节点响应的区块头与序列相同或者以序列为前缀
+type headerCandidate struct {
+	headers []*common.BlockHeader
+	peers   []*Peer
+}
+
+// headerFirstEnabled 是否使用区块头优先的同步方式，由配置 sync.header_first 决定，默认开启
+func headerFirstEnabled() bool {
+	return config.Bool("sync.header_first", true)
+}
+
+// headerRoutine
+//
+//	@Description: 区块头同步协程，定时向速率最高的几个节点请求区块头链之后的区块头，同步完成后暂停请求；
+//	轻节点同步完成后继续请求新的区块头
+//	@receiver bs
+//	@param ctx - 同步协程的 context
+func (bs *BlockSyncer) headerRoutine(ctx context.Context) {
+	defer bs.wg.Done()
+	if !bs.headerFirst {
+		return
+	}
+
+	ticker := bs.clock.NewTicker(headerSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if bs.getStatus() == synced && !bs.light {
+				continue
+			}
+			bs.syncHeaders(ctx)
+		}
+	}
+}
+
+// syncHeaders
+//
+//	@Description: 进行一轮区块头同步，同时向多个节点请求相同区间的区块头，选出认可节点最多的合法区块头序列追加到区块头链
+//	@receiver bs
+//	@param ctx - 同步协程的 context
+func (bs *BlockSyncer) syncHeaders(ctx context.Context) {
+	peers := bs.headerPeers()
+	headers := bs.headerChain()
+
+	if len(peers) == 0 {
+		if bs.light {
+			return
+		}
+
+		// 没有支持区块头同步的节点，已经验证的区块头全部下载完成后退回到直接下载区块
+		bs.lock.Lock()
+		if bs.headers != nil && bs.headers.Height() <= bs.knownHeight {
+			log.Infoln("No peer supports header sync, fallback to block sync.")
+			bs.headers = nil
+		}
+		bs.lock.Unlock()
+		return
+	}
+
+	if headers == nil {
+		headers = bs.initHeaderChain()
+		if headers == nil {
+			return
+		}
+	}
+
+	bs.lock.RLock()
+	knownHeight, remoteHeight := bs.knownHeight, bs.remoteHeight
+	bs.lock.RUnlock()
+
+	start := headers.Height() + 1
+	if start > remoteHeight || start-knownHeight > maxHeaderAhead {
+		return
+	}
+	count := min(maxHeaderRange, remoteHeight-start+1)
+
+	results := make([][]*common.BlockHeader, len(peers))
+	var wg sync.WaitGroup
+	wg.Add(len(peers))
+	for idx := range peers {
+		go func(idx int) {
+			defer wg.Done()
+			results[idx] = bs.fetchHeaders(ctx, start, count, peers[idx])
+		}(idx)
+	}
+	wg.Wait()
+
+	for _, candidate := range selectHeaderCandidates(peers, results) {
+		err := headers.Append(candidate.headers)
+		if err == nil {
+			tip := headers.Tip()
+			log.WithFields(log.Fields{
+				"height": tip.Height,
+				"hash":   hex.EncodeToString(tip.BlockHash[:])[:8],
+				"peers":  candidatePeerIDs(candidate),
+			}).Infoln("Append verified headers.")
+
+			// 轻节点的区块头已经写入区块头存储，区块头链的高度就是已经同步的高度
+			if bs.light {
+				bs.lock.Lock()
+				bs.knownHeight = tip.Height
+				bs.lastProgress = bs.clock.Now()
+				bs.lock.Unlock()
+			}
+			return
+		}
+
+		rejectHeaderCandidate(candidate, start, err)
+	}
+}
+
+// rejectHeaderCandidate
+//
+//	@Description: 处理追加失败的候选区块头序列，只有签名、VRF 或者 VDF 证明验证失败时才扣除响应节点的分数，
+//	区块头无法链接可能是区块头链在验证期间被其他序列追加，或者对端处于分叉上，不扣分
+//	@param candidate - 追加失败的候选区块头序列
+//	@param start - 起始高度
+//	@param err - 追加失败的原因
+func rejectHeaderCandidate(candidate *headerCandidate, start int64, err error) {
+	fields := log.Fields{
+		"start": start,
+		"count": len(candidate.headers),
+		"peers": candidatePeerIDs(candidate),
+		"error": err,
+	}
+
+	if !core.InvalidHeaderProof(err) {
+		log.WithFields(fields).Debugln("Append sync headers failed.")
+		return
+	}
+
+	log.WithFields(fields).Warning("Verify sync headers failed.")
+	for _, p := range candidate.peers {
+		p.handler.penalizePeer(p.peerID, offenseInvalidHeader)
+	}
+}
+
+// fetchHeaders
+//
+//	@Description: 向对端请求区块头，超时或者响应错误时与区块请求一样扣除对端的分数
+//	@receiver bs
+//	@param ctx - 同步协程的 context
+//	@param start - 起始高度
+//	@param count - 区块头数量
+//	@param p - 节点实例
+//	@return []*common.BlockHeader - 对端响应的区块头，请求失败时为 nil
+func (bs *BlockSyncer) fetchHeaders(ctx context.Context, start, count int64, p *Peer) []*common.BlockHeader {
+	ctx, cancel := context.WithTimeout(ctx, syncResponseTimeout)
+	defer cancel()
+
+	headers, err := requestSyncHeaders(ctx, start, count, p)
+	if err == nil {
+		if len(headers) > 0 {
+			bs.markDelivered(p.peerID)
+		}
+		return headers
+	}
+
+	log.WithFields(log.Fields{
+		"peer":  p.peerID,
+		"start": start,
+		"error": err,
+	}).Debugln("Sync headers request failed.")
+
+	switch {
+	case err == context.Canceled:
+	case err == context.DeadlineExceeded:
+		if status := p.Status(); status != nil && status.Height >= start {
+			p.handler.penalizePeer(p.peerID, offenseSyncTimeout)
+		}
+	case err != errPeerStopped:
+		p.handler.penalizePeer(p.peerID, offenseMalformedMessage)
+	}
+	return nil
+}
+
+// headerPeers 选取速率最高的 headerSyncPeers 个支持区块头同步的节点
+func (bs *BlockSyncer) headerPeers() []*Peer {
+	bs.peerStatusLock.RLock()
+	defer bs.peerStatusLock.RUnlock()
+
+	peers := make([]*Peer, 0, len(bs.peerSet))
+	for _, p := range bs.peerSet {
+		if !p.Stopped() && p.FullNode() && p.SupportHeaderSync() {
+			peers = append(peers, p)
+		}
+	}
+
+	sort.SliceStable(peers, func(i, j int) bool {
+		return bs.rateOf(peers[i].peerID) > bs.rateOf(peers[j].peerID)
+	})
+	if len(peers) > headerSyncPeers {
+		peers = peers[:headerSyncPeers]
+	}
+	return peers
+}
+
+// headerChain 获取当前的区块头链，没有使用区块头优先同步时返回 nil
+func (bs *BlockSyncer) headerChain() *core.HeaderChain {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+
+	return bs.headers
+}
+
+// initHeaderChain
+//
+//	@Description: 以本地最新区块为起点创建区块头链，本地已经存在的区块不再下载；轻节点以区块头存储中的最新区块头为起点
+//	@receiver bs
+//	@return *core.HeaderChain - 区块头链，本地还没有创世区块时返回 nil
+func (bs *BlockSyncer) initHeaderChain() *core.HeaderChain {
+	if bs.chain == nil {
+		return nil
+	}
+
+	var headers *core.HeaderChain
+	var err error
+	if bs.light {
+		headers, err = bs.chain.NewLightHeaderChain(bs.store)
+	} else {
+		headers, err = bs.chain.NewHeaderChain()
+	}
+	if err != nil {
+		log.WithError(err).Debugln("Create header chain failed.")
+		return nil
+	}
+
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+
+	base := headers.Height()
+	for height := range bs.blockMap {
+		if height <= base {
+			delete(bs.blockMap, height)
+		}
+	}
+	for height := range bs.requestDeadline {
+		if height <= base {
+			delete(bs.requestDeadline, height)
+		}
+	}
+	bs.knownHeight = max(bs.knownHeight, base)
+	bs.headers = headers
+
+	log.WithField("height", base).Infoln("Start header first sync.")
+	return headers
+}
+
+// selectHeaderCandidates
+//
+//	@Description: 将各个节点响应的区块头按照内容分组，响应以该序列为前缀的节点都认可这个序列。
+//	认可节点较多的序列优先，认可节点数量相同时较长的序列优先，避免单个节点响应更长的分叉区块头就被追加到区块头链
+//	@param peers - 节点列表
+//	@param results - 每个节点响应的区块头，与节点列表一一对应
+//	@return []*headerCandidate - 排序后的候选区块头序列
+func selectHeaderCandidates(peers []*Peer, results [][]*common.BlockHeader) []*headerCandidate {
+	candidates := make([]*headerCandidate, 0, len(results))
+
+	for _, headers := range results {
+		if len(headers) == 0 {
+			continue
+		}
+
+		matched := false
+		for _, c := range candidates {
+			if sameHeaders(c.headers, headers) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			candidates = append(candidates, &headerCandidate{headers: headers})
+		}
+	}
+
+	for _, c := range candidates {
+		for idx, headers := range results {
+			if len(headers) >= len(c.headers) && sameHeaders(c.headers, headers[:len(c.headers)]) {
+				c.peers = append(c.peers, peers[idx])
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if len(candidates[i].peers) != len(candidates[j].peers) {
+			return len(candidates[i].peers) > len(candidates[j].peers)
+		}
+		return len(candidates[i].headers) > len(candidates[j].headers)
+	})
+	return candidates
+}
+
+// sameHeaders 两个区块头序列的长度和每个区块哈希都相同
+func sameHeaders(a, b []*common.BlockHeader) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if !bytes.Equal(a[idx].BlockHash[:], b[idx].BlockHash[:]) {
+			return false
+		}
+	}
+	return true
+}
+
+// candidatePeerIDs 候选区块头序列的响应节点，用于日志
+func candidatePeerIDs(candidate *headerCandidate) []string {
+	ids := make([]string, 0, len(candidate.peers))
+	for _, p := range candidate.peers {
+		ids = append(ids, p.peerID.String())
+	}
+	return ids
+}
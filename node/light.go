@@ -0,0 +1,237 @@
+// Package node
+// @Description: 轻节点的查询。轻节点只保存已经验证的区块头，交易和 data# 数据向支持证明的完整节点请求，
+// 交易使用本地区块头中的 Merkle 根验证，data# 数据使用区块参数中的状态根验证；响应无效的节点扣除分数并向下一个节点请求。
+// 创世参数中没有启用状态树的旧链，区块中没有状态根，轻节点不提供 data# 数据查询
+package node
+
+import (
+	"context"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"sort"
+	"time"
+)
+
+const (
+	lightQueryPeers   = 3               // 单次查询最多请求的节点数量
+	lightQueryTimeout = 5 * time.Second // 单个节点的请求超时时间
+	lightDataMaxLag   = 3               // data# 数据的证明最多落后本地区块头的高度，落后更多的节点向下一个节点请求
+)
+
+var (
+	errNotLightNode    = errors.New("node is not running in light mode")
+	errNoProofPeer     = errors.New("no peer supports proof request")
+	errProofNotFound   = errors.New("not found on proof peers")
+	errHeaderNotSynced = errors.New("block header not synced")
+	errProofMismatch   = errors.New("proof response not match request")
+	errDataUnsupported = errors.New("chain has no data root, data can not be verified in light mode")
+	errDataNotFound    = errors.New("data not found")
+)
+
+// Light 是否为轻节点
+func (pm *P2PManager) Light() bool {
+	return pm.light
+}
+
+// HeaderHeight 轻节点已经同步并验证的区块头高度，不是轻节点时返回区块链的高度
+func (pm *P2PManager) HeaderHeight() int64 {
+	if !pm.light {
+		return pm.chain.Height()
+	}
+	return pm.headers.Height()
+}
+
+// LightTransaction
+//
+//	@Description: 向完整节点请求交易，交易需要通过本地区块头的 Merkle 证明验证
+//	@receiver pm
+//	@param ctx - 查询上下文
+//	@param hash - 交易哈希
+//	@return *common.Transaction - 交易，包含所在区块的高度、哈希和交易在区块中的位置
+//	@return error - 没有节点能够提供通过验证的交易时返回错误
+func (pm *P2PManager) LightTransaction(ctx context.Context, hash common.Hash) (*common.Transaction, error) {
+	if !pm.light {
+		return nil, errNotLightNode
+	}
+
+	var tx *common.Transaction
+	err := pm.lightQuery(ctx, (*Peer).SupportProof, func(ctx context.Context, p *Peer) (bool, error) {
+		rsp, err := requestTxProof(ctx, hash, p)
+		if err != nil || rsp == nil {
+			return false, err
+		}
+
+		tx, err = pm.verifyProofTransaction(rsp.Transaction, rsp.Proof)
+		if err == nil && tx.Body.Hash != hash {
+			err = errProofMismatch
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// LightAddressData
+//
+//	@Description: 向完整节点请求 data# 数据，数据需要通过本地区块头中状态根的证明验证。请求使用本地最新的区块头高度，
+//	完整节点的高度落后超过 lightDataMaxLag 时数据可能不是最新的，向下一个节点请求
+//	@receiver pm
+//	@param ctx - 查询上下文
+//	@param address - 数据的地址
+//	@param key - 数据的 key
+//	@return []byte - 数据
+//	@return error - 数据被证明不存在时返回 errDataNotFound，没有节点能够提供通过验证的证明时返回错误
+func (pm *P2PManager) LightAddressData(ctx context.Context, address, key []byte) ([]byte, error) {
+	if !pm.light {
+		return nil, errNotLightNode
+	}
+	if pm.chain.DataVersion() == core.DataVersionNone {
+		return nil, errDataUnsupported
+	}
+
+	tip := pm.headers.Height()
+	var value []byte
+	found := false
+	err := pm.lightQuery(ctx, (*Peer).SupportDataProof, func(ctx context.Context, p *Peer) (bool, error) {
+		rsp, err := requestDataProof(ctx, address, key, tip, p)
+		if err != nil || rsp == nil {
+			return false, err
+		}
+
+		// 完整节点响应的高度不会超过请求的高度
+		if rsp.Height > tip || rsp.Height < 0 {
+			return false, errProofMismatch
+		}
+		if rsp.Height < tip-lightDataMaxLag {
+			return false, nil
+		}
+		header, err := pm.headers.GetHeaderByHeight(rsp.Height)
+		if err != nil {
+			return false, errHeaderNotSynced
+		}
+		root, err := core.BlockDataRoot(header)
+		if err != nil {
+			return false, errHeaderNotSynced
+		}
+
+		found, err = core.VerifyDataProof(root, address, key, rsp.Value, rsp.Proof)
+		if err == nil && found != rsp.Found {
+			err = errProofMismatch
+		}
+		if err != nil {
+			return false, err
+		}
+		value = rsp.Value
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errDataNotFound
+	}
+	return value, nil
+}
+
+// lightQuery
+//
+//	@Description: 依次向速率最高的几个支持证明的完整节点发送查询，直到某个节点的响应通过验证；
+//	响应无法通过验证的节点扣除分数，本地区块头还没有同步到对应高度时不扣除分数
+//	@receiver pm
+//	@param ctx - 查询上下文
+//	@param support - 对端是否支持该查询
+//	@param query - 向单个节点查询并验证响应，返回是否得到了结果
+//	@return error - 所有节点都没有结果时返回最后一个错误
+func (pm *P2PManager) lightQuery(ctx context.Context, support func(p *Peer) bool,
+	query func(ctx context.Context, p *Peer) (bool, error)) error {
+	peers := pm.proofPeers(support)
+	if len(peers) == 0 {
+		return errNoProofPeer
+	}
+
+	lastErr := errProofNotFound
+	for _, p := range peers {
+		reqCtx, cancel := context.WithTimeout(ctx, lightQueryTimeout)
+		found, err := query(reqCtx, p)
+		cancel()
+
+		if found {
+			return nil
+		}
+		if err == nil {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"peer":  p.peerID,
+			"error": err,
+		}).Debugln("Light query failed.")
+		lastErr = err
+
+		switch err {
+		case context.Canceled, context.DeadlineExceeded, errPeerStopped, errHeaderNotSynced:
+		default:
+			pm.penalizePeer(p.peerID, offenseInvalidProof)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return lastErr
+}
+
+// proofPeers 选取速率最高的 lightQueryPeers 个支持 support 查询的完整节点
+func (pm *P2PManager) proofPeers(support func(p *Peer) bool) []*Peer {
+	pm.peerSetLock.RLock()
+	peers := make([]*Peer, 0, len(pm.peerSet))
+	for _, p := range pm.peerSet {
+		if !p.Stopped() && p.FullNode() && support(p) {
+			peers = append(peers, p)
+		}
+	}
+	pm.peerSetLock.RUnlock()
+
+	bs := pm.blockSyncer
+	bs.peerStatusLock.RLock()
+	sort.SliceStable(peers, func(i, j int) bool {
+		return bs.rateOf(peers[i].peerID) > bs.rateOf(peers[j].peerID)
+	})
+	bs.peerStatusLock.RUnlock()
+	if len(peers) > lightQueryPeers {
+		peers = peers[:lightQueryPeers]
+	}
+	return peers
+}
+
+// verifyProofTransaction
+//
+//	@Description: 反序列化完整节点响应的交易，并使用本地对应高度的区块头验证交易的 Merkle 证明
+//	@receiver pm
+//	@param txBytes - 序列化的交易
+//	@param proof - Merkle 证明
+//	@return *common.Transaction - 通过验证的交易
+//	@return error - 本地没有对应的区块头或者验证失败时返回错误
+func (pm *P2PManager) verifyProofTransaction(txBytes, proof []byte) (*common.Transaction, error) {
+	tx, err := utils.DeserializeTransaction(txBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.Body.Height > pm.headers.Height() {
+		return nil, errHeaderNotSynced
+	}
+	header, err := pm.headers.GetHeaderByHeight(tx.Body.Height)
+	if err != nil {
+		return nil, errHeaderNotSynced
+	}
+
+	if err = core.VerifyTransactionProof(header, tx, proof, pm.chainID); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
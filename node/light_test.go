@@ -0,0 +1,154 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	"testing"
+)
+
+// testDataManagers 使用同一个启用状态树的创世文件创建完整节点和轻节点，创世数据写入 address 下的 name
+func testDataManagers(t *testing.T, address []byte) (*P2PManager, *P2PManager) {
+	prv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	params, err := crypto.GenerateGenesisParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TimeParam = 1000
+	params.DataVersion = core.DataVersionTrie
+	g, err := core.SealGenesis(7, 1700000000000, params, []core.GenesisAlloc{
+		{Address: hex.EncodeToString(address), Key: "name", Value: "genesis"},
+	}, prv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	managers := make([]*P2PManager, 2)
+	for idx, light := range []bool{false, true} {
+		db, err := utils.NewMemoryLevelDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		chain := core.NewBlockchain(db, nil, nil)
+		if err = chain.InitGenesis(g); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = chain.Calculator().Stop(context.Background()) })
+
+		managers[idx], err = NewP2PManager(&P2PManagerConfig{
+			Chain:   chain,
+			ChainID: chain.ChainID(),
+			DB:      memoryDB{},
+			Light:   light,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 轻节点的区块头存储从创世区块开始
+	genesis, err := managers[1].chain.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = managers[1].headers.Append([]*common.BlockHeader{&genesis.Header}); err != nil {
+		t.Fatal(err)
+	}
+	return managers[0], managers[1]
+}
+
+// testDataResponder 使用完整节点的处理函数响应轻节点的 data# 数据请求，tamper 不为 nil 时修改响应
+func testDataResponder(t *testing.T, full *P2PManager, rp *p2p.Peer, received chan *p2p.Message,
+	tamper func(msg *p2p.DataProofMsg)) {
+	req := testReceiveRequest(t, received, p2p.StatusCodeGetDataProofMsg)
+	if tamper == nil {
+		handleGetDataProofMsg(full, req, &Peer{peer: rp})
+		return
+	}
+
+	dataReq, err := utils.DeserializeDataProofReq(req.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := full.chain.ProveData(dataReq.Address, dataReq.Key, dataReq.Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &p2p.DataProofMsg{Height: proof.Height, Found: proof.Found, Value: proof.Value, Proof: proof.Proof}
+	tamper(msg)
+	payload, err := utils.SerializeDataProofMsg(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp.SendMessage(&p2p.Message{Code: p2p.StatusCodeDataProofMsg, Payload: payload, ReplyTo: req.RequestID})
+}
+
+func TestLightAddressData(t *testing.T) {
+	address := make([]byte, 20)
+	address[0] = 0xaa
+	full, light := testDataManagers(t, address)
+
+	p, rp, received := testRequestPeer(t, light)
+	p.status.Capabilities = CapabilityFullNode | CapabilityDataProof
+	light.peerSet = append(light.peerSet, p)
+
+	query := func(key string, tamper func(msg *p2p.DataProofMsg)) ([]byte, error) {
+		done := make(chan struct{})
+		go func() {
+			testDataResponder(t, full, rp, received, tamper)
+			close(done)
+		}()
+		defer func() { <-done }()
+		return light.LightAddressData(context.Background(), address, []byte(key))
+	}
+
+	// 数据通过创世区块状态根的证明验证
+	value, err := query("name", nil)
+	if err != nil || string(value) != "genesis" {
+		t.Fatalf("unexpected data %q, error %v", value, err)
+	}
+
+	// 不存在的数据通过证明确认不存在
+	if _, err = query("missing", nil); err != errDataNotFound {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// 修改数据或者隐藏数据的响应无法通过验证
+	if _, err = query("name", func(msg *p2p.DataProofMsg) { msg.Value = []byte("forged") }); err == nil {
+		t.Fatal("forged data verified")
+	}
+	if _, err = query("name", func(msg *p2p.DataProofMsg) { msg.Found, msg.Value = false, nil }); err == nil {
+		t.Fatal("hidden data verified")
+	}
+	if _, err = query("name", func(msg *p2p.DataProofMsg) { msg.Height = 5 }); err != errProofMismatch {
+		t.Fatalf("response above local headers accepted, error %v", err)
+	}
+
+	// 旧的链没有状态根，轻节点不提供 data# 数据查询
+	old := testManager(t)
+	old.light = true
+	if _, err = old.LightAddressData(context.Background(), address, []byte("name")); err != errDataUnsupported {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestHandleGetDataProofMsgMalformed(t *testing.T) {
+	full, _ := testDataManagers(t, make([]byte, 20))
+	p, _ := testTxGossipPeer(t, full)
+
+	payload, err := utils.SerializeDataProofReq(&p2p.DataProofReq{Address: []byte{1}, Key: []byte("name")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handleGetDataProofMsg(full, &p2p.Message{Code: p2p.StatusCodeGetDataProofMsg, Payload: payload}, p)
+	if full.reputation.Score(p.peerID) >= 0 {
+		t.Fatal("malformed data proof request not penalized")
+	}
+}
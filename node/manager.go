@@ -3,16 +3,17 @@
 package node
 
 import (
+	"bufio"
 	"context"
-	"crypto/elliptic"
 	"encoding/hex"
+	"fmt"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/core"
 	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/interfaces"
 	"github.com/chain-lab/go-norn/metrics"
 	"github.com/chain-lab/go-norn/p2p"
 	"github.com/chain-lab/go-norn/utils"
-	"github.com/gookit/config/v2"
 	lru "github.com/hashicorp/golang-lru"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
@@ -22,10 +23,9 @@ import (
 	"github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/libp2p/go-libp2p/p2p/discovery/routing"
 	dutil "github.com/libp2p/go-libp2p/p2p/discovery/util"
-	"github.com/multiformats/go-multiaddr"
 	log "github.com/sirupsen/logrus"
-	"math/rand"
-	"net"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"strings"
 	"sync"
 	"time"
 )
@@ -33,23 +33,23 @@ import (
 type msgHandler func(pm *P2PManager, msg *p2p.Message, p *Peer)
 
 var handlerMap = map[p2p.StatusCode]msgHandler{
-	p2p.StatusCodeStatusMsg:        handleStatusMsg,        // 状态消息，目前接收对端的高度信息
-	p2p.StatusCodeBlockBodiesMsg:   handleBlockMsg,         // 对应上一个状态码，如果对端请求区块，在缓冲区中取出区块进行响应
-	p2p.StatusCodeSyncStatusReq:    handleSyncStatusReq,    // 携带本地的高度信息，请求对端的状态信息，例如高度/缓冲区高度
-	p2p.StatusCodeSyncStatusMsg:    handleSyncStatusMsg,    // 响应对端的状态请求
-	p2p.StatusCodeSyncGetBlocksMsg: handleSyncGetBlocksMsg, // 根据高度请求区块
-	p2p.StatusCodeSyncBlocksMsg:    handleSyncBlockMsg,     // 响应对应高度的区块
-	p2p.StatusCodeTimeSyncReq:      handleTimeSyncReq,      // 时间同步请求
-	p2p.StatusCodeTimeSyncRsp:      handleTimeSyncRsp,      // 时间同步响应
-	//p2p.StatusCodeGetBlockBodiesMsg: handleGetBlockBodiesMsg, // 请求本地缓冲区中不存在的区块
+	p2p.StatusCodeStatusMsg:            handleStatusMsg,            // 状态消息，目前接收对端的高度信息
+	p2p.StatusCodeBlockBodiesMsg:       handleBlockMsg,             // 对应上一个状态码，如果对端请求区块，在缓冲区中取出区块进行响应
+	p2p.StatusCodeSyncStatusReq:        handleSyncStatusReq,        // 携带本地的高度信息，请求对端的状态信息，例如高度/缓冲区高度
+	p2p.StatusCodeSyncStatusMsg:        handleSyncStatusMsg,        // 响应对端的状态请求
+	p2p.StatusCodeSyncGetBlocksMsg:     handleSyncGetBlocksMsg,     // 根据高度请求区块
+	p2p.StatusCodeSyncBlocksMsg:        handleSyncBlockMsg,         // 响应对应高度的区块
+	p2p.StatusCodeSyncGetBlockRangeMsg: handleSyncGetBlockRangeMsg, // 根据高度区间批量请求区块
+	p2p.StatusCodeSyncGetHeadersMsg:    handleSyncGetHeadersMsg,    // 根据高度区间请求区块头
+	p2p.StatusCodeTimeSyncReq:          handleTimeSyncReq,          // 时间同步请求
+	p2p.StatusCodeGetBlockBodiesMsg:    handleGetBlockBodiesMsg,    // 根据哈希值请求区块，用于补齐广播区块缺失的父区块
+	p2p.StatusCodeGetTxProofMsg:        handleGetTxProofMsg,        // 轻节点请求交易以及交易的 Merkle 证明
+	p2p.StatusCodeGetDataProofMsg:      handleGetDataProofMsg,      // 轻节点请求 data# 数据以及数据到区块状态根的证明
+	p2p.StatusCodeTransactionsMsg:      handleTransactionsMsg,      // 对端批量广播的交易
 	//p2p.StatusCodeNewBlockHashesMsg: handleNewBlockHashMsg,   // 广播新打包的区块哈希值，在同步旧区块（非缓冲区同步状态）时不处理
 	//p2p.StatusCodeNewBlockMsg:       handleNewBlockMsg,       // 广播新打包的区块，在同步旧区块（非缓冲区同步状态）时不处理
 }
 
-var (
-	managerInst *P2PManager = nil
-)
-
 const (
 	// 节点重连限制，如果节点在 10 分钟内尝试连接过但是失败，跳过该节点
 	retryInterval          = 10 * time.Minute
@@ -58,10 +58,10 @@ const (
 	maxKnownTransaction    = 32768 // 最大已知交易的 LRU 缓存大小
 	maxSyncerStatusChannel = 512   // 同步器 syncer 的最大状态 channel 限制
 
-	packageBlockInterval = 2       // 区块打包间隔
-	gossipNodes          = 8       // UDP Gossip 广播节点数量
-	udpBufferSize        = 1024    // UDP 缓冲大小 = 1M
-	pubsubMaxSize        = 1 << 22 // 4 MB
+	blockPastTolerance = 30000   // 新广播的区块时间戳允许落后本地逻辑时钟的范围，单位 ms
+	pubsubMaxSize      = 1 << 22 // 4 MB
+
+	packageCheckInterval = time.Second // 检查是否进入新的出块时隙的间隔，时隙更短时使用半个时隙
 
 	NetworkRendezvous = "chronos"
 	TxGossipTopic     = "/chronos/1.0.1/transactions"
@@ -70,14 +70,52 @@ const (
 	TxProtocolId      = protocol.ID("/chronos/1.0.0/transaction")
 )
 
+var errManagerNotStarted = errors.New("p2p manager not started")
+
+// ChainRendezvous
+//
+//	@Description: 获取某个链 ID 下节点发现使用的 rendezvous，链 ID 为 0 时与旧版本保持一致
+//	@param chainID - 链 ID
+//	@return string - 节点发现的 rendezvous
+func ChainRendezvous(chainID int64) string {
+	if chainID == 0 {
+		return NetworkRendezvous
+	}
+
+	return fmt.Sprintf("%s-%d", NetworkRendezvous, chainID)
+}
+
+// ChainTopic
+//
+//	@Description: 获取某个链 ID 下的广播 topic，将 topic 中的网络名称替换为带有链 ID 的名称
+//	@param topic - 广播 topic，如 BlockGossipTopic
+//	@param chainID - 链 ID
+//	@return string - 带有链 ID 的 topic
+func ChainTopic(topic string, chainID int64) string {
+	if chainID == 0 {
+		return topic
+	}
+
+	return strings.Replace(topic, "/"+NetworkRendezvous+"/",
+		"/"+ChainRendezvous(chainID)+"/", 1)
+}
+
 // P2PManagerConfig P2PManager 的实例化配置信息
 type P2PManagerConfig struct {
-	TxPool       *core.TxPool     // 交易池实例
-	Chain        *core.BlockChain // 区块链实例
-	Genesis      bool             // 是否创世节点
-	InitialDelta int64            // 初始时间偏移，仅仅用于进行时间同步测试
+	TxPool       *core.TxPool           // 交易池实例
+	Chain        *core.BlockChain       // 区块链实例
+	Genesis      bool                   // 是否创世节点
+	InitialDelta int64                  // 初始时间偏移，仅仅用于进行时间同步测试
+	ChainID      int64                  // 链 ID，用于区分节点发现和广播的网络
+	DB           interfaces.DBInterface // 数据库实例，用于持久化节点的禁止连接记录
+	Filter       MessageFilter          // 入站消息过滤，为 nil 时接收所有消息
+	Clock        utils.Clock            // 时间同步、区块打包和区块同步使用的时钟，为 nil 时使用系统时钟
+	Light        bool                   // 轻节点，只同步并验证区块头，区块头存放在 DB 中
 }
 
+// MessageFilter 入站消息过滤函数，返回 false 时丢弃来自 from 的消息，模拟测试中用于注入网络分区和丢包
+type MessageFilter func(from peer.ID) bool
+
 type P2PManager struct {
 	id         peer.ID               // 本地 P2P 节点 id
 	triedPeers map[peer.ID]time.Time // 尝试连接的节点和连接时间戳，在一定时间内不再进行尝试
@@ -93,6 +131,7 @@ type P2PManager struct {
 
 	knownBlock       *lru.Cache // 本地已知的区块缓存
 	knownTransaction *lru.Cache // 本地已知的交易缓存
+	parentRequests   *lru.Cache // 最近请求过的缺失父区块
 
 	txPool *core.TxPool     // 交易池实例
 	chain  *core.BlockChain // 区块链实例
@@ -103,6 +142,19 @@ type P2PManager struct {
 
 	peerSetLock sync.RWMutex // 节点管理锁
 	genesis     bool         // 是否创世节点
+	chainID     int64        // 链 ID
+
+	host       host.Host         // 本地 p2p 节点实例，用于断开被禁止的节点
+	reputation *Reputation       // 节点信誉管理
+	txLimiter  *txLimiter        // 交易广播的速率限制
+	light      bool              // 是否为轻节点
+	headers    *core.HeaderStore // 轻节点的区块头存储
+	filter     MessageFilter
+	clock      utils.Clock // 区块打包和重连限制使用的时钟
+
+	ctx    context.Context    // manager 协程的 context，停止时取消
+	cancel context.CancelFunc // 取消 manager 的所有协程
+	wg     sync.WaitGroup     // 等待 manager 的协程退出
 }
 
 func NewP2PManager(config *P2PManagerConfig) (*P2PManager, error) {
@@ -122,12 +174,33 @@ func NewP2PManager(config *P2PManagerConfig) (*P2PManager, error) {
 		return nil, err
 	}
 
+	// 创建缺失父区块的请求记录
+	parentRequestCache, err := lru.New(maxParentRequests)
+
+	if err != nil {
+		log.WithField("error", err).Debugln("Create parent request cache failed.")
+		return nil, err
+	}
+
+	clock := config.Clock
+	if clock == nil {
+		clock = utils.NewRealClock()
+	}
+
+	// 轻节点只保存区块头
+	var headerStore *core.HeaderStore
+	if config.Light {
+		headerStore = core.NewHeaderStore(config.DB)
+	}
+
 	// 区块同步配置
 	blockSyncerConfig := &BlockSyncerConfig{
-		Chain: config.Chain,
+		Chain:       config.Chain,
+		Clock:       clock,
+		HeaderStore: headerStore,
 	}
 	bs := NewBlockSyncer(blockSyncerConfig)
-	ts := NewTimeSyncer(config.Genesis, config.InitialDelta)
+	ts := NewTimeSyncer(config.Genesis, config.InitialDelta, clock)
 
 	manager := &P2PManager{
 		// todo: 限制节点数量，Kad 应该限制了节点数量不超过20个
@@ -135,11 +208,15 @@ func NewP2PManager(config *P2PManagerConfig) (*P2PManager, error) {
 		peerSet:    make([]*Peer, 0, 40),
 		peers:      make(map[peer.ID]*Peer),
 
+		reputation: NewReputation(config.DB),
+		txLimiter:  newTxLimiter(config.Chain.TxCapacity),
+
 		blockBroadcastQueue: make(chan *common.Block, 512),
 		txBroadcastQueue:    make(chan *common.Transaction, 10240),
 
 		knownBlock:       knownBlockCache,
 		knownTransaction: knownTxCache,
+		parentRequests:   parentRequestCache,
 
 		txPool: config.TxPool,
 		chain:  config.Chain,
@@ -148,19 +225,67 @@ func NewP2PManager(config *P2PManagerConfig) (*P2PManager, error) {
 		timeSyncer:  ts,
 
 		genesis: config.Genesis,
+		chainID: config.ChainID,
+		light:   config.Light,
+		headers: headerStore,
+		filter:  config.Filter,
+		clock:   clock,
 	}
 
-	metrics.RoutineCreateCounterObserve(15)
+	ts.manager = manager
 
-	// 启动节点的打包交易协程
-	go manager.packageBlockRoutine()
+	return manager, nil
+}
+
+// Start
+//
+//	@Description: 启动交易打包协程、区块同步器和时间同步器
+//	@receiver pm
+//	@param ctx - 父 context，取消后所有协程退出
+//	@return error
+func (pm *P2PManager) Start(ctx context.Context) error {
+	pm.ctx, pm.cancel = context.WithCancel(ctx)
+
+	// 启动节点的打包交易协程，轻节点不参与出块
+	if !pm.light {
+		metrics.RoutineCreateCounterObserve(15)
+		pm.wg.Add(1)
+		go pm.packageBlockRoutine(pm.ctx)
+	}
 
 	// 启动区块同步器和时间同步器
-	bs.Start()
-	ts.Start()
-	managerInst = manager
+	if err := pm.blockSyncer.Start(pm.ctx); err != nil {
+		return err
+	}
+	return pm.timeSyncer.Start(pm.ctx)
+}
 
-	return manager, nil
+// Stop
+//
+//	@Description: 停止同步器和 manager 的所有协程，并断开与所有对端节点的连接
+//	@receiver pm
+//	@param ctx - 停止的超时 context
+//	@return error - 超时时返回错误
+func (pm *P2PManager) Stop(ctx context.Context) error {
+	if pm.cancel == nil {
+		return nil
+	}
+	pm.cancel()
+
+	if err := pm.timeSyncer.Stop(ctx); err != nil {
+		return err
+	}
+	if err := pm.blockSyncer.Stop(ctx); err != nil {
+		return err
+	}
+
+	pm.peerSetLock.RLock()
+	for _, p := range pm.peerSet {
+		p.Close()
+	}
+	pm.peerSetLock.RUnlock()
+
+	return utils.WaitGroupContext(ctx, &pm.wg)
 }
 
 // GetBlockChain
@@ -184,30 +309,49 @@ func (pm *P2PManager) AddTransaction(tx *common.Transaction) {
 	pm.txPool.Add(tx)
 }
 
-// GetP2PManager
-//
-//	@Description: 获取 Manager 实例
-//	@return *P2PManager - Manager 实例
-func GetP2PManager() *P2PManager {
-	// todo: 这样的写法会有脏读的问题，也就是在分配地址后，对象可能还没有完全初始化
-	return managerInst
-}
-
 // packageBlockRoutine
 //
 //	@Description: 交易打包实例
 //	@receiver pm
-func (pm *P2PManager) packageBlockRoutine() {
-	ticker := time.NewTicker(1 * time.Second)
+//	@param ctx - manager 的 context
+func (pm *P2PManager) packageBlockRoutine(ctx context.Context) {
+	defer pm.wg.Done()
+
+	ticker := pm.clock.NewTicker(packageCheckInterval)
+	defer ticker.Stop()
 
+	// 本地打包节点的共识私钥和公钥，公钥用于获取本地节点的共识阈值
+	prv, err := pm.chain.ConsensusKey()
+	if err != nil {
+		log.WithError(err).Errorln("Get consensus key failed.")
+		return
+	}
+	localPublicKey := [33]byte(crypto.PublicKey2Bytes(&prv.PublicKey))
+
+	// 每个时隙只尝试打包一次
+	tick := packageCheckInterval
+	lastSlot := int64(-1)
 	for {
 		select {
-		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			// 时隙规则由创世区块确定
+			rules := pm.chain.SlotRules()
+			if rules == nil {
+				log.Infoln("Waiting for genesis block.")
+				continue
+			}
+			if interval := time.Duration(rules.SlotInterval) * time.Millisecond / 2; interval < tick {
+				tick = interval
+				ticker.Reset(tick)
+			}
+
 			timestamp := pm.timeSyncer.GetLogicClock()
-			// 如果逻辑时间距离 2s 则进行区块的打包
-			if (timestamp/1000)%packageBlockInterval != 0 {
+			if !isPackageSlot(rules, timestamp, lastSlot) {
 				continue
 			}
+			lastSlot = rules.Slot(timestamp)
 
 			//  如果未完成同步，则不进行打包
 			if !pm.Synced() {
@@ -223,33 +367,43 @@ func (pm *P2PManager) packageBlockRoutine() {
 			}
 
 			// 获取当前的 VDF 计算信息
-			calc := crypto.GetCalculatorInstance()
+			calc := pm.chain.Calculator()
+			if calc == nil {
+				log.Infoln("Waiting for VDF calculator.")
+				continue
+			}
 			seed, pi := calc.GetSeedParams()
 			log.Debugf("Get seed: %s", hex.EncodeToString(seed.Bytes()))
 
-			// 判断当前节点是否为共识节点
-			consensus, err := crypto.VRFCheckLocalConsensus(seed.Bytes())
+			// 判断当前节点是否为共识节点，阈值由创世参数和本地节点的权重决定
+			version := pm.chain.VRFVersion()
+			threshold := pm.chain.ConsensusThreshold(localPublicKey)
+			consensus, err := crypto.VRFCheckLocalConsensus(version, prv, seed.Bytes(), threshold)
 			if !consensus || err != nil {
 				//log.Infof("Local is not consensus node")
 				continue
 			}
 
 			// VRF 计算得到的随机数，需要包含到区块中
-			randNumber, s, t, err := crypto.VRFCalculate(elliptic.P256(), seed.Bytes())
+			randNumber, s, t, err := crypto.VRFProve(version, prv, seed.Bytes())
+			if err != nil {
+				log.WithError(err).Warning("Calculate VRF failed.")
+				continue
+			}
 			log.Infof("Package with seed: %s", hex.EncodeToString(seed.Bytes()))
 
 			params := common.GeneralParams{
 				Result:       seed.Bytes(),
 				Proof:        pi.Bytes(),
 				RandomNumber: [33]byte(randNumber),
-				S:            s.Bytes(),
-				T:            t.Bytes(),
+				S:            s,
+				T:            t,
 			}
 
 			// 交易池打包返回一个交易数组
 			txs := pm.txPool.Package()
 			log.Infof("Package %d txs.", len(txs))
-			newBlock, err := pm.chain.PackageNewBlock(txs, timestamp, &params, packageBlockInterval)
+			newBlock, err := pm.chain.PackageNewBlock(txs, timestamp, &params)
 			log.Infof("Package new block.")
 
 			if err != nil {
@@ -259,12 +413,27 @@ func (pm *P2PManager) packageBlockRoutine() {
 
 			pm.chain.AppendBlockTask(newBlock)
 			log.Infoln("Append block to buffer.")
-			pm.blockBroadcastQueue <- newBlock
+			select {
+			case pm.blockBroadcastQueue <- newBlock:
+			case <-ctx.Done():
+				return
+			}
 			log.Infof("Package new block# 0x%s", hex.EncodeToString(newBlock.Header.BlockHash[:]))
 		}
 	}
 }
 
+// isPackageSlot
+//
+//	@Description: 判断逻辑时间是否进入了新的出块时隙，时隙由创世参数中的时隙长度确定，每个时隙进行一次区块的打包
+//	@param rules - 区块时间规则
+//	@param timestamp - 逻辑时间，单位为毫秒
+//	@param lastSlot - 上一次打包的时隙
+//	@return bool - 是否进行打包
+func isPackageSlot(rules *core.SlotRules, timestamp int64, lastSlot int64) bool {
+	return rules.Slot(timestamp) > lastSlot
+}
+
 // NewPeer
 //
 //	@Description: 创建一个新的 Peer，它基于底层发送消息的 p2p.Peer 实现
@@ -275,13 +444,16 @@ func (pm *P2PManager) packageBlockRoutine() {
 //	@return *Peer - node.Peer 实例
 //	@return error - 如果出错则返回报错
 func (pm *P2PManager) NewPeer(peerId peer.ID, s *network.Stream,
-	remoteAddr string) (*Peer,
-	error) {
+	remoteAddr string, status *p2p.HandshakeMsg, rw *bufio.ReadWriter,
+	outbound bool) (*Peer, error) {
 	cfg := PeerConfig{
 		chain:          pm.chain,
 		txPool:         pm.txPool,
 		handler:        pm,
 		remoteMultAddr: remoteAddr,
+		status:         status,
+		rw:             rw,
+		outbound:       outbound,
 	}
 
 	// 调用公有的 NewPeer 方法创建新节点
@@ -325,10 +497,15 @@ func (pm *P2PManager) isKnownTransaction(hash common.Hash) bool {
 //
 //	@Description: 区块广播协程，这里使用的是 go-libp2p 的 gossip 广播
 //	@receiver pm
-func (pm *P2PManager) broadcastBlock() {
+//	@param ctx - manager 的 context
+func (pm *P2PManager) broadcastBlock(ctx context.Context) {
+	defer pm.wg.Done()
+
 	log.Infoln("P2P manger broadcast block routine start!")
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case block := <-pm.blockBroadcastQueue:
 			//blockHash := block.Header.BlockHash
 			blockData, err := utils.SerializeBlock(block)
@@ -340,7 +517,7 @@ func (pm *P2PManager) broadcastBlock() {
 				len(blockData)/1024, len(block.Transactions))
 
 			// 向 Topic 中广播区块
-			err = pm.blockTopic.Publish(context.Background(), blockData)
+			err = pm.blockTopic.Publish(ctx, blockData)
 			if err != nil {
 				log.WithError(err).Errorln("Publish block failed.")
 				continue
@@ -352,21 +529,6 @@ func (pm *P2PManager) broadcastBlock() {
 	}
 }
 
-// broadcastTransaction
-//
-//	@Description: 交易广播协程，这里使用的是基于 UDP 的 gossip 协议，如果使用 go-libp2p 会影响到区块的广播效率，进而导致分叉
-//	@receiver pm
-func (pm *P2PManager) broadcastTransaction() {
-	log.Infoln("P2P manger broadcast transaction routine start!")
-	for {
-		select {
-		case tx := <-pm.txBroadcastQueue:
-			pm.UDPGossipBroadcast(tx)
-			time.Sleep(200 * time.Microsecond)
-		}
-	}
-}
-
 // gossipBlockSubscribe
 //
 //	@Description: 区块接收协程，在 topic 中接收到其它节点广播的区块
@@ -376,11 +538,17 @@ func (pm *P2PManager) broadcastTransaction() {
 //	@param h - 本地 p2p 节点实例
 func (pm *P2PManager) gossipBlockSubscribe(ctx context.Context,
 	sub *pubsub.Subscription, h host.Host) {
+	defer pm.wg.Done()
+	defer sub.Cancel()
+
 	log.Infoln("P2P gossip block subscription routine start!")
 	for {
-		// 从订阅中接收消息
+		// 从订阅中接收消息，ctx 取消时退出
 		blockMsg, err := sub.Next(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
 			log.Errorf("Get block data from subscription failed: %s", err)
 			continue
 		}
@@ -392,24 +560,16 @@ func (pm *P2PManager) gossipBlockSubscribe(ctx context.Context,
 
 		metrics.GossipReceiveBlocksCountInc()
 
-		// 获取同步状态，如果未同步完成则跳过处理
-		status := pm.blockSyncer.getStatus()
-		if status == blockSyncing || status == syncPaused {
+		// 区块已经在 topic validator 中完成反序列化和验证
+		block, ok := blockMsg.ValidatorData.(*common.Block)
+		if !ok {
 			continue
 		}
 
-		// 尝试反序列化区块，如果失败则跳过并报错
-		block, err := utils.DeserializeBlock(blockMsg.Data)
-		if err != nil {
-			log.WithField("error",
-				err).Warning("Deserialize block from bytes failed.")
-			return
-		}
-
 		blockHash := block.Header.BlockHash
 		strHash := hex.EncodeToString(blockHash[:])
 		if pm.knownBlock.Contains(strHash) {
-			return
+			continue
 		}
 
 		pm.markBlock(strHash)
@@ -418,18 +578,73 @@ func (pm *P2PManager) gossipBlockSubscribe(ctx context.Context,
 		if block.Header.Height == 0 {
 			metrics.RoutineCreateCounterObserve(18)
 			go pm.chain.InsertBlock(block)
-			return
+			continue
 		}
 
-		if verifyBlockVRF(block) {
-			log.WithField("status", status).Debugln("Receive block from p2p.")
-			pm.chain.AppendBlockTask(block)
-			//pm.blockBroadcastQueue <- block
-		} else {
-			//log.Infoln(hex.EncodeToString(block.Header.PublicKey[:]))
-			log.Warning("Block VRF verify failed.")
-		}
+		pm.rewardPeer(blockMsg.ReceivedFrom)
+		pm.chain.AppendBlockTask(block)
+		//pm.blockBroadcastQueue <- block
+
+		// 转发区块的节点已经验证过该区块，向它补齐缺失的父区块
+		pm.peerSetLock.RLock()
+		p := pm.peers[blockMsg.ReceivedFrom]
+		pm.peerSetLock.RUnlock()
+		pm.fetchMissingParents(block, p)
+	}
+}
+
+// validateGossipBlock
+//
+//	@Description: 区块广播 topic 的 validator，在消息交付和继续转发之前验证区块。格式、签名或 VRF 错误的区块返回 ValidationReject，
+//	不再转发给其他节点，并扣除转发节点的分数；同步未完成、时间戳不在窗口内或者被过滤的消息返回 ValidationIgnore，不扣分也不转发
+//	@receiver pm
+//	@param ctx - validator 的 context
+//	@param from - 转发消息的节点
+//	@param msg - 广播消息，验证通过后反序列化的区块保存在 ValidatorData 中
+//	@return pubsub.ValidationResult - 验证结果
+func (pm *P2PManager) validateGossipBlock(ctx context.Context, from peer.ID,
+	msg *pubsub.Message) pubsub.ValidationResult {
+	// 本地发布的区块在打包时已经验证
+	if from == pm.id {
+		return pubsub.ValidationAccept
+	}
+
+	if pm.filter != nil && !pm.filter(from) {
+		return pubsub.ValidationIgnore
+	}
+
+	// 未同步完成时无法验证区块，也不继续转发
+	status := pm.blockSyncer.getStatus()
+	if status == blockSyncing || status == syncPaused {
+		return pubsub.ValidationIgnore
+	}
+
+	block, err := utils.DeserializeBlock(msg.Data)
+	if err != nil {
+		log.WithField("error", err).Warning("Deserialize block from bytes failed.")
+		pm.penalizePeer(from, offenseMalformedMessage)
+		return pubsub.ValidationReject
 	}
+
+	// 验证区块头哈希和打包节点签名，签名错误的区块不标记为已知区块，避免阻挡正确的区块
+	if !core.VerifyBlockSignature(block) {
+		log.WithField("height", block.Header.Height).Warning("Block signature verify failed.")
+		pm.penalizePeer(from, offenseInvalidSignature)
+		return pubsub.ValidationReject
+	}
+
+	if !verifyBlockTimestamp(pm, block, true) {
+		return pubsub.ValidationIgnore
+	}
+
+	if block.Header.Height != 0 && !verifyBlockVRF(pm.chain, block) {
+		log.Warning("Block VRF verify failed.")
+		pm.penalizePeer(from, offenseInvalidVRF)
+		return pubsub.ValidationReject
+	}
+
+	msg.ValidatorData = block
+	return pubsub.ValidationAccept
 }
 
 // appendBlockToSyncer
@@ -437,8 +652,9 @@ func (pm *P2PManager) gossipBlockSubscribe(ctx context.Context,
 //	@Description: 将区块添加到同步器中，这里是在端对端接收到区块时进行添加
 //	@receiver pm
 //	@param block - 区块实例
-func (pm *P2PManager) appendBlockToSyncer(block *common.Block) {
-	pm.blockSyncer.appendBlock(block)
+//	@return error - 区块与已经验证的区块头不一致时返回错误
+func (pm *P2PManager) appendBlockToSyncer(block *common.Block) error {
+	return pm.blockSyncer.appendBlock(block)
 }
 
 // SetSynced
@@ -473,7 +689,7 @@ func (pm *P2PManager) markTransaction(hash string) {
 //	@receiver pm
 //	@return uint8
 func (pm *P2PManager) syncStatus() uint8 {
-	return pm.blockSyncer.status
+	return pm.blockSyncer.getStatus()
 }
 
 // HandleStream
@@ -482,18 +698,25 @@ func (pm *P2PManager) syncStatus() uint8 {
 //	@receiver pm
 //	@param s - 接收到新的连接请求
 func (pm *P2PManager) HandleStream(s network.Stream) {
+	conn := s.Conn()
+	remoteAddr := conn.RemoteMultiaddr().String()
+
+	// 握手在加锁之前进行，避免双方同时建立连接时互相等待
+	status, rw, err := pm.handshake(conn.RemotePeer(), s)
+	if err != nil {
+		return
+	}
+
 	pm.peerSetLock.Lock()
 	defer pm.peerSetLock.Unlock()
 
-	if len(pm.peerSet) > streamLimit {
+	// 握手期间本地节点可能已经通过 CheckAndCreateStream 建立了连接
+	if !pm.acceptStream(conn.RemotePeer(), false) {
 		_ = s.Close()
 		return
 	}
 
-	conn := s.Conn()
-	remoteAddr := conn.RemoteMultiaddr().String()
-
-	_, err := pm.NewPeer(conn.RemotePeer(), &s, remoteAddr)
+	_, err = pm.NewPeer(conn.RemotePeer(), &s, remoteAddr, status, rw, false)
 
 	log.Infoln("Receive new stream, handle stream.")
 	log.Infoln(conn.RemoteMultiaddr())
@@ -517,40 +740,17 @@ func (pm *P2PManager) HandleStream(s network.Stream) {
 func (pm *P2PManager) Discover(ctx context.Context, h host.Host,
 	dht *dht.IpfsDHT,
 	rendezvous string) {
-	var err error
 	var routingDiscovery = routing.NewRoutingDiscovery(dht)
 	dutil.Advertise(ctx, routingDiscovery, rendezvous)
 
 	// 广播路由的初始化不能使用下面的语句，否则广播网络无法工作, 逆天 go-libp2p，
 	// _, err := routingDiscovery.Advertise(ctx, rendezvous)
 
-	pm.id = h.ID()
-
-	// 利用 go-libp2p-pubsub 构建广播网络
-	pm.gossip, err = pubsub.NewGossipSub(ctx, h,
-		pubsub.WithMaxMessageSize(pubsubMaxSize),
-	)
-	if err != nil {
-		log.WithError(err).Fatalf("Create gossip sub failed")
-		return
-	}
-
-	pm.blockTopic, err = pm.gossip.Join(BlockGossipTopic)
-	if err != nil {
-		log.WithError(err).Fatalf("Join block topic failed")
-		return
-	}
-	log.Infof("Join to topic %s", BlockGossipTopic)
-
-	blockSub, err := pm.blockTopic.Subscribe(pubsub.WithBufferSize(512))
-	if err != nil {
-		log.WithError(err).Fatalf("Get sub from topic failed")
+	if err := pm.Attach(h); err != nil {
+		log.WithError(err).Fatalf("Attach p2p host failed")
 		return
 	}
 
-	go pm.TransactionUDP()
-	go pm.gossipBlockSubscribe(ctx, blockSub, h)
-
 	// 每 500ms 通过 Kademlia 获取对端节点列表
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
@@ -575,36 +775,88 @@ func (pm *P2PManager) Discover(ctx context.Context, h host.Host,
 
 }
 
+// Attach
+//
+//	@Description: 将 manager 绑定到本地的 p2p 节点上，构建区块广播网络并启动区块订阅协程，交易通过节点之间的数据流广播，
+//	需要在 Start 之后调用，协程在 manager 停止时退出。节点发现由调用方完成，模拟测试中直接调用 CheckAndCreateStream 连接其它节点
+//	@receiver pm
+//	@param h - 本地节点实例
+//	@return error
+func (pm *P2PManager) Attach(h host.Host) error {
+	if pm.ctx == nil {
+		return errManagerNotStarted
+	}
+
+	var err error
+	ctx := pm.ctx
+	pm.id = h.ID()
+	pm.host = h
+
+	// 利用 go-libp2p-pubsub 构建广播网络，节点评分使用节点信誉中的分数。
+	// 本地打包的区块直接发送给所有订阅节点，否则启动后第一次心跳建立 mesh 之前发布的区块会被丢弃
+	scoreParams, scoreThresholds := pm.gossipScoreParams()
+	pm.gossip, err = pubsub.NewGossipSub(ctx, h,
+		pubsub.WithMaxMessageSize(pubsubMaxSize),
+		pubsub.WithPeerScore(scoreParams, scoreThresholds),
+		pubsub.WithFloodPublish(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	// 区块在交付和转发之前由 validator 验证，不合法的区块不会继续在网络中传播
+	blockTopic := ChainTopic(BlockGossipTopic, pm.chainID)
+	err = pm.gossip.RegisterTopicValidator(blockTopic, pm.validateGossipBlock)
+	if err != nil {
+		return err
+	}
+
+	pm.blockTopic, err = pm.gossip.Join(blockTopic)
+	if err != nil {
+		return err
+	}
+	log.Infof("Join to topic %s", blockTopic)
+
+	blockSub, err := pm.blockTopic.Subscribe(pubsub.WithBufferSize(512))
+	if err != nil {
+		return err
+	}
+
+	// 轻节点不处理广播的区块，区块头由同步器定时请求
+	if pm.light {
+		blockSub.Cancel()
+		return nil
+	}
+	pm.wg.Add(1)
+	go pm.gossipBlockSubscribe(ctx, blockSub, h)
+
+	return nil
+}
+
+// acceptMessage
+//
+//	@Description: 检查是否接收来自对端的消息，设置了过滤函数时由过滤函数决定
+//	@receiver pm
+//	@param from - 消息来源节点
+//	@return bool - 是否接收
+func (pm *P2PManager) acceptMessage(from peer.ID) bool {
+	return pm.filter == nil || pm.filter(from)
+}
+
 // CheckAndCreateStream
 //
-//	@Description: 检查并创建数据传输流
+//	@Description: 检查并创建数据传输流，连接和握手在加锁之外进行，握手失败时禁止节点会断开连接并获取节点锁，
+//	加锁握手会导致死锁，并且握手超时期间阻塞所有的节点操作
 //	@receiver pm
 //	@param ctx - 处理上下文
 //	@param h - 本地节点实例
 //	@param p - 对端节点实例
 func (pm *P2PManager) CheckAndCreateStream(ctx context.Context, h host.Host,
 	p peer.AddrInfo) {
-	pm.peerSetLock.Lock()
-	defer pm.peerSetLock.Unlock()
-	// 对端节点 ID 和本地节点 ID 相同
-	if len(pm.peers) >= streamLimit || p.ID == h.ID() {
+	if !pm.shouldConnect(h, p.ID) {
 		return
 	}
 
-	// 如果在 retryInterval 内尝试连接过，跳过该节点
-	if tried, ok := pm.triedPeers[p.ID]; ok {
-		if time.Since(tried) < retryInterval {
-			return
-		}
-	}
-
-	// 如果节点已经连接，并且当前的状态正常
-	if remote, ok := pm.peers[p.ID]; ok {
-		if !remote.Stopped() {
-			return
-		}
-	}
-
 	// 如果没有连接，建立新的连接
 	if h.Network().Connectedness(p.ID) != network.Connected {
 		_, err := h.Network().DialPeer(ctx, p.ID)
@@ -619,14 +871,29 @@ func (pm *P2PManager) CheckAndCreateStream(ctx context.Context, h host.Host,
 
 	stream, err := h.NewStream(ctx, p.ID, ProtocolId)
 	if err != nil {
-		log.WithError(err).Errorln("Create new stream failed: %d", err)
-		pm.triedPeers[p.ID] = time.Now()
+		log.WithError(err).Errorln("Create new stream failed.")
+		pm.markTried(p.ID)
 		return
 	}
 
 	conn := stream.Conn()
 
-	_, err = pm.NewPeer(p.ID, &stream, conn.RemoteMultiaddr().String())
+	status, rw, err := pm.handshake(p.ID, stream)
+	if err != nil {
+		pm.markTried(p.ID)
+		return
+	}
+
+	pm.peerSetLock.Lock()
+	defer pm.peerSetLock.Unlock()
+
+	// 握手期间对端可能已经通过 HandleStream 建立了连接
+	if !pm.acceptStream(p.ID, true) {
+		_ = stream.Close()
+		return
+	}
+
+	_, err = pm.NewPeer(p.ID, &stream, conn.RemoteMultiaddr().String(), status, rw, true)
 	if err != nil {
 		log.WithError(err).Debugln("Create new peer failed.")
 		return
@@ -635,6 +902,86 @@ func (pm *P2PManager) CheckAndCreateStream(ctx context.Context, h host.Host,
 	metrics.ConnectedNodeInc()
 }
 
+// acceptStream
+//
+//	@Description: 检查是否接受与节点 id 新建立的数据流，需要持有 peerSetLock。握手在加锁之外进行，双方同时发起连接时
+//	每个节点都会得到一条入站和一条出站的数据流，双方都保留由节点 ID 较小的一方发起的数据流并关闭另一条，
+//	避免双方各自关闭对方保留的数据流后断开连接
+//	@receiver pm
+//	@param id - 对端节点 ID
+//	@param outbound - 新的数据流是否由本地节点发起
+//	@return bool - 是否接受新的数据流，接受时已有的重复连接会被关闭
+func (pm *P2PManager) acceptStream(id peer.ID, outbound bool) bool {
+	remote, ok := pm.peers[id]
+	if !ok {
+		return len(pm.peers) < streamLimit
+	}
+	// 断开连接的节点由新的连接替换，不增加节点数量
+	if remote.Stopped() {
+		return true
+	}
+
+	// 同一方向的重复数据流，保留已有的连接
+	if remote.outbound == outbound {
+		return false
+	}
+
+	// 新的数据流不是由节点 ID 较小的一方发起的，保留已有的连接
+	if outbound != (pm.id < id) {
+		return false
+	}
+
+	remote.Close()
+	return true
+}
+
+// shouldConnect
+//
+//	@Description: 检查是否需要与节点建立连接，节点数量达到上限、节点被禁止连接、最近尝试连接失败或者已经连接时跳过
+//	@receiver pm
+//	@param h - 本地节点实例
+//	@param id - 对端节点 ID
+//	@return bool - 是否需要建立连接
+func (pm *P2PManager) shouldConnect(h host.Host, id peer.ID) bool {
+	pm.peerSetLock.Lock()
+	defer pm.peerSetLock.Unlock()
+
+	// 对端节点 ID 和本地节点 ID 相同，或者对端处于禁止连接的状态
+	if len(pm.peers) >= streamLimit || id == h.ID() || pm.isBanned(id) {
+		return false
+	}
+
+	// 如果在 retryInterval 内尝试连接过，跳过该节点
+	if tried, ok := pm.triedPeers[id]; ok {
+		if pm.clock.Since(tried) < retryInterval {
+			return false
+		}
+	}
+
+	// 如果节点已经连接，并且当前的状态正常
+	if remote, ok := pm.peers[id]; ok && !remote.Stopped() {
+		return false
+	}
+
+	return true
+}
+
+// markTried 记录连接失败的时间，retryInterval 内不再尝试连接
+func (pm *P2PManager) markTried(id peer.ID) {
+	pm.peerSetLock.Lock()
+	defer pm.peerSetLock.Unlock()
+
+	pm.triedPeers[id] = pm.clock.Now()
+}
+
+// GossipPeers 区块广播 topic 中已经订阅的其他节点，还没有加入 topic 时返回 nil
+func (pm *P2PManager) GossipPeers() []peer.ID {
+	if pm.blockTopic == nil {
+		return nil
+	}
+	return pm.blockTopic.ListPeers()
+}
+
 func (pm *P2PManager) Synced() bool {
 	status := pm.blockSyncer.getStatus()
 
@@ -642,8 +989,9 @@ func (pm *P2PManager) Synced() bool {
 		pm.startRoutine.Do(func() {
 			log.Infoln("Block && time sync finish!")
 			metrics.RoutineCreateCounterObserve(16)
-			go pm.broadcastBlock()
-			go pm.broadcastTransaction()
+			pm.wg.Add(2)
+			go pm.broadcastBlock(pm.ctx)
+			go pm.broadcastTransaction(pm.ctx)
 		})
 		return true
 	}
@@ -653,13 +1001,17 @@ func (pm *P2PManager) Synced() bool {
 // StatusMessage 生成同步信息给对端
 func (pm *P2PManager) StatusMessage() *p2p.SyncStatusMsg {
 	block, err := pm.chain.GetLatestBlock()
+	genesisHash := pm.chain.GenesisHash()
 
-	if err != nil || !pm.Synced() {
+	// 轻节点没有区块，不参与其他节点同步目标的选择
+	if err != nil || !pm.Synced() || pm.light {
 		return &p2p.SyncStatusMsg{
 			LatestHeight:        -1,
 			LatestHash:          [32]byte{},
 			BufferedStartHeight: 0,
 			BufferedEndHeight:   -1,
+			GenesisHash:         genesisHash,
+			ChainID:             pm.chainID,
 		}
 	}
 
@@ -668,6 +1020,8 @@ func (pm *P2PManager) StatusMessage() *p2p.SyncStatusMsg {
 		LatestHash:          block.Header.BlockHash,
 		BufferedStartHeight: 0,
 		BufferedEndHeight:   pm.chain.BufferedHeight(),
+		GenesisHash:         genesisHash,
+		ChainID:             pm.chainID,
 	}
 }
 
@@ -681,124 +1035,16 @@ func (pm *P2PManager) removePeerIfStopped(idx int) bool {
 	p := pm.peerSet[idx]
 	if p.Stopped() {
 		pm.peerSet = append(pm.peerSet[:idx], pm.peerSet[idx+1:]...)
-		delete(pm.peers, p.peerID)
+		// 节点可能已经重新建立了连接，只移除仍然指向断开连接的 Peer 的记录
+		if pm.peers[p.peerID] == p {
+			delete(pm.peers, p.peerID)
+		}
 		return true
 	}
 
 	return false
 }
 
-// UDPGossipBroadcast
-//
-//	@Description: 交易的 UDP 广播方法，随机选取，邻居节点发送交易
-//	@receiver pm
-//	@param tx
-func (pm *P2PManager) UDPGossipBroadcast(tx *common.Transaction) {
-	pm.peerSetLock.RLock()
-	defer pm.peerSetLock.RUnlock()
-
-	txData, err := utils.SerializeTransaction(tx)
-	if err != nil {
-		// todo: 如果交易序列化失败先不处理
-		log.WithError(err).Errorln("Serialize transaction failed.")
-		return
-	}
-
-	if len(pm.peerSet) <= 0 {
-		return
-	}
-
-	for i := 0; i < gossipNodes; i++ {
-		randomIdx := rand.Intn(len(pm.peerSet))
-		p := pm.peerSet[randomIdx]
-
-		if p == nil {
-			continue
-		}
-
-		multAddr, err := multiaddr.NewMultiaddr(p.addr)
-		//log.Infoln(p.peer.Id().String())
-		if err != nil {
-			log.WithError(err).Errorln("Get peer addr failed.")
-			continue
-		}
-
-		ip4Addr, err := multAddr.ValueForProtocol(multiaddr.P_IP4)
-		if err != nil {
-			log.WithError(err).Errorln("Get IPV4 address failed.")
-			continue
-		}
-
-		ip, err := net.ResolveIPAddr("ip", ip4Addr)
-		if err != nil {
-			log.WithError(err).Errorln("Cast string to ip failed.")
-			continue
-		}
-
-		socket, err := net.DialUDP("udp", nil, &net.UDPAddr{
-			IP:   ip.IP,
-			Port: 53333,
-		})
-
-		if err != nil {
-			continue
-		}
-
-		_, err = socket.Write(txData)
-		if err != nil {
-			continue
-		}
-
-		metrics.GossipUDPSendCountInc()
-	}
-}
-
-// TransactionUDP
-//
-//	@Description: 从 UDP 中接收其它节点广播到该节点的交易
-//	@receiver pm
-func (pm *P2PManager) TransactionUDP() {
-	port := config.Int("node.udp", 53333)
-	listen, err := net.ListenUDP("udp", &net.UDPAddr{
-		IP:   net.IPv4(0, 0, 0, 0),
-		Port: port,
-	})
-
-	if err != nil {
-		log.WithError(err).Warningln("Transaction UDP port listen failed.")
-		return
-	}
-
-	defer listen.Close()
-	log.Infoln("Transaction gossip routine start!")
-
-	for {
-		var data [udpBufferSize]byte
-		n, _, err := listen.ReadFromUDP(data[:])
-		if err != nil {
-			log.WithError(err).Warningln("Receive tx failed.")
-			continue
-		}
-
-		tx, err := utils.DeserializeTransaction(data[:n])
-
-		if err != nil {
-			log.WithError(err).Warningln("Deserialize data failed.")
-			continue
-		}
-
-		// todo: 如何验证一个交易是非法的？当前的逻辑下可以使用 Verify 方法来校验
-		txHash := hex.EncodeToString(tx.Body.Hash[:])
-		exists := pm.knownTransaction.Contains(txHash)
-		if !exists {
-			pm.AddTransaction(tx)
-		}
-
-		metrics.GossipUDPRecvCountInc()
-		//}
-	}
-}
-
 func (pm *P2PManager) GetConnectNodeInfo() (string, []string) {
 	pm.peerSetLock.RLock()
 	defer pm.peerSetLock.RUnlock()
@@ -812,7 +1058,17 @@ func (pm *P2PManager) GetConnectNodeInfo() (string, []string) {
 	return pm.id.String(), result
 }
 
-func GetLogicClock() int64 {
-	pm := GetP2PManager()
+// GetLogicClock 获取本地节点的逻辑时钟
+func (pm *P2PManager) GetLogicClock() int64 {
 	return pm.timeSyncer.GetLogicClock()
 }
+
+// TimeSyncStats 获取本地节点的时间同步状态
+func (pm *P2PManager) TimeSyncStats() TimeSyncStats {
+	return pm.timeSyncer.Stats()
+}
+
+// BlockSyncStats 获取本地节点的区块同步状态以及各个节点声明的链头
+func (pm *P2PManager) BlockSyncStats() BlockSyncStats {
+	return pm.blockSyncer.Stats()
+}
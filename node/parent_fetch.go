@@ -0,0 +1,139 @@
+// Package node
+// @Description: 补齐广播区块缺失的父区块。节点短暂断开或者暂停后收到的广播区块可能缺少父区块，这类区块在缓冲区中一直等待父区块，
+// 这里向发送该区块的节点按哈希值逐个请求缺失的祖先区块，最多补齐到落后阈值以内的区块，落后更多时由同步器重新同步区块
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/metrics"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	maxParentDepth     = 2 * core.MaxBufferSize // 单个广播区块最多向前补齐的父区块数量，超过后同步器会重新进入同步
+	maxParentRequests  = 256                    // 记录最近请求过的父区块数量，避免多个广播区块重复请求
+	parentFetchTimeout = 5 * time.Second        // 单个父区块请求的超时时间
+)
+
+// missingParent
+//
+//	@Description: 区块的父区块是否需要补齐，父区块不高于本地数据库高度时已经无法改变规范链，不再补齐
+//	@receiver pm
+//	@param block - 广播或者补齐得到的区块
+//	@return bool - 父区块不在本地并且需要补齐时返回 true
+func (pm *P2PManager) missingParent(block *common.Block) bool {
+	if block.Header.Height-1 <= pm.chain.Height() {
+		return false
+	}
+	return !pm.chain.HasBlock(block.Header.PrevBlockHash)
+}
+
+// fetchMissingParents
+//
+//	@Description: 区块的父区块缺失时，启动协程向发送区块的节点补齐父区块，同一个父区块最近请求过时不再重复请求
+//	@receiver pm
+//	@param block - 已经通过验证并加入缓冲区的区块
+//	@param p - 发送区块的节点，为 nil 或者不支持按哈希值请求时不补齐
+func (pm *P2PManager) fetchMissingParents(block *common.Block, p *Peer) {
+	if p == nil || !p.SupportBlockByHash() || !pm.missingParent(block) {
+		return
+	}
+
+	prevHash := block.PrevBlockHash()
+	if ok, _ := pm.parentRequests.ContainsOrAdd(prevHash, nil); ok {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"height": block.Header.Height - 1,
+		"hash":   prevHash[:8],
+		"peer":   p.peerID,
+	}).Infoln("Block parent missing, fetch from peer.")
+
+	metrics.RoutineCreateCounterObserve(31)
+	go pm.fetchParents(block, p)
+}
+
+// fetchParents
+//
+//	@Description: 从区块开始逐个向前请求父区块，父区块通过验证后加入缓冲区，直到父区块已经在本地或者达到 maxParentDepth；
+//	请求失败时移除正在请求的父区块的记录，之后收到的区块可以再次触发补齐
+//	@receiver pm
+//	@param block - 缺少父区块的区块
+//	@param p - 请求的节点
+func (pm *P2PManager) fetchParents(block *common.Block, p *Peer) {
+	for depth := 1; ; depth++ {
+		hash := common.Hash(block.Header.PrevBlockHash)
+
+		ctx, cancel := context.WithTimeout(pm.ctx, parentFetchTimeout)
+		parent, err := requestBlockByHash(ctx, hash, p)
+		cancel()
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"peer":  p.peerID,
+				"hash":  hex.EncodeToString(hash[:])[:8],
+				"error": err,
+			}).Debugln("Fetch parent block failed.")
+
+			if err != context.Canceled && err != context.DeadlineExceeded && err != errPeerStopped {
+				pm.penalizePeer(p.peerID, offenseMalformedMessage)
+			}
+			pm.parentRequests.Remove(block.PrevBlockHash())
+			return
+		}
+
+		// 对端也没有该区块，等待其他节点广播的区块再次触发补齐
+		if parent == nil || parent.Header.Height != block.Header.Height-1 || !pm.acceptParentBlock(parent, p) {
+			pm.parentRequests.Remove(block.PrevBlockHash())
+			return
+		}
+		metrics.ParentFetchInc()
+
+		if depth >= maxParentDepth || !pm.missingParent(parent) {
+			return
+		}
+		pm.parentRequests.Add(parent.PrevBlockHash(), nil)
+		block = parent
+	}
+}
+
+// acceptParentBlock
+//
+//	@Description: 验证补齐得到的父区块的签名、时间戳和 VRF 证明，通过后加入缓冲区，验证失败时扣除对端的分数
+//	@receiver pm
+//	@param block - 对端响应的父区块
+//	@param p - 响应的节点
+//	@return bool - 区块是否通过验证
+func (pm *P2PManager) acceptParentBlock(block *common.Block, p *Peer) bool {
+	if !core.VerifyBlockSignature(block) {
+		log.WithField("height", block.Header.Height).Warning("Parent block signature verify failed.")
+		pm.penalizePeer(p.peerID, offenseInvalidSignature)
+		return false
+	}
+
+	if !verifyBlockTimestamp(pm, block, false) {
+		return false
+	}
+
+	if !verifyBlockVRF(pm.chain, block) {
+		log.WithField("height", block.Header.Height).Warning("Parent block VRF verify failed.")
+		pm.penalizePeer(p.peerID, offenseInvalidVRF)
+		return false
+	}
+
+	strHash := block.BlockHash()
+	pm.markBlock(strHash)
+	p.MarkBlock(strHash)
+
+	log.WithFields(log.Fields{
+		"height": block.Header.Height,
+		"hash":   strHash[:8],
+	}).Debugln("Append fetched parent block.")
+	pm.chain.AppendBlockTask(block)
+	return true
+}
@@ -1,6 +1,8 @@
 package node
 
 import (
+	"bufio"
+	"context"
 	"encoding/hex"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/core"
@@ -10,7 +12,9 @@ import (
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,6 +26,15 @@ const (
 	maxQueuedBlocks    = 4
 	maxQueuedBlockAnns = 4
 	messageQueueCap    = 5000
+
+	defaultRequestTimeout = 10 * time.Second       // 请求没有设置超时时间时使用的默认超时时间
+	statusInterval        = 490 * time.Millisecond // 同步期间请求对端状态的间隔
+	syncedStatusInterval  = 3 * time.Second        // 同步完成后请求对端状态的间隔，需要小于 peerHeadTTL
+)
+
+var (
+	errRequestNotSupported = errors.New("peer does not support request id")
+	errPeerStopped         = errors.New("peer stopped")
 )
 
 type PeerConfig struct {
@@ -29,6 +42,9 @@ type PeerConfig struct {
 	txPool         *core.TxPool
 	handler        *P2PManager
 	remoteMultAddr string
+	status         *p2p.HandshakeMsg // 对端的握手消息
+	rw             *bufio.ReadWriter // 握手时使用的 ReadWriter
+	outbound       bool              // 数据流是否由本地节点发起
 }
 
 type Peer struct {
@@ -51,6 +67,14 @@ type Peer struct {
 
 	msgQueue chan *p2p.Message
 
+	status   *p2p.HandshakeMsg // 对端握手时的状态
+	version  uint32            // 与对端协商的协议版本
+	outbound bool              // 数据流是否由本地节点发起，双方同时建立连接时用于选择保留的数据流
+
+	requestID   atomic.Uint64                // 最近一次请求使用的请求 ID
+	pending     map[uint64]chan *p2p.Message // 请求 ID -> 等待响应的 channel
+	pendingLock sync.Mutex                   // 等待响应的请求表的锁
+
 	lock       sync.RWMutex
 	markSynced bool
 	// todo: 这里是传值还是需要传指针用于构建 channel？
@@ -69,7 +93,14 @@ func NewPeer(peerId peer.ID, s *network.Stream, config PeerConfig) (*Peer, error
 	msgQueue := make(chan *p2p.Message, messageQueueCap)
 
 	// 创建一个 p2p.Peer 实例，p2p.Peer 用于发送消息，并接收到的消息传递到 node.Peer
-	pp, err := p2p.NewPeer(peerId, s, msgQueue)
+	var pp *p2p.Peer
+	var err error
+	if config.rw != nil {
+		pp, err = p2p.NewPeerWithReadWriter(peerId, s, config.rw,
+			frameConfig(config.status), msgQueue)
+	} else {
+		pp, err = p2p.NewPeer(peerId, s, msgQueue)
+	}
 	if err != nil {
 		log.WithField("error", err).Errorln("Create p2p peer failed.")
 		return nil, err
@@ -102,9 +133,16 @@ func NewPeer(peerId peer.ID, s *network.Stream, config PeerConfig) (*Peer, error
 		txBroadcast:     make(chan *common.Transaction, maxQueuedTxs),
 		txAnnounce:      make(chan common.Hash, maxQueuedTxAnns),
 		msgQueue:        msgQueue,
+		status:          config.status,
+		outbound:        config.outbound,
+		pending:         make(map[uint64]chan *p2p.Message),
 		markSynced:      false,
 	}
 
+	if config.status != nil {
+		p.version = negotiatedVersion(config.status.ProtocolVersion)
+	}
+
 	metrics.RoutineCreateCounterObserve(25)
 	//go p.broadcastBlock()
 	//go p.broadcastBlockHash()
@@ -169,14 +207,174 @@ func (p *Peer) Stopped() bool {
 	return p.peer.Stopped()
 }
 
+// Close 断开与对端的连接
+func (p *Peer) Close() {
+	p.peer.Close()
+}
+
+// Status 对端在握手时发送的状态
+func (p *Peer) Status() *p2p.HandshakeMsg {
+	return p.status
+}
+
+// Version 与对端协商的协议版本
+func (p *Peer) Version() uint32 {
+	return p.version
+}
+
+// SupportRequest 对端是否支持带有请求 ID 的请求/响应
+func (p *Peer) SupportRequest() bool {
+	return p.version >= RequestProtocolVersion
+}
+
+// SupportBlockRange 对端是否支持按高度区间批量请求区块
+func (p *Peer) SupportBlockRange() bool {
+	return p.SupportRequest() && p.status != nil &&
+		p.status.Capabilities&CapabilityBlockRange != 0
+}
+
+// SupportHeaderSync 对端是否支持按高度区间请求区块头
+func (p *Peer) SupportHeaderSync() bool {
+	return p.SupportRequest() && p.status != nil &&
+		p.status.Capabilities&CapabilityHeaderSync != 0
+}
+
+// SupportBlockByHash 对端是否支持按哈希值请求区块
+func (p *Peer) SupportBlockByHash() bool {
+	return p.SupportRequest() && p.status != nil &&
+		p.status.Capabilities&CapabilityBlockByHash != 0
+}
+
+// FullNode 对端是否保存完整的区块，没有握手信息的旧版本节点都是完整节点
+func (p *Peer) FullNode() bool {
+	return p.status == nil || p.status.Capabilities&CapabilityFullNode != 0
+}
+
+// SupportProof 对端是否支持为轻节点生成 Merkle 证明
+func (p *Peer) SupportProof() bool {
+	return p.SupportRequest() && p.status != nil &&
+		p.status.Capabilities&CapabilityProof != 0
+}
+
+// SupportDataProof 对端是否支持为轻节点生成 data# 数据的证明
+func (p *Peer) SupportDataProof() bool {
+	return p.SupportRequest() && p.status != nil &&
+		p.status.Capabilities&CapabilityDataProof != 0
+}
+
+// SupportTxGossip 对端是否接收通过数据流批量广播的交易
+func (p *Peer) SupportTxGossip() bool {
+	return p.status != nil && p.status.Capabilities&CapabilityTxGossip != 0
+}
+
+// Request
+//
+//	@Description: 向对端发送带有请求 ID 的请求，并等待对应的响应，ctx 没有设置超时时间时使用默认的超时时间
+//	@receiver p
+//	@param ctx - 请求上下文，用于超时和取消
+//	@param code - 请求的消息类型
+//	@param payload - 请求的数据
+//	@return *p2p.Message - 对端的响应消息
+//	@return error - 对端不支持、连接断开或者超时时返回错误
+func (p *Peer) Request(ctx context.Context, code p2p.StatusCode,
+	payload []byte) (*p2p.Message, error) {
+	if !p.SupportRequest() {
+		return nil, errRequestNotSupported
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultRequestTimeout)
+		defer cancel()
+	}
+
+	id := p.requestID.Add(1)
+	reply := make(chan *p2p.Message, 1)
+
+	p.pendingLock.Lock()
+	p.pending[id] = reply
+	p.pendingLock.Unlock()
+
+	defer func() {
+		p.pendingLock.Lock()
+		delete(p.pending, id)
+		p.pendingLock.Unlock()
+	}()
+
+	p.peer.SendMessage(&p2p.Message{
+		Code:      code,
+		Payload:   payload,
+		RequestID: id,
+	})
+
+	// 连接断开时不再等待
+	select {
+	case msg := <-reply:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.peer.Done():
+		return nil, errPeerStopped
+	}
+}
+
+// Reply
+//
+//	@Description: 响应对端的请求，响应中携带请求的 ID，请求中没有 ID 时与普通消息一致
+//	@receiver p
+//	@param req - 对端的请求消息
+//	@param code - 响应的消息类型
+//	@param payload - 响应的数据
+func (p *Peer) Reply(req *p2p.Message, code p2p.StatusCode, payload []byte) {
+	var replyTo uint64
+	if req != nil {
+		replyTo = req.RequestID
+	}
+
+	p.peer.SendMessage(&p2p.Message{
+		Code:    code,
+		Payload: payload,
+		ReplyTo: replyTo,
+	})
+}
+
+// deliverReply
+//
+//	@Description: 将响应消息交给等待的请求
+//	@receiver p
+//	@param msg - 响应消息
+//	@return bool - 是否有等待该响应的请求
+func (p *Peer) deliverReply(msg *p2p.Message) bool {
+	p.pendingLock.Lock()
+	reply, ok := p.pending[msg.ReplyTo]
+	delete(p.pending, msg.ReplyTo)
+	p.pendingLock.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	reply <- msg
+	return true
+}
+
+// Handle 处理对端发来的消息，连接断开后退出
 func (p *Peer) Handle() {
 	for {
-		if p.peer.Stopped() {
-			break
-		}
-
 		select {
+		case <-p.peer.Done():
+			return
 		case msg := <-p.msgQueue:
+			// 被过滤的消息直接丢弃，模拟网络中的丢包
+			if !p.handler.acceptMessage(p.peerID) {
+				continue
+			}
+
+			// 响应消息交给等待的请求处理，请求已经超时或者没有对应的请求时仍然交给 handler 处理
+			if msg.ReplyTo != 0 && p.deliverReply(msg) {
+				continue
+			}
+
 			handle := handlerMap[msg.Code]
 
 			if handle != nil {
@@ -190,25 +388,28 @@ func (p *Peer) Handle() {
 
 // sendStatus
 //
-//	@Description: 向其它节点请求同步信息
+//	@Description: 向其它节点请求同步信息，同步完成后降低请求频率，继续跟踪对端的链头，本地落后时重新进入同步
 //	@receiver p
 func (p *Peer) sendStatus() {
-	ticker := time.NewTicker(490 * time.Millisecond)
+	interval := statusInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
-		if p.peer.Stopped() {
-			break
-		}
-
 		select {
+		case <-p.peer.Done():
+			return
 		case <-ticker.C:
 			height := p.chain.Height()
 			requestSyncStatusMsg(height, p)
 		}
 
-		// fixed[230725]：在同步完成后不再请求对端高度
-		status := p.handler.blockSyncer.getStatus()
-		if status == synced {
-			break
+		next := statusInterval
+		if p.handler.blockSyncer.getStatus() == synced {
+			next = syncedStatusInterval
+		}
+		if next != interval {
+			interval = next
+			ticker.Reset(interval)
 		}
 	}
 }
@@ -0,0 +1,249 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/libp2p/go-libp2p/core/network"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"testing"
+	"time"
+)
+
+// testRequestPeer 通过 mocknet 连接一个支持请求/响应的对端，返回本地的 Peer、对端的 p2p.Peer 以及对端收到的消息
+func testRequestPeer(t *testing.T, pm *P2PManager) (*Peer, *p2p.Peer, chan *p2p.Message) {
+	mn := mocknet.New()
+	t.Cleanup(func() { _ = mn.Close() })
+
+	local, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan *p2p.Message, messageQueueCap)
+	remotePeers := make(chan *p2p.Peer, 1)
+	remote.SetStreamHandler(ProtocolId, func(s network.Stream) {
+		rp, err := p2p.NewPeer(local.ID(), &s, received)
+		if err == nil {
+			t.Cleanup(rp.Close)
+			remotePeers <- rp
+		}
+	})
+
+	s, err := local.NewStream(context.Background(), remote.ID(), ProtocolId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPeer(remote.ID(), &s, PeerConfig{
+		chain:   pm.chain,
+		handler: pm,
+		status:  &p2p.HandshakeMsg{ProtocolVersion: RequestProtocolVersion},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+
+	select {
+	case rp := <-remotePeers:
+		return p, rp, received
+	case <-time.After(5 * time.Second):
+		t.Fatal("remote peer not created")
+	}
+	return nil, nil, nil
+}
+
+// testReceiveRequest 等待对端收到下一条 code 类型的请求，跳过其他类型的消息
+func testReceiveRequest(t *testing.T, received chan *p2p.Message, code p2p.StatusCode) *p2p.Message {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-received:
+			if msg.Code != code {
+				continue
+			}
+			if msg.RequestID == 0 {
+				t.Fatal("request sent without request id")
+			}
+			return msg
+		case <-timeout:
+			t.Fatalf("request %d not received", code)
+		}
+	}
+}
+
+// testRequestResult 在后台发出的请求的结果
+type testRequestResult struct {
+	reply *p2p.Message
+	err   error
+}
+
+// testRequestAsync 在后台发出请求，测试协程负责读取请求并响应
+func testRequestAsync(ctx context.Context, p *Peer, code p2p.StatusCode, payload []byte) chan testRequestResult {
+	result := make(chan testRequestResult, 1)
+	go func() {
+		reply, err := p.Request(ctx, code, payload)
+		result <- testRequestResult{reply: reply, err: err}
+	}()
+	return result
+}
+
+func TestPeerRequestReply(t *testing.T) {
+	pm := testTxGossipManager(t)
+	p, rp, received := testRequestPeer(t, pm)
+
+	result := testRequestAsync(context.Background(), p, p2p.StatusCodeGetBlockBodiesMsg, []byte("request"))
+	req := testReceiveRequest(t, received, p2p.StatusCodeGetBlockBodiesMsg)
+	rp.SendMessage(&p2p.Message{
+		Code:    p2p.StatusCodeBlockBodiesMsg,
+		Payload: []byte("reply"),
+		ReplyTo: req.RequestID,
+	})
+
+	res := <-result
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	reply := res.reply
+	if reply.Code != p2p.StatusCodeBlockBodiesMsg || !bytes.Equal(reply.Payload, []byte("reply")) {
+		t.Fatalf("unexpected reply %d", reply.Code)
+	}
+	if len(p.pending) != 0 {
+		t.Fatalf("%d pending requests left", len(p.pending))
+	}
+
+	// 响应携带请求的 ID
+	req = &p2p.Message{Code: p2p.StatusCodeGetBlockBodiesMsg, RequestID: 42}
+	p.Reply(req, p2p.StatusCodeBlockBodiesMsg, []byte("reply"))
+	if p.deliverReply(&p2p.Message{ReplyTo: 42}) {
+		t.Fatal("reply delivered without pending request")
+	}
+}
+
+func TestPeerRequestTimeout(t *testing.T) {
+	pm := testTxGossipManager(t)
+	p, rp, received := testRequestPeer(t, pm)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := p.Request(ctx, p2p.StatusCodeGetBlockBodiesMsg, []byte("first")); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(p.pending) != 0 {
+		t.Fatal("timed out request still pending")
+	}
+
+	// 超时后到达的响应不会交给之后的请求
+	first := testReceiveRequest(t, received, p2p.StatusCodeGetBlockBodiesMsg)
+	if p.deliverReply(&p2p.Message{ReplyTo: first.RequestID}) {
+		t.Fatal("late reply delivered")
+	}
+	rp.SendMessage(&p2p.Message{
+		Code:    p2p.StatusCodeBlockBodiesMsg,
+		Payload: []byte("late"),
+		ReplyTo: first.RequestID,
+	})
+
+	result := testRequestAsync(context.Background(), p, p2p.StatusCodeGetBlockBodiesMsg, []byte("second"))
+	req := testReceiveRequest(t, received, p2p.StatusCodeGetBlockBodiesMsg)
+	rp.SendMessage(&p2p.Message{
+		Code:    p2p.StatusCodeBlockBodiesMsg,
+		Payload: []byte("second"),
+		ReplyTo: req.RequestID,
+	})
+
+	res := <-result
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if reply := res.reply; !bytes.Equal(reply.Payload, []byte("second")) {
+		t.Fatalf("request received reply %q", reply.Payload)
+	}
+}
+
+func TestPeerRequestStopped(t *testing.T) {
+	pm := testTxGossipManager(t)
+	p, _, received := testRequestPeer(t, pm)
+
+	// 连接断开时不等待超时
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result := testRequestAsync(ctx, p, p2p.StatusCodeGetBlockBodiesMsg, []byte("request"))
+	testReceiveRequest(t, received, p2p.StatusCodeGetBlockBodiesMsg)
+	p.Close()
+	if res := <-result; res.err != errPeerStopped {
+		t.Fatalf("unexpected error %v", res.err)
+	}
+
+	// 旧版本节点不支持请求/响应
+	legacy, _ := testTxGossipPeer(t, pm)
+	if _, err := legacy.Request(ctx, p2p.StatusCodeGetBlockBodiesMsg, nil); err != errRequestNotSupported {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestTimeSyncRequest(t *testing.T) {
+	pm := testTxGossipManager(t)
+	ts := pm.timeSyncer
+	p, rp, received := testRequestPeer(t, pm)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		ts.syncPeer(ctx, p)
+		close(done)
+	}()
+
+	// 对端的时钟快 500ms，并且在响应中伪造请求时间
+	req := testReceiveRequest(t, received, p2p.StatusCodeTimeSyncReq)
+	remote := time.Now().UnixMilli() + 500
+	payload, err := utils.SerializeTimeSyncMsg(&p2p.TimeSyncMsg{
+		ReqTime:    remote,
+		RecReqTime: remote,
+		RspTime:    remote,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp.SendMessage(&p2p.Message{
+		Code:    p2p.StatusCodeTimeSyncRsp,
+		Payload: payload,
+		ReplyTo: req.RequestID,
+	})
+	<-done
+
+	if len(ts.samples) != 1 {
+		t.Fatalf("%d time sync samples", len(ts.samples))
+	}
+	sample := ts.samples[0]
+	if sample.offset < 400 || sample.offset > 600 || sample.rtt > 1000 {
+		t.Fatalf("sample uses remote request time, offset %d, rtt %d", sample.offset, sample.rtt)
+	}
+
+	// 没有对应请求的响应不会加入样本
+	payload, _ = utils.SerializeTimeSyncMsg(&p2p.TimeSyncMsg{})
+	rp.SendMessage(&p2p.Message{Code: p2p.StatusCodeTimeSyncRsp, Payload: payload, ReplyTo: 1000})
+	rp.Send(p2p.StatusCodeTimeSyncRsp, payload)
+	time.Sleep(100 * time.Millisecond)
+	if len(ts.samples) != 1 {
+		t.Fatal("unsolicited time sync respond accepted")
+	}
+
+	// 旧版本节点不参与时间同步
+	legacy, _ := testTxGossipPeer(t, pm)
+	pm.peerSet = append(pm.peerSet, p)
+	for _, selected := range ts.selectPeers(len(pm.peerSet)) {
+		if selected == legacy {
+			t.Fatal("legacy peer selected for time sync")
+		}
+	}
+}
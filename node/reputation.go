@@ -0,0 +1,477 @@
+// Package node
+// @Description: 节点信誉，对发送错误数据的节点按照错误类型扣分，分数低于阈值时断开连接并在一段时间内禁止连接，
+// 禁止连接的记录保存在数据库中，重启后仍然有效；分数同时作为 gossipsub 的应用层分数
+package node
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/chain-lab/go-norn/interfaces"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/gookit/config/v2"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// offense 节点的错误行为类型
+type offense uint8
+
+const (
+	offenseMalformedMessage offense = iota // 消息无法反序列化
+	offenseInvalidSignature                // 区块签名验证失败
+	offenseInvalidVRF                      // 区块 VRF 验证失败
+	offenseSyncTimeout                     // 区块同步请求没有响应
+	offenseInvalidHeader                   // 同步的区块头验证失败，或者区块与已验证的区块头不一致
+	offenseInvalidProof                    // 响应轻节点的交易或数据无法通过 Merkle 证明验证
+)
+
+// offensePenalty 每种错误行为扣除的分数
+var offensePenalty = map[offense]float64{
+	offenseMalformedMessage: 20,
+	offenseInvalidSignature: 50,
+	offenseInvalidVRF:       50,
+	offenseSyncTimeout:      5,
+	offenseInvalidHeader:    50,
+	offenseInvalidProof:     50,
+}
+
+func (o offense) String() string {
+	switch o {
+	case offenseMalformedMessage:
+		return "malformed message"
+	case offenseInvalidSignature:
+		return "invalid signature"
+	case offenseInvalidVRF:
+		return "invalid vrf"
+	case offenseSyncTimeout:
+		return "sync timeout"
+	case offenseInvalidHeader:
+		return "invalid header"
+	case offenseInvalidProof:
+		return "invalid proof"
+	}
+	return "unknown"
+}
+
+const (
+	maxPeerScore       = 100.0            // 节点分数上限
+	minPeerScore       = -100.0           // 节点分数下限
+	validBlockReward   = 1.0              // 发送合法区块的奖励分数
+	scoreHalfLife      = 10 * time.Minute // 分数向 0 衰减的半衰期
+	defaultBanScore    = -100             // 默认的禁止连接分数阈值
+	defaultBanDuration = 3600             // 默认的禁止连接时长，单位为秒
+	txRateFactor       = 2                // 未配置速率限制时，每个节点每秒允许广播的交易数量为链每秒打包容量的倍数
+)
+
+// peerScore 节点分数以及最后一次更新的时间，用于计算衰减
+type peerScore struct {
+	score   float64
+	updated time.Time
+}
+
+// PeerReputation 对外提供的节点信誉信息
+type PeerReputation struct {
+	ID          peer.ID   // 节点 ID
+	Score       float64   // 当前分数
+	BannedUntil time.Time // 禁止连接的截止时间，未被禁止时为零值
+}
+
+type Reputation struct {
+	scores      map[peer.ID]*peerScore // 节点 ID -> 节点分数
+	bans        map[peer.ID]time.Time  // 节点 ID -> 禁止连接的截止时间
+	threshold   float64                // 禁止连接的分数阈值
+	banDuration time.Duration          // 分数低于阈值时禁止连接的时长
+
+	db   interfaces.DBInterface // 用于持久化禁止连接记录的数据库，为空时不持久化
+	lock sync.Mutex
+}
+
+// NewReputation
+//
+//	@Description: 创建节点信誉管理实例，从数据库中加载未过期的禁止连接记录
+//	@param db - 数据库实例，可以为空
+//	@return *Reputation - 节点信誉管理实例
+func NewReputation(db interfaces.DBInterface) *Reputation {
+	r := &Reputation{
+		scores:      make(map[peer.ID]*peerScore),
+		bans:        make(map[peer.ID]time.Time),
+		threshold:   float64(config.Int("p2p.ban_threshold", defaultBanScore)),
+		banDuration: time.Duration(config.Int("p2p.ban_duration", defaultBanDuration)) * time.Second,
+		db:          db,
+	}
+
+	r.load()
+	return r
+}
+
+// Score
+//
+//	@Description: 获取节点的当前分数，未记录的节点分数为 0
+//	@receiver r
+//	@param id - 节点 ID
+//	@return float64 - 节点分数
+func (r *Reputation) Score(id peer.ID) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// gossipsub 会查询所有节点的分数，未记录的节点不创建分数记录
+	if _, ok := r.scores[id]; !ok {
+		return 0
+	}
+	return r.decayed(id).score
+}
+
+// Penalize
+//
+//	@Description: 按照错误行为类型扣除节点分数，分数低于阈值时禁止节点连接
+//	@receiver r
+//	@param id - 节点 ID
+//	@param o - 错误行为类型
+//	@return float64 - 扣分后的分数
+//	@return bool - 节点是否因为本次扣分被禁止连接
+func (r *Reputation) Penalize(id peer.ID, o offense) (float64, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s := r.decayed(id)
+	s.score = math.Max(minPeerScore, s.score-offensePenalty[o])
+
+	if s.score > r.threshold {
+		return s.score, false
+	}
+
+	if _, ok := r.bans[id]; ok {
+		return s.score, false
+	}
+
+	r.bans[id] = time.Now().Add(r.banDuration)
+	r.save()
+	return s.score, true
+}
+
+// Reward
+//
+//	@Description: 节点发送了合法的区块，增加少量分数
+//	@receiver r
+//	@param id - 节点 ID
+func (r *Reputation) Reward(id peer.ID) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s := r.decayed(id)
+	s.score = math.Min(maxPeerScore, s.score+validBlockReward)
+}
+
+// Ban
+//
+//	@Description: 禁止节点在一段时间内连接，已有更晚的截止时间时保持不变
+//	@receiver r
+//	@param id - 节点 ID
+//	@param duration - 禁止连接的时长
+func (r *Reputation) Ban(id peer.ID, duration time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	until := time.Now().Add(duration)
+	if current, ok := r.bans[id]; ok && current.After(until) {
+		return
+	}
+
+	r.bans[id] = until
+	r.save()
+}
+
+// IsBanned
+//
+//	@Description: 检查节点是否处于禁止连接的状态，过期的记录会被删除，同时重置节点的分数
+//	@receiver r
+//	@param id - 节点 ID
+//	@return bool - 是否禁止连接
+func (r *Reputation) IsBanned(id peer.ID) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	until, ok := r.bans[id]
+	if !ok {
+		return false
+	}
+
+	if time.Now().After(until) {
+		delete(r.bans, id)
+		delete(r.scores, id)
+		r.save()
+		return false
+	}
+
+	return true
+}
+
+// Reputations
+//
+//	@Description: 获取所有记录了分数或者被禁止连接的节点信息，按照分数从低到高排序
+//	@receiver r
+//	@return []PeerReputation - 节点信誉列表
+func (r *Reputation) Reputations() []PeerReputation {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ids := make(map[peer.ID]struct{}, len(r.scores)+len(r.bans))
+	for id := range r.scores {
+		ids[id] = struct{}{}
+	}
+	for id := range r.bans {
+		ids[id] = struct{}{}
+	}
+
+	result := make([]PeerReputation, 0, len(ids))
+	now := time.Now()
+	for id := range ids {
+		item := PeerReputation{
+			ID:    id,
+			Score: r.decayed(id).score,
+		}
+		if until, ok := r.bans[id]; ok && now.Before(until) {
+			item.BannedUntil = until
+		}
+		result = append(result, item)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Score < result[j].Score
+	})
+	return result
+}
+
+// decayed
+//
+//	@Description: 获取节点分数并按照半衰期向 0 衰减，调用时需要持有锁
+//	@receiver r
+//	@param id - 节点 ID
+//	@return *peerScore - 衰减后的节点分数
+func (r *Reputation) decayed(id peer.ID) *peerScore {
+	now := time.Now()
+	s, ok := r.scores[id]
+	if !ok {
+		s = &peerScore{updated: now}
+		r.scores[id] = s
+		return s
+	}
+
+	elapsed := now.Sub(s.updated)
+	s.score *= math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+	s.updated = now
+	return s
+}
+
+// save
+//
+//	@Description: 将禁止连接记录写入数据库，调用时需要持有锁
+//	@receiver r
+func (r *Reputation) save() {
+	if r.db == nil {
+		return
+	}
+
+	record := make(map[string]int64, len(r.bans))
+	for id, until := range r.bans {
+		record[hex.EncodeToString([]byte(id))] = until.UnixMilli()
+	}
+
+	value, err := json.Marshal(record)
+	if err != nil {
+		log.WithError(err).Warningln("Encode peer bans failed.")
+		return
+	}
+
+	if err = r.db.Insert(utils.PeerBansDBKey(), value); err != nil {
+		log.WithError(err).Warningln("Save peer bans failed.")
+	}
+}
+
+// load
+//
+//	@Description: 从数据库中加载禁止连接记录，跳过已经过期的记录
+//	@receiver r
+func (r *Reputation) load() {
+	if r.db == nil {
+		return
+	}
+
+	value, err := r.db.Get(utils.PeerBansDBKey())
+	if err != nil || len(value) == 0 {
+		return
+	}
+
+	record := make(map[string]int64)
+	if err = json.Unmarshal(value, &record); err != nil {
+		log.WithError(err).Warningln("Decode peer bans failed.")
+		return
+	}
+
+	now := time.Now()
+	for strID, until := range record {
+		id, err := hex.DecodeString(strID)
+		if err != nil {
+			continue
+		}
+
+		expire := time.UnixMilli(until)
+		if now.Before(expire) {
+			r.bans[peer.ID(id)] = expire
+		}
+	}
+
+	log.Infof("Load %d banned peers.", len(r.bans))
+}
+
+// penalizePeer
+//
+//	@Description: 按照错误行为扣除节点分数，分数低于阈值时断开并禁止节点连接
+//	@receiver pm
+//	@param id - 节点 ID
+//	@param o - 错误行为类型
+func (pm *P2PManager) penalizePeer(id peer.ID, o offense) {
+	if id == "" {
+		return
+	}
+
+	score, banned := pm.reputation.Penalize(id, o)
+	log.WithFields(log.Fields{
+		"peer":    id,
+		"offense": o,
+		"score":   score,
+	}).Debugln("Penalize peer.")
+
+	if banned {
+		log.WithFields(log.Fields{
+			"peer":     id,
+			"score":    score,
+			"duration": pm.reputation.banDuration,
+		}).Warningln("Peer score below threshold, disconnect and ban.")
+		pm.disconnectPeer(id)
+	}
+}
+
+// rewardPeer
+//
+//	@Description: 节点发送了合法的区块，增加节点分数
+//	@receiver pm
+//	@param id - 节点 ID
+func (pm *P2PManager) rewardPeer(id peer.ID) {
+	if id == "" {
+		return
+	}
+
+	pm.reputation.Reward(id)
+}
+
+// disconnectPeer
+//
+//	@Description: 关闭与节点的数据流以及底层连接
+//	@receiver pm
+//	@param id - 节点 ID
+func (pm *P2PManager) disconnectPeer(id peer.ID) {
+	pm.peerSetLock.RLock()
+	p, ok := pm.peers[id]
+	pm.peerSetLock.RUnlock()
+
+	if ok {
+		p.Close()
+	}
+
+	if pm.host != nil {
+		_ = pm.host.Network().ClosePeer(id)
+	}
+}
+
+// PeerReputations
+//
+//	@Description: 获取节点信誉列表，用于 RPC 查询
+//	@receiver pm
+//	@return []PeerReputation - 节点信誉列表
+func (pm *P2PManager) PeerReputations() []PeerReputation {
+	return pm.reputation.Reputations()
+}
+
+// gossipScoreParams
+//
+//	@Description: gossipsub 的节点评分参数，只使用应用层分数，即节点信誉中的分数
+//	@receiver pm
+//	@return *pubsub.PeerScoreParams - 评分参数
+//	@return *pubsub.PeerScoreThresholds - 评分阈值，按照禁止连接阈值的比例设置
+func (pm *P2PManager) gossipScoreParams() (*pubsub.PeerScoreParams, *pubsub.PeerScoreThresholds) {
+	params := &pubsub.PeerScoreParams{
+		Topics:            make(map[string]*pubsub.TopicScoreParams),
+		AppSpecificScore:  pm.reputation.Score,
+		AppSpecificWeight: 1,
+		DecayInterval:     time.Second,
+		DecayToZero:       0.01,
+	}
+
+	threshold := math.Min(pm.reputation.threshold, -1)
+	thresholds := &pubsub.PeerScoreThresholds{
+		GossipThreshold:   threshold * 0.5,  // 低于该分数时不再与节点交换 gossip 元数据
+		PublishThreshold:  threshold * 0.75, // 低于该分数时不再向节点发布消息
+		GraylistThreshold: threshold * 0.9,  // 低于该分数时忽略节点的所有消息
+	}
+
+	return params, thresholds
+}
+
+// txLimiter 按照来源节点统计每秒收到的广播交易数量
+type txLimiter struct {
+	limit    int             // 每个节点每秒允许的交易数量，为 0 时按照链的打包容量计算
+	capacity func() int64    // 链每秒最多打包的交易数量
+	counts   map[peer.ID]int // 节点 ID -> 当前窗口内的交易数量
+	window   time.Time       // 当前统计窗口的开始时间
+	lock     sync.Mutex
+}
+
+// newTxLimiter
+//
+//	@Description: 创建交易广播的速率限制，配置项 p2p.tx_rate 未设置时限制为链每秒打包容量的 txRateFactor 倍，
+//	转发所有交易的正常节点在交易池积压时也不会超出限制
+//	@param capacity - 获取链每秒最多打包的交易数量
+//	@return *txLimiter
+func newTxLimiter(capacity func() int64) *txLimiter {
+	return &txLimiter{
+		limit:    config.Int("p2p.tx_rate", 0),
+		capacity: capacity,
+		counts:   make(map[peer.ID]int),
+		window:   time.Now(),
+	}
+}
+
+// rate 每个节点每秒允许的交易数量
+func (l *txLimiter) rate() int {
+	if l.limit > 0 || l.capacity == nil {
+		return l.limit
+	}
+	return txRateFactor * int(l.capacity())
+}
+
+// allow
+//
+//	@Description: 记录来自节点 id 的 n 笔交易
+//	@receiver l
+//	@param id - 来源节点
+//	@param n - 交易数量
+//	@return bool - 是否在速率限制内，超出限制的交易直接丢弃，不扣除节点的分数
+func (l *txLimiter) allow(id peer.ID, n int) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.window) >= time.Second {
+		l.counts = make(map[peer.ID]int)
+		l.window = now
+	}
+
+	l.counts[id] += n
+	return l.counts[id] <= l.rate()
+}
@@ -0,0 +1,114 @@
+package node
+
+import (
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"testing"
+	"time"
+)
+
+type memoryDB map[string][]byte
+
+func (db memoryDB) Get(key []byte) ([]byte, error) {
+	value, ok := db[string(key)]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return value, nil
+}
+
+func (db memoryDB) Insert(key []byte, value []byte) error {
+	db[string(key)] = value
+	return nil
+}
+
+func (db memoryDB) Remove(key []byte) error {
+	delete(db, string(key))
+	return nil
+}
+
+func (db memoryDB) BatchInsert(key [][]byte, value [][]byte) error {
+	for idx := range key {
+		db[string(key[idx])] = value[idx]
+	}
+	return nil
+}
+
+func (db memoryDB) BatchDelete(key [][]byte) error {
+	for idx := range key {
+		delete(db, string(key[idx]))
+	}
+	return nil
+}
+
+func TestReputationBan(t *testing.T) {
+	db := memoryDB{}
+	r := NewReputation(db)
+	id := peer.ID("misbehaving-peer")
+
+	// 两次签名错误不足以达到禁止连接的阈值
+	r.Penalize(id, offenseInvalidSignature)
+	if _, banned := r.Penalize(id, offenseMalformedMessage); banned {
+		t.Fatal("Peer banned before reaching threshold.")
+	}
+
+	if _, banned := r.Penalize(id, offenseInvalidSignature); !banned {
+		t.Fatal("Peer not banned below threshold.")
+	}
+
+	if r.Score(id) >= 0 {
+		t.Fatal("Peer score not decreased after offense.")
+	}
+
+	// 重启后从数据库恢复禁止连接记录
+	restored := NewReputation(db)
+	if !restored.IsBanned(id) {
+		t.Fatal("Peer ban not restored from database.")
+	}
+
+	if restored.IsBanned(peer.ID("honest-peer")) {
+		t.Fatal("Unknown peer is banned.")
+	}
+}
+
+func TestReputationBanExpire(t *testing.T) {
+	r := NewReputation(memoryDB{})
+	id := peer.ID("incompatible-peer")
+
+	r.Ban(id, -time.Second)
+	if r.IsBanned(id) {
+		t.Fatal("Expired ban still active.")
+	}
+}
+
+func TestTxLimiter(t *testing.T) {
+	l := &txLimiter{
+		limit:  10,
+		counts: make(map[peer.ID]int),
+		window: time.Now(),
+	}
+	id := peer.ID("flooding-peer")
+
+	if !l.allow(id, 10) {
+		t.Fatal("Transactions within limit rejected.")
+	}
+	if l.allow(id, 1) {
+		t.Fatal("Transactions over limit accepted.")
+	}
+
+	if !l.allow(peer.ID("other-peer"), 5) {
+		t.Fatal("Limit shared between peers.")
+	}
+}
+
+func TestTxLimiterCapacity(t *testing.T) {
+	l := newTxLimiter(func() int64 { return 5000 })
+	if l.rate() != txRateFactor*5000 {
+		t.Fatalf("Unexpected rate %d.", l.rate())
+	}
+
+	// 链的打包容量以内的转发不会被丢弃
+	if !l.allow(peer.ID("relay"), 5000) {
+		t.Fatal("Transactions within chain capacity rejected.")
+	}
+}
@@ -3,16 +3,115 @@
 package node
 
 import (
+	"context"
 	"encoding/binary"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/p2p"
 	"github.com/chain-lab/go-norn/utils"
+	"github.com/syndtr/goleveldb/leveldb/errors"
 )
 
+var errUnexpectedReply = errors.New("unexpected reply")
+
 func requestBlockWithHash(blockHash common.Hash, p *Peer) {
 	p.peer.Send(p2p.StatusCodeGetBlockBodiesMsg, blockHash[:])
 }
 
+// requestBlockByHash
+//
+//	@Description: 通过请求/响应的方式按哈希值获取区块，用于补齐广播区块缺失的父区块
+//	@param ctx - 请求上下文
+//	@param hash - 区块哈希值
+//	@param p - 节点实例
+//	@return *common.Block - 对端响应的区块，对端没有该区块时返回 nil
+//	@return error - 请求失败或者响应的区块哈希值不一致时返回错误
+func requestBlockByHash(ctx context.Context, hash common.Hash, p *Peer) (*common.Block, error) {
+	reply, err := p.Request(ctx, p2p.StatusCodeGetBlockBodiesMsg, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Code != p2p.StatusCodeBlockBodiesMsg {
+		return nil, errUnexpectedReply
+	}
+
+	// 对端没有该区块时响应空的数据包
+	if len(reply.Payload) == 0 {
+		return nil, nil
+	}
+
+	block, err := utils.DeserializeBlock(reply.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if common.Hash(block.Header.BlockHash) != hash {
+		return nil, errUnexpectedReply
+	}
+	return block, nil
+}
+
+// requestTxProof
+//
+//	@Description: 轻节点通过请求/响应的方式获取交易以及交易的 Merkle 证明
+//	@param ctx - 请求上下文
+//	@param hash - 交易哈希值
+//	@param p - 节点实例
+//	@return *p2p.TxProofMsg - 对端响应的交易和证明，对端没有该交易时返回 nil
+//	@return error - 请求失败时返回错误
+func requestTxProof(ctx context.Context, hash common.Hash, p *Peer) (*p2p.TxProofMsg, error) {
+	reply, err := p.Request(ctx, p2p.StatusCodeGetTxProofMsg, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Code != p2p.StatusCodeTxProofMsg {
+		return nil, errUnexpectedReply
+	}
+
+	// 对端没有该交易时响应空的数据包
+	if len(reply.Payload) == 0 {
+		return nil, nil
+	}
+	return utils.DeserializeTxProofMsg(reply.Payload)
+}
+
+// requestDataProof
+//
+//	@Description: 轻节点通过请求/响应的方式获取 data# 数据以及数据到区块状态根的证明
+//	@param ctx - 请求上下文
+//	@param address - 数据的地址
+//	@param key - 数据的 key
+//	@param height - 本地已经验证的区块头高度
+//	@param p - 节点实例
+//	@return *p2p.DataProofMsg - 对端响应的数据和证明，对端无法生成证明时返回 nil
+//	@return error - 请求失败时返回错误
+func requestDataProof(ctx context.Context, address, key []byte, height int64, p *Peer) (*p2p.DataProofMsg, error) {
+	payload, err := utils.SerializeDataProofReq(&p2p.DataProofReq{
+		Address: address,
+		Key:     key,
+		Height:  height,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := p.Request(ctx, p2p.StatusCodeGetDataProofMsg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Code != p2p.StatusCodeDataProofMsg {
+		return nil, errUnexpectedReply
+	}
+
+	// 对端无法生成证明时响应空的数据包
+	if len(reply.Payload) == 0 {
+		return nil, nil
+	}
+	return utils.DeserializeDataProofMsg(reply.Payload)
+}
+
 func requestSyncStatusMsg(height int64, p *Peer) {
 	byteHeight := make([]byte, 8)
 	binary.LittleEndian.PutUint64(byteHeight, uint64(height))
@@ -28,12 +127,160 @@ func requestSyncGetBlock(height int64, p *Peer) {
 	p.peer.Send(p2p.StatusCodeSyncGetBlocksMsg, byteHeight)
 }
 
-func requestTimeSync(msg *p2p.TimeSyncMsg, p *Peer) {
-	byteTimeSyncMsg, err := utils.SerializeTimeSyncMsg(msg)
+// requestSyncBlock
+//
+//	@Description: 通过请求/响应的方式获取某个高度的区块，只接受对应高度的区块
+//	@param ctx - 请求上下文
+//	@param height - 区块高度
+//	@param p - 节点实例
+//	@return *common.Block - 对端响应的区块
+//	@return error - 请求失败或者响应的区块不正确时返回错误
+func requestSyncBlock(ctx context.Context, height int64, p *Peer) (*common.Block, error) {
+	byteHeight := make([]byte, 8)
+	binary.LittleEndian.PutUint64(byteHeight, uint64(height))
+
+	reply, err := p.Request(ctx, p2p.StatusCodeSyncGetBlocksMsg, byteHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Code != p2p.StatusCodeSyncBlocksMsg {
+		return nil, errUnexpectedReply
+	}
+
+	block, err := utils.DeserializeBlock(reply.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Header.Height != height {
+		return nil, errUnexpectedReply
+	}
+
+	return block, nil
+}
+
+// requestSyncBlockRange
+//
+//	@Description: 通过请求/响应的方式获取从 start 开始的至多 count 个区块，对端可以返回更少的区块，
+//	但返回的区块必须从 start 开始连续
+//	@param ctx - 请求上下文
+//	@param start - 起始高度
+//	@param count - 请求的区块数量
+//	@param p - 节点实例
+//	@return []*common.Block - 对端响应的区块，按高度排列
+//	@return error - 请求失败或者响应的区块不正确时返回错误
+func requestSyncBlockRange(ctx context.Context, start, count int64, p *Peer) ([]*common.Block, error) {
+	payload, err := utils.SerializeSyncBlockRangeReq(&p2p.SyncBlockRangeReq{
+		Start: start,
+		Count: count,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := p.Request(ctx, p2p.StatusCodeSyncGetBlockRangeMsg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Code != p2p.StatusCodeSyncBlockRangeMsg {
+		return nil, errUnexpectedReply
+	}
+
+	blocks, err := utils.DeserializeBlocks(reply.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(blocks)) > count {
+		return nil, errUnexpectedReply
+	}
+	for idx, block := range blocks {
+		if block.Header.Height != start+int64(idx) {
+			return nil, errUnexpectedReply
+		}
+	}
+
+	return blocks, nil
+}
+
+// requestSyncHeaders
+//
+//	@Description: 通过请求/响应的方式获取从 start 开始的至多 count 个区块头，返回的区块头必须从 start 开始连续
+//	@param ctx - 请求上下文
+//	@param start - 起始高度
+//	@param count - 请求的区块头数量
+//	@param p - 节点实例
+//	@return []*common.BlockHeader - 对端响应的区块头，按高度排列
+//	@return error - 请求失败或者响应的区块头不正确时返回错误
+func requestSyncHeaders(ctx context.Context, start, count int64, p *Peer) ([]*common.BlockHeader, error) {
+	payload, err := utils.SerializeSyncBlockRangeReq(&p2p.SyncBlockRangeReq{
+		Start: start,
+		Count: count,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := p.Request(ctx, p2p.StatusCodeSyncGetHeadersMsg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.Code != p2p.StatusCodeSyncHeadersMsg {
+		return nil, errUnexpectedReply
+	}
+
+	headers, err := utils.DeserializeBlockHeaders(reply.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(headers)) > count {
+		return nil, errUnexpectedReply
+	}
+	for idx, header := range headers {
+		if header.Height != start+int64(idx) {
+			return nil, errUnexpectedReply
+		}
+	}
+
+	return headers, nil
+}
+
+// requestTimeSync
+//
+//	@Description: 通过请求/响应的方式向对端发起一次时间同步，请求的发出时间由本地记录，不使用对端响应中回显的值，
+//	避免对端伪造请求时间影响往返时延和偏移的计算
+//	@param ctx - 请求上下文
+//	@param ts - 时间同步器，用于读取本地逻辑时钟
+//	@param p - 节点实例
+//	@return *p2p.TimeSyncMsg - 四个时间戳都已填写的时间同步消息
+//	@return error - 请求失败或者响应不正确时返回错误
+func requestTimeSync(ctx context.Context, ts *TimeSyncer, p *Peer) (*p2p.TimeSyncMsg, error) {
+	reqTime := ts.GetLogicClock()
+	payload, err := utils.SerializeTimeSyncMsg(&p2p.TimeSyncMsg{ReqTime: reqTime})
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := p.Request(ctx, p2p.StatusCodeTimeSyncReq, payload)
+	if err != nil {
+		return nil, err
+	}
+	recRspTime := ts.GetLogicClock()
+
+	if reply.Code != p2p.StatusCodeTimeSyncRsp {
+		return nil, errUnexpectedReply
+	}
 
+	msg, err := utils.DeserializeTimeSyncMsg(reply.Payload)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	p.peer.Send(p2p.StatusCodeTimeSyncReq, byteTimeSyncMsg)
+	msg.ReqTime = reqTime
+	msg.RecRspTime = recRspTime
+	return msg, nil
 }
@@ -4,12 +4,19 @@ package node
 
 import (
 	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
 	"github.com/chain-lab/go-norn/p2p"
 	"github.com/chain-lab/go-norn/utils"
 	log "github.com/sirupsen/logrus"
 )
 
-func respondGetBlockBodies(block *common.Block, p *Peer) {
+// respondGetBlockBodies 响应按哈希值请求的区块，本地没有该区块时响应空的数据包，避免对端等待超时
+func respondGetBlockBodies(block *common.Block, req *p2p.Message, p *Peer) {
+	if block == nil {
+		p.Reply(req, p2p.StatusCodeBlockBodiesMsg, nil)
+		return
+	}
+
 	bytesBlockData, err := utils.SerializeBlock(block)
 
 	if err != nil {
@@ -17,8 +24,53 @@ func respondGetBlockBodies(block *common.Block, p *Peer) {
 		return
 	}
 
-	p.peer.Send(p2p.StatusCodeBlockBodiesMsg, bytesBlockData)
+	p.Reply(req, p2p.StatusCodeBlockBodiesMsg, bytesBlockData)
+}
+
+// respondTxProof 响应交易以及交易的 Merkle 证明，本地没有该交易时响应空的数据包
+func respondTxProof(tx *common.Transaction, proof []byte, req *p2p.Message, p *Peer) {
+	if tx == nil {
+		p.Reply(req, p2p.StatusCodeTxProofMsg, nil)
+		return
+	}
+
+	bytesTransactionData, err := utils.SerializeTransaction(tx)
+	if err != nil {
+		log.WithField("error", err).Debugln("Serialize transaction to bytes failed.")
+		return
+	}
+
+	bytesProofData, err := utils.SerializeTxProofMsg(&p2p.TxProofMsg{
+		Transaction: bytesTransactionData,
+		Proof:       proof,
+	})
+	if err != nil {
+		return
+	}
+
+	p.Reply(req, p2p.StatusCodeTxProofMsg, bytesProofData)
+}
+
+// respondDataProof 响应 data# 数据以及数据到区块状态根的证明，没有证明时响应空的数据包
+func respondDataProof(proof *core.DataProof, req *p2p.Message, p *Peer) {
+	if proof == nil {
+		p.Reply(req, p2p.StatusCodeDataProofMsg, nil)
+		return
+	}
+
+	bytesProofData, err := utils.SerializeDataProofMsg(&p2p.DataProofMsg{
+		Height: proof.Height,
+		Found:  proof.Found,
+		Value:  proof.Value,
+		Proof:  proof.Proof,
+	})
+	if err != nil {
+		return
+	}
+
+	p.Reply(req, p2p.StatusCodeDataProofMsg, bytesProofData)
 }
+
 func respondGetPooledTransaction(tx *common.Transaction, p *Peer) {
 	bytesTransactionData, err := utils.SerializeTransaction(tx)
 
@@ -30,7 +82,7 @@ func respondGetPooledTransaction(tx *common.Transaction, p *Peer) {
 	p.peer.Send(p2p.StatusCodePooledTransactionsMsg, bytesTransactionData)
 }
 
-func respondSyncGetBlock(block *common.Block, p *Peer) {
+func respondSyncGetBlock(block *common.Block, req *p2p.Message, p *Peer) {
 	bytesBlockData, err := utils.SerializeBlock(block)
 
 	if err != nil {
@@ -38,10 +90,41 @@ func respondSyncGetBlock(block *common.Block, p *Peer) {
 		return
 	}
 
-	p.peer.Send(p2p.StatusCodeSyncBlocksMsg, bytesBlockData)
+	p.Reply(req, p2p.StatusCodeSyncBlocksMsg, bytesBlockData)
+}
+
+// respondSyncBlockRange 响应区间区块请求，没有可以返回的区块时同样响应空的数据包，避免对端等待超时
+func respondSyncBlockRange(blocks []*common.Block, req *p2p.Message, p *Peer) {
+	bytesBlocksData, count, err := utils.SerializeBlocks(blocks, maxBlockRangeBytes)
+
+	if err != nil {
+		log.WithField("error", err).Debugln("Serialize blocks to bytes failed.")
+		return
+	}
+
+	if count < len(blocks) {
+		log.WithFields(log.Fields{
+			"count":    len(blocks),
+			"returned": count,
+		}).Traceln("Block range respond truncated.")
+	}
+
+	p.Reply(req, p2p.StatusCodeSyncBlockRangeMsg, bytesBlocksData)
+}
+
+// respondSyncHeaders 响应区间区块头请求
+func respondSyncHeaders(headers []*common.BlockHeader, req *p2p.Message, p *Peer) {
+	bytesHeadersData, _, err := utils.SerializeBlockHeaders(headers, maxBlockRangeBytes)
+
+	if err != nil {
+		log.WithField("error", err).Debugln("Serialize headers to bytes failed.")
+		return
+	}
+
+	p.Reply(req, p2p.StatusCodeSyncHeadersMsg, bytesHeadersData)
 }
 
-func respondGetSyncStatus(msg *p2p.SyncStatusMsg, p *Peer) {
+func respondGetSyncStatus(msg *p2p.SyncStatusMsg, req *p2p.Message, p *Peer) {
 	//metrics.RespondGetSyncStatusGauge.Inc()
 	byteStatusMsg, err := utils.SerializeStatusMsg(msg)
 
@@ -49,11 +132,11 @@ func respondGetSyncStatus(msg *p2p.SyncStatusMsg, p *Peer) {
 		return
 	}
 
-	p.peer.Send(p2p.StatusCodeSyncStatusMsg, byteStatusMsg)
+	p.Reply(req, p2p.StatusCodeSyncStatusMsg, byteStatusMsg)
 	//metrics.RespondGetSyncStatusGauge.Dec()
 }
 
-func respondTimeSync(msg *p2p.TimeSyncMsg, p *Peer) {
+func respondTimeSync(msg *p2p.TimeSyncMsg, req *p2p.Message, p *Peer) {
 	//metrics.RespondTimeSyncRoutineGauge.Inc()
 	byteTimeSyncMsg, err := utils.SerializeTimeSyncMsg(msg)
 
@@ -61,6 +144,6 @@ func respondTimeSync(msg *p2p.TimeSyncMsg, p *Peer) {
 		return
 	}
 
-	p.peer.Send(p2p.StatusCodeTimeSyncRsp, byteTimeSyncMsg)
+	p.Reply(req, p2p.StatusCodeTimeSyncRsp, byteTimeSyncMsg)
 	//metrics.RespondTimeSyncRoutineGauge.Dec()
 }
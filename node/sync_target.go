@@ -0,0 +1,377 @@
+// Package node
+// @Description: 同步目标的选择。同步器记录每个节点在同步状态消息中声明的最新高度和缓冲区高度，按照节点信誉加权，
+// 选择多数节点都已经达到的高度作为同步目标，单个节点声明的异常高度不会让同步器一直等待；
+// 声明过期的节点不参与选择，同步进度长时间停滞时排除声明了更高高度却没有提供数据的节点，并重新计算同步目标。
+// 同步完成后继续跟踪各个节点的链头，本地数据库落后多数节点超过缓冲区大小时重新进入同步区块状态
+package node
+
+import (
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/metrics"
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"time"
+)
+
+const (
+	peerHeadTTL        = 10 * time.Second // 节点声明的链头的有效期，超过后不参与同步目标的选择
+	syncTargetInterval = time.Second      // 没有收到状态消息时重新计算同步目标的间隔
+	syncStallTimeout   = 30 * time.Second // 同步进度停滞的超时时间，也是被排除的节点不参与选择的时长
+)
+
+// peerStatus 同步状态消息以及发送消息的节点
+type peerStatus struct {
+	peer *Peer
+	msg  *p2p.SyncStatusMsg
+}
+
+// peerHead 节点在同步状态消息中声明的链头
+type peerHead struct {
+	id           peer.ID
+	peer         *Peer
+	height       int64     // 数据库中的最新高度
+	bufferHeight int64     // 缓冲区的最新高度，为 -1 时没有缓冲区信息
+	updated      time.Time // 收到状态消息的时间
+}
+
+// SyncPeerHead 节点声明的链头，用于 RPC 查询
+type SyncPeerHead struct {
+	ID           peer.ID
+	Height       int64
+	BufferHeight int64
+	Weight       float64
+	Selected     bool // 是否为支持当前同步目标的节点
+}
+
+// BlockSyncStats 区块同步状态，用于 RPC 查询
+type BlockSyncStats struct {
+	Status       uint8
+	KnownHeight  int64 // 已经插入的高度
+	RemoteHeight int64 // 多数节点已经达到的数据库高度
+	TargetHeight int64 // 多数节点已经达到的缓冲区高度，本地数据库到达该高度后同步完成
+	Peers        []SyncPeerHead
+}
+
+// peerWeight
+//
+//	@Description: 节点在同步目标选择中的权重，信誉分数为 0 的节点权重为 1，分数越低权重越小，分数达到下限时不参与选择
+//	@param p - 节点实例
+//	@return float64 - 权重
+func peerWeight(p *Peer) float64 {
+	if p == nil || p.handler == nil || p.handler.reputation == nil {
+		return 1
+	}
+
+	weight := 1 + p.handler.reputation.Score(p.peerID)/maxPeerScore
+	if weight < 0 {
+		return 0
+	}
+	return weight
+}
+
+// quorumHeight
+//
+//	@Description: 按高度从高到低累加节点的权重，返回累计权重首次超过总权重一半时的高度，即加权的多数节点都已经达到的最高高度
+//	@param heights - 节点声明的高度，小于 0 的高度不参与计算
+//	@param weights - 节点的权重，与高度一一对应
+//	@return int64 - 多数节点达到的高度，没有节点参与计算时返回 -1
+func quorumHeight(heights []int64, weights []float64) int64 {
+	idx := make([]int, 0, len(heights))
+	total := 0.0
+	for i := range heights {
+		if heights[i] < 0 || weights[i] <= 0 {
+			continue
+		}
+		idx = append(idx, i)
+		total += weights[i]
+	}
+
+	sort.SliceStable(idx, func(a, b int) bool {
+		return heights[idx[a]] > heights[idx[b]]
+	})
+
+	sum := 0.0
+	for _, i := range idx {
+		sum += weights[i]
+		if 2*sum > total {
+			return heights[i]
+		}
+	}
+	return -1
+}
+
+// updatePeerHead
+//
+//	@Description: 记录节点在状态消息中声明的链头，还没有完成同步的节点不参与同步目标的选择
+//	@receiver bs
+//	@param status - 状态消息以及发送消息的节点
+func (bs *BlockSyncer) updatePeerHead(status *peerStatus) {
+	id := status.peer.peerID
+	if status.msg.LatestHeight == -1 {
+		delete(bs.heads, id)
+		return
+	}
+
+	bs.heads[id] = &peerHead{
+		id:           id,
+		peer:         status.peer,
+		height:       status.msg.LatestHeight,
+		bufferHeight: status.msg.BufferedEndHeight,
+		updated:      bs.clock.Now(),
+	}
+}
+
+// markDelivered 记录节点提供了区块或区块头，同步停滞时不排除最近提供过数据的节点
+func (bs *BlockSyncer) markDelivered(id peer.ID) {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+
+	bs.delivered[id] = bs.clock.Now()
+}
+
+// activeHeads
+//
+//	@Description: 删除过期以及已经断开的节点的链头，返回可以参与同步目标选择的链头，调用时需要持有 lock
+//	@receiver bs
+//	@param now - 当前时间
+//	@return []*peerHead - 没有过期也没有被排除的链头
+func (bs *BlockSyncer) activeHeads(now time.Time) []*peerHead {
+	heads := make([]*peerHead, 0, len(bs.heads))
+	for id, head := range bs.heads {
+		if (head.peer != nil && head.peer.Stopped()) || now.Sub(head.updated) > peerHeadTTL {
+			delete(bs.heads, id)
+			continue
+		}
+
+		if until, ok := bs.excluded[id]; ok {
+			if now.Before(until) {
+				continue
+			}
+			delete(bs.excluded, id)
+		}
+		heads = append(heads, head)
+	}
+
+	sort.Slice(heads, func(i, j int) bool {
+		return heads[i].id < heads[j].id
+	})
+	return heads
+}
+
+// excludeStalledPeers
+//
+//	@Description: 同步进度停滞超过 syncStallTimeout 时，排除声明的高度高于已插入高度、但是在这段时间内没有提供数据的节点，
+//	调用时需要持有 lock
+//	@receiver bs
+//	@param now - 当前时间
+func (bs *BlockSyncer) excludeStalledPeers(now time.Time) {
+	if bs.status == synced || bs.knownHeight >= bs.remoteHeight || now.Sub(bs.lastProgress) < syncStallTimeout {
+		return
+	}
+
+	for id, head := range bs.heads {
+		if head.height <= bs.knownHeight || now.Sub(bs.delivered[id]) < syncStallTimeout {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"peer":   id,
+			"height": head.height,
+			"known":  bs.knownHeight,
+		}).Warning("Sync stalled, exclude peer from sync target.")
+		bs.excluded[id] = now.Add(syncStallTimeout)
+	}
+
+	// 重新选择同步目标后重新开始计算停滞时间
+	bs.lastProgress = now
+}
+
+// evaluateTarget
+//
+//	@Description: 根据没有过期的链头重新计算同步目标，数据库高度和缓冲区高度分别取加权的多数节点都已经达到的高度，
+//	同步目标或者支持同步目标的节点变化时输出日志，调用时需要持有 lock
+//	@receiver bs
+func (bs *BlockSyncer) evaluateTarget() {
+	now := bs.clock.Now()
+	bs.excludeStalledPeers(now)
+
+	heads := bs.activeHeads(now)
+	heights := make([]int64, len(heads))
+	bufferHeights := make([]int64, len(heads))
+	weights := make([]float64, len(heads))
+	for idx, head := range heads {
+		heights[idx] = head.height
+		bufferHeights[idx] = head.bufferHeight
+		weights[idx] = peerWeight(head.peer)
+	}
+
+	remote := quorumHeight(heights, weights)
+	if remote == -1 {
+		// 没有可用的链头时保持原来的同步目标，等待新的状态消息
+		return
+	}
+	// 只在同步区块时更新缓冲区目标，同步缓冲区期间其他节点的缓冲区持续增长，目标不再变化
+	target := bs.targetHeight
+	if h := quorumHeight(bufferHeights, weights); h != -1 && bs.status == blockSyncing {
+		target = h
+	}
+
+	selected := make([]peer.ID, 0, len(heads))
+	for idx, head := range heads {
+		if head.height >= remote && weights[idx] > 0 {
+			selected = append(selected, head.id)
+		}
+	}
+
+	changed := remote != bs.remoteHeight || target != bs.targetHeight || !samePeerIDs(selected, bs.selected)
+	bs.remoteHeight, bs.targetHeight = remote, target
+	bs.selected = selected
+
+	if changed {
+		metrics.BlockSyncTargetSet(float64(remote), float64(len(selected)))
+		log.WithFields(log.Fields{
+			"remote": remote,
+			"target": target,
+			"peers":  selected,
+		}).Infoln("Select sync target.")
+	}
+}
+
+// updateSyncStatus
+//
+//	@Description: 重新计算同步目标并更新同步状态，已插入的高度到达多数节点的数据库高度后开始同步缓冲区，
+//	本地数据库到达多数节点的缓冲区高度后同步完成；同步完成后检查本地是否已经落后。
+//	轻节点没有缓冲区，区块头到达多数节点的数据库高度后同步完成，之后由区块头同步协程继续跟随
+//	@receiver bs
+func (bs *BlockSyncer) updateSyncStatus() {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+
+	bs.evaluateTarget()
+	if bs.light {
+		if bs.status != synced && bs.remoteHeight >= 0 && bs.knownHeight >= bs.remoteHeight {
+			log.WithFields(log.Fields{
+				"height": bs.knownHeight,
+				"peers":  bs.selected,
+			}).Infoln("Reach remote height of selected peers, headers synced.")
+			metrics.BlockSyncerStatusSet(int8(synced))
+			bs.status = synced
+		}
+		return
+	}
+
+	if bs.status == synced {
+		bs.checkFallBehind()
+		return
+	}
+
+	if bs.status == blockSyncing && bs.remoteHeight >= 0 && bs.knownHeight >= bs.remoteHeight {
+		log.WithFields(log.Fields{
+			"height": bs.knownHeight,
+			"peers":  bs.selected,
+		}).Infoln("Reach remote height of selected peers, start buffer sync.")
+		metrics.BlockSyncerStatusSet(int8(bufferSyncing))
+		bs.status = bufferSyncing
+	}
+
+	if bs.chain != nil && bs.targetHeight >= 0 && bs.chain.Height() >= bs.targetHeight {
+		log.WithFields(log.Fields{
+			"target": bs.targetHeight,
+			"peers":  bs.selected,
+		}).Infoln("Reach target block height.")
+		metrics.BlockSyncerStatusSet(int8(synced))
+		bs.status = synced
+	}
+}
+
+// checkFallBehind
+//
+//	@Description: 本地数据库落后多数节点的数据库高度超过缓冲区大小时，缺失的区块已经无法通过广播和补齐父区块得到，
+//	从本地数据库高度开始重新同步区块，同步期间停止出块，调用时需要持有 lock
+//	@receiver bs
+func (bs *BlockSyncer) checkFallBehind() {
+	if bs.chain == nil || bs.remoteHeight < 0 {
+		return
+	}
+
+	height := bs.chain.Height()
+	if bs.remoteHeight-height <= core.MaxBufferSize {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"height": height,
+		"remote": bs.remoteHeight,
+		"peers":  bs.selected,
+	}).Warning("Fall behind selected peers, start catch up.")
+	metrics.BlockSyncerStatusSet(int8(blockSyncing))
+	metrics.BlockSyncCatchUpInc()
+
+	bs.status = blockSyncing
+	bs.knownHeight = height
+	bs.blockMap = make(map[int64]*common.Block)
+	bs.requestDeadline = make(map[int64]time.Time)
+	bs.headers = nil
+	bs.progress = syncProgress{}
+	bs.lastProgress = bs.clock.Now()
+
+	// 重新选择缓冲区目标
+	bs.evaluateTarget()
+}
+
+// Stats
+//
+//	@Description: 获取区块同步状态以及各个节点声明的链头
+//	@receiver bs
+//	@return BlockSyncStats - 区块同步状态，节点按照高度从高到低排序
+func (bs *BlockSyncer) Stats() BlockSyncStats {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+
+	selected := make(map[peer.ID]bool, len(bs.selected))
+	for _, id := range bs.selected {
+		selected[id] = true
+	}
+
+	peers := make([]SyncPeerHead, 0, len(bs.heads))
+	for id, head := range bs.heads {
+		peers = append(peers, SyncPeerHead{
+			ID:           id,
+			Height:       head.height,
+			BufferHeight: head.bufferHeight,
+			Weight:       peerWeight(head.peer),
+			Selected:     selected[id],
+		})
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Height != peers[j].Height {
+			return peers[i].Height > peers[j].Height
+		}
+		return peers[i].ID < peers[j].ID
+	})
+
+	return BlockSyncStats{
+		Status:       bs.status,
+		KnownHeight:  bs.knownHeight,
+		RemoteHeight: bs.remoteHeight,
+		TargetHeight: bs.targetHeight,
+		Peers:        peers,
+	}
+}
+
+// samePeerIDs 两个已排序的节点 ID 列表是否相同
+func samePeerIDs(a, b []peer.ID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
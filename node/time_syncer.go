@@ -1,12 +1,21 @@
 // Package node
-// @Description: 时间同步器
+// @Description: 时间同步器，参考 NTP 的做法：每轮向多个节点发出请求，使用往返时延修正每个样本的时钟偏移，
+// 通过中位数和绝对中位差剔除异常样本后按往返时延加权得到本轮的偏移。首轮同步直接跳变逻辑时钟，
+// 之后以有限的速率平滑调整（slew），保证逻辑时钟单调递增。为了抵御恶意节点，每轮需要足够数量和比例的不同节点
+// 给出一致的偏移才会调整逻辑时钟或者计入同步确认，同步完成后每轮的调整量也受到限制
 package node
 
 import (
+	"context"
 	"github.com/chain-lab/go-norn/metrics"
 	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/gookit/config/v2"
+	"github.com/libp2p/go-libp2p/core/peer"
 	log "github.com/sirupsen/logrus"
+	"math"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 )
@@ -17,8 +26,16 @@ const (
 	//syncInterval       = 5 * time.Second  // 时间同步间隔
 	syncInterval = 3 * time.Second // 时间同步间隔
 	//autoSyncInterval   = 10 * time.Second // 自动重启任务间隔
-	confirmThreshold   = 5    // 时间同步确认阈值
-	availableThreshold = 1000 // 1000 ms 容忍范围
+	confirmThreshold = 5    // 时间同步确认阈值
+	syncSamples      = 5    // 每轮进行时间同步的节点数量
+	outlierFactor    = 3    // 偏离中位数超过 outlierFactor 倍绝对中位差（再加上样本自身误差）的样本视为异常
+	maxSlewRate      = 0.05 // 平滑调整的最大速率，每经过 1s 物理时间逻辑时钟最多调整 50ms
+
+	defaultSyncTolerance = 100  // 一致和确认同步的容忍范围，单位 ms
+	defaultSyncQuorum    = 3    // 每轮至少需要给出一致偏移的不同节点数量
+	minSyncQuorum        = 1    // 可以请求的节点少于法定节点数时，每轮至少需要给出一致偏移的节点数量
+	defaultSyncAgreement = 0.67 // 每轮给出一致偏移的节点占响应节点的最小比例
+	defaultMaxAdjustment = 200  // 首轮之后每轮目标偏移的最大调整量，单位 ms
 )
 
 const (
@@ -27,66 +44,207 @@ const (
 	CONFIRMING SyncStatus = 3
 )
 
+// timeSample 一次时间同步得到的样本
+type timeSample struct {
+	peer   peer.ID // 样本来源，测试中可能为空
+	offset int64   // 对端逻辑时钟相对本地物理时钟的偏移
+	rtt    int64   // 扣除对端处理时间后的往返时延
+}
+
+// roundResult 一轮时间同步的计算结果
+type roundResult struct {
+	offset     int64   // 按往返时延加权的偏移
+	dispersion float64 // 离散度：有效样本偏移的加权标准差加上最小往返时延的一半
+	rtt        float64 // 有效样本的平均往返时延
+	accepted   int     // 有效样本数量
+	agreed     int     // 与本轮偏移一致的样本数量
+	rejected   int     // 被剔除的样本数量
+}
+
+// TimeSyncStats 时间同步状态，用于 RPC 查询
+type TimeSyncStats struct {
+	Status       SyncStatus
+	Offset       int64   // 逻辑时钟当前相对物理时钟的偏移
+	Target       int64   // 平滑调整的目标偏移
+	Dispersion   float64 // 最近一轮的离散度
+	RTT          float64 // 最近一轮有效样本的平均往返时延
+	Samples      int     // 最近一轮的有效样本数量
+	Rejected     int     // 最近一轮剔除的样本数量
+	Agreed       int     // 最近一轮与偏移一致的节点数量
+	ConfirmTimes int     // 连续确认的轮数
+	LastRound    int64   // 最近一轮同步完成时的逻辑时间
+}
+
 type TimeSyncer struct {
 	syncerLock sync.RWMutex
-	timer      *time.Ticker
+	timer      utils.Ticker
+	clock      utils.Clock // 逻辑时钟和同步定时器使用的时钟
 	genesis    bool
+	manager    *P2PManager // 所属的 manager，用于选择进行时间同步的节点
+
+	cancel context.CancelFunc // 取消时间同步协程
+	wg     sync.WaitGroup     // 等待时间同步协程退出
 
 	// 需要 syncerLock 加锁才能进行修改、读取
 	status       SyncStatus
-	delta        int64
+	delta        int64     // slewTime 时刻逻辑时钟相对物理时钟的偏移
+	target       int64     // 平滑调整的目标偏移
+	slewTime     time.Time // 开始向 target 调整的物理时间
 	confirmTimes int
+	roundStart   int64        // 当前轮开始时的逻辑时间，更早发出的请求的响应被丢弃
+	samples      []timeSample // 当前轮收集到的样本
+	last         roundResult  // 最近一轮的计算结果
+	lastRound    int64
+
+	tolerance     int64   // 一致和确认同步的容忍范围
+	quorum        int     // 每轮至少需要给出一致偏移的不同节点数量
+	roundQuorum   int     // 当前轮实际使用的法定节点数，不超过本轮发出请求的节点数量，需要 syncerLock 加锁
+	agreement     float64 // 每轮给出一致偏移的节点占响应节点的最小比例
+	maxAdjustment int64   // 首轮之后每轮目标偏移的最大调整量
 }
 
-func NewTimeSyncer(genesis bool, delta int64) *TimeSyncer {
+// NewTimeSyncer 创建时间同步器，clock 为 nil 时使用系统时钟，容忍范围、法定节点数、一致比例和最大调整量从配置文件的 timesync 项读取
+func NewTimeSyncer(genesis bool, delta int64, clock utils.Clock) *TimeSyncer {
+	if clock == nil {
+		clock = utils.NewRealClock()
+	}
+
+	quorum := config.Int("timesync.quorum", defaultSyncQuorum)
 	metrics.TimeSyncerStatusSet(int8(INITIAL))
 	return &TimeSyncer{
 		status:       INITIAL,
 		delta:        delta,
+		target:       delta,
+		slewTime:     clock.Now(),
 		confirmTimes: 0,
 		genesis:      genesis,
+		clock:        clock,
+
+		tolerance:     config.Int64("timesync.tolerance", defaultSyncTolerance),
+		quorum:        quorum,
+		roundQuorum:   quorum,
+		agreement:     config.Float("timesync.agreement", defaultSyncAgreement),
+		maxAdjustment: config.Int64("timesync.max_adjustment", defaultMaxAdjustment),
 	}
 }
 
-// syncRoutine 时间同步协程函数，每隔 syncInterval 选择节点发出一次同步请求
-func (ts *TimeSyncer) syncRoutine() {
+// syncRoutine 时间同步协程函数，每隔 syncInterval 结算上一轮收集到的样本，并向最多 syncSamples
+// 个（不少于法定节点数）随机节点发出新一轮的同步请求，ctx 取消后退出
+func (ts *TimeSyncer) syncRoutine(ctx context.Context) {
+	defer ts.wg.Done()
+
 	log.Infoln("Start time syncer routine.")
-	ts.timer = time.NewTicker(syncInterval)
+	ts.timer = ts.clock.NewTicker(syncInterval)
+	defer ts.timer.Stop()
 	for {
 		select {
-		case <-ts.timer.C:
-			pm := GetP2PManager()
-			peersLen := len(pm.peerSet)
+		case <-ctx.Done():
+			return
+		case <-ts.timer.C():
+			ts.processRound()
 
-			if peersLen <= 0 {
-				continue
+			samples := syncSamples
+			if ts.quorum > samples {
+				samples = ts.quorum
 			}
 
-			r := rand.New(rand.NewSource(time.Now().UnixMilli()))
-			peer := pm.peerSet[r.Intn(peersLen)]
+			peers := ts.selectPeers(samples)
+			ts.startRound(len(peers))
+			for _, p := range peers {
+				ts.wg.Add(1)
+				go func(p *Peer) {
+					defer ts.wg.Done()
+					// 本轮结束前没有收到的响应不再等待
+					reqCtx, cancel := context.WithTimeout(ctx, syncInterval)
+					defer cancel()
+					ts.syncPeer(reqCtx, p)
+				}(p)
+			}
 
-			msg := &p2p.TimeSyncMsg{
-				Code:       0,
-				ReqTime:    ts.GetLogicClock(),
-				RecReqTime: 0,
-				RspTime:    0,
-				RecRspTime: 0,
+			if len(peers) > 0 {
+				log.Infof("Request time sync to %d remote peers.", len(peers))
 			}
-			requestTimeSync(msg, peer)
-			log.Infoln("Request to remote time sync.")
 		}
 	}
 }
 
-// todo: 添加上下文
-func (ts *TimeSyncer) Start() {
+// startRound
+//
+//	@Description: 记录新一轮发出请求的节点数量。小规模网络中可以请求的节点少于法定节点数时，
+//	本轮的法定节点数降低为请求的节点数量（不少于 minSyncQuorum），否则节点永远无法完成时间同步；
+//	一致比例的要求不变，请求的节点中仍然需要足够比例给出一致的偏移
+//	@receiver ts
+//	@param requested - 本轮发出请求的节点数量
+func (ts *TimeSyncer) startRound(requested int) {
+	ts.syncerLock.Lock()
+	defer ts.syncerLock.Unlock()
+
+	quorum := ts.quorum
+	if requested < quorum {
+		quorum = requested
+	}
+	if quorum < minSyncQuorum {
+		quorum = minSyncQuorum
+	}
+	ts.roundQuorum = quorum
+}
+
+// syncPeer 向节点 p 发出一次时间同步请求，并将得到的样本加入当前轮
+func (ts *TimeSyncer) syncPeer(ctx context.Context, p *Peer) {
+	msg, err := requestTimeSync(ctx, ts, p)
+	if err != nil {
+		log.WithError(err).Debugln("Request time sync failed.")
+		return
+	}
+
+	ts.ProcessSyncRespond(msg, p)
+}
+
+// selectPeers 从已连接并且支持请求/响应的节点中随机选择最多 n 个不同的节点
+func (ts *TimeSyncer) selectPeers(n int) []*Peer {
+	pm := ts.manager
+	pm.peerSetLock.RLock()
+	defer pm.peerSetLock.RUnlock()
+
+	r := rand.New(rand.NewSource(ts.clock.Now().UnixNano()))
+	result := make([]*Peer, 0, n)
+	for _, idx := range r.Perm(len(pm.peerSet)) {
+		if len(result) >= n {
+			break
+		}
+		// 旧版本节点的响应无法与请求关联，不参与时间同步
+		if !pm.peerSet[idx].SupportRequest() {
+			continue
+		}
+		result = append(result, pm.peerSet[idx])
+	}
+	return result
+}
+
+// Start 启动时间同步协程，创世节点不需要进行时间同步
+func (ts *TimeSyncer) Start(ctx context.Context) error {
 	if !ts.genesis {
+		var routineCtx context.Context
+		routineCtx, ts.cancel = context.WithCancel(ctx)
+
 		metrics.RoutineCreateCounterObserve(27)
-		go ts.syncRoutine()
+		ts.wg.Add(1)
+		go ts.syncRoutine(routineCtx)
 	} else {
 		metrics.TimeSyncerStatusSet(int8(SYNCED))
 		ts.status = SYNCED
 	}
+	return nil
+}
+
+// Stop 停止时间同步协程，并等待协程退出
+func (ts *TimeSyncer) Stop(ctx context.Context) error {
+	if ts.cancel == nil {
+		return nil
+	}
+	ts.cancel()
+
+	return utils.WaitGroupContext(ctx, &ts.wg)
 }
 
 // GetLogicClock 计算逻辑时钟 = 物理时钟 + 网络误差
@@ -95,11 +253,12 @@ func (ts *TimeSyncer) GetLogicClock() int64 {
 	ts.syncerLock.RLock()
 	defer ts.syncerLock.RUnlock()
 
-	return time.Now().UnixMilli() + ts.delta
+	now := ts.clock.Now()
+	return now.UnixMilli() + ts.currentDelta(now)
 }
 
-// ProcessSyncRequest 处理时间同步请求消息
-func (ts *TimeSyncer) ProcessSyncRequest(msg *p2p.TimeSyncMsg, p *Peer) {
+// ProcessSyncRequest 处理时间同步请求消息，req 为对端的原始请求，用于在响应中携带请求 ID
+func (ts *TimeSyncer) ProcessSyncRequest(msg *p2p.TimeSyncMsg, req *p2p.Message, p *Peer) {
 	//if ts.status == SYNCED {
 	msg.RspTime = ts.GetLogicClock()
 	//} else {
@@ -107,32 +266,100 @@ func (ts *TimeSyncer) ProcessSyncRequest(msg *p2p.TimeSyncMsg, p *Peer) {
 	//}
 
 	//metrics.RoutineCreateCounterObserve(28)
-	respondTimeSync(msg, p)
+	respondTimeSync(msg, req, p)
 }
 
+// ProcessSyncRespond
+//
+//	@Description: 处理时间同步响应，计算经过往返时延修正的偏移并加入当前轮的样本，样本在下一次定时器触发时统一结算
+//	@receiver ts
+//	@param msg - 时间同步消息，ReqTime、RecRspTime 为本地记录的逻辑时间，RecReqTime、RspTime 为对端逻辑时间
+//	@param p - 响应的节点，同一轮中每个节点只保留一个样本
 func (ts *TimeSyncer) ProcessSyncRespond(msg *p2p.TimeSyncMsg, p *Peer) {
-	if msg.Code != 0 {
+	if msg.Code != 0 || p == nil {
 		log.Warningln("Remote peer respond time sync error.")
 		return
 	}
 
-	// 计算本地节点和请求同步对节点之间的时间差
-	delta := (msg.RspTime-msg.RecRspTime)/2 + (msg.RecReqTime-msg.ReqTime)/2
-	log.Infof("Time sync delta = %d.", delta) //todo: summary
+	// 与 NTP 相同，往返时延扣除对端的处理时间，偏移取请求和响应两个方向差值的平均
+	rtt := (msg.RecRspTime - msg.ReqTime) - (msg.RspTime - msg.RecReqTime)
+	offset := ((msg.RecReqTime - msg.ReqTime) + (msg.RspTime - msg.RecRspTime)) / 2
+	if rtt < 0 {
+		log.Warnf("Drop time sync sample with negative rtt %d.", rtt)
+		return
+	}
+
+	ts.syncerLock.Lock()
+	defer ts.syncerLock.Unlock()
 
+	if msg.ReqTime < ts.roundStart {
+		log.Debugln("Drop time sync respond from previous round.")
+		return
+	}
+
+	sample := timeSample{peer: p.peerID, rtt: rtt}
+	for _, s := range ts.samples {
+		if s.peer == sample.peer {
+			return
+		}
+	}
+
+	// 偏移是相对本地逻辑时钟计算的，加上当前的偏差后转换为相对物理时钟的偏移
+	sample.offset = offset + ts.currentDelta(ts.clock.Now())
+	ts.samples = append(ts.samples, sample)
+	log.Debugf("Time sync sample offset = %d, rtt = %d.", sample.offset, rtt)
+}
+
+// processRound
+//
+//	@Description: 结算当前轮收集到的样本并开始新的一轮。给出一致偏移的节点数量或者比例不足时，本轮不调整逻辑时钟并清空确认次数。
+//	首轮结果直接跳变逻辑时钟；之后每轮的调整量不超过 maxAdjustment，同步完成之前直接跳变，同步完成之后只进行平滑调整，
+//	避免逻辑时钟回退。本轮结果与目标偏移的差值在容忍范围内时记为一次确认
+//	@receiver ts
+func (ts *TimeSyncer) processRound() {
 	ts.syncerLock.Lock()
 	defer ts.syncerLock.Unlock()
+
+	now := ts.clock.Now()
+	samples := ts.samples
+	ts.samples = nil
+	// 逻辑时钟调整之后再记录新一轮的开始时间，避免跳变回退后新一轮的响应被丢弃
+	defer func() {
+		ts.roundStart = now.UnixMilli() + ts.currentDelta(now)
+	}()
+
+	if len(samples) == 0 {
+		return
+	}
+
+	result := filterSamples(samples)
+	result.agreed = countAgreed(samples, result.offset, ts.tolerance)
+	correction := result.offset - ts.target
+	ts.last = result
+	ts.lastRound = now.UnixMilli() + ts.currentDelta(now)
+	metrics.TimeSyncDeltaSet(float64(correction))
+	metrics.TimeSyncRoundObserve(float64(result.offset), result.dispersion,
+		result.rtt, result.accepted, result.rejected)
+	log.Infof("Time sync round offset = %d, correction = %d, dispersion = %.2f, samples = %d/%d.",
+		result.offset, correction, result.dispersion, result.accepted, len(samples))
+
+	// 少数节点无法单独改变逻辑时钟，也不能使节点进入同步完成状态
+	if result.agreed < ts.roundQuorum || float64(result.agreed) < ts.agreement*float64(len(samples)) {
+		log.Warnf("Time sync round without quorum, %d of %d peers agreed.",
+			result.agreed, len(samples))
+		ts.confirmTimes = 0
+		return
+	}
+
 	if ts.status == INITIAL {
 		metrics.TimeSyncerStatusSet(int8(CONFIRMING))
 		ts.status = CONFIRMING
-		ts.delta += delta
+		ts.step(now, result.offset)
 		return
 	}
 
-	// 如果在容忍范围内，则调整本地偏差值
-	if abs(delta) < availableThreshold {
-		ts.delta += delta
-		metrics.TimeSyncDeltaSet(float64(delta))
+	// 如果在容忍范围内，则记为一次确认
+	if abs(correction) <= ts.tolerance {
 		if ts.status == CONFIRMING {
 			ts.confirmTimes += 1
 		}
@@ -141,14 +368,77 @@ func (ts *TimeSyncer) ProcessSyncRespond(msg *p2p.TimeSyncMsg, p *Peer) {
 		ts.confirmTimes = 0
 	}
 
+	// 限制每轮的调整量，即使多数节点给出的偏移相差很大，也需要多轮才能完成调整
+	if correction > ts.maxAdjustment {
+		correction = ts.maxAdjustment
+	} else if correction < -ts.maxAdjustment {
+		correction = -ts.maxAdjustment
+	}
+
+	if ts.status == SYNCED {
+		ts.slew(now, ts.target+correction)
+	} else {
+		ts.step(now, ts.target+correction)
+	}
+
 	// 如果连续确认 confirmThreshold 次后在容忍范围，则认为时间的同步完成
-	if ts.status == CONFIRMING && ts.confirmTimes == confirmThreshold {
+	if ts.status == CONFIRMING && ts.confirmTimes >= confirmThreshold {
 		metrics.TimeSyncerStatusSet(int8(SYNCED))
 		ts.status = SYNCED
 		log.Infoln("Time syncer sync finished.")
 	}
 }
 
+// currentDelta 计算 now 时刻逻辑时钟的偏移，偏移以 maxSlewRate 的速率从 delta 向 target 调整，需要持有 syncerLock
+func (ts *TimeSyncer) currentDelta(now time.Time) int64 {
+	diff := ts.target - ts.delta
+	if diff == 0 {
+		return ts.delta
+	}
+
+	maxStep := int64(float64(now.Sub(ts.slewTime).Milliseconds()) * maxSlewRate)
+	if abs(diff) <= maxStep {
+		return ts.target
+	}
+	if diff > 0 {
+		return ts.delta + maxStep
+	}
+	return ts.delta - maxStep
+}
+
+// step 直接将逻辑时钟的偏移设置为 offset，需要持有 syncerLock
+func (ts *TimeSyncer) step(now time.Time, offset int64) {
+	ts.delta = offset
+	ts.target = offset
+	ts.slewTime = now
+}
+
+// slew 从当前偏移开始向 offset 平滑调整，需要持有 syncerLock
+func (ts *TimeSyncer) slew(now time.Time, offset int64) {
+	ts.delta = ts.currentDelta(now)
+	ts.target = offset
+	ts.slewTime = now
+}
+
+// Stats 获取时间同步的状态和最近一轮的样本统计
+func (ts *TimeSyncer) Stats() TimeSyncStats {
+	ts.syncerLock.RLock()
+	defer ts.syncerLock.RUnlock()
+
+	return TimeSyncStats{
+		Status:       ts.status,
+		Offset:       ts.currentDelta(ts.clock.Now()),
+		Target:       ts.target,
+		Dispersion:   ts.last.dispersion,
+		RTT:          ts.last.rtt,
+		Samples:      ts.last.accepted,
+		Rejected:     ts.last.rejected,
+		Agreed:       ts.last.agreed,
+		ConfirmTimes: ts.confirmTimes,
+		LastRound:    ts.lastRound,
+	}
+}
+
 func (ts *TimeSyncer) synced() bool {
 	// 开启读锁，并且 defer 解锁
 	ts.syncerLock.RLock()
@@ -157,6 +447,91 @@ func (ts *TimeSyncer) synced() bool {
 	return ts.status == SYNCED
 }
 
+// filterSamples
+//
+//	@Description: 使用中位数和绝对中位差剔除异常样本，剩余样本按照往返时延的倒数加权得到本轮的偏移
+//	@param samples - 本轮的样本，不能为空
+//	@return roundResult - 本轮的计算结果
+func filterSamples(samples []timeSample) roundResult {
+	offsets := make([]int64, len(samples))
+	for idx, s := range samples {
+		offsets[idx] = s.offset
+	}
+	median := medianInt64(offsets)
+
+	deviations := make([]int64, len(samples))
+	for idx, s := range samples {
+		deviations[idx] = abs(s.offset - median)
+	}
+	mad := medianInt64(deviations)
+
+	// 每个样本的误差上界为往返时延的一半，在此基础上允许 outlierFactor 倍的绝对中位差
+	accepted := make([]timeSample, 0, len(samples))
+	for _, s := range samples {
+		if abs(s.offset-median) <= outlierFactor*mad+s.rtt/2 {
+			accepted = append(accepted, s)
+		}
+	}
+
+	// 中位数附近的样本一定会被保留，这里只是防御性检查
+	if len(accepted) == 0 {
+		return roundResult{offset: median, rejected: len(samples)}
+	}
+
+	var weightSum, offsetSum, rttSum float64
+	minRTT := accepted[0].rtt
+	for _, s := range accepted {
+		w := 1 / float64(s.rtt+1)
+		weightSum += w
+		offsetSum += w * float64(s.offset)
+		rttSum += float64(s.rtt)
+		if s.rtt < minRTT {
+			minRTT = s.rtt
+		}
+	}
+	mean := offsetSum / weightSum
+
+	var variance float64
+	for _, s := range accepted {
+		w := 1 / float64(s.rtt+1)
+		variance += w * (float64(s.offset) - mean) * (float64(s.offset) - mean)
+	}
+	variance /= weightSum
+
+	return roundResult{
+		offset:     int64(math.Round(mean)),
+		dispersion: math.Sqrt(variance) + float64(minRTT)/2,
+		rtt:        rttSum / float64(len(accepted)),
+		accepted:   len(accepted),
+		rejected:   len(samples) - len(accepted),
+	}
+}
+
+// countAgreed 统计偏移与 offset 的差值不超过 tolerance 加上样本自身误差（往返时延的一半）的节点数量。
+// 容忍范围固定而不随离散度变化，避免恶意节点通过拉大离散度使所有样本都被视为一致
+func countAgreed(samples []timeSample, offset int64, tolerance int64) int {
+	agreed := 0
+	for _, s := range samples {
+		if abs(s.offset-offset) <= tolerance+s.rtt/2 {
+			agreed++
+		}
+	}
+	return agreed
+}
+
+// medianInt64 计算中位数，values 会被排序
+func medianInt64(values []int64) int64 {
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
 func abs(x int64) int64 {
 	if x < 0 {
 		return -x
@@ -0,0 +1,269 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"testing"
+	"time"
+)
+
+// testTimeSample 模拟一次时间同步，对端逻辑时钟比本地物理时钟快 offset，单程耗时 leg
+func testTimeSample(ts *TimeSyncer, clock *utils.FakeClock, p *Peer, offset int64, leg time.Duration) {
+	reqTime := ts.GetLogicClock()
+	clock.Advance(leg)
+	remote := clock.Now().UnixMilli() + offset
+	clock.Advance(leg)
+
+	ts.ProcessSyncRespond(&p2p.TimeSyncMsg{
+		ReqTime:    reqTime,
+		RecReqTime: remote,
+		RspTime:    remote,
+		RecRspTime: ts.GetLogicClock(),
+	}, p)
+}
+
+// testTimePeers 创建 n 个用于时间同步测试的节点
+func testTimePeers(n int) []*Peer {
+	peers := make([]*Peer, n)
+	for i := range peers {
+		peers[i] = &Peer{peerID: peer.ID(fmt.Sprintf("peer-%d", i))}
+	}
+	return peers
+}
+
+func TestTimeSyncRejectOutlier(t *testing.T) {
+	clock := utils.NewFakeClock(time.UnixMilli(1700000000000))
+	ts := NewTimeSyncer(false, 0, clock)
+
+	peers := testTimePeers(5)
+
+	testTimeSample(ts, clock, peers[0], 200, 10*time.Millisecond)
+	testTimeSample(ts, clock, peers[1], 210, 20*time.Millisecond)
+	testTimeSample(ts, clock, peers[2], 190, 30*time.Millisecond)
+	testTimeSample(ts, clock, peers[3], 205, 40*time.Millisecond)
+	testTimeSample(ts, clock, peers[4], 5000, 10*time.Millisecond)
+	ts.processRound()
+
+	stats := ts.Stats()
+	if stats.Samples != 4 || stats.Rejected != 1 || stats.Agreed != 4 {
+		t.Fatalf("unexpected samples %d, rejected %d", stats.Samples, stats.Rejected)
+	}
+	if stats.Offset < 190 || stats.Offset > 210 {
+		t.Fatalf("unexpected offset %d", stats.Offset)
+	}
+	if stats.Status != CONFIRMING {
+		t.Fatalf("unexpected status %d", stats.Status)
+	}
+
+	// 低时延的样本权重更高
+	if stats.Offset > 205 {
+		t.Fatalf("offset %d not weighted by rtt", stats.Offset)
+	}
+}
+
+func TestTimeSyncSlew(t *testing.T) {
+	clock := utils.NewFakeClock(time.UnixMilli(1700000000000))
+	ts := NewTimeSyncer(false, 0, clock)
+	ts.status = SYNCED
+	peers := testTimePeers(3)
+
+	round := func(offset int64) {
+		for _, p := range peers {
+			testTimeSample(ts, clock, p, offset, 10*time.Millisecond)
+		}
+		ts.processRound()
+	}
+
+	round(150)
+	start := clock.Now().UnixMilli()
+
+	// 同步完成后不直接跳变，逻辑时钟以有限速率向目标调整
+	if delta := ts.GetLogicClock() - start; delta != 0 {
+		t.Fatalf("logic clock stepped by %d", delta)
+	}
+	if stats := ts.Stats(); stats.Target != 150 {
+		t.Fatalf("unexpected target %d", stats.Target)
+	}
+
+	clock.Advance(time.Second)
+	if delta := ts.GetLogicClock() - clock.Now().UnixMilli(); delta != 50 {
+		t.Fatalf("unexpected slew delta %d", delta)
+	}
+
+	clock.Advance(10 * time.Second)
+	if delta := ts.GetLogicClock() - clock.Now().UnixMilli(); delta != 150 {
+		t.Fatalf("slew not finished, delta %d", delta)
+	}
+
+	// 每轮的调整量受到限制，向后调整时逻辑时钟仍然单调递增
+	round(-1000)
+	if stats := ts.Stats(); stats.Target != 150-defaultMaxAdjustment {
+		t.Fatalf("adjustment not limited, target %d", stats.Target)
+	}
+
+	last := ts.GetLogicClock()
+	for i := 0; i < 100; i++ {
+		clock.Advance(100 * time.Millisecond)
+		now := ts.GetLogicClock()
+		if now <= last {
+			t.Fatalf("logic clock not monotonic, %d after %d", now, last)
+		}
+		last = now
+	}
+}
+
+func TestTimeSyncQuorum(t *testing.T) {
+	clock := utils.NewFakeClock(time.UnixMilli(1700000000000))
+	ts := NewTimeSyncer(false, 0, clock)
+	peers := testTimePeers(4)
+
+	// 单个节点无法改变逻辑时钟，重复的响应只计入一次
+	testTimeSample(ts, clock, peers[0], 5000, 10*time.Millisecond)
+	testTimeSample(ts, clock, peers[0], 5000, 10*time.Millisecond)
+	ts.processRound()
+	if stats := ts.Stats(); stats.Status != INITIAL || stats.Offset != 0 {
+		t.Fatalf("single peer adjusted clock, stats %+v", stats)
+	}
+
+	// 两个恶意节点和两个诚实节点，一致的比例不足
+	testTimeSample(ts, clock, peers[0], 5000, 10*time.Millisecond)
+	testTimeSample(ts, clock, peers[1], 5000, 10*time.Millisecond)
+	testTimeSample(ts, clock, peers[2], 0, 10*time.Millisecond)
+	testTimeSample(ts, clock, peers[3], 0, 10*time.Millisecond)
+	ts.processRound()
+	if stats := ts.Stats(); stats.Status != INITIAL || stats.Offset != 0 {
+		t.Fatalf("clock adjusted without agreement, stats %+v", stats)
+	}
+
+	// 多数节点一致时才进入确认阶段
+	testTimeSample(ts, clock, peers[0], 5000, 10*time.Millisecond)
+	testTimeSample(ts, clock, peers[1], 100, 10*time.Millisecond)
+	testTimeSample(ts, clock, peers[2], 100, 10*time.Millisecond)
+	testTimeSample(ts, clock, peers[3], 100, 10*time.Millisecond)
+	ts.processRound()
+	if stats := ts.Stats(); stats.Status != CONFIRMING || stats.Offset != 100 || stats.Agreed != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestTimeSyncDropStaleRespond(t *testing.T) {
+	clock := utils.NewFakeClock(time.UnixMilli(1700000000000))
+	ts := NewTimeSyncer(false, 0, clock)
+
+	reqTime := ts.GetLogicClock()
+	clock.Advance(syncInterval)
+	ts.processRound()
+
+	// 上一轮发出的请求在新一轮开始后才收到响应
+	ts.ProcessSyncRespond(&p2p.TimeSyncMsg{
+		ReqTime:    reqTime,
+		RecReqTime: reqTime,
+		RspTime:    reqTime,
+		RecRspTime: ts.GetLogicClock(),
+	}, testTimePeers(1)[0])
+	ts.processRound()
+
+	if stats := ts.Stats(); stats.Samples != 0 || stats.Status != INITIAL {
+		t.Fatalf("stale respond accepted, stats %+v", stats)
+	}
+}
+
+// testTimeResponder 在后台响应对端收到的时间同步请求，对端与本地使用同一个时钟
+func testTimeResponder(t *testing.T, clock *utils.FakeClock, rp *p2p.Peer, received chan *p2p.Message) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+
+	go func() {
+		for {
+			select {
+			case msg := <-received:
+				if msg.Code != p2p.StatusCodeTimeSyncReq {
+					continue
+				}
+				tMsg, err := utils.DeserializeTimeSyncMsg(msg.Payload)
+				if err != nil {
+					continue
+				}
+				tMsg.RecReqTime = clock.Now().UnixMilli()
+				tMsg.RspTime = tMsg.RecReqTime
+				payload, err := utils.SerializeTimeSyncMsg(tMsg)
+				if err != nil {
+					continue
+				}
+				rp.SendMessage(&p2p.Message{
+					Code:    p2p.StatusCodeTimeSyncRsp,
+					Payload: payload,
+					ReplyTo: msg.RequestID,
+				})
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func TestTimeSyncSmallNetwork(t *testing.T) {
+	clock := utils.NewFakeClock(time.UnixMilli(1700000000000))
+	chain := testSyncChain(t)
+	pm, err := NewP2PManager(&P2PManagerConfig{
+		Chain:   chain,
+		ChainID: chain.ChainID(),
+		DB:      memoryDB{},
+		Clock:   clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := pm.timeSyncer
+
+	// 三个节点的网络中，非创世节点只能向两个节点请求时间同步
+	for i := 0; i < 2; i++ {
+		p, rp, received := testRequestPeer(t, pm)
+		pm.peerSet = append(pm.peerSet, p)
+		testTimeResponder(t, clock, rp, received)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err = ts.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Stop(context.Background())
+	clock.BlockUntil(1)
+
+	// roundState 读取当前轮的开始时间和已经收集到的样本数量
+	roundState := func() (int64, int) {
+		ts.syncerLock.RLock()
+		defer ts.syncerLock.RUnlock()
+		return ts.roundStart, len(ts.samples)
+	}
+	// waitRound 等待 cond 满足，超时后测试失败
+	waitRound := func(round int, cond func(int64, int) bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond(roundState()) {
+			if time.Now().After(deadline) {
+				_, collected := roundState()
+				t.Fatalf("round %d timed out with %d samples", round, collected)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	for round := 0; round < 2*confirmThreshold; round++ {
+		// 等待上一轮结算完成，再等待本轮两个节点的样本都到达
+		before, _ := roundState()
+		clock.Advance(syncInterval)
+		waitRound(round, func(start int64, _ int) bool { return start != before })
+		if ts.Stats().Status == SYNCED {
+			break
+		}
+		waitRound(round, func(_ int64, collected int) bool { return collected >= 2 })
+	}
+
+	if stats := ts.Stats(); stats.Status != SYNCED {
+		t.Fatalf("time syncer not synced with two peers, stats %+v", stats)
+	}
+}
@@ -0,0 +1,101 @@
+// Package node
+// @Description: 交易广播。交易通过握手之后的 libp2p 数据流批量发送给支持交易广播的节点，消息来源由数据流的加密连接认证；
+// 每个节点的已知交易缓存用于去重，已经发送给对端或者从对端收到的交易不再发送给该节点
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/metrics"
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	txBatchSize     = 256                    // 单次广播的最大交易数量
+	txBatchBytes    = 1 << 20                // 单个交易广播消息的最大长度，1 MB
+	txBatchInterval = 100 * time.Millisecond // 交易没有攒满一批时的广播间隔
+	maxTxDataSize   = 1 << 17                // 交易 data 字段的最大长度，128 KB，超出的交易不广播也不接收
+)
+
+// broadcastTransaction
+//
+//	@Description: 交易广播协程，从广播队列中收集交易，攒满 txBatchSize 笔或者到达广播间隔时批量发送
+//	@receiver pm
+//	@param ctx - manager 的 context
+func (pm *P2PManager) broadcastTransaction(ctx context.Context) {
+	defer pm.wg.Done()
+
+	// 交易广播与网络传输相关，使用系统时钟
+	ticker := time.NewTicker(txBatchInterval)
+	defer ticker.Stop()
+
+	log.Infoln("P2P manger broadcast transaction routine start!")
+	batch := make([]*common.Transaction, 0, txBatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tx := <-pm.txBroadcastQueue:
+			if len(tx.Body.Data) > maxTxDataSize {
+				log.WithField("size", len(tx.Body.Data)).Debugln("Transaction too large, skip broadcast.")
+				continue
+			}
+
+			batch = append(batch, tx)
+			if len(batch) >= txBatchSize {
+				pm.gossipTransactions(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				pm.gossipTransactions(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// gossipTransactions
+//
+//	@Description: 将一批交易发送给所有支持交易广播的节点，每个节点只发送它还不知道的交易，
+//	序列化后超过 txBatchBytes 时拆分为多条消息
+//	@receiver pm
+//	@param txs - 待广播的交易
+func (pm *P2PManager) gossipTransactions(txs []*common.Transaction) {
+	pm.peerSetLock.RLock()
+	peers := make([]*Peer, 0, len(pm.peerSet))
+	for _, p := range pm.peerSet {
+		if !p.Stopped() && p.SupportTxGossip() {
+			peers = append(peers, p)
+		}
+	}
+	pm.peerSetLock.RUnlock()
+
+	for _, p := range peers {
+		unknown := make([]*common.Transaction, 0, len(txs))
+		for _, tx := range txs {
+			txHash := hex.EncodeToString(tx.Body.Hash[:])
+			if p.KnownTransaction(txHash) {
+				continue
+			}
+			p.MarkTransaction(txHash)
+			unknown = append(unknown, tx)
+		}
+
+		for len(unknown) > 0 {
+			payload, count, err := utils.SerializeTransactions(unknown, txBatchBytes)
+			if err != nil {
+				log.WithError(err).Errorln("Serialize transactions failed.")
+				break
+			}
+
+			p.peer.Send(p2p.StatusCodeTransactionsMsg, payload)
+			metrics.GossipTxSendCountAdd(count)
+			unknown = unknown[count:]
+		}
+	}
+}
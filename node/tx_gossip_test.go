@@ -0,0 +1,271 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/p2p"
+	"github.com/chain-lab/go-norn/utils"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	karmem "karmem.org/golang"
+	"testing"
+	"time"
+)
+
+// testTx 构建一笔在链 chainID 上签名、携带 data 的交易
+func testTx(t *testing.T, chainID int64, data []byte) *common.Transaction {
+	prv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := time.Now().UnixMilli()
+	txBody := common.TransactionBody{
+		Data:      data,
+		Timestamp: timestamp,
+		Expire:    timestamp + 3000,
+	}
+	txBody.Public = [33]byte(crypto.PublicKey2Bytes(&prv.PublicKey))
+	txBody.Address = crypto.PublicKeyBytes2Address(txBody.Public)
+
+	writer := karmem.NewWriter(1024)
+	if _, err = txBody.WriteAsRoot(writer); err != nil {
+		t.Fatal(err)
+	}
+
+	txHash := common.TransactionSigningHash(writer.Bytes(), chainID)
+	txBody.Signature, err = ecdsa.SignASN1(rand.Reader, prv, txHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txBody.Hash = [32]byte(txHash)
+	return &common.Transaction{Body: txBody}
+}
+
+// testTxGossipManager 创建已经完成同步的连接管理器，广播协程不启动，广播队列由测试读取
+func testTxGossipManager(t *testing.T) *P2PManager {
+	pm := testManager(t)
+	pm.txPool = core.NewTxPool(pm.chain)
+	pm.startRoutine.Do(func() {})
+	pm.blockSyncer.setSynced()
+	pm.timeSyncer.status = SYNCED
+	return pm
+}
+
+// testTxGossipPeer 通过 mocknet 连接一个支持交易广播的对端，返回本地的 Peer 以及对端收到的消息
+func testTxGossipPeer(t *testing.T, pm *P2PManager) (*Peer, chan *p2p.Message) {
+	mn := mocknet.New()
+	t.Cleanup(func() { _ = mn.Close() })
+
+	local, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan *p2p.Message, messageQueueCap)
+	remote.SetStreamHandler(ProtocolId, func(s network.Stream) {
+		rp, err := p2p.NewPeer(local.ID(), &s, received)
+		if err == nil {
+			t.Cleanup(rp.Close)
+		}
+	})
+
+	s, err := local.NewStream(context.Background(), remote.ID(), ProtocolId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPeer(remote.ID(), &s, PeerConfig{
+		chain:   pm.chain,
+		txPool:  pm.txPool,
+		handler: pm,
+		status: &p2p.HandshakeMsg{
+			ProtocolVersion: MinProtocolVersion,
+			Capabilities:    CapabilityTxGossip,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+
+	pm.peerSet = append(pm.peerSet, p)
+	pm.peers[p.peerID] = p
+	return p, received
+}
+
+// testReceiveTxs 等待对端收到下一条交易广播消息，跳过其他类型的消息
+func testReceiveTxs(t *testing.T, received chan *p2p.Message) ([]*common.Transaction, int) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-received:
+			if msg.Code != p2p.StatusCodeTransactionsMsg {
+				continue
+			}
+			txs, err := utils.DeserializeTransactions(msg.Payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return txs, len(msg.Payload)
+		case <-timeout:
+			t.Fatal("transactions not received")
+		}
+	}
+}
+
+func TestGossipTransactionsBatch(t *testing.T) {
+	pm := testTxGossipManager(t)
+	_, received := testTxGossipPeer(t, pm)
+
+	// 12 笔 100 KB 的交易超过单条消息的长度限制，需要拆分为两条消息
+	txs := make([]*common.Transaction, 12)
+	for i := range txs {
+		txs[i] = testTx(t, pm.chainID, make([]byte, 100<<10))
+	}
+	pm.gossipTransactions(txs)
+
+	count := 0
+	for messages := 0; count < len(txs); messages++ {
+		if messages == 2 {
+			t.Fatalf("batch split into more than 2 messages, %d txs received", count)
+		}
+
+		batch, size := testReceiveTxs(t, received)
+		if size > txBatchBytes {
+			t.Fatalf("message size %d over limit", size)
+		}
+		for _, tx := range batch {
+			if tx.Body.Hash != txs[count].Body.Hash {
+				t.Fatalf("unexpected transaction at %d", count)
+			}
+			count++
+		}
+	}
+}
+
+func TestGossipTransactionsKnown(t *testing.T) {
+	pm := testTxGossipManager(t)
+	p, received := testTxGossipPeer(t, pm)
+
+	first := testTx(t, pm.chainID, []byte("first"))
+	fromPeer := testTx(t, pm.chainID, []byte("from peer"))
+	p.MarkTransaction(hex.EncodeToString(fromPeer.Body.Hash[:]))
+
+	// 从对端收到的交易不再发回给对端
+	pm.gossipTransactions([]*common.Transaction{first, fromPeer})
+	batch, _ := testReceiveTxs(t, received)
+	if len(batch) != 1 || batch[0].Body.Hash != first.Body.Hash {
+		t.Fatalf("unexpected batch with %d txs", len(batch))
+	}
+
+	// 已经发送过的交易不会再发送，下一条消息只包含新的交易
+	second := testTx(t, pm.chainID, []byte("second"))
+	pm.gossipTransactions([]*common.Transaction{first})
+	pm.gossipTransactions([]*common.Transaction{first, second})
+	batch, _ = testReceiveTxs(t, received)
+	if len(batch) != 1 || batch[0].Body.Hash != second.Body.Hash {
+		t.Fatalf("known transaction sent again, batch with %d txs", len(batch))
+	}
+}
+
+// testTxSender 用于直接调用处理函数的对端，不建立连接
+func testTxSender(t *testing.T, id string) *Peer {
+	knownTxs, err := lru.New(maxKnownTxs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Peer{peerID: peer.ID(id), knownTxs: knownTxs}
+}
+
+// testTransactionsMsg 序列化一批交易作为对端发送的交易广播消息
+func testTransactionsMsg(t *testing.T, txs ...*common.Transaction) *p2p.Message {
+	payload, count, err := utils.SerializeTransactions(txs, txBatchBytes)
+	if err != nil || count != len(txs) {
+		t.Fatalf("serialize transactions failed: %v", err)
+	}
+	return &p2p.Message{Code: p2p.StatusCodeTransactionsMsg, Payload: payload}
+}
+
+func TestHandleTransactionsMsg(t *testing.T) {
+	pm := testTxGossipManager(t)
+	p := testTxSender(t, "sender")
+
+	tx := testTx(t, pm.chainID, []byte("transaction"))
+	txHash := hex.EncodeToString(tx.Body.Hash[:])
+	handleTransactionsMsg(pm, testTransactionsMsg(t, tx), p)
+	if !pm.txPool.Contain(txHash) || !p.KnownTransaction(txHash) {
+		t.Fatal("transaction not accepted")
+	}
+	if len(pm.txBroadcastQueue) != 1 {
+		t.Fatalf("%d transactions queued for broadcast", len(pm.txBroadcastQueue))
+	}
+
+	// 重复收到的交易不再广播
+	handleTransactionsMsg(pm, testTransactionsMsg(t, tx), testTxSender(t, "relay"))
+	if len(pm.txBroadcastQueue) != 1 {
+		t.Fatal("duplicate transaction broadcast again")
+	}
+	if score := pm.reputation.Score(p.peerID); score < 0 {
+		t.Fatalf("honest peer penalized, score %f", score)
+	}
+}
+
+func TestHandleTransactionsMsgSize(t *testing.T) {
+	pm := testTxGossipManager(t)
+	p := testTxSender(t, "sender")
+
+	large := testTx(t, pm.chainID, make([]byte, maxTxDataSize+1))
+	handleTransactionsMsg(pm, testTransactionsMsg(t, large), p)
+	if pm.txPool.Contain(hex.EncodeToString(large.Body.Hash[:])) {
+		t.Fatal("oversize transaction accepted")
+	}
+	if score := pm.reputation.Score(p.peerID); score >= 0 {
+		t.Fatal("oversize transaction not penalized")
+	}
+}
+
+func TestHandleTransactionsMsgRateLimit(t *testing.T) {
+	pm := testTxGossipManager(t)
+	pm.txLimiter.limit = 1
+	p := testTxSender(t, "relay")
+
+	// 超出速率限制的交易只丢弃，不扣除转发节点的分数
+	a, b := testTx(t, pm.chainID, []byte("a")), testTx(t, pm.chainID, []byte("b"))
+	handleTransactionsMsg(pm, testTransactionsMsg(t, a, b), p)
+	if pm.txPool.Contain(hex.EncodeToString(a.Body.Hash[:])) {
+		t.Fatal("transactions over limit accepted")
+	}
+	if score := pm.reputation.Score(p.peerID); score != 0 {
+		t.Fatalf("relay penalized for rate limit, score %f", score)
+	}
+}
+
+func TestHandleTransactionsMsgChainID(t *testing.T) {
+	pm := testTxGossipManager(t)
+	p := testTxSender(t, "sender")
+
+	// 其他链上签名的交易无法通过验证
+	other := testTx(t, pm.chainID+1, []byte("other chain"))
+	handleTransactionsMsg(pm, testTransactionsMsg(t, other), p)
+	if pm.txPool.Contain(hex.EncodeToString(other.Body.Hash[:])) {
+		t.Fatal("transaction signed for another chain accepted")
+	}
+	if score := pm.reputation.Score(p.peerID); score >= 0 {
+		t.Fatal("transaction signed for another chain not penalized")
+	}
+}
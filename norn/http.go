@@ -0,0 +1,44 @@
+// Package norn
+// @Description: 事件订阅服务，每个节点使用独立的 ServeMux，避免多个节点注册到同一个默认的 ServeMux
+package norn
+
+import (
+	"context"
+	"github.com/chain-lab/go-norn/pubsub"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+)
+
+// eventServer 将事件路由的 websocket 订阅接口包装为可以由生命周期管理的服务
+type eventServer struct {
+	server *http.Server
+}
+
+func newEventServer(addr string, router *pubsub.EventRouter) *eventServer {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subscribe", router.HandleConnect)
+
+	return &eventServer{
+		server: &http.Server{Addr: addr, Handler: mux},
+	}
+}
+
+func (s *eventServer) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		err := s.server.Serve(lis)
+		if err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Errorf("Event server on %s failed.", s.server.Addr)
+		}
+	}()
+	return nil
+}
+
+func (s *eventServer) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
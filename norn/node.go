@@ -0,0 +1,205 @@
+// Package norn
+// @Description: 节点容器，持有一个节点的区块链、交易池、VDF 计算器、连接管理器、事件路由和 RPC 服务，
+// 子系统之间通过容器显式传递而不是通过包级别的单例获取，因此同一个进程中可以运行多个节点
+package norn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/interfaces"
+	"github.com/chain-lab/go-norn/node"
+	"github.com/chain-lab/go-norn/pubsub"
+	"github.com/chain-lab/go-norn/rpc"
+	"github.com/chain-lab/go-norn/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+var (
+	errNilDatabase = errors.New("node database is nil")
+	errCreateChain = errors.New("create blockchain failed")
+)
+
+// Config 节点的实例化配置信息
+type Config struct {
+	DB            interfaces.DBInterface // 数据库实例，由调用方负责关闭
+	Genesis       bool                   // 是否创世节点
+	GenesisConfig *core.Genesis          // 创世文件，为 nil 时不从创世文件初始化
+	InitialDelta  int64                  // 初始时间偏移，仅仅用于进行时间同步测试
+	ChainID       int64                  // 链 ID，使用创世文件或者本地已有创世区块时以创世参数为准
+	RPCAddress    string                 // RPC 服务的监听地址，为空时使用配置文件中的地址
+	EventAddress  string                 // 事件订阅服务的监听地址，为空时不启动
+	DisableRPC    bool                   // 不启动 RPC 服务
+	ConsensusKey  *ecdsa.PrivateKey      // 共识私钥，为 nil 时使用配置文件中的 consensus.prv
+	Filter        node.MessageFilter     // 入站消息过滤，仅用于模拟测试
+	Clock         utils.Clock            // 节点使用的时钟，为 nil 时使用系统时钟，模拟测试中使用 utils.FakeClock
+	Light         bool                   // 轻节点，只同步并验证区块头，需要创世文件或者本地已有的创世区块作为信任起点
+}
+
+// Node 节点容器
+type Node struct {
+	config *Config
+
+	chain     *core.BlockChain    // 区块链实例
+	txPool    *core.TxPool        // 交易池实例
+	manager   *node.P2PManager    // 连接管理器
+	router    *pubsub.EventRouter // 事件路由
+	rpcServer *rpc.Server         // RPC 服务
+
+	lifecycle *utils.Lifecycle // 子系统的生命周期管理
+}
+
+// New
+//
+//	@Description: 创建节点以及节点的所有子系统，子系统在 Start 之前不会启动任何协程
+//	@param config - 节点配置
+//	@return *Node - 节点实例
+//	@return error
+func New(config *Config) (*Node, error) {
+	if config.DB == nil {
+		return nil, errNilDatabase
+	}
+
+	router := pubsub.NewEventRouter()
+	chain := core.NewBlockchain(config.DB, router, config.Clock)
+	if chain == nil {
+		return nil, errCreateChain
+	}
+
+	// 链 ID 以创世文件为准，本地已经存在创世区块时，使用创世参数中的链 ID
+	chainID := config.ChainID
+	if config.GenesisConfig != nil {
+		chainID = config.GenesisConfig.ChainID
+	}
+	if chain.GenesisHash() != (common.Hash{}) {
+		chainID = chain.ChainID()
+	}
+	chain.SetChainID(chainID)
+	log.Infof("Create node with chain id %d.", chainID)
+
+	if config.ConsensusKey != nil {
+		chain.SetConsensusKey(config.ConsensusKey)
+	}
+
+	txPool := core.NewTxPool(chain)
+	chain.SetTxPool(txPool)
+
+	manager, err := node.NewP2PManager(&node.P2PManagerConfig{
+		TxPool:       txPool,
+		Chain:        chain,
+		Genesis:      config.Genesis,
+		InitialDelta: config.InitialDelta,
+		ChainID:      chainID,
+		DB:           config.DB,
+		Filter:       config.Filter,
+		Clock:        config.Clock,
+		Light:        config.Light,
+	})
+	if err != nil {
+		log.WithError(err).Errorln("Create p2p manager failed.")
+		return nil, err
+	}
+
+	n := &Node{
+		config:    config,
+		chain:     chain,
+		txPool:    txPool,
+		manager:   manager,
+		router:    router,
+		lifecycle: utils.NewLifecycle(),
+	}
+	if !config.DisableRPC {
+		n.rpcServer = rpc.NewServer(manager, config.RPCAddress)
+	}
+
+	// 子系统按照依赖顺序注册，停止时按照相反的顺序停止：
+	// 先停止对外的 RPC 和网络，再停止交易池和区块链，最后停止事件路由
+	n.lifecycle.Register("event router", router)
+	n.lifecycle.Register("blockchain", chain)
+	n.lifecycle.Register("transaction pool", txPool)
+	n.lifecycle.Register("p2p manager", manager)
+	if n.rpcServer != nil {
+		n.lifecycle.Register("rpc server", n.rpcServer)
+	}
+	if config.EventAddress != "" {
+		n.lifecycle.Register("event server", newEventServer(config.EventAddress, router))
+	}
+
+	return n, nil
+}
+
+// Start
+//
+//	@Description: 按照依赖顺序启动所有子系统，配置了创世文件时使用创世文件初始化区块链
+//	@receiver n
+//	@param ctx - 子系统协程的父 context
+//	@return error
+func (n *Node) Start(ctx context.Context) error {
+	if err := n.lifecycle.Start(ctx); err != nil {
+		return err
+	}
+
+	// 轻节点从创世区块开始验证区块头，无法从网络中获取可信的创世区块
+	if n.config.Light && n.config.GenesisConfig == nil && n.chain.GenesisHash() == (common.Hash{}) {
+		log.Warningln("Light client has no genesis block, start with genesis file first.")
+	}
+
+	// 从创世文件初始化区块链，本地已有的创世区块需要与文件一致
+	if n.config.GenesisConfig != nil {
+		if err := n.chain.InitGenesis(n.config.GenesisConfig); err != nil {
+			log.WithError(err).Errorln("Init genesis failed.")
+			_ = n.lifecycle.Stop(ctx)
+			return err
+		}
+
+		// 创世节点使用创世文件启动时，默认已经完成同步
+		if n.config.Genesis {
+			n.manager.SetSynced()
+		}
+	}
+
+	return nil
+}
+
+// Stop
+//
+//	@Description: 按照与启动相反的顺序停止所有子系统，数据库由调用方在 Stop 之后关闭
+//	@receiver n
+//	@param ctx - 停止的超时 context
+//	@return error
+func (n *Node) Stop(ctx context.Context) error {
+	return n.lifecycle.Stop(ctx)
+}
+
+// Chain 节点的区块链实例
+func (n *Node) Chain() *core.BlockChain {
+	return n.chain
+}
+
+// TxPool 节点的交易池实例
+func (n *Node) TxPool() *core.TxPool {
+	return n.txPool
+}
+
+// Calculator 节点的 VDF 计算实例，还没有创世区块时返回 nil
+func (n *Node) Calculator() *crypto.Calculator {
+	return n.chain.Calculator()
+}
+
+// Manager 节点的连接管理器
+func (n *Node) Manager() *node.P2PManager {
+	return n.manager
+}
+
+// EventRouter 节点的事件路由
+func (n *Node) EventRouter() *pubsub.EventRouter {
+	return n.router
+}
+
+// RPCServer 节点的 RPC 服务，关闭 RPC 时返回 nil
+func (n *Node) RPCServer() *rpc.Server {
+	return n.rpcServer
+}
@@ -0,0 +1,245 @@
+// Package p2p
+// @Description: 消息的分帧编码。旧版本使用 base64 编码并以 0xff 结尾，新版本使用带长度前缀的二进制帧，
+// 帧头中记录了负载使用的压缩算法。数据流本身由 libp2p 的安全传输层加密和认证，这里只负责分帧，
+// 双方在握手时协商使用的分帧方式和压缩算法，与旧版本节点通信时仍然使用旧的分帧方式
+package p2p
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"io"
+	karmem "karmem.org/golang"
+	"sync"
+)
+
+// Framing 消息的分帧方式
+type Framing uint8
+
+const (
+	FramingLegacy Framing = iota // base64 编码，以 0xff 结尾
+	FramingBinary                // 4 字节大端长度 + 1 字节压缩算法 + 负载
+)
+
+// Compression 二进制帧负载使用的压缩算法
+type Compression uint8
+
+const (
+	CompressionNone Compression = iota
+	CompressionSnappy
+	CompressionZstd
+)
+
+const (
+	MaxFrameSize        = 1 << 22 // 单个帧解码后的最大长度，4 MB
+	frameHeaderSize     = 5       // 二进制帧头长度
+	compressMinSize     = 256     // 负载小于该长度时不压缩
+	legacyFrameEndMark  = 0xff    // 旧版本分帧的结束标志
+	messageWriterBuffer = 1024
+)
+
+var (
+	errFrameTooLarge      = errors.New("frame exceeds max frame size")
+	errMalformedFrame     = errors.New("malformed frame")
+	errUnknownCompression = errors.New("unknown or not negotiated compression")
+
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+// FrameConfig 与对端协商得到的分帧配置，零值为旧版本的分帧方式
+type FrameConfig struct {
+	Framing     Framing
+	Compression Compression
+}
+
+func initZstd() {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+		zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxFrameSize))
+	})
+}
+
+// writeMessage 按照分帧配置将消息编码后写入数据流
+func writeMessage(rw *bufio.ReadWriter, msg *Message, cfg FrameConfig) error {
+	msgWriter := karmem.NewWriter(messageWriterBuffer)
+	if _, err := msg.WriteAsRoot(msgWriter); err != nil {
+		return err
+	}
+
+	msgBytes := msgWriter.Bytes()
+	if len(msgBytes) > MaxFrameSize {
+		return errFrameTooLarge
+	}
+
+	var err error
+	if cfg.Framing == FramingBinary {
+		err = writeBinaryFrame(rw, msgBytes, cfg.Compression)
+	} else {
+		err = writeLegacyFrame(rw, msgBytes)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 这里必须强制 Flush， 否则短消息收不到
+	return rw.Flush()
+}
+
+// readMessage 按照分帧配置从数据流中读取一条消息，帧格式错误时返回错误，调用方需要断开连接
+func readMessage(rw *bufio.ReadWriter, cfg FrameConfig) (*Message, error) {
+	var data []byte
+	var err error
+	if cfg.Framing == FramingBinary {
+		data, err = readBinaryFrame(rw, cfg.Compression)
+	} else {
+		data, err = readLegacyFrame(rw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 空帧，直接跳过
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	msg := new(Message)
+	msg.ReadAsRoot(karmem.NewReader(data))
+	if int(msg.Size) != len(msg.Payload) {
+		return nil, errMalformedFrame
+	}
+
+	return msg, nil
+}
+
+func writeLegacyFrame(w io.Writer, data []byte) error {
+	l := base64.StdEncoding.EncodedLen(len(data))
+	encoded := make([]byte, l, l+1)
+	base64.StdEncoding.Encode(encoded, data)
+	encoded = append(encoded, legacyFrameEndMark)
+
+	_, err := w.Write(encoded)
+	return err
+}
+
+// readLegacyFrame 读取旧版本的帧，限制帧的长度，base64 解码失败时返回错误
+func readLegacyFrame(r *bufio.ReadWriter) ([]byte, error) {
+	limit := base64.StdEncoding.EncodedLen(MaxFrameSize) + 1
+	var encoded []byte
+
+	for {
+		chunk, err := r.ReadSlice(legacyFrameEndMark)
+		if len(encoded)+len(chunk) > limit {
+			return nil, errFrameTooLarge
+		}
+		encoded = append(encoded, chunk...)
+
+		if err == nil {
+			break
+		}
+		if err != bufio.ErrBufferFull {
+			return nil, err
+		}
+	}
+
+	if len(encoded) <= 1 {
+		return nil, nil
+	}
+	encoded = encoded[:len(encoded)-1]
+
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	l, err := base64.StdEncoding.Decode(decoded, encoded)
+	if err != nil {
+		return nil, errMalformedFrame
+	}
+
+	return decoded[:l], nil
+}
+
+// writeBinaryFrame 写入二进制帧，负载较短或者压缩后没有变小时不压缩
+func writeBinaryFrame(w io.Writer, data []byte, compression Compression) error {
+	codec := CompressionNone
+	body := data
+
+	if len(data) >= compressMinSize {
+		var compressed []byte
+		switch compression {
+		case CompressionSnappy:
+			compressed = snappy.Encode(nil, data)
+		case CompressionZstd:
+			initZstd()
+			compressed = zstdEncoder.EncodeAll(data, nil)
+		}
+
+		if compressed != nil && len(compressed) < len(data) {
+			codec = compression
+			body = compressed
+		}
+	}
+
+	header := make([]byte, frameHeaderSize)
+	binary.BigEndian.PutUint32(header, uint32(len(body)))
+	header[4] = byte(codec)
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// readBinaryFrame 读取二进制帧，只接受未压缩或者协商得到的压缩算法，解压后的长度同样受到限制
+func readBinaryFrame(r io.Reader, compression Compression) ([]byte, error) {
+	header := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size > MaxFrameSize {
+		return nil, errFrameTooLarge
+	}
+
+	codec := Compression(header[4])
+	if codec != CompressionNone && codec != compression {
+		return nil, errUnknownCompression
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	switch codec {
+	case CompressionSnappy:
+		l, err := snappy.DecodedLen(body)
+		if err != nil {
+			return nil, errMalformedFrame
+		}
+		if l > MaxFrameSize {
+			return nil, errFrameTooLarge
+		}
+		data, err := snappy.Decode(nil, body)
+		if err != nil {
+			return nil, errMalformedFrame
+		}
+		return data, nil
+	case CompressionZstd:
+		initZstd()
+		data, err := zstdDecoder.DecodeAll(body, nil)
+		if err != nil {
+			return nil, errMalformedFrame
+		}
+		if len(data) > MaxFrameSize {
+			return nil, errFrameTooLarge
+		}
+		return data, nil
+	}
+
+	return body, nil
+}
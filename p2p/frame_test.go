@@ -0,0 +1,339 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/golang/snappy"
+	"github.com/libp2p/go-libp2p/core/network"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"io"
+	"testing"
+	"time"
+)
+
+// testFrameBuffer 创建读写同一块缓冲区的 ReadWriter
+func testFrameBuffer(buf *bytes.Buffer) *bufio.ReadWriter {
+	return bufio.NewReadWriter(bufio.NewReader(buf), bufio.NewWriter(buf))
+}
+
+// testFrameMessage 创建负载为 payload 的消息
+func testFrameMessage(payload []byte) *Message {
+	return &Message{
+		Code:      StatusCodeTransactionsMsg,
+		Size:      uint32(len(payload)),
+		Payload:   payload,
+		RequestID: 7,
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	configs := map[string]FrameConfig{
+		"legacy": {},
+		"binary": {Framing: FramingBinary},
+		"snappy": {Framing: FramingBinary, Compression: CompressionSnappy},
+		"zstd":   {Framing: FramingBinary, Compression: CompressionZstd},
+	}
+	payloads := map[string][]byte{
+		"empty":        {},
+		"short":        []byte("payload"),
+		"compressible": bytes.Repeat([]byte("norn"), 4096),
+	}
+
+	for name, cfg := range configs {
+		for kind, payload := range payloads {
+			var buf bytes.Buffer
+			rw := testFrameBuffer(&buf)
+
+			// 连续写入两条消息，读取时不能越过帧的边界
+			for i := 0; i < 2; i++ {
+				if err := writeMessage(rw, testFrameMessage(payload), cfg); err != nil {
+					t.Fatalf("%s %s: write failed: %v", name, kind, err)
+				}
+			}
+
+			// 可以压缩的负载使用协商得到的压缩算法
+			if cfg.Framing == FramingBinary && kind == "compressible" {
+				if codec := Compression(buf.Bytes()[4]); codec != cfg.Compression {
+					t.Fatalf("%s %s: frame compressed with %d", name, kind, codec)
+				}
+			}
+
+			for i := 0; i < 2; i++ {
+				msg, err := readMessage(rw, cfg)
+				if err != nil {
+					t.Fatalf("%s %s: read failed: %v", name, kind, err)
+				}
+				if msg.Code != StatusCodeTransactionsMsg || msg.RequestID != 7 ||
+					!bytes.Equal(msg.Payload, payload) {
+					t.Fatalf("%s %s: unexpected message", name, kind)
+				}
+			}
+		}
+	}
+}
+
+func TestFrameShortPayloadUncompressed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeBinaryFrame(&buf, []byte("short"), CompressionZstd); err != nil {
+		t.Fatal(err)
+	}
+	if codec := Compression(buf.Bytes()[4]); codec != CompressionNone {
+		t.Fatalf("short payload compressed with %d", codec)
+	}
+
+	// 对端可以发送未压缩的帧，即使协商了压缩算法
+	data, err := readBinaryFrame(&buf, CompressionSnappy)
+	if err != nil || !bytes.Equal(data, []byte("short")) {
+		t.Fatalf("uncompressed frame rejected: %v", err)
+	}
+}
+
+func TestFrameOversize(t *testing.T) {
+	// 写入时超过长度限制
+	rw := testFrameBuffer(&bytes.Buffer{})
+	msg := testFrameMessage(make([]byte, MaxFrameSize))
+	for _, cfg := range []FrameConfig{{}, {Framing: FramingBinary}} {
+		if err := writeMessage(rw, msg, cfg); err != errFrameTooLarge {
+			t.Fatalf("oversize message written, error %v", err)
+		}
+	}
+
+	// 帧头中的长度超过限制时不分配内存
+	header := make([]byte, frameHeaderSize)
+	binary.BigEndian.PutUint32(header, MaxFrameSize+1)
+	if _, err := readBinaryFrame(bytes.NewReader(header), CompressionNone); err != errFrameTooLarge {
+		t.Fatalf("oversize binary frame accepted, error %v", err)
+	}
+
+	// 旧版本的帧没有结束标志时不会无限读取
+	legacy := bytes.Repeat([]byte("A"), 2*MaxFrameSize)
+	if _, err := readLegacyFrame(testFrameBuffer(bytes.NewBuffer(legacy))); err != errFrameTooLarge {
+		t.Fatalf("oversize legacy frame accepted, error %v", err)
+	}
+
+	// 解压后的长度超过限制
+	large := bytes.Repeat([]byte{0}, MaxFrameSize+1)
+	var buf bytes.Buffer
+	body := snappy.Encode(nil, large)
+	binary.Write(&buf, binary.BigEndian, uint32(len(body)))
+	buf.WriteByte(byte(CompressionSnappy))
+	buf.Write(body)
+	if _, err := readBinaryFrame(&buf, CompressionSnappy); err != errFrameTooLarge {
+		t.Fatalf("snappy bomb accepted, error %v", err)
+	}
+
+	initZstd()
+	buf.Reset()
+	body = zstdEncoder.EncodeAll(large, nil)
+	binary.Write(&buf, binary.BigEndian, uint32(len(body)))
+	buf.WriteByte(byte(CompressionZstd))
+	buf.Write(body)
+	if _, err := readBinaryFrame(&buf, CompressionZstd); err == nil {
+		t.Fatal("zstd bomb accepted")
+	}
+}
+
+func TestFrameMalformed(t *testing.T) {
+	// 帧头或者负载不完整
+	var buf bytes.Buffer
+	if err := writeBinaryFrame(&buf, []byte("payload"), CompressionNone); err != nil {
+		t.Fatal(err)
+	}
+	frame := buf.Bytes()
+	if _, err := readBinaryFrame(bytes.NewReader(frame[:3]), CompressionNone); err != io.ErrUnexpectedEOF {
+		t.Fatalf("truncated header accepted, error %v", err)
+	}
+	if _, err := readBinaryFrame(bytes.NewReader(frame[:len(frame)-1]), CompressionNone); err != io.ErrUnexpectedEOF {
+		t.Fatalf("truncated body accepted, error %v", err)
+	}
+
+	// 没有协商的压缩算法和未知的压缩算法
+	for _, codec := range []Compression{CompressionZstd, 0xff} {
+		tampered := append([]byte{}, frame...)
+		tampered[4] = byte(codec)
+		if _, err := readBinaryFrame(bytes.NewReader(tampered), CompressionSnappy); err != errUnknownCompression {
+			t.Fatalf("compression %d accepted, error %v", codec, err)
+		}
+	}
+
+	// 压缩数据损坏
+	for _, codec := range []Compression{CompressionSnappy, CompressionZstd} {
+		tampered := append([]byte{}, frame...)
+		tampered[4] = byte(codec)
+		if _, err := readBinaryFrame(bytes.NewReader(tampered), codec); err != errMalformedFrame {
+			t.Fatalf("corrupted compression %d accepted, error %v", codec, err)
+		}
+	}
+
+	// 旧版本的帧 base64 解码失败
+	legacy := append([]byte("not base64!"), legacyFrameEndMark)
+	if _, err := readMessage(testFrameBuffer(bytes.NewBuffer(legacy)), FrameConfig{}); err != errMalformedFrame {
+		t.Fatalf("malformed legacy frame accepted, error %v", err)
+	}
+
+	// 消息中记录的长度与负载不一致
+	msg := testFrameMessage([]byte("payload"))
+	msg.Size = 100
+	rw := testFrameBuffer(&bytes.Buffer{})
+	if err := writeMessage(rw, msg, FrameConfig{Framing: FramingBinary}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readMessage(rw, FrameConfig{Framing: FramingBinary}); err != errMalformedFrame {
+		t.Fatalf("message with wrong size accepted, error %v", err)
+	}
+
+	// 空帧直接跳过
+	buf.Reset()
+	if err := writeBinaryFrame(&buf, nil, CompressionNone); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := readMessage(testFrameBuffer(&buf), FrameConfig{Framing: FramingBinary}); msg != nil || err != nil {
+		t.Fatalf("empty binary frame not skipped, error %v", err)
+	}
+	empty := bytes.NewBuffer([]byte{legacyFrameEndMark})
+	if msg, err := readMessage(testFrameBuffer(empty), FrameConfig{}); msg != nil || err != nil {
+		t.Fatalf("empty legacy frame not skipped, error %v", err)
+	}
+}
+
+// testStreams 通过 mocknet 创建一对相连的数据流
+func testStreams(t *testing.T) (network.Stream, network.Stream) {
+	mn := mocknet.New()
+	t.Cleanup(func() { _ = mn.Close() })
+
+	local, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan network.Stream, 1)
+	remote.SetStreamHandler("/frame/test", func(s network.Stream) {
+		accepted <- s
+	})
+	s, err := local.NewStream(context.Background(), remote.ID(), "/frame/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 协议协商是惰性的，第一次写入后对端才会收到数据流
+	if _, err = s.Write(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case rs := <-accepted:
+		t.Cleanup(func() { _ = rs.Reset() })
+		t.Cleanup(func() { _ = s.Reset() })
+		return s, rs
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream not accepted")
+	}
+	return nil, nil
+}
+
+func TestFrameHandshakeNegotiation(t *testing.T) {
+	cases := map[string]FrameConfig{
+		"legacy": {},
+		"zstd":   {Framing: FramingBinary, Compression: CompressionZstd},
+	}
+
+	for name, cfg := range cases {
+		local, remote := testStreams(t)
+
+		// 握手消息始终使用旧的分帧方式，之后双方切换到协商得到的分帧方式
+		type result struct {
+			msg *Message
+			rw  *bufio.ReadWriter
+			err error
+		}
+		remoteResult := make(chan result, 1)
+		go func() {
+			msg, rw, err := Handshake(remote, []byte("remote"), time.Second)
+			remoteResult <- result{msg, rw, err}
+		}()
+
+		msg, rw, err := Handshake(local, []byte("local"), time.Second)
+		if err != nil || !bytes.Equal(msg.Payload, []byte("remote")) {
+			t.Fatalf("%s: local handshake failed: %v", name, err)
+		}
+		res := <-remoteResult
+		if res.err != nil || !bytes.Equal(res.msg.Payload, []byte("local")) {
+			t.Fatalf("%s: remote handshake failed: %v", name, res.err)
+		}
+
+		received := make(chan *Message, 1)
+		lp, _ := NewPeerWithReadWriter("remote", &local, rw, cfg, make(chan *Message, 1))
+		rp, _ := NewPeerWithReadWriter("local", &remote, res.rw, cfg, received)
+
+		payload := bytes.Repeat([]byte("norn"), 4096)
+		lp.SendMessage(&Message{Code: StatusCodeTransactionsMsg, Payload: payload})
+		select {
+		case msg = <-received:
+			if !bytes.Equal(msg.Payload, payload) {
+				t.Fatalf("%s: unexpected payload", name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: message not received", name)
+		}
+
+		lp.Close()
+		rp.Close()
+	}
+}
+
+func TestFrameMismatchDisconnect(t *testing.T) {
+	local, remote := testStreams(t)
+
+	// 旧版本节点发送的帧不能按二进制帧解析，格式错误时断开连接
+	lp, _ := NewPeer("remote", &local, make(chan *Message, 1))
+	defer lp.Close()
+	rp, _ := NewPeerWithReadWriter("local", &remote,
+		bufio.NewReadWriter(bufio.NewReader(remote), bufio.NewWriter(remote)),
+		FrameConfig{Framing: FramingBinary}, make(chan *Message, 1))
+
+	lp.Send(StatusCodeTransactionsMsg, []byte("payload"))
+	select {
+	case <-rp.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("peer not disconnected after mismatched frame")
+	}
+}
+
+func TestFrameHandshakeLegacyFallback(t *testing.T) {
+	local, remote := testStreams(t)
+
+	// 旧版本节点不发送握手消息，直接发送其他消息
+	rp, _ := NewPeer("local", &remote, make(chan *Message, 1))
+	defer rp.Close()
+	rp.Send(StatusCodeTransactionsMsg, []byte("first"))
+
+	msg, rw, err := Handshake(local, []byte("local"), time.Second)
+	if err != nil || msg != nil {
+		t.Fatalf("legacy peer not accepted, error %v", err)
+	}
+
+	// 已经读取的第一条消息和之后的消息都按照旧的分帧方式交给 Peer
+	received := make(chan *Message, 2)
+	lp, _ := NewPeerWithReadWriter("remote", &local, rw, FrameConfig{}, received)
+	defer lp.Close()
+	rp.Send(StatusCodeTransactionsMsg, []byte("second"))
+
+	for _, payload := range []string{"first", "second"} {
+		select {
+		case msg = <-received:
+			if msg.Code != StatusCodeTransactionsMsg || string(msg.Payload) != payload {
+				t.Fatalf("unexpected message %d %q", msg.Code, msg.Payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message %s not received", payload)
+		}
+	}
+}
@@ -7,7 +7,7 @@ import (
 
 var _ unsafe.Pointer
 
-var _Null = make([]byte, 64)
+var _Null = make([]byte, 128)
 var _NullReader = karmem.NewReader(_Null)
 
 type (
@@ -39,6 +39,15 @@ const (
 	StatusCodeBufferedBlocksMsg             StatusCode = 22
 	StatusCodeTimeSyncReq                   StatusCode = 23
 	StatusCodeTimeSyncRsp                   StatusCode = 24
+	StatusCodeHandshakeMsg                  StatusCode = 25
+	StatusCodeSyncGetBlockRangeMsg          StatusCode = 26
+	StatusCodeSyncBlockRangeMsg             StatusCode = 27
+	StatusCodeSyncGetHeadersMsg             StatusCode = 28
+	StatusCodeSyncHeadersMsg                StatusCode = 29
+	StatusCodeGetTxProofMsg                 StatusCode = 30
+	StatusCodeTxProofMsg                    StatusCode = 31
+	StatusCodeGetDataProofMsg               StatusCode = 32
+	StatusCodeDataProofMsg                  StatusCode = 33
 )
 
 type (
@@ -46,10 +55,15 @@ type (
 )
 
 const (
-	PacketIdentifierSyncStatusMsg    = 12064657818327214469
-	PacketIdentifierTimeSyncMsg      = 6014709404869090737
-	PacketIdentifierMessage          = 14302180353067076632
-	PacketIdentifierBroadcastMessage = 3104464370606199534
+	PacketIdentifierSyncStatusMsg     = 12064657818327214469
+	PacketIdentifierTimeSyncMsg       = 6014709404869090737
+	PacketIdentifierMessage           = 14302180353067076632
+	PacketIdentifierBroadcastMessage  = 3104464370606199534
+	PacketIdentifierHandshakeMsg      = 12875338193121472530
+	PacketIdentifierSyncBlockRangeReq = 6711174443027905350
+	PacketIdentifierTxProofMsg        = 53899367113649704
+	PacketIdentifierDataProofReq      = 6064056009839487699
+	PacketIdentifierDataProofMsg      = 9239857110496496279
 )
 
 type SyncStatusMsg struct {
@@ -57,6 +71,8 @@ type SyncStatusMsg struct {
 	LatestHash          [32]byte // db 中的最新区块哈希
 	BufferedStartHeight int64    // 缓冲区起始区块高度
 	BufferedEndHeight   int64    // 缓冲区截止区块高度
+	GenesisHash         [32]byte // 创世区块哈希
+	ChainID             int64    // 链 ID
 }
 
 func NewSyncStatusMsg() SyncStatusMsg {
@@ -77,14 +93,14 @@ func (x *SyncStatusMsg) WriteAsRoot(writer *karmem.Writer) (offset uint, err err
 
 func (x *SyncStatusMsg) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
 	offset = start
-	size := uint(64)
+	size := uint(104)
 	if offset == 0 {
 		offset, err = writer.Alloc(size)
 		if err != nil {
 			return 0, err
 		}
 	}
-	writer.Write4At(offset, uint32(60))
+	writer.Write4At(offset, uint32(100))
 	__LatestHeightOffset := offset + 4
 	writer.Write8At(__LatestHeightOffset, *(*uint64)(unsafe.Pointer(&x.LatestHeight)))
 	__LatestHashOffset := offset + 12
@@ -93,6 +109,10 @@ func (x *SyncStatusMsg) Write(writer *karmem.Writer, start uint) (offset uint, e
 	writer.Write8At(__BufferedStartHeightOffset, *(*uint64)(unsafe.Pointer(&x.BufferedStartHeight)))
 	__BufferedEndHeightOffset := offset + 52
 	writer.Write8At(__BufferedEndHeightOffset, *(*uint64)(unsafe.Pointer(&x.BufferedEndHeight)))
+	__GenesisHashOffset := offset + 60
+	writer.WriteAt(__GenesisHashOffset, (*[32]byte)(unsafe.Pointer(&x.GenesisHash))[:])
+	__ChainIDOffset := offset + 92
+	writer.Write8At(__ChainIDOffset, *(*uint64)(unsafe.Pointer(&x.ChainID)))
 
 	return offset, nil
 }
@@ -111,6 +131,13 @@ func (x *SyncStatusMsg) Read(viewer *SyncStatusMsgViewer, reader *karmem.Reader)
 	}
 	x.BufferedStartHeight = viewer.BufferedStartHeight()
 	x.BufferedEndHeight = viewer.BufferedEndHeight()
+	__GenesisHashSlice := viewer.GenesisHash()
+	__GenesisHashLen := len(__GenesisHashSlice)
+	copy(x.GenesisHash[:], __GenesisHashSlice)
+	for i := __GenesisHashLen; i < len(x.GenesisHash); i++ {
+		x.GenesisHash[i] = 0
+	}
+	x.ChainID = viewer.ChainID()
 }
 
 type TimeSyncMsg struct {
@@ -178,6 +205,8 @@ type Message struct {
 	Size      uint32
 	Payload   []byte
 	ReceiveAt int64
+	RequestID uint64
+	ReplyTo   uint64
 }
 
 func NewMessage() Message {
@@ -198,14 +227,14 @@ func (x *Message) WriteAsRoot(writer *karmem.Writer) (offset uint, err error) {
 
 func (x *Message) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
 	offset = start
-	size := uint(32)
+	size := uint(48)
 	if offset == 0 {
 		offset, err = writer.Alloc(size)
 		if err != nil {
 			return 0, err
 		}
 	}
-	writer.Write4At(offset, uint32(29))
+	writer.Write4At(offset, uint32(45))
 	__CodeOffset := offset + 4
 	writer.Write1At(__CodeOffset, *(*uint8)(unsafe.Pointer(&x.Code)))
 	__SizeOffset := offset + 5
@@ -224,6 +253,10 @@ func (x *Message) Write(writer *karmem.Writer, start uint) (offset uint, err err
 	writer.WriteAt(__PayloadOffset, *(*[]byte)(unsafe.Pointer(&__PayloadSlice)))
 	__ReceiveAtOffset := offset + 21
 	writer.Write8At(__ReceiveAtOffset, *(*uint64)(unsafe.Pointer(&x.ReceiveAt)))
+	__RequestIDOffset := offset + 29
+	writer.Write8At(__RequestIDOffset, *(*uint64)(unsafe.Pointer(&x.RequestID)))
+	__ReplyToOffset := offset + 37
+	writer.Write8At(__ReplyToOffset, *(*uint64)(unsafe.Pointer(&x.ReplyTo)))
 
 	return offset, nil
 }
@@ -246,6 +279,8 @@ func (x *Message) Read(viewer *MessageViewer, reader *karmem.Reader) {
 		x.Payload[i] = 0
 	}
 	x.ReceiveAt = viewer.ReceiveAt()
+	x.RequestID = viewer.RequestID()
+	x.ReplyTo = viewer.ReplyTo()
 }
 
 type BroadcastMessage struct {
@@ -334,8 +369,391 @@ func (x *BroadcastMessage) Read(viewer *BroadcastMessageViewer, reader *karmem.R
 	}
 }
 
+type HandshakeMsg struct {
+	ProtocolVersion uint32   // 协议版本
+	GenesisHash     [32]byte // 创世区块哈希
+	ChainID         int64    // 链 ID
+	Height          int64    // 最新区块高度
+	Capabilities    uint64   // 节点支持的功能
+}
+
+func NewHandshakeMsg() HandshakeMsg {
+	return HandshakeMsg{}
+}
+
+func (x *HandshakeMsg) PacketIdentifier() PacketIdentifier {
+	return PacketIdentifierHandshakeMsg
+}
+
+func (x *HandshakeMsg) Reset() {
+	x.Read((*HandshakeMsgViewer)(unsafe.Pointer(&_Null)), _NullReader)
+}
+
+func (x *HandshakeMsg) WriteAsRoot(writer *karmem.Writer) (offset uint, err error) {
+	return x.Write(writer, 0)
+}
+
+func (x *HandshakeMsg) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
+	offset = start
+	size := uint(64)
+	if offset == 0 {
+		offset, err = writer.Alloc(size)
+		if err != nil {
+			return 0, err
+		}
+	}
+	writer.Write4At(offset, uint32(64))
+	__ProtocolVersionOffset := offset + 4
+	writer.Write4At(__ProtocolVersionOffset, *(*uint32)(unsafe.Pointer(&x.ProtocolVersion)))
+	__GenesisHashOffset := offset + 8
+	writer.WriteAt(__GenesisHashOffset, (*[32]byte)(unsafe.Pointer(&x.GenesisHash))[:])
+	__ChainIDOffset := offset + 40
+	writer.Write8At(__ChainIDOffset, *(*uint64)(unsafe.Pointer(&x.ChainID)))
+	__HeightOffset := offset + 48
+	writer.Write8At(__HeightOffset, *(*uint64)(unsafe.Pointer(&x.Height)))
+	__CapabilitiesOffset := offset + 56
+	writer.Write8At(__CapabilitiesOffset, *(*uint64)(unsafe.Pointer(&x.Capabilities)))
+
+	return offset, nil
+}
+
+func (x *HandshakeMsg) ReadAsRoot(reader *karmem.Reader) {
+	x.Read(NewHandshakeMsgViewer(reader, 0), reader)
+}
+
+func (x *HandshakeMsg) Read(viewer *HandshakeMsgViewer, reader *karmem.Reader) {
+	x.ProtocolVersion = viewer.ProtocolVersion()
+	__GenesisHashSlice := viewer.GenesisHash()
+	__GenesisHashLen := len(__GenesisHashSlice)
+	copy(x.GenesisHash[:], __GenesisHashSlice)
+	for i := __GenesisHashLen; i < len(x.GenesisHash); i++ {
+		x.GenesisHash[i] = 0
+	}
+	x.ChainID = viewer.ChainID()
+	x.Height = viewer.Height()
+	x.Capabilities = viewer.Capabilities()
+}
+
+type SyncBlockRangeReq struct {
+	Start int64
+	Count int64
+}
+
+func NewSyncBlockRangeReq() SyncBlockRangeReq {
+	return SyncBlockRangeReq{}
+}
+
+func (x *SyncBlockRangeReq) PacketIdentifier() PacketIdentifier {
+	return PacketIdentifierSyncBlockRangeReq
+}
+
+func (x *SyncBlockRangeReq) Reset() {
+	x.Read((*SyncBlockRangeReqViewer)(unsafe.Pointer(&_Null)), _NullReader)
+}
+
+func (x *SyncBlockRangeReq) WriteAsRoot(writer *karmem.Writer) (offset uint, err error) {
+	return x.Write(writer, 0)
+}
+
+func (x *SyncBlockRangeReq) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
+	offset = start
+	size := uint(24)
+	if offset == 0 {
+		offset, err = writer.Alloc(size)
+		if err != nil {
+			return 0, err
+		}
+	}
+	writer.Write4At(offset, uint32(20))
+	__StartOffset := offset + 4
+	writer.Write8At(__StartOffset, *(*uint64)(unsafe.Pointer(&x.Start)))
+	__CountOffset := offset + 12
+	writer.Write8At(__CountOffset, *(*uint64)(unsafe.Pointer(&x.Count)))
+
+	return offset, nil
+}
+
+func (x *SyncBlockRangeReq) ReadAsRoot(reader *karmem.Reader) {
+	x.Read(NewSyncBlockRangeReqViewer(reader, 0), reader)
+}
+
+func (x *SyncBlockRangeReq) Read(viewer *SyncBlockRangeReqViewer, reader *karmem.Reader) {
+	x.Start = viewer.Start()
+	x.Count = viewer.Count()
+}
+
+type TxProofMsg struct {
+	Transaction []byte
+	Proof       []byte
+}
+
+func NewTxProofMsg() TxProofMsg {
+	return TxProofMsg{}
+}
+
+func (x *TxProofMsg) PacketIdentifier() PacketIdentifier {
+	return PacketIdentifierTxProofMsg
+}
+
+func (x *TxProofMsg) Reset() {
+	x.Read((*TxProofMsgViewer)(unsafe.Pointer(&_Null)), _NullReader)
+}
+
+func (x *TxProofMsg) WriteAsRoot(writer *karmem.Writer) (offset uint, err error) {
+	return x.Write(writer, 0)
+}
+
+func (x *TxProofMsg) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
+	offset = start
+	size := uint(32)
+	if offset == 0 {
+		offset, err = writer.Alloc(size)
+		if err != nil {
+			return 0, err
+		}
+	}
+	writer.Write4At(offset, uint32(28))
+	__TransactionSize := uint(1 * len(x.Transaction))
+	__TransactionOffset, err := writer.Alloc(__TransactionSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+4, uint32(__TransactionOffset))
+	writer.Write4At(offset+4+4, uint32(__TransactionSize))
+	writer.Write4At(offset+4+4+4, 1)
+	__TransactionSlice := *(*[3]uint)(unsafe.Pointer(&x.Transaction))
+	__TransactionSlice[1] = __TransactionSize
+	__TransactionSlice[2] = __TransactionSize
+	writer.WriteAt(__TransactionOffset, *(*[]byte)(unsafe.Pointer(&__TransactionSlice)))
+	__ProofSize := uint(1 * len(x.Proof))
+	__ProofOffset, err := writer.Alloc(__ProofSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+16, uint32(__ProofOffset))
+	writer.Write4At(offset+16+4, uint32(__ProofSize))
+	writer.Write4At(offset+16+4+4, 1)
+	__ProofSlice := *(*[3]uint)(unsafe.Pointer(&x.Proof))
+	__ProofSlice[1] = __ProofSize
+	__ProofSlice[2] = __ProofSize
+	writer.WriteAt(__ProofOffset, *(*[]byte)(unsafe.Pointer(&__ProofSlice)))
+
+	return offset, nil
+}
+
+func (x *TxProofMsg) ReadAsRoot(reader *karmem.Reader) {
+	x.Read(NewTxProofMsgViewer(reader, 0), reader)
+}
+
+func (x *TxProofMsg) Read(viewer *TxProofMsgViewer, reader *karmem.Reader) {
+	__TransactionSlice := viewer.Transaction(reader)
+	__TransactionLen := len(__TransactionSlice)
+	if __TransactionLen > cap(x.Transaction) {
+		x.Transaction = append(x.Transaction, make([]byte, __TransactionLen-len(x.Transaction))...)
+	}
+	x.Transaction = x.Transaction[:__TransactionLen]
+	copy(x.Transaction, __TransactionSlice)
+	for i := __TransactionLen; i < len(x.Transaction); i++ {
+		x.Transaction[i] = 0
+	}
+	__ProofSlice := viewer.Proof(reader)
+	__ProofLen := len(__ProofSlice)
+	if __ProofLen > cap(x.Proof) {
+		x.Proof = append(x.Proof, make([]byte, __ProofLen-len(x.Proof))...)
+	}
+	x.Proof = x.Proof[:__ProofLen]
+	copy(x.Proof, __ProofSlice)
+	for i := __ProofLen; i < len(x.Proof); i++ {
+		x.Proof[i] = 0
+	}
+}
+
+type DataProofReq struct {
+	Address []byte
+	Key     []byte
+	Height  int64
+}
+
+func NewDataProofReq() DataProofReq {
+	return DataProofReq{}
+}
+
+func (x *DataProofReq) PacketIdentifier() PacketIdentifier {
+	return PacketIdentifierDataProofReq
+}
+
+func (x *DataProofReq) Reset() {
+	x.Read((*DataProofReqViewer)(unsafe.Pointer(&_Null)), _NullReader)
+}
+
+func (x *DataProofReq) WriteAsRoot(writer *karmem.Writer) (offset uint, err error) {
+	return x.Write(writer, 0)
+}
+
+func (x *DataProofReq) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
+	offset = start
+	size := uint(40)
+	if offset == 0 {
+		offset, err = writer.Alloc(size)
+		if err != nil {
+			return 0, err
+		}
+	}
+	writer.Write4At(offset, uint32(36))
+	__AddressSize := uint(1 * len(x.Address))
+	__AddressOffset, err := writer.Alloc(__AddressSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+4, uint32(__AddressOffset))
+	writer.Write4At(offset+4+4, uint32(__AddressSize))
+	writer.Write4At(offset+4+4+4, 1)
+	__AddressSlice := *(*[3]uint)(unsafe.Pointer(&x.Address))
+	__AddressSlice[1] = __AddressSize
+	__AddressSlice[2] = __AddressSize
+	writer.WriteAt(__AddressOffset, *(*[]byte)(unsafe.Pointer(&__AddressSlice)))
+	__KeySize := uint(1 * len(x.Key))
+	__KeyOffset, err := writer.Alloc(__KeySize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+16, uint32(__KeyOffset))
+	writer.Write4At(offset+16+4, uint32(__KeySize))
+	writer.Write4At(offset+16+4+4, 1)
+	__KeySlice := *(*[3]uint)(unsafe.Pointer(&x.Key))
+	__KeySlice[1] = __KeySize
+	__KeySlice[2] = __KeySize
+	writer.WriteAt(__KeyOffset, *(*[]byte)(unsafe.Pointer(&__KeySlice)))
+	__HeightOffset := offset + 28
+	writer.Write8At(__HeightOffset, *(*uint64)(unsafe.Pointer(&x.Height)))
+
+	return offset, nil
+}
+
+func (x *DataProofReq) ReadAsRoot(reader *karmem.Reader) {
+	x.Read(NewDataProofReqViewer(reader, 0), reader)
+}
+
+func (x *DataProofReq) Read(viewer *DataProofReqViewer, reader *karmem.Reader) {
+	__AddressSlice := viewer.Address(reader)
+	__AddressLen := len(__AddressSlice)
+	if __AddressLen > cap(x.Address) {
+		x.Address = append(x.Address, make([]byte, __AddressLen-len(x.Address))...)
+	}
+	x.Address = x.Address[:__AddressLen]
+	copy(x.Address, __AddressSlice)
+	for i := __AddressLen; i < len(x.Address); i++ {
+		x.Address[i] = 0
+	}
+	__KeySlice := viewer.Key(reader)
+	__KeyLen := len(__KeySlice)
+	if __KeyLen > cap(x.Key) {
+		x.Key = append(x.Key, make([]byte, __KeyLen-len(x.Key))...)
+	}
+	x.Key = x.Key[:__KeyLen]
+	copy(x.Key, __KeySlice)
+	for i := __KeyLen; i < len(x.Key); i++ {
+		x.Key[i] = 0
+	}
+	x.Height = viewer.Height()
+}
+
+type DataProofMsg struct {
+	Height int64
+	Found  bool
+	Value  []byte
+	Proof  []byte
+}
+
+func NewDataProofMsg() DataProofMsg {
+	return DataProofMsg{}
+}
+
+func (x *DataProofMsg) PacketIdentifier() PacketIdentifier {
+	return PacketIdentifierDataProofMsg
+}
+
+func (x *DataProofMsg) Reset() {
+	x.Read((*DataProofMsgViewer)(unsafe.Pointer(&_Null)), _NullReader)
+}
+
+func (x *DataProofMsg) WriteAsRoot(writer *karmem.Writer) (offset uint, err error) {
+	return x.Write(writer, 0)
+}
+
+func (x *DataProofMsg) Write(writer *karmem.Writer, start uint) (offset uint, err error) {
+	offset = start
+	size := uint(40)
+	if offset == 0 {
+		offset, err = writer.Alloc(size)
+		if err != nil {
+			return 0, err
+		}
+	}
+	writer.Write4At(offset, uint32(37))
+	__HeightOffset := offset + 4
+	writer.Write8At(__HeightOffset, *(*uint64)(unsafe.Pointer(&x.Height)))
+	__FoundOffset := offset + 12
+	writer.Write1At(__FoundOffset, *(*uint8)(unsafe.Pointer(&x.Found)))
+	__ValueSize := uint(1 * len(x.Value))
+	__ValueOffset, err := writer.Alloc(__ValueSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+13, uint32(__ValueOffset))
+	writer.Write4At(offset+13+4, uint32(__ValueSize))
+	writer.Write4At(offset+13+4+4, 1)
+	__ValueSlice := *(*[3]uint)(unsafe.Pointer(&x.Value))
+	__ValueSlice[1] = __ValueSize
+	__ValueSlice[2] = __ValueSize
+	writer.WriteAt(__ValueOffset, *(*[]byte)(unsafe.Pointer(&__ValueSlice)))
+	__ProofSize := uint(1 * len(x.Proof))
+	__ProofOffset, err := writer.Alloc(__ProofSize)
+	if err != nil {
+		return 0, err
+	}
+	writer.Write4At(offset+25, uint32(__ProofOffset))
+	writer.Write4At(offset+25+4, uint32(__ProofSize))
+	writer.Write4At(offset+25+4+4, 1)
+	__ProofSlice := *(*[3]uint)(unsafe.Pointer(&x.Proof))
+	__ProofSlice[1] = __ProofSize
+	__ProofSlice[2] = __ProofSize
+	writer.WriteAt(__ProofOffset, *(*[]byte)(unsafe.Pointer(&__ProofSlice)))
+
+	return offset, nil
+}
+
+func (x *DataProofMsg) ReadAsRoot(reader *karmem.Reader) {
+	x.Read(NewDataProofMsgViewer(reader, 0), reader)
+}
+
+func (x *DataProofMsg) Read(viewer *DataProofMsgViewer, reader *karmem.Reader) {
+	x.Height = viewer.Height()
+	x.Found = viewer.Found()
+	__ValueSlice := viewer.Value(reader)
+	__ValueLen := len(__ValueSlice)
+	if __ValueLen > cap(x.Value) {
+		x.Value = append(x.Value, make([]byte, __ValueLen-len(x.Value))...)
+	}
+	x.Value = x.Value[:__ValueLen]
+	copy(x.Value, __ValueSlice)
+	for i := __ValueLen; i < len(x.Value); i++ {
+		x.Value[i] = 0
+	}
+	__ProofSlice := viewer.Proof(reader)
+	__ProofLen := len(__ProofSlice)
+	if __ProofLen > cap(x.Proof) {
+		x.Proof = append(x.Proof, make([]byte, __ProofLen-len(x.Proof))...)
+	}
+	x.Proof = x.Proof[:__ProofLen]
+	copy(x.Proof, __ProofSlice)
+	for i := __ProofLen; i < len(x.Proof); i++ {
+		x.Proof[i] = 0
+	}
+}
+
 type SyncStatusMsgViewer struct {
-	_data [64]byte
+	_data [104]byte
 }
 
 func NewSyncStatusMsgViewer(reader *karmem.Reader, offset uint32) (v *SyncStatusMsgViewer) {
@@ -379,6 +797,21 @@ func (x *SyncStatusMsgViewer) BufferedEndHeight() (v int64) {
 	}
 	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 52))
 }
+func (x *SyncStatusMsgViewer) GenesisHash() (v []byte) {
+	if 60+32 > x.size() {
+		return []byte{}
+	}
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(unsafe.Pointer(&x._data), 60)), 32, 32,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *SyncStatusMsgViewer) ChainID() (v int64) {
+	if 92+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 92))
+}
 
 type TimeSyncMsgViewer struct {
 	_data [40]byte
@@ -430,7 +863,7 @@ func (x *TimeSyncMsgViewer) RecRspTime() (v int64) {
 }
 
 type MessageViewer struct {
-	_data [32]byte
+	_data [48]byte
 }
 
 func NewMessageViewer(reader *karmem.Reader, offset uint32) (v *MessageViewer) {
@@ -480,6 +913,18 @@ func (x *MessageViewer) ReceiveAt() (v int64) {
 	}
 	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 21))
 }
+func (x *MessageViewer) RequestID() (v uint64) {
+	if 29+8 > x.size() {
+		return v
+	}
+	return *(*uint64)(unsafe.Add(unsafe.Pointer(&x._data), 29))
+}
+func (x *MessageViewer) ReplyTo() (v uint64) {
+	if 37+8 > x.size() {
+		return v
+	}
+	return *(*uint64)(unsafe.Add(unsafe.Pointer(&x._data), 37))
+}
 
 type BroadcastMessageViewer struct {
 	_data [32]byte
@@ -529,3 +974,251 @@ func (x *BroadcastMessageViewer) Data(reader *karmem.Reader) (v []byte) {
 	}
 	return *(*[]byte)(unsafe.Pointer(&slice))
 }
+
+type HandshakeMsgViewer struct {
+	_data [64]byte
+}
+
+func NewHandshakeMsgViewer(reader *karmem.Reader, offset uint32) (v *HandshakeMsgViewer) {
+	if !reader.IsValidOffset(offset, 8) {
+		return (*HandshakeMsgViewer)(unsafe.Pointer(&_Null))
+	}
+	v = (*HandshakeMsgViewer)(unsafe.Add(reader.Pointer, offset))
+	if !reader.IsValidOffset(offset, v.size()) {
+		return (*HandshakeMsgViewer)(unsafe.Pointer(&_Null))
+	}
+	return v
+}
+
+func (x *HandshakeMsgViewer) size() uint32 {
+	return *(*uint32)(unsafe.Pointer(&x._data))
+}
+func (x *HandshakeMsgViewer) ProtocolVersion() (v uint32) {
+	if 4+4 > x.size() {
+		return v
+	}
+	return *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 4))
+}
+func (x *HandshakeMsgViewer) GenesisHash() (v []byte) {
+	if 8+32 > x.size() {
+		return []byte{}
+	}
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(unsafe.Pointer(&x._data), 8)), 32, 32,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *HandshakeMsgViewer) ChainID() (v int64) {
+	if 40+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 40))
+}
+func (x *HandshakeMsgViewer) Height() (v int64) {
+	if 48+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 48))
+}
+func (x *HandshakeMsgViewer) Capabilities() (v uint64) {
+	if 56+8 > x.size() {
+		return v
+	}
+	return *(*uint64)(unsafe.Add(unsafe.Pointer(&x._data), 56))
+}
+
+type SyncBlockRangeReqViewer struct {
+	_data [24]byte
+}
+
+func NewSyncBlockRangeReqViewer(reader *karmem.Reader, offset uint32) (v *SyncBlockRangeReqViewer) {
+	if !reader.IsValidOffset(offset, 8) {
+		return (*SyncBlockRangeReqViewer)(unsafe.Pointer(&_Null))
+	}
+	v = (*SyncBlockRangeReqViewer)(unsafe.Add(reader.Pointer, offset))
+	if !reader.IsValidOffset(offset, v.size()) {
+		return (*SyncBlockRangeReqViewer)(unsafe.Pointer(&_Null))
+	}
+	return v
+}
+
+func (x *SyncBlockRangeReqViewer) size() uint32 {
+	return *(*uint32)(unsafe.Pointer(&x._data))
+}
+func (x *SyncBlockRangeReqViewer) Start() (v int64) {
+	if 4+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 4))
+}
+func (x *SyncBlockRangeReqViewer) Count() (v int64) {
+	if 12+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 12))
+}
+
+type TxProofMsgViewer struct {
+	_data [32]byte
+}
+
+func NewTxProofMsgViewer(reader *karmem.Reader, offset uint32) (v *TxProofMsgViewer) {
+	if !reader.IsValidOffset(offset, 8) {
+		return (*TxProofMsgViewer)(unsafe.Pointer(&_Null))
+	}
+	v = (*TxProofMsgViewer)(unsafe.Add(reader.Pointer, offset))
+	if !reader.IsValidOffset(offset, v.size()) {
+		return (*TxProofMsgViewer)(unsafe.Pointer(&_Null))
+	}
+	return v
+}
+
+func (x *TxProofMsgViewer) size() uint32 {
+	return *(*uint32)(unsafe.Pointer(&x._data))
+}
+func (x *TxProofMsgViewer) Transaction(reader *karmem.Reader) (v []byte) {
+	if 4+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 4))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 4+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *TxProofMsgViewer) Proof(reader *karmem.Reader) (v []byte) {
+	if 16+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 16))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 16+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+
+type DataProofReqViewer struct {
+	_data [40]byte
+}
+
+func NewDataProofReqViewer(reader *karmem.Reader, offset uint32) (v *DataProofReqViewer) {
+	if !reader.IsValidOffset(offset, 8) {
+		return (*DataProofReqViewer)(unsafe.Pointer(&_Null))
+	}
+	v = (*DataProofReqViewer)(unsafe.Add(reader.Pointer, offset))
+	if !reader.IsValidOffset(offset, v.size()) {
+		return (*DataProofReqViewer)(unsafe.Pointer(&_Null))
+	}
+	return v
+}
+
+func (x *DataProofReqViewer) size() uint32 {
+	return *(*uint32)(unsafe.Pointer(&x._data))
+}
+func (x *DataProofReqViewer) Address(reader *karmem.Reader) (v []byte) {
+	if 4+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 4))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 4+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *DataProofReqViewer) Key(reader *karmem.Reader) (v []byte) {
+	if 16+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 16))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 16+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *DataProofReqViewer) Height() (v int64) {
+	if 28+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 28))
+}
+
+type DataProofMsgViewer struct {
+	_data [40]byte
+}
+
+func NewDataProofMsgViewer(reader *karmem.Reader, offset uint32) (v *DataProofMsgViewer) {
+	if !reader.IsValidOffset(offset, 8) {
+		return (*DataProofMsgViewer)(unsafe.Pointer(&_Null))
+	}
+	v = (*DataProofMsgViewer)(unsafe.Add(reader.Pointer, offset))
+	if !reader.IsValidOffset(offset, v.size()) {
+		return (*DataProofMsgViewer)(unsafe.Pointer(&_Null))
+	}
+	return v
+}
+
+func (x *DataProofMsgViewer) size() uint32 {
+	return *(*uint32)(unsafe.Pointer(&x._data))
+}
+func (x *DataProofMsgViewer) Height() (v int64) {
+	if 4+8 > x.size() {
+		return v
+	}
+	return *(*int64)(unsafe.Add(unsafe.Pointer(&x._data), 4))
+}
+func (x *DataProofMsgViewer) Found() (v bool) {
+	if 12+1 > x.size() {
+		return v
+	}
+	return *(*bool)(unsafe.Add(unsafe.Pointer(&x._data), 12))
+}
+func (x *DataProofMsgViewer) Value(reader *karmem.Reader) (v []byte) {
+	if 13+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 13))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 13+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
+func (x *DataProofMsgViewer) Proof(reader *karmem.Reader) (v []byte) {
+	if 25+12 > x.size() {
+		return []byte{}
+	}
+	offset := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 25))
+	size := *(*uint32)(unsafe.Add(unsafe.Pointer(&x._data), 25+4))
+	if !reader.IsValidOffset(offset, size) {
+		return []byte{}
+	}
+	length := uintptr(size / 1)
+	slice := [3]uintptr{
+		uintptr(unsafe.Add(reader.Pointer, offset)), length, length,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slice))
+}
@@ -5,13 +5,13 @@ package p2p
 
 import (
 	"bufio"
+	"bytes"
 	"context"
-	"encoding/base64"
 	"github.com/chain-lab/go-norn/metrics"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	log "github.com/sirupsen/logrus"
-	karmem "karmem.org/golang"
+	"io"
 	"sync"
 	"time"
 )
@@ -30,8 +30,10 @@ var (
 
 type Peer struct {
 	peerID peer.ID
+	stream network.Stream
 
 	rw        *bufio.ReadWriter
+	frame     FrameConfig // 与对端协商的分帧配置
 	wg        sync.WaitGroup
 	msgQueue  chan *Message
 	sendQueue chan *Message
@@ -39,17 +41,29 @@ type Peer struct {
 	wLock sync.RWMutex
 	rLock sync.RWMutex
 
-	stopped bool
+	stopped   bool
+	done      chan struct{} // 连接断开时关闭，用于通知读写协程和上层的协程退出
+	closeOnce sync.Once
 }
 
 func NewPeer(id peer.ID, s *network.Stream, msgQueue chan *Message) (*Peer, error) {
+	//rw:       bufio.NewReadWriter(bufio.NewReaderSize(*s, bufferSize), bufio.NewWriterSize(*s, bufferSize)),
+	rw := bufio.NewReadWriter(bufio.NewReader(*s), bufio.NewWriter(*s))
+	return NewPeerWithReadWriter(id, s, rw, FrameConfig{}, msgQueue)
+}
+
+// NewPeerWithReadWriter 使用握手时创建的 ReadWriter 以及握手时协商的分帧配置创建 Peer，避免握手时预读的数据丢失
+func NewPeerWithReadWriter(id peer.ID, s *network.Stream, rw *bufio.ReadWriter,
+	frame FrameConfig, msgQueue chan *Message) (*Peer, error) {
 	p := Peer{
-		peerID: id,
-		//rw:       bufio.NewReadWriter(bufio.NewReaderSize(*s, bufferSize), bufio.NewWriterSize(*s, bufferSize)),
-		rw:        bufio.NewReadWriter(bufio.NewReader(*s), bufio.NewWriter(*s)),
+		peerID:    id,
+		stream:    *s,
+		rw:        rw,
+		frame:     frame,
 		msgQueue:  msgQueue,
 		sendQueue: make(chan *Message, messageQueueCap),
 		stopped:   false,
+		done:      make(chan struct{}),
 	}
 
 	metrics.RoutineCreateCounterObserve(13)
@@ -62,6 +76,70 @@ func (p *Peer) Stopped() bool {
 	return p.stopped
 }
 
+// Done 返回连接断开时关闭的 channel
+func (p *Peer) Done() <-chan struct{} {
+	return p.done
+}
+
+// Close 主动断开与对端的连接，可以重复调用
+func (p *Peer) Close() {
+	p.closeOnce.Do(func() {
+		p.stopped = true
+		close(p.done)
+		_ = p.stream.Reset()
+		metrics.ConnectedNodeDec()
+	})
+}
+
+// Handshake 在创建 Peer 之前与对端交换握手消息，双方先发送本地的握手消息，再读取对端的握手消息
+// 握手消息始终使用旧版本的分帧方式，保证新旧版本的节点都能完成握手，返回的 ReadWriter 需要用于创建 Peer。
+// 不支持握手的旧版本节点第一条消息不是握手消息，此时返回 nil 的握手消息，调用方按照旧的分帧方式、没有任何功能位处理该节点，
+// 已经读取的消息放回 ReadWriter 的头部，创建 Peer 后正常处理
+func Handshake(s network.Stream, payload []byte, timeout time.Duration) (*Message, *bufio.ReadWriter, error) {
+	rw := bufio.NewReadWriter(bufio.NewReader(s), bufio.NewWriter(s))
+
+	_ = s.SetDeadline(time.Now().Add(timeout))
+	defer s.SetDeadline(time.Time{})
+
+	msg := &Message{
+		Code:    StatusCodeHandshakeMsg,
+		Size:    uint32(len(payload)),
+		Payload: payload,
+	}
+	if err := writeMessage(rw, msg, FrameConfig{}); err != nil {
+		return nil, nil, err
+	}
+
+	remote, err := readMessage(rw, FrameConfig{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if remote == nil || remote.Code != StatusCodeHandshakeMsg {
+		rw, err = unreadMessage(rw, remote)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, rw, nil
+	}
+
+	return remote, rw, nil
+}
+
+// unreadMessage 将已经读取的消息按照旧的分帧方式重新编码，放回 ReadWriter 读取的头部，msg 为 nil 时不做修改
+func unreadMessage(rw *bufio.ReadWriter, msg *Message) (*bufio.ReadWriter, error) {
+	if msg == nil {
+		return rw, nil
+	}
+
+	var buf bytes.Buffer
+	if err := writeMessage(bufio.NewReadWriter(nil, bufio.NewWriter(&buf)), msg, FrameConfig{}); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(io.MultiReader(&buf, rw.Reader))
+	return bufio.NewReadWriter(reader, rw.Writer), nil
+}
+
 func (p *Peer) Run() {
 	var (
 		readErr = make(chan error, 1)
@@ -112,24 +190,21 @@ func (p *Peer) readLoop(errc chan<- error) {
 		}
 
 		log.Traceln("New read loop.")
-		dataBytes, err := p.rw.ReadBytes(0xff)
+		msg, err := readMessage(p.rw, p.frame)
 		if err != nil {
-			log.WithField("error", err).Debugln("Read bytes error.")
+			// 读取失败或者帧格式错误时无法继续解析后续的数据，直接断开连接
+			log.WithFields(log.Fields{
+				"peer":  p.peerID,
+				"error": err,
+			}).Debugln("Read message error, disconnect peer.")
 			errc <- err
+			p.Close()
 			return
 		}
 
-		if len(dataBytes) == 0 {
+		if msg == nil {
 			continue
 		}
-		//log.Infof("Receive byte data %v", dataBytes)
-		dataBytes = dataBytes[:len(dataBytes)-1]
-
-		decodedPayload := make([]byte, base64.StdEncoding.DecodedLen(len(dataBytes)))
-		l, _ := base64.StdEncoding.Decode(decodedPayload, dataBytes)
-
-		msg := new(Message)
-		msg.ReadAsRoot(karmem.NewReader(decodedPayload[:l]))
 
 		now := time.Now()
 
@@ -149,64 +224,54 @@ func (p *Peer) handle(msg *Message) {
 	case msg.Code == StatusCodePongMsg:
 		return
 	default:
-		metrics.RecvQueueCountInc()
-		p.msgQueue <- msg
+		select {
+		case p.msgQueue <- msg:
+			metrics.RecvQueueCountInc()
+		case <-p.done:
+		}
 	}
 	return
 }
 
 // Send 方法用于提供一个通用的消息发送接口
 func (p *Peer) Send(msgCode StatusCode, payload []byte) {
-	msg := Message{
-		Code:      msgCode,
-		Size:      uint32(len(payload)),
-		Payload:   payload,
-		ReceiveAt: 0,
-	}
+	p.SendMessage(&Message{
+		Code:    msgCode,
+		Payload: payload,
+	})
+}
+
+// SendMessage 发送一个完整的消息，用于携带请求 ID 或者响应的请求 ID
+func (p *Peer) SendMessage(msg *Message) {
+	msg.Size = uint32(len(msg.Payload))
+	msg.ReceiveAt = 0
 
-	p.sendQueue <- &msg
-	metrics.SendQueueCountInc()
+	select {
+	case p.sendQueue <- msg:
+		metrics.SendQueueCountInc()
+	case <-p.done:
+	}
 }
 
 func (p *Peer) writeLoop() {
 	log.Traceln("Start write loop.")
-	for {
-		if p.stopped {
-			break
-		}
 
+	defer p.wg.Done()
+	for {
 		select {
+		case <-p.done:
+			return
 		case msg := <-p.sendQueue:
-			msgWriter := karmem.NewWriter(1024)
-
-			if _, err := msg.WriteAsRoot(msgWriter); err != nil {
-				log.WithField("error", err).Errorln("Encode data failed.")
-				break
-			}
-
-			msgBytes := msgWriter.Bytes()
-
-			encodedPayload := make([]byte, base64.StdEncoding.EncodedLen(len(msgBytes)))
-			base64.StdEncoding.Encode(encodedPayload, msgBytes)
-			encodedPayload = append(encodedPayload, 0xff)
-
-			length, err := p.rw.Write(encodedPayload)
-
-			if err != nil {
+			if err := writeMessage(p.rw, msg, p.frame); err != nil {
 				log.WithFields(
 					log.Fields{
-						"error":  err,
-						"length": length,
-						"code":   msg.Code,
+						"error": err,
+						"code":  msg.Code,
 					}).Debugln("Send data to peer errored.")
-				p.stopped = true
+				p.Close()
 				log.Debugln("Peer closed.")
-				metrics.ConnectedNodeDec()
-				break
+				return
 			}
-
-			// 这里必须强制 Flush， 否则短消息收不到
-			p.rw.Flush()
 		}
 	}
 }
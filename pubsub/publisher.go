@@ -57,6 +57,22 @@ func (e *EventPublisher) Publish(data []byte) {
 	}
 }
 
+// Close 关闭所有订阅连接
+func (e *EventPublisher) Close() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for idx, conn := range e.conns {
+		if conn == nil {
+			continue
+		}
+
+		_ = conn.Close()
+		e.conns[idx] = nil
+		e.count--
+	}
+}
+
 func (e *EventPublisher) AppendNewConnection(conn *websocket.Conn) {
 	e.lock.Lock()
 	defer e.lock.Unlock()
@@ -7,8 +7,10 @@
 package pubsub
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/chain-lab/go-norn/utils"
 	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
 	"net/http"
@@ -16,9 +18,7 @@ import (
 )
 
 var (
-	routerOnce = sync.Once{}
-	routerInst *EventRouter
-	upgrader   = websocket.Upgrader{
+	upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
 	}
@@ -30,27 +30,71 @@ type EventRouter struct {
 	publishMap  map[EventTopic]*EventPublisher
 	publishChan chan Event
 
+	done     chan struct{} // 路由停止时关闭，停止后不再接收事件
+	stopOnce sync.Once
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
 	lock sync.RWMutex
 }
 
-func CreateNewEventRouter() *EventRouter {
-	routerOnce.Do(func() {
-		routerInst = &EventRouter{
-			publishMap:  make(map[EventTopic]*EventPublisher),
-			publishChan: make(chan Event, 256),
+// NewEventRouter 创建事件路由，每个节点持有各自的事件路由
+func NewEventRouter() *EventRouter {
+	return &EventRouter{
+		publishMap:  make(map[EventTopic]*EventPublisher),
+		publishChan: make(chan Event, 256),
+		done:        make(chan struct{}),
+	}
+}
+
+// AppendEvent 添加需要发布的事件，路由停止后事件被丢弃
+func (e *EventRouter) AppendEvent(event Event) {
+	select {
+	case e.publishChan <- event:
+	case <-e.done:
+	}
+}
+
+// Start 启动事件发布协程
+func (e *EventRouter) Start(ctx context.Context) error {
+	var processCtx context.Context
+	processCtx, e.cancel = context.WithCancel(ctx)
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.Process(processCtx)
+	}()
+	return nil
+}
+
+// Stop 停止事件发布协程，并关闭所有订阅连接
+func (e *EventRouter) Stop(ctx context.Context) error {
+	e.stopOnce.Do(func() {
+		close(e.done)
+		if e.cancel != nil {
+			e.cancel()
 		}
 	})
 
-	return routerInst
-}
+	if err := utils.WaitGroupContext(ctx, &e.wg); err != nil {
+		return err
+	}
 
-func (e *EventRouter) AppendEvent(event Event) {
-	e.publishChan <- event
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, publisher := range e.publishMap {
+		publisher.Close()
+	}
+	return nil
 }
 
-func (e *EventRouter) Process() {
+// Process 从事件队列中读取事件并发布给订阅者，ctx 取消后退出
+func (e *EventRouter) Process(ctx context.Context) {
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case event := <-e.publishChan:
 			topic := toTopicStr(event.Address, event.Type)
 			log.Infof("Receive task with topic %s", topic)
@@ -13,7 +13,7 @@ import (
 )
 
 func TestEventRouter_HandleConnect(t *testing.T) {
-	router := CreateNewEventRouter()
+	router := NewEventRouter()
 	http.HandleFunc("/subscribe", router.HandleConnect)
 	log.Fatal(http.ListenAndServe("localhost:8888", nil))
 }
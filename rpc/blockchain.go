@@ -10,7 +10,6 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"github.com/chain-lab/go-norn/common"
@@ -33,18 +32,23 @@ const (
 
 type blockchainService struct {
 	pb.UnimplementedBlockchainServer
+	pm *node.P2PManager // 所属节点的 manager
 	// todo: block cache?
 }
 
 func (s *blockchainService) GetBlockNumber(ctx context.Context,
 	in *emptypb.Empty) (resp *pb.BlockNumberResp, err error) {
-	pm := node.GetP2PManager()
+	pm := s.pm
 	// todo: check pm and chain is null
 	chain := pm.GetBlockChain()
 
 	resp = new(pb.BlockNumberResp)
 	resp.Timestamp = proto.Uint64(uint64(time.Now().Unix()))
 	resp.Number = proto.Uint64(uint64(chain.Height()))
+	// 轻节点只保存区块头，返回已经验证的区块头高度
+	if pm.Light() {
+		resp.Number = proto.Uint64(uint64(pm.HeaderHeight()))
+	}
 
 	return resp, nil
 }
@@ -67,7 +71,7 @@ func (s *blockchainService) GetBlockByHash(ctx context.Context,
 		return nil, err
 	}
 
-	pm := node.GetP2PManager()
+	pm := s.pm
 	// todo: check pm and chain is null
 	chain := pm.GetBlockChain()
 
@@ -99,7 +103,7 @@ func (s *blockchainService) GetBlockByNumber(ctx context.Context,
 	if full == nil {
 		full = proto.Bool(false)
 	}
-	pm := node.GetP2PManager()
+	pm := s.pm
 	// todo: check pm and chain is null
 	chain := pm.GetBlockChain()
 
@@ -127,7 +131,7 @@ func (s *blockchainService) GetTransactionByHash(ctx context.Context,
 	}
 
 	strHash := removePrefixIfExists(*txHash)
-	pm := node.GetP2PManager()
+	pm := s.pm
 	chain := pm.GetBlockChain()
 	hash, err := hex.DecodeString(strHash)
 	if err != nil {
@@ -135,7 +139,13 @@ func (s *blockchainService) GetTransactionByHash(ctx context.Context,
 		return nil, err
 	}
 
-	tx, err := chain.GetTransactionByHash(common.Hash(hash))
+	var tx *common.Transaction
+	if pm.Light() {
+		// 轻节点向完整节点请求交易，并使用本地区块头验证交易的 Merkle 证明
+		tx, err = pm.LightTransaction(ctx, common.Hash(hash))
+	} else {
+		tx, err = chain.GetTransactionByHash(common.Hash(hash))
+	}
 	if err != nil {
 		log.WithError(err).Debugln("Get transaction by hash failed.")
 		return nil, err
@@ -166,7 +176,7 @@ func (s *blockchainService) GetTransactionByBlockHashAndIndex(ctx context.
 		return nil, err
 	}
 
-	pm := node.GetP2PManager()
+	pm := s.pm
 	// todo: check pm and chain is null
 	chain := pm.GetBlockChain()
 
@@ -203,7 +213,7 @@ func (s *blockchainService) GetTransactionByBlockNumberAndIndex(ctx context.
 		return nil, fmt.Errorf("block height is required")
 	}
 
-	pm := node.GetP2PManager()
+	pm := s.pm
 	// todo: check pm and chain is null
 	chain := pm.GetBlockChain()
 
@@ -230,7 +240,7 @@ func (s *blockchainService) GetTransactionByBlockNumberAndIndex(ctx context.
 func (s *blockchainService) ReadContractAddress(ctx context.
 	Context, in *pb.ReadContractAddressReq) (resp *pb.ReadContractAddressResp,
 	err error) {
-	pm := node.GetP2PManager()
+	pm := s.pm
 	// todo: check pm and chain is null
 	chain := pm.GetBlockChain()
 	address := in.Address
@@ -238,7 +248,18 @@ func (s *blockchainService) ReadContractAddress(ctx context.
 
 	resp = new(pb.ReadContractAddressResp)
 
-	data, err := chain.ReadAddressData(*address, *key)
+	var data []byte
+	if pm.Light() {
+		// 轻节点向完整节点请求数据，并使用区块头中的状态根验证
+		addr, decodeErr := hex.DecodeString(*address)
+		if decodeErr != nil {
+			log.WithError(decodeErr).Debugln("Decode address failed.")
+			return nil, decodeErr
+		}
+		data, err = pm.LightAddressData(ctx, addr, []byte(*key))
+	} else {
+		data, err = chain.ReadAddressData(*address, *key)
+	}
 
 	if err != nil {
 		log.WithError(err).Debugln("Read blockchain data failed.")
@@ -309,9 +330,7 @@ func (s *blockchainService) SendTransactionWithData(ctx context.
 	txBody.WriteAsRoot(writer)
 	txBodyBytes := writer.Bytes()
 
-	hash := sha256.New()
-	hash.Write(txBodyBytes)
-	txHashBytes := hash.Sum(nil)
+	txHashBytes := common.TransactionSigningHash(txBodyBytes, s.pm.GetBlockChain().ChainID())
 	txSignatureBytes, err := ecdsa.SignASN1(rand.Reader, prv, txHashBytes)
 
 	if err != nil {
@@ -326,7 +345,7 @@ func (s *blockchainService) SendTransactionWithData(ctx context.
 		Body: txBody,
 	}
 
-	pm := node.GetP2PManager()
+	pm := s.pm
 	pm.AddTransaction(tx)
 
 	resp = new(pb.SendTransactionWithDataResp)
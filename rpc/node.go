@@ -14,12 +14,13 @@ import (
 
 type nodeService struct {
 	pb.UnimplementedNodeServer
+	pm *node.P2PManager // 所属节点的 manager
 }
 
 func (s *nodeService) ConnectedNodeList(ctx context.Context,
 	in *pb.ConnectedNodeReq) (*pb.ConnectedNodeResp, error) {
 	resp := new(pb.ConnectedNodeResp)
-	pm := node.GetP2PManager()
+	pm := s.pm
 
 	local, remotes := pm.GetConnectNodeInfo()
 	resp.Code = pb.NodeStatusRespCodes_NODE_STATUS_SUCCESS.Enum()
@@ -29,3 +30,107 @@ func (s *nodeService) ConnectedNodeList(ctx context.Context,
 
 	return resp, nil
 }
+
+// PeerScores
+//
+//	@Description: 获取节点信誉分数以及禁止连接的状态
+//	@receiver s
+//	@param ctx
+//	@param in - 请求参数，目前为空
+//	@return *pb.PeerScoresResp - 节点分数列表，按照分数从低到高排序
+//	@return error
+func (s *nodeService) PeerScores(ctx context.Context,
+	in *pb.PeerScoresReq) (*pb.PeerScoresResp, error) {
+	resp := new(pb.PeerScoresResp)
+	pm := s.pm
+
+	for _, item := range pm.PeerReputations() {
+		id := item.ID.String()
+		score := item.Score
+		banned := !item.BannedUntil.IsZero()
+		bannedUntil := int64(0)
+		if banned {
+			bannedUntil = item.BannedUntil.UnixMilli()
+		}
+
+		resp.Scores = append(resp.Scores, &pb.PeerScore{
+			Id:          &id,
+			Score:       &score,
+			Banned:      &banned,
+			BannedUntil: &bannedUntil,
+		})
+	}
+
+	resp.Code = pb.NodeStatusRespCodes_NODE_STATUS_SUCCESS.Enum()
+	return resp, nil
+}
+
+// TimeSyncStatus
+//
+//	@Description: 获取节点的时间同步状态，包括逻辑时钟的偏移、最近一轮的离散度、样本统计和一致的节点数量
+//	@receiver s
+//	@param ctx
+//	@param in - 请求参数，目前为空
+//	@return *pb.TimeSyncStatusResp - 时间同步状态，时间单位为 ms
+//	@return error
+func (s *nodeService) TimeSyncStatus(ctx context.Context,
+	in *pb.TimeSyncStatusReq) (*pb.TimeSyncStatusResp, error) {
+	stats := s.pm.TimeSyncStats()
+
+	status := int32(stats.Status)
+	samples := int32(stats.Samples)
+	rejected := int32(stats.Rejected)
+	agreed := int32(stats.Agreed)
+	confirmTimes := int32(stats.ConfirmTimes)
+
+	resp := &pb.TimeSyncStatusResp{
+		Code:         pb.NodeStatusRespCodes_NODE_STATUS_SUCCESS.Enum(),
+		Status:       &status,
+		Offset:       &stats.Offset,
+		TargetOffset: &stats.Target,
+		Dispersion:   &stats.Dispersion,
+		Rtt:          &stats.RTT,
+		Samples:      &samples,
+		Rejected:     &rejected,
+		ConfirmTimes: &confirmTimes,
+		LastRound:    &stats.LastRound,
+		Agreed:       &agreed,
+	}
+	return resp, nil
+}
+
+// BlockSyncStatus
+//
+//	@Description: 获取节点的区块同步状态，包括同步目标以及各个节点声明的链头，selected 表示节点支持当前的同步目标
+//	@receiver s
+//	@param ctx
+//	@param in - 请求参数，目前为空
+//	@return *pb.BlockSyncStatusResp - 区块同步状态，节点按照高度从高到低排序
+//	@return error
+func (s *nodeService) BlockSyncStatus(ctx context.Context,
+	in *pb.BlockSyncStatusReq) (*pb.BlockSyncStatusResp, error) {
+	stats := s.pm.BlockSyncStats()
+
+	status := int32(stats.Status)
+	resp := &pb.BlockSyncStatusResp{
+		Code:         pb.NodeStatusRespCodes_NODE_STATUS_SUCCESS.Enum(),
+		Status:       &status,
+		KnownHeight:  &stats.KnownHeight,
+		RemoteHeight: &stats.RemoteHeight,
+		TargetHeight: &stats.TargetHeight,
+	}
+
+	for idx := range stats.Peers {
+		item := &stats.Peers[idx]
+		id := item.ID.String()
+
+		resp.Peers = append(resp.Peers, &pb.SyncPeerHead{
+			Id:           &id,
+			Height:       &item.Height,
+			BufferHeight: &item.BufferHeight,
+			Weight:       &item.Weight,
+			Selected:     &item.Selected,
+		})
+	}
+	return resp, nil
+}
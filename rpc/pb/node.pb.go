@@ -167,6 +167,539 @@ func (x *ConnectedNodeResp) GetRemote() []string {
 	return nil
 }
 
+type PeerScoresReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *PeerScoresReq) Reset() {
+	*x = PeerScoresReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_node_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerScoresReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerScoresReq) ProtoMessage() {}
+
+func (x *PeerScoresReq) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerScoresReq.ProtoReflect.Descriptor instead.
+func (*PeerScoresReq) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{2}
+}
+
+type PeerScore struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id          *string  `protobuf:"bytes,1,opt,name=id,proto3,oneof" json:"id,omitempty"`
+	Score       *float64 `protobuf:"fixed64,2,opt,name=score,proto3,oneof" json:"score,omitempty"`
+	Banned      *bool    `protobuf:"varint,3,opt,name=banned,proto3,oneof" json:"banned,omitempty"`
+	BannedUntil *int64   `protobuf:"varint,4,opt,name=banned_until,json=bannedUntil,proto3,oneof" json:"banned_until,omitempty"`
+}
+
+func (x *PeerScore) Reset() {
+	*x = PeerScore{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_node_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerScore) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerScore) ProtoMessage() {}
+
+func (x *PeerScore) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerScore.ProtoReflect.Descriptor instead.
+func (*PeerScore) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PeerScore) GetId() string {
+	if x != nil && x.Id != nil {
+		return *x.Id
+	}
+	return ""
+}
+
+func (x *PeerScore) GetScore() float64 {
+	if x != nil && x.Score != nil {
+		return *x.Score
+	}
+	return 0
+}
+
+func (x *PeerScore) GetBanned() bool {
+	if x != nil && x.Banned != nil {
+		return *x.Banned
+	}
+	return false
+}
+
+func (x *PeerScore) GetBannedUntil() int64 {
+	if x != nil && x.BannedUntil != nil {
+		return *x.BannedUntil
+	}
+	return 0
+}
+
+type PeerScoresResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code   *NodeStatusRespCodes `protobuf:"varint,1,opt,name=code,proto3,enum=NodeStatusRespCodes,oneof" json:"code,omitempty"`
+	Scores []*PeerScore         `protobuf:"bytes,2,rep,name=scores,proto3" json:"scores,omitempty"`
+}
+
+func (x *PeerScoresResp) Reset() {
+	*x = PeerScoresResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_node_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerScoresResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerScoresResp) ProtoMessage() {}
+
+func (x *PeerScoresResp) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerScoresResp.ProtoReflect.Descriptor instead.
+func (*PeerScoresResp) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PeerScoresResp) GetCode() NodeStatusRespCodes {
+	if x != nil && x.Code != nil {
+		return *x.Code
+	}
+	return NodeStatusRespCodes_DEFAULT
+}
+
+func (x *PeerScoresResp) GetScores() []*PeerScore {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+type TimeSyncStatusReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *TimeSyncStatusReq) Reset() {
+	*x = TimeSyncStatusReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_node_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TimeSyncStatusReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeSyncStatusReq) ProtoMessage() {}
+
+func (x *TimeSyncStatusReq) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeSyncStatusReq.ProtoReflect.Descriptor instead.
+func (*TimeSyncStatusReq) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{5}
+}
+
+type TimeSyncStatusResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code         *NodeStatusRespCodes `protobuf:"varint,1,opt,name=code,proto3,enum=NodeStatusRespCodes,oneof" json:"code,omitempty"`
+	Status       *int32               `protobuf:"varint,2,opt,name=status,proto3,oneof" json:"status,omitempty"`
+	Offset       *int64               `protobuf:"varint,3,opt,name=offset,proto3,oneof" json:"offset,omitempty"`
+	TargetOffset *int64               `protobuf:"varint,4,opt,name=target_offset,json=targetOffset,proto3,oneof" json:"target_offset,omitempty"`
+	Dispersion   *float64             `protobuf:"fixed64,5,opt,name=dispersion,proto3,oneof" json:"dispersion,omitempty"`
+	Rtt          *float64             `protobuf:"fixed64,6,opt,name=rtt,proto3,oneof" json:"rtt,omitempty"`
+	Samples      *int32               `protobuf:"varint,7,opt,name=samples,proto3,oneof" json:"samples,omitempty"`
+	Rejected     *int32               `protobuf:"varint,8,opt,name=rejected,proto3,oneof" json:"rejected,omitempty"`
+	ConfirmTimes *int32               `protobuf:"varint,9,opt,name=confirm_times,json=confirmTimes,proto3,oneof" json:"confirm_times,omitempty"`
+	LastRound    *int64               `protobuf:"varint,10,opt,name=last_round,json=lastRound,proto3,oneof" json:"last_round,omitempty"`
+	Agreed       *int32               `protobuf:"varint,11,opt,name=agreed,proto3,oneof" json:"agreed,omitempty"`
+}
+
+func (x *TimeSyncStatusResp) Reset() {
+	*x = TimeSyncStatusResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_node_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TimeSyncStatusResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeSyncStatusResp) ProtoMessage() {}
+
+func (x *TimeSyncStatusResp) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeSyncStatusResp.ProtoReflect.Descriptor instead.
+func (*TimeSyncStatusResp) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TimeSyncStatusResp) GetCode() NodeStatusRespCodes {
+	if x != nil && x.Code != nil {
+		return *x.Code
+	}
+	return NodeStatusRespCodes_DEFAULT
+}
+
+func (x *TimeSyncStatusResp) GetStatus() int32 {
+	if x != nil && x.Status != nil {
+		return *x.Status
+	}
+	return 0
+}
+
+func (x *TimeSyncStatusResp) GetOffset() int64 {
+	if x != nil && x.Offset != nil {
+		return *x.Offset
+	}
+	return 0
+}
+
+func (x *TimeSyncStatusResp) GetTargetOffset() int64 {
+	if x != nil && x.TargetOffset != nil {
+		return *x.TargetOffset
+	}
+	return 0
+}
+
+func (x *TimeSyncStatusResp) GetDispersion() float64 {
+	if x != nil && x.Dispersion != nil {
+		return *x.Dispersion
+	}
+	return 0
+}
+
+func (x *TimeSyncStatusResp) GetRtt() float64 {
+	if x != nil && x.Rtt != nil {
+		return *x.Rtt
+	}
+	return 0
+}
+
+func (x *TimeSyncStatusResp) GetSamples() int32 {
+	if x != nil && x.Samples != nil {
+		return *x.Samples
+	}
+	return 0
+}
+
+func (x *TimeSyncStatusResp) GetRejected() int32 {
+	if x != nil && x.Rejected != nil {
+		return *x.Rejected
+	}
+	return 0
+}
+
+func (x *TimeSyncStatusResp) GetConfirmTimes() int32 {
+	if x != nil && x.ConfirmTimes != nil {
+		return *x.ConfirmTimes
+	}
+	return 0
+}
+
+func (x *TimeSyncStatusResp) GetLastRound() int64 {
+	if x != nil && x.LastRound != nil {
+		return *x.LastRound
+	}
+	return 0
+}
+
+func (x *TimeSyncStatusResp) GetAgreed() int32 {
+	if x != nil && x.Agreed != nil {
+		return *x.Agreed
+	}
+	return 0
+}
+
+type BlockSyncStatusReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *BlockSyncStatusReq) Reset() {
+	*x = BlockSyncStatusReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_node_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockSyncStatusReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockSyncStatusReq) ProtoMessage() {}
+
+func (x *BlockSyncStatusReq) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockSyncStatusReq.ProtoReflect.Descriptor instead.
+func (*BlockSyncStatusReq) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{7}
+}
+
+type SyncPeerHead struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id           *string  `protobuf:"bytes,1,opt,name=id,proto3,oneof" json:"id,omitempty"`
+	Height       *int64   `protobuf:"varint,2,opt,name=height,proto3,oneof" json:"height,omitempty"`
+	BufferHeight *int64   `protobuf:"varint,3,opt,name=buffer_height,json=bufferHeight,proto3,oneof" json:"buffer_height,omitempty"`
+	Weight       *float64 `protobuf:"fixed64,4,opt,name=weight,proto3,oneof" json:"weight,omitempty"`
+	Selected     *bool    `protobuf:"varint,5,opt,name=selected,proto3,oneof" json:"selected,omitempty"`
+}
+
+func (x *SyncPeerHead) Reset() {
+	*x = SyncPeerHead{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_node_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SyncPeerHead) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncPeerHead) ProtoMessage() {}
+
+func (x *SyncPeerHead) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncPeerHead.ProtoReflect.Descriptor instead.
+func (*SyncPeerHead) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SyncPeerHead) GetId() string {
+	if x != nil && x.Id != nil {
+		return *x.Id
+	}
+	return ""
+}
+
+func (x *SyncPeerHead) GetHeight() int64 {
+	if x != nil && x.Height != nil {
+		return *x.Height
+	}
+	return 0
+}
+
+func (x *SyncPeerHead) GetBufferHeight() int64 {
+	if x != nil && x.BufferHeight != nil {
+		return *x.BufferHeight
+	}
+	return 0
+}
+
+func (x *SyncPeerHead) GetWeight() float64 {
+	if x != nil && x.Weight != nil {
+		return *x.Weight
+	}
+	return 0
+}
+
+func (x *SyncPeerHead) GetSelected() bool {
+	if x != nil && x.Selected != nil {
+		return *x.Selected
+	}
+	return false
+}
+
+type BlockSyncStatusResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code         *NodeStatusRespCodes `protobuf:"varint,1,opt,name=code,proto3,enum=NodeStatusRespCodes,oneof" json:"code,omitempty"`
+	Status       *int32               `protobuf:"varint,2,opt,name=status,proto3,oneof" json:"status,omitempty"`
+	KnownHeight  *int64               `protobuf:"varint,3,opt,name=known_height,json=knownHeight,proto3,oneof" json:"known_height,omitempty"`
+	RemoteHeight *int64               `protobuf:"varint,4,opt,name=remote_height,json=remoteHeight,proto3,oneof" json:"remote_height,omitempty"`
+	TargetHeight *int64               `protobuf:"varint,5,opt,name=target_height,json=targetHeight,proto3,oneof" json:"target_height,omitempty"`
+	Peers        []*SyncPeerHead      `protobuf:"bytes,6,rep,name=peers,proto3" json:"peers,omitempty"`
+}
+
+func (x *BlockSyncStatusResp) Reset() {
+	*x = BlockSyncStatusResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_node_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockSyncStatusResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockSyncStatusResp) ProtoMessage() {}
+
+func (x *BlockSyncStatusResp) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockSyncStatusResp.ProtoReflect.Descriptor instead.
+func (*BlockSyncStatusResp) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BlockSyncStatusResp) GetCode() NodeStatusRespCodes {
+	if x != nil && x.Code != nil {
+		return *x.Code
+	}
+	return NodeStatusRespCodes_DEFAULT
+}
+
+func (x *BlockSyncStatusResp) GetStatus() int32 {
+	if x != nil && x.Status != nil {
+		return *x.Status
+	}
+	return 0
+}
+
+func (x *BlockSyncStatusResp) GetKnownHeight() int64 {
+	if x != nil && x.KnownHeight != nil {
+		return *x.KnownHeight
+	}
+	return 0
+}
+
+func (x *BlockSyncStatusResp) GetRemoteHeight() int64 {
+	if x != nil && x.RemoteHeight != nil {
+		return *x.RemoteHeight
+	}
+	return 0
+}
+
+func (x *BlockSyncStatusResp) GetTargetHeight() int64 {
+	if x != nil && x.TargetHeight != nil {
+		return *x.TargetHeight
+	}
+	return 0
+}
+
+func (x *BlockSyncStatusResp) GetPeers() []*SyncPeerHead {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
 var File_node_proto protoreflect.FileDescriptor
 
 var file_node_proto_rawDesc = []byte{
@@ -180,16 +713,118 @@ var file_node_proto_rawDesc = []byte{
 	0x20, 0x01, 0x28, 0x09, 0x48, 0x01, 0x52, 0x05, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x88, 0x01, 0x01,
 	0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09,
 	0x52, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x63, 0x6f, 0x64,
-	0x65, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x2a, 0x3b, 0x0a, 0x13, 0x4e,
+	0x65, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x22, 0x0f, 0x0a, 0x0d, 0x50,
+	0x65, 0x65, 0x72, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x52, 0x65, 0x71, 0x22, 0xad, 0x01, 0x0a,
+	0x09, 0x50, 0x65, 0x65, 0x72, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x12, 0x13, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x02, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12,
+	0x19, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x48, 0x01,
+	0x52, 0x05, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x88, 0x01, 0x01, 0x12, 0x1b, 0x0a, 0x06, 0x62, 0x61,
+	0x6e, 0x6e, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x48, 0x02, 0x52, 0x06, 0x62, 0x61,
+	0x6e, 0x6e, 0x65, 0x64, 0x88, 0x01, 0x01, 0x12, 0x26, 0x0a, 0x0c, 0x62, 0x61, 0x6e, 0x6e, 0x65,
+	0x64, 0x5f, 0x75, 0x6e, 0x74, 0x69, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x48, 0x03, 0x52,
+	0x0b, 0x62, 0x61, 0x6e, 0x6e, 0x65, 0x64, 0x55, 0x6e, 0x74, 0x69, 0x6c, 0x88, 0x01, 0x01, 0x42,
+	0x05, 0x0a, 0x03, 0x5f, 0x69, 0x64, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x73, 0x63, 0x6f, 0x72, 0x65,
+	0x42, 0x09, 0x0a, 0x07, 0x5f, 0x62, 0x61, 0x6e, 0x6e, 0x65, 0x64, 0x42, 0x0f, 0x0a, 0x0d, 0x5f,
+	0x62, 0x61, 0x6e, 0x6e, 0x65, 0x64, 0x5f, 0x75, 0x6e, 0x74, 0x69, 0x6c, 0x22, 0x6c, 0x0a, 0x0e,
+	0x50, 0x65, 0x65, 0x72, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x12, 0x2d,
+	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x4e,
 	0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x43, 0x6f, 0x64,
-	0x65, 0x73, 0x12, 0x0b, 0x0a, 0x07, 0x44, 0x45, 0x46, 0x41, 0x55, 0x4c, 0x54, 0x10, 0x00, 0x12,
-	0x17, 0x0a, 0x13, 0x4e, 0x4f, 0x44, 0x45, 0x5f, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x53,
-	0x55, 0x43, 0x43, 0x45, 0x53, 0x53, 0x10, 0x64, 0x32, 0x42, 0x0a, 0x04, 0x4e, 0x6f, 0x64, 0x65,
-	0x12, 0x3a, 0x0a, 0x11, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x65, 0x64, 0x4e, 0x6f, 0x64,
-	0x65, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x11, 0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x65,
-	0x64, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x71, 0x1a, 0x12, 0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x65,
-	0x63, 0x74, 0x65, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x73, 0x70, 0x42, 0x08, 0x5a, 0x06,
-	0x72, 0x70, 0x63, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x73, 0x48, 0x00, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x88, 0x01, 0x01, 0x12, 0x22, 0x0a,
+	0x06, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a, 0x2e,
+	0x50, 0x65, 0x65, 0x72, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x52, 0x06, 0x73, 0x63, 0x6f, 0x72, 0x65,
+	0x73, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x22, 0x13, 0x0a, 0x11, 0x54, 0x69,
+	0x6d, 0x65, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x22,
+	0x9b, 0x04, 0x0a, 0x12, 0x54, 0x69, 0x6d, 0x65, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x12, 0x2d, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x43, 0x6f, 0x64, 0x65, 0x73, 0x48, 0x00, 0x52, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x88, 0x01, 0x01, 0x12, 0x1b, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x05, 0x48, 0x01, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x88,
+	0x01, 0x01, 0x12, 0x1b, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x03, 0x48, 0x02, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x88, 0x01, 0x01, 0x12,
+	0x28, 0x0a, 0x0d, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x48, 0x03, 0x52, 0x0c, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x4f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x88, 0x01, 0x01, 0x12, 0x23, 0x0a, 0x0a, 0x64, 0x69, 0x73,
+	0x70, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x48, 0x04, 0x52,
+	0x0a, 0x64, 0x69, 0x73, 0x70, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x88, 0x01, 0x01, 0x12, 0x15,
+	0x0a, 0x03, 0x72, 0x74, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x48, 0x05, 0x52, 0x03, 0x72,
+	0x74, 0x74, 0x88, 0x01, 0x01, 0x12, 0x1d, 0x0a, 0x07, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x73,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x48, 0x06, 0x52, 0x07, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65,
+	0x73, 0x88, 0x01, 0x01, 0x12, 0x1f, 0x0a, 0x08, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x65, 0x64,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x48, 0x07, 0x52, 0x08, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74,
+	0x65, 0x64, 0x88, 0x01, 0x01, 0x12, 0x28, 0x0a, 0x0d, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d,
+	0x5f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x48, 0x08, 0x52, 0x0c,
+	0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x88, 0x01, 0x01, 0x12,
+	0x22, 0x0a, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x72, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x0a, 0x20,
+	0x01, 0x28, 0x03, 0x48, 0x09, 0x52, 0x09, 0x6c, 0x61, 0x73, 0x74, 0x52, 0x6f, 0x75, 0x6e, 0x64,
+	0x88, 0x01, 0x01, 0x12, 0x1b, 0x0a, 0x06, 0x61, 0x67, 0x72, 0x65, 0x65, 0x64, 0x18, 0x0b, 0x20,
+	0x01, 0x28, 0x05, 0x48, 0x0a, 0x52, 0x06, 0x61, 0x67, 0x72, 0x65, 0x65, 0x64, 0x88, 0x01, 0x01,
+	0x42, 0x07, 0x0a, 0x05, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x42,
+	0x10, 0x0a, 0x0e, 0x5f, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x6f, 0x66, 0x66, 0x73, 0x65,
+	0x74, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x64, 0x69, 0x73, 0x70, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x42, 0x06, 0x0a, 0x04, 0x5f, 0x72, 0x74, 0x74, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x73, 0x61, 0x6d,
+	0x70, 0x6c, 0x65, 0x73, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x65,
+	0x64, 0x42, 0x10, 0x0a, 0x0e, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x5f, 0x74, 0x69,
+	0x6d, 0x65, 0x73, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x72, 0x6f, 0x75,
+	0x6e, 0x64, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x61, 0x67, 0x72, 0x65, 0x65, 0x64, 0x22, 0x14, 0x0a,
+	0x12, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x52, 0x65, 0x71, 0x22, 0xe4, 0x01, 0x0a, 0x0c, 0x53, 0x79, 0x6e, 0x63, 0x50, 0x65, 0x65, 0x72,
+	0x48, 0x65, 0x61, 0x64, 0x12, 0x13, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x48, 0x00, 0x52, 0x02, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x1b, 0x0a, 0x06, 0x68, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x48, 0x01, 0x52, 0x06, 0x68, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x88, 0x01, 0x01, 0x12, 0x28, 0x0a, 0x0d, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72,
+	0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x48, 0x02, 0x52,
+	0x0c, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x88, 0x01, 0x01,
+	0x12, 0x1b, 0x0a, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01,
+	0x48, 0x03, 0x52, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x88, 0x01, 0x01, 0x12, 0x1f, 0x0a,
+	0x08, 0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x48,
+	0x04, 0x52, 0x08, 0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x65, 0x64, 0x88, 0x01, 0x01, 0x42, 0x05,
+	0x0a, 0x03, 0x5f, 0x69, 0x64, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74,
+	0x42, 0x10, 0x0a, 0x0e, 0x5f, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x5f, 0x68, 0x65, 0x69, 0x67,
+	0x68, 0x74, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x42, 0x0b, 0x0a,
+	0x09, 0x5f, 0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x65, 0x64, 0x22, 0xcb, 0x02, 0x0a, 0x13, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x12, 0x2d, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x14, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x43, 0x6f, 0x64, 0x65, 0x73, 0x48, 0x00, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x88, 0x01,
+	0x01, 0x12, 0x1b, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x05, 0x48, 0x01, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x88, 0x01, 0x01, 0x12, 0x26,
+	0x0a, 0x0c, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x03, 0x48, 0x02, 0x52, 0x0b, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x48, 0x65, 0x69,
+	0x67, 0x68, 0x74, 0x88, 0x01, 0x01, 0x12, 0x28, 0x0a, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65,
+	0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x48, 0x03, 0x52,
+	0x0c, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x88, 0x01, 0x01,
+	0x12, 0x28, 0x0a, 0x0d, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68,
+	0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x48, 0x04, 0x52, 0x0c, 0x74, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x88, 0x01, 0x01, 0x12, 0x23, 0x0a, 0x05, 0x70, 0x65,
+	0x65, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x53, 0x79, 0x6e, 0x63,
+	0x50, 0x65, 0x65, 0x72, 0x48, 0x65, 0x61, 0x64, 0x52, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x42,
+	0x07, 0x0a, 0x05, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x73, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x42, 0x0f, 0x0a, 0x0d, 0x5f, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x5f, 0x68, 0x65,
+	0x69, 0x67, 0x68, 0x74, 0x42, 0x10, 0x0a, 0x0e, 0x5f, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x5f,
+	0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x42, 0x10, 0x0a, 0x0e, 0x5f, 0x74, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x2a, 0x3b, 0x0a, 0x13, 0x4e, 0x6f, 0x64, 0x65,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x43, 0x6f, 0x64, 0x65, 0x73, 0x12,
+	0x0b, 0x0a, 0x07, 0x44, 0x45, 0x46, 0x41, 0x55, 0x4c, 0x54, 0x10, 0x00, 0x12, 0x17, 0x0a, 0x13,
+	0x4e, 0x4f, 0x44, 0x45, 0x5f, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x53, 0x55, 0x43, 0x43,
+	0x45, 0x53, 0x53, 0x10, 0x64, 0x32, 0xea, 0x01, 0x0a, 0x04, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x3a,
+	0x0a, 0x11, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x65, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x4c,
+	0x69, 0x73, 0x74, 0x12, 0x11, 0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x65, 0x64, 0x4e,
+	0x6f, 0x64, 0x65, 0x52, 0x65, 0x71, 0x1a, 0x12, 0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x65, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x73, 0x70, 0x12, 0x2d, 0x0a, 0x0a, 0x50, 0x65,
+	0x65, 0x72, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x12, 0x0e, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x53,
+	0x63, 0x6f, 0x72, 0x65, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x0f, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x53,
+	0x63, 0x6f, 0x72, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x12, 0x39, 0x0a, 0x0e, 0x54, 0x69, 0x6d,
+	0x65, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x2e, 0x54, 0x69,
+	0x6d, 0x65, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x1a,
+	0x13, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x52, 0x65, 0x73, 0x70, 0x12, 0x3c, 0x0a, 0x0f, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x79, 0x6e,
+	0x63, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x13, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53,
+	0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x1a, 0x14, 0x2e, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x79, 0x6e, 0x63, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x42, 0x08, 0x5a, 0x06, 0x72, 0x70, 0x63, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -205,21 +840,40 @@ func file_node_proto_rawDescGZIP() []byte {
 }
 
 var file_node_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_node_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_node_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_node_proto_goTypes = []interface{}{
-	(NodeStatusRespCodes)(0),  // 0: NodeStatusRespCodes
-	(*ConnectedNodeReq)(nil),  // 1: ConnectedNodeReq
-	(*ConnectedNodeResp)(nil), // 2: ConnectedNodeResp
+	(NodeStatusRespCodes)(0),    // 0: NodeStatusRespCodes
+	(*ConnectedNodeReq)(nil),    // 1: ConnectedNodeReq
+	(*ConnectedNodeResp)(nil),   // 2: ConnectedNodeResp
+	(*PeerScoresReq)(nil),       // 3: PeerScoresReq
+	(*PeerScore)(nil),           // 4: PeerScore
+	(*PeerScoresResp)(nil),      // 5: PeerScoresResp
+	(*TimeSyncStatusReq)(nil),   // 6: TimeSyncStatusReq
+	(*TimeSyncStatusResp)(nil),  // 7: TimeSyncStatusResp
+	(*BlockSyncStatusReq)(nil),  // 8: BlockSyncStatusReq
+	(*SyncPeerHead)(nil),        // 9: SyncPeerHead
+	(*BlockSyncStatusResp)(nil), // 10: BlockSyncStatusResp
 }
 var file_node_proto_depIdxs = []int32{
-	0, // 0: ConnectedNodeResp.code:type_name -> NodeStatusRespCodes
-	1, // 1: Node.ConnectedNodeList:input_type -> ConnectedNodeReq
-	2, // 2: Node.ConnectedNodeList:output_type -> ConnectedNodeResp
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: ConnectedNodeResp.code:type_name -> NodeStatusRespCodes
+	0,  // 1: PeerScoresResp.code:type_name -> NodeStatusRespCodes
+	4,  // 2: PeerScoresResp.scores:type_name -> PeerScore
+	0,  // 3: TimeSyncStatusResp.code:type_name -> NodeStatusRespCodes
+	0,  // 4: BlockSyncStatusResp.code:type_name -> NodeStatusRespCodes
+	9,  // 5: BlockSyncStatusResp.peers:type_name -> SyncPeerHead
+	1,  // 6: Node.ConnectedNodeList:input_type -> ConnectedNodeReq
+	3,  // 7: Node.PeerScores:input_type -> PeerScoresReq
+	6,  // 8: Node.TimeSyncStatus:input_type -> TimeSyncStatusReq
+	8,  // 9: Node.BlockSyncStatus:input_type -> BlockSyncStatusReq
+	2,  // 10: Node.ConnectedNodeList:output_type -> ConnectedNodeResp
+	5,  // 11: Node.PeerScores:output_type -> PeerScoresResp
+	7,  // 12: Node.TimeSyncStatus:output_type -> TimeSyncStatusResp
+	10, // 13: Node.BlockSyncStatus:output_type -> BlockSyncStatusResp
+	10, // [10:14] is the sub-list for method output_type
+	6,  // [6:10] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_node_proto_init() }
@@ -252,15 +906,116 @@ func file_node_proto_init() {
 				return nil
 			}
 		}
+		file_node_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerScoresReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_node_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerScore); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_node_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerScoresResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_node_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TimeSyncStatusReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_node_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TimeSyncStatusResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_node_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockSyncStatusReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_node_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SyncPeerHead); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_node_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockSyncStatusResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_node_proto_msgTypes[1].OneofWrappers = []interface{}{}
+	file_node_proto_msgTypes[3].OneofWrappers = []interface{}{}
+	file_node_proto_msgTypes[4].OneofWrappers = []interface{}{}
+	file_node_proto_msgTypes[6].OneofWrappers = []interface{}{}
+	file_node_proto_msgTypes[8].OneofWrappers = []interface{}{}
+	file_node_proto_msgTypes[9].OneofWrappers = []interface{}{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_node_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   2,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
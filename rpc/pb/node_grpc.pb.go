@@ -23,6 +23,9 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type NodeClient interface {
 	ConnectedNodeList(ctx context.Context, in *ConnectedNodeReq, opts ...grpc.CallOption) (*ConnectedNodeResp, error)
+	PeerScores(ctx context.Context, in *PeerScoresReq, opts ...grpc.CallOption) (*PeerScoresResp, error)
+	TimeSyncStatus(ctx context.Context, in *TimeSyncStatusReq, opts ...grpc.CallOption) (*TimeSyncStatusResp, error)
+	BlockSyncStatus(ctx context.Context, in *BlockSyncStatusReq, opts ...grpc.CallOption) (*BlockSyncStatusResp, error)
 }
 
 type nodeClient struct {
@@ -42,11 +45,41 @@ func (c *nodeClient) ConnectedNodeList(ctx context.Context, in *ConnectedNodeReq
 	return out, nil
 }
 
+func (c *nodeClient) PeerScores(ctx context.Context, in *PeerScoresReq, opts ...grpc.CallOption) (*PeerScoresResp, error) {
+	out := new(PeerScoresResp)
+	err := c.cc.Invoke(ctx, "/Node/PeerScores", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) TimeSyncStatus(ctx context.Context, in *TimeSyncStatusReq, opts ...grpc.CallOption) (*TimeSyncStatusResp, error) {
+	out := new(TimeSyncStatusResp)
+	err := c.cc.Invoke(ctx, "/Node/TimeSyncStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nodeClient) BlockSyncStatus(ctx context.Context, in *BlockSyncStatusReq, opts ...grpc.CallOption) (*BlockSyncStatusResp, error) {
+	out := new(BlockSyncStatusResp)
+	err := c.cc.Invoke(ctx, "/Node/BlockSyncStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NodeServer is the server API for Node service.
 // All implementations must embed UnimplementedNodeServer
 // for forward compatibility
 type NodeServer interface {
 	ConnectedNodeList(context.Context, *ConnectedNodeReq) (*ConnectedNodeResp, error)
+	PeerScores(context.Context, *PeerScoresReq) (*PeerScoresResp, error)
+	TimeSyncStatus(context.Context, *TimeSyncStatusReq) (*TimeSyncStatusResp, error)
+	BlockSyncStatus(context.Context, *BlockSyncStatusReq) (*BlockSyncStatusResp, error)
 	mustEmbedUnimplementedNodeServer()
 }
 
@@ -57,6 +90,15 @@ type UnimplementedNodeServer struct {
 func (UnimplementedNodeServer) ConnectedNodeList(context.Context, *ConnectedNodeReq) (*ConnectedNodeResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ConnectedNodeList not implemented")
 }
+func (UnimplementedNodeServer) PeerScores(context.Context, *PeerScoresReq) (*PeerScoresResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PeerScores not implemented")
+}
+func (UnimplementedNodeServer) TimeSyncStatus(context.Context, *TimeSyncStatusReq) (*TimeSyncStatusResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TimeSyncStatus not implemented")
+}
+func (UnimplementedNodeServer) BlockSyncStatus(context.Context, *BlockSyncStatusReq) (*BlockSyncStatusResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BlockSyncStatus not implemented")
+}
 func (UnimplementedNodeServer) mustEmbedUnimplementedNodeServer() {}
 
 // UnsafeNodeServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +130,60 @@ func _Node_ConnectedNodeList_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Node_PeerScores_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeerScoresReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).PeerScores(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/PeerScores",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).PeerScores(ctx, req.(*PeerScoresReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_TimeSyncStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TimeSyncStatusReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).TimeSyncStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/TimeSyncStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).TimeSyncStatus(ctx, req.(*TimeSyncStatusReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Node_BlockSyncStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockSyncStatusReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeServer).BlockSyncStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Node/BlockSyncStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeServer).BlockSyncStatus(ctx, req.(*BlockSyncStatusReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Node_ServiceDesc is the grpc.ServiceDesc for Node service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,6 +195,18 @@ var Node_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ConnectedNodeList",
 			Handler:    _Node_ConnectedNodeList_Handler,
 		},
+		{
+			MethodName: "PeerScores",
+			Handler:    _Node_PeerScores_Handler,
+		},
+		{
+			MethodName: "TimeSyncStatus",
+			Handler:    _Node_TimeSyncStatus_Handler,
+		},
+		{
+			MethodName: "BlockSyncStatus",
+			Handler:    _Node_BlockSyncStatus_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "node.proto",
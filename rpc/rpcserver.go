@@ -7,40 +7,82 @@
 package rpc
 
 import (
+	"context"
+	"github.com/chain-lab/go-norn/node"
 	"github.com/chain-lab/go-norn/rpc/pb"
 	"github.com/gookit/config/v2"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"net"
+	"sync"
 )
 
-func RPCServerStart() {
-	// 从配置文件中获取 RPC 绑定的 ip 和 port
-	addr := config.String("rpc.address")
-	lis, err := net.Listen("tcp", addr)
-
-	if err != nil {
-		log.WithField("error", err).Errorln("RPC listen port failed.")
-		return
-	}
+// Server RPC 服务，实现了服务的启动和停止
+type Server struct {
+	addr   string
+	server *grpc.Server
+	wg     sync.WaitGroup
+}
 
+// NewServer 创建 RPC 服务并注册所有的 Service，Service 通过 pm 访问节点的数据，addr 为空时使用配置文件中的地址
+func NewServer(pm *node.P2PManager, addr string) *Server {
 	limiter := NewRateLimiter(3000)
 
 	s := grpc.NewServer(
 		grpc.UnaryInterceptor(limiter.UnaryInterceptor),
 	)
 	// 注册 RPC 处理的 Service
-	pb.RegisterTransactionServiceServer(s, &transactionService{})
-	pb.RegisterNodeServer(s, &nodeService{})
-	pb.RegisterBlockchainServer(s, &blockchainService{})
+	pb.RegisterTransactionServiceServer(s, &transactionService{pm: pm})
+	pb.RegisterNodeServer(s, &nodeService{pm: pm})
+	pb.RegisterBlockchainServer(s, &blockchainService{pm: pm})
 	reflection.Register(s)
 
-	log.Traceln("RPC server started.")
+	if addr == "" {
+		// 从配置文件中获取 RPC 绑定的 ip 和 port
+		addr = config.String("rpc.address")
+	}
+
+	return &Server{addr: addr, server: s}
+}
+
+// Start 监听配置文件中的地址，并在协程中处理 RPC 请求
+func (s *Server) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.addr)
 
-	err = s.Serve(lis)
 	if err != nil {
-		log.WithField("error", err).Errorln("RPC server failed.")
-		return
+		log.WithField("error", err).Errorln("RPC listen port failed.")
+		return err
+	}
+
+	log.Traceln("RPC server started.")
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		err := s.server.Serve(lis)
+		if err != nil {
+			log.WithField("error", err).Errorln("RPC server failed.")
+		}
+	}()
+	return nil
+}
+
+// Stop 等待正在处理的请求完成后停止 RPC 服务，超时后直接关闭所有连接
+func (s *Server) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		s.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
 	}
 }
@@ -18,6 +18,7 @@ import (
 
 type transactionService struct {
 	pb.UnimplementedTransactionServiceServer
+	pm *node.P2PManager // 所属节点的 manager
 }
 
 //var TransactionService = transactionService{}
@@ -49,7 +50,7 @@ func (s *transactionService) SubmitTransaction(ctx context.Context, in *pb.Submi
 	}
 
 	//对交易的签名进行验证，如果验证错误直接返回
-	if !transaction.Verify() {
+	if !transaction.Verify(s.pm.GetBlockChain().ChainID()) {
 		resp.Status = pb.SubmitTransactionStatus_SIGNATURE_FAILED.Enum()
 		resp.Error = proto.String("Verify transaction signature failed.")
 		//log.Infoln("Verify transaction signature failed.")
@@ -67,7 +68,7 @@ func (s *transactionService) SubmitTransaction(ctx context.Context, in *pb.Submi
 	//
 	//pool.Add(transaction)
 
-	pm := node.GetP2PManager()
+	pm := s.pm
 
 	if pm == nil {
 		resp.Status = pb.SubmitTransactionStatus_FORMAT_ERROR.Enum()
@@ -0,0 +1,556 @@
+// Package simulation
+// @Description: 进程内的多节点模拟网络，用于共识相关的测试。节点之间使用 libp2p 的 mocknet 作为内存传输，
+// 不需要监听端口也不需要访问外部网络；创世参数使用较小的 VDF 时间参数，并支持注入网络分区、链路延迟和随机丢包
+package simulation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/chain-lab/go-norn/core"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/node"
+	"github.com/chain-lab/go-norn/norn"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	mrand "math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeParam = 1000 // 模拟网络默认的 VDF 时间参数，保证每一轮 VDF 在毫秒级别完成
+	DefaultChainID   = 7    // 模拟网络默认的链 ID
+
+	pollInterval     = 200 * time.Millisecond // 等待区块高度时的轮询间隔
+	fakePollInterval = 20 * time.Millisecond  // 使用模拟时钟时的轮询间隔，每次轮询推进一次模拟时钟
+	defaultClockStep = 250 * time.Millisecond // 每次轮询推进模拟时钟的默认时长
+	gossipTimeout    = 10 * time.Second       // 启动时等待广播网络建立的超时时间
+)
+
+var (
+	errNoNodes        = errors.New("simulation needs at least one node")
+	errNotConverged   = errors.New("chains not converged")
+	errGossipNotReady = errors.New("gossip subscriptions not ready")
+)
+
+// Config 模拟网络的配置信息
+type Config struct {
+	Nodes     int           // 节点数量
+	TimeParam int64         // VDF 时间参数，为 0 时使用 DefaultTimeParam
+	ChainID   int64         // 链 ID，为 0 时使用 DefaultChainID
+	Latency   time.Duration // 节点之间的链路延迟
+	DropRate  float64       // 入站消息的随机丢弃概率，取值 [0, 1]
+	ClockSkew []int64       // 每个节点的时钟偏移，单位为毫秒，未设置的节点没有偏移
+	Seed      int64         // 丢包使用的随机数种子，相同的种子得到相同的丢包序列
+
+	// 所有节点共用的模拟时钟，为 nil 时使用系统时钟。设置后打包时隙、时间同步和缓冲区的处理
+	// 只在 WaitHeight 推进模拟时钟时发生，网络传输仍然使用真实的时间
+	Clock     *utils.FakeClock
+	ClockStep time.Duration // WaitHeight 每次轮询推进模拟时钟的时长，为 0 时为 250ms
+
+	// 每个打包间隔期望的出块节点数量，为 0 时等于节点数量，即每个节点在每个间隔都满足共识条件。
+	// VDF 的 seed 只在收到新区块时更新，期望值较小时某个 seed 下可能没有节点满足共识条件，导致链停止增长
+	ExpectedProducers int64
+}
+
+// SimNode 模拟网络中的一个节点
+type SimNode struct {
+	*norn.Node
+
+	Index int               // 节点在网络中的序号
+	Host  host.Host         // 节点在 mocknet 中的 p2p 实例
+	Key   *ecdsa.PrivateKey // 节点的共识私钥
+
+	db *utils.LevelDB // 节点的内存数据库
+}
+
+// Network 模拟网络
+type Network struct {
+	config  *Config
+	mocknet mocknet.Mocknet
+	genesis *core.Genesis
+	nodes   []*SimNode
+	index   map[peer.ID]int // 节点 ID -> 节点序号
+
+	faultLock sync.Mutex
+	groups    []int       // 每个节点所属的分区，不同分区之间的消息全部丢弃
+	dropRate  float64     // 入站消息的随机丢弃概率
+	rand      *mrand.Rand // 丢包使用的随机数生成器
+}
+
+// NewNetwork
+//
+//	@Description: 创建模拟网络，生成所有节点共用的创世文件，并为每个节点创建内存数据库、共识私钥和 mocknet 节点
+//	@param config - 模拟网络配置
+//	@return *Network - 模拟网络实例
+//	@return error
+func NewNetwork(config *Config) (*Network, error) {
+	if config.Nodes <= 0 {
+		return nil, errNoNodes
+	}
+	if config.TimeParam == 0 {
+		config.TimeParam = DefaultTimeParam
+	}
+	if config.ChainID == 0 {
+		config.ChainID = DefaultChainID
+	}
+	if config.ExpectedProducers == 0 {
+		config.ExpectedProducers = int64(config.Nodes)
+	}
+	if config.ClockStep == 0 {
+		config.ClockStep = defaultClockStep
+	}
+
+	mn := mocknet.New()
+	mn.SetLinkDefaults(mocknet.LinkOptions{Latency: config.Latency})
+
+	n := &Network{
+		config:   config,
+		mocknet:  mn,
+		nodes:    make([]*SimNode, 0, config.Nodes),
+		index:    make(map[peer.ID]int),
+		groups:   make([]int, config.Nodes),
+		dropRate: config.DropRate,
+		rand:     mrand.New(mrand.NewSource(config.Seed)),
+	}
+
+	keys := make([]*ecdsa.PrivateKey, config.Nodes)
+	for i := range keys {
+		prv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			_ = mn.Close()
+			return nil, err
+		}
+		keys[i] = prv
+	}
+
+	genesis, err := n.createGenesis(keys[0])
+	if err != nil {
+		_ = mn.Close()
+		return nil, err
+	}
+	n.genesis = genesis
+
+	// 先创建所有的 p2p 节点，消息过滤函数需要通过节点 ID 查找节点序号
+	hosts := make([]host.Host, config.Nodes)
+	for i := range hosts {
+		hosts[i], err = mn.GenPeer()
+		if err != nil {
+			_ = mn.Close()
+			return nil, err
+		}
+		n.index[hosts[i].ID()] = i
+	}
+
+	for i := 0; i < config.Nodes; i++ {
+		simNode, err := n.createNode(i, hosts[i], keys[i])
+		if err != nil {
+			n.closeDB()
+			_ = mn.Close()
+			return nil, err
+		}
+		n.nodes = append(n.nodes, simNode)
+	}
+
+	return n, nil
+}
+
+// createGenesis
+//
+//	@Description: 生成模拟网络的创世文件，VDF 使用较小的时间参数，所有节点使用默认权重
+//	@receiver n
+//	@param prv - 创世区块的签名私钥
+//	@return *core.Genesis - 创世文件
+//	@return error
+func (n *Network) createGenesis(prv *ecdsa.PrivateKey) (*core.Genesis, error) {
+	genesisParams, err := crypto.GenerateGenesisParams()
+	if err != nil {
+		return nil, err
+	}
+
+	genesisParams.TimeParam = n.config.TimeParam
+	genesisParams.ExpectedProducers = n.config.ExpectedProducers
+	genesisParams.TotalWeight = int64(n.config.Nodes)
+	genesisParams.DataVersion = core.DataVersionTrie
+
+	return core.SealGenesis(n.config.ChainID, n.clock().Now().UnixMilli(), genesisParams, nil, prv)
+}
+
+// createNode
+//
+//	@Description: 创建模拟网络中的一个节点，节点不启动 RPC，所有节点都从同一个创世文件启动
+//	@receiver n
+//	@param idx - 节点序号
+//	@param h - 节点的 p2p 实例
+//	@param prv - 节点的共识私钥
+//	@return *SimNode - 节点实例
+//	@return error
+func (n *Network) createNode(idx int, h host.Host, prv *ecdsa.PrivateKey) (*SimNode, error) {
+	db, err := utils.NewMemoryLevelDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var skew int64
+	if idx < len(n.config.ClockSkew) {
+		skew = n.config.ClockSkew[idx]
+	}
+
+	inst, err := norn.New(&norn.Config{
+		DB:            db,
+		Genesis:       true,
+		GenesisConfig: n.genesis,
+		InitialDelta:  skew,
+		ChainID:       n.config.ChainID,
+		DisableRPC:    true,
+		ConsensusKey:  prv,
+		Filter:        n.filter(idx),
+		Clock:         n.clock(),
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &SimNode{
+		Node:  inst,
+		Index: idx,
+		Host:  h,
+		Key:   prv,
+		db:    db,
+	}, nil
+}
+
+// Start
+//
+//	@Description: 启动所有节点，并在 mocknet 中两两建立连接。返回前等待每个节点都收到其他所有节点
+//	在区块 topic 上的订阅，否则模拟时钟推进后最早打包的区块可能只广播到部分节点
+//	@receiver n
+//	@param ctx - 节点协程的父 context
+//	@return error
+func (n *Network) Start(ctx context.Context) error {
+	if err := n.mocknet.LinkAll(); err != nil {
+		return err
+	}
+
+	for _, simNode := range n.nodes {
+		if err := simNode.Start(ctx); err != nil {
+			return err
+		}
+
+		pm := simNode.Manager()
+		simNode.Host.SetStreamHandler(node.ProtocolId, pm.HandleStream)
+		if err := pm.Attach(simNode.Host); err != nil {
+			return err
+		}
+	}
+
+	for i, simNode := range n.nodes {
+		for _, remote := range n.nodes[i+1:] {
+			simNode.Manager().CheckAndCreateStream(ctx, simNode.Host, peer.AddrInfo{
+				ID:    remote.Host.ID(),
+				Addrs: remote.Host.Addrs(),
+			})
+		}
+	}
+
+	if err := n.waitGossip(ctx); err != nil {
+		return err
+	}
+
+	log.Infof("Simulation network with %d nodes started.", len(n.nodes))
+	return nil
+}
+
+// waitGossip 等待所有节点在区块 topic 上发现其他所有节点，超时时间为 gossipTimeout
+func (n *Network) waitGossip(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, gossipTimeout)
+	defer cancel()
+
+	return n.waitUntil(ctx, pollInterval, false, func() error {
+		for idx, simNode := range n.nodes {
+			if peers := len(simNode.Manager().GossipPeers()); peers < len(n.nodes)-1 {
+				return fmt.Errorf("%w: node %d has %d gossip peers", errGossipNotReady, idx, peers)
+			}
+		}
+		return nil
+	})
+}
+
+// Stop
+//
+//	@Description: 停止所有节点，关闭内存数据库和 mocknet
+//	@receiver n
+//	@param ctx - 停止的超时 context
+//	@return error
+func (n *Network) Stop(ctx context.Context) error {
+	var result error
+	for _, simNode := range n.nodes {
+		if err := simNode.Stop(ctx); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	n.closeDB()
+	if err := n.mocknet.Close(); err != nil && result == nil {
+		result = err
+	}
+	return result
+}
+
+// closeDB 关闭所有节点的内存数据库
+func (n *Network) closeDB() {
+	for _, simNode := range n.nodes {
+		_ = simNode.db.Close()
+	}
+}
+
+// Nodes 模拟网络中的所有节点
+func (n *Network) Nodes() []*SimNode {
+	return n.nodes
+}
+
+// Node 获取序号为 idx 的节点
+func (n *Network) Node(idx int) *SimNode {
+	return n.nodes[idx]
+}
+
+// Genesis 模拟网络的创世文件
+func (n *Network) Genesis() *core.Genesis {
+	return n.genesis
+}
+
+// clock 节点使用的时钟，没有设置模拟时钟时使用系统时钟
+func (n *Network) clock() utils.Clock {
+	if n.config.Clock == nil {
+		return utils.NewRealClock()
+	}
+	return n.config.Clock
+}
+
+// Partition
+//
+//	@Description: 将节点划分为多个分区，不同分区之间的消息全部丢弃，未列出的节点各自成为一个分区
+//	@receiver n
+//	@param groups - 每个分区包含的节点序号
+func (n *Network) Partition(groups ...[]int) {
+	n.faultLock.Lock()
+	defer n.faultLock.Unlock()
+
+	for idx := range n.groups {
+		n.groups[idx] = -idx - 1
+	}
+	for group, members := range groups {
+		for _, idx := range members {
+			n.groups[idx] = group
+		}
+	}
+	log.Infof("Simulation network partitioned: %v", groups)
+}
+
+// Heal 恢复网络分区，所有节点之间可以正常通信
+func (n *Network) Heal() {
+	n.faultLock.Lock()
+	defer n.faultLock.Unlock()
+
+	for idx := range n.groups {
+		n.groups[idx] = 0
+	}
+	log.Infoln("Simulation network healed.")
+}
+
+// SetDropRate 设置入站消息的随机丢弃概率
+func (n *Network) SetDropRate(rate float64) {
+	n.faultLock.Lock()
+	defer n.faultLock.Unlock()
+
+	n.dropRate = rate
+}
+
+// SetLatency
+//
+//	@Description: 设置所有节点之间的链路延迟，对已经建立的链路和之后建立的链路都生效
+//	@receiver n
+//	@param latency - 链路延迟
+func (n *Network) SetLatency(latency time.Duration) {
+	options := mocknet.LinkOptions{Latency: latency}
+	n.mocknet.SetLinkDefaults(options)
+
+	for i, a := range n.nodes {
+		for _, b := range n.nodes[i+1:] {
+			for _, link := range n.mocknet.LinksBetweenPeers(a.Host.ID(), b.Host.ID()) {
+				link.SetOptions(options)
+			}
+		}
+	}
+}
+
+// filter
+//
+//	@Description: 生成节点的入站消息过滤函数，按照当前的分区和丢包概率决定是否接收消息
+//	@receiver n
+//	@param idx - 接收消息的节点序号
+//	@return node.MessageFilter - 消息过滤函数
+func (n *Network) filter(idx int) node.MessageFilter {
+	return func(from peer.ID) bool {
+		remote, ok := n.index[from]
+		if !ok {
+			return false
+		}
+
+		n.faultLock.Lock()
+		defer n.faultLock.Unlock()
+
+		if n.groups[idx] != n.groups[remote] {
+			return false
+		}
+		return n.dropRate <= 0 || n.rand.Float64() >= n.dropRate
+	}
+}
+
+// WaitHeight
+//
+//	@Description: 等待所有节点的最新区块高度达到 height，使用模拟时钟时每次轮询将模拟时钟推进 ClockStep
+//	@receiver n
+//	@param ctx - 等待的超时 context
+//	@param height - 目标高度
+//	@return error - 超时时返回错误，包含每个节点的当前高度
+func (n *Network) WaitHeight(ctx context.Context, height int64) error {
+	return n.waitHeights(ctx, height, n.Heights)
+}
+
+// WaitBufferedHeight
+//
+//	@Description: 等待所有节点缓冲区视图的高度达到 height，缓冲区中的区块还没有写入数据库，
+//	用于观察少于 core.MaxBufferSize 个高度内的分叉
+//	@receiver n
+//	@param ctx - 等待的超时 context
+//	@param height - 目标高度
+//	@return error - 超时时返回错误，包含每个节点的当前缓冲区高度
+func (n *Network) WaitBufferedHeight(ctx context.Context, height int64) error {
+	return n.waitHeights(ctx, height, n.BufferedHeights)
+}
+
+// waitHeights 等待 heights 返回的所有高度达到 height
+func (n *Network) waitHeights(ctx context.Context, height int64, heights func() []int64) error {
+	interval := pollInterval
+	if n.config.Clock != nil {
+		interval = fakePollInterval
+	}
+
+	return n.waitUntil(ctx, interval, true, func() error {
+		current := heights()
+		for _, h := range current {
+			if h < height {
+				return fmt.Errorf("wait height %d failed, heights %v", height, current)
+			}
+		}
+		return nil
+	})
+}
+
+// waitUntil
+//
+//	@Description: 按照 interval 轮询 check 直到返回 nil
+//	@receiver n
+//	@param ctx - 等待的超时 context
+//	@param interval - 轮询间隔
+//	@param advance - 使用模拟时钟时是否每次轮询推进 ClockStep
+//	@param check - 条件检查函数，条件不满足时返回原因
+//	@return error - 超时时返回 check 最后一次返回的错误
+func (n *Network) waitUntil(ctx context.Context, interval time.Duration, advance bool,
+	check func() error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%v: %w", err, ctx.Err())
+		case <-ticker.C:
+			if advance && n.config.Clock != nil {
+				n.config.Clock.Advance(n.config.ClockStep)
+			}
+		}
+	}
+}
+
+// Heights 所有节点的最新区块高度，还没有区块的节点为 -1
+func (n *Network) Heights() []int64 {
+	heights := make([]int64, len(n.nodes))
+	for idx, simNode := range n.nodes {
+		heights[idx] = simNode.Chain().Height()
+	}
+	return heights
+}
+
+// BufferedHeights 所有节点缓冲区视图的最新高度
+func (n *Network) BufferedHeights() []int64 {
+	heights := make([]int64, len(n.nodes))
+	for idx, simNode := range n.nodes {
+		heights[idx] = simNode.Chain().BufferedHeight()
+	}
+	return heights
+}
+
+// CheckConvergence
+//
+//	@Description: 检查节点的链是否一致。区块通过前一个区块的哈希链接，
+//	因此只需要比较所有节点共同拥有的最高区块，哈希相同时该高度以下的区块都相同
+//	@receiver n
+//	@param indexes - 需要比较的节点序号，为空时比较所有节点
+//	@return int64 - 比较的高度
+//	@return error - 节点的链不一致时返回错误，包含每个节点在该高度的区块哈希
+func (n *Network) CheckConvergence(indexes ...int) (int64, error) {
+	nodes := n.nodes
+	if len(indexes) > 0 {
+		nodes = make([]*SimNode, 0, len(indexes))
+		for _, idx := range indexes {
+			nodes = append(nodes, n.nodes[idx])
+		}
+	}
+
+	heights := make([]int64, len(nodes))
+	height := nodes[0].Chain().Height()
+	for idx, simNode := range nodes {
+		heights[idx] = simNode.Chain().Height()
+		if heights[idx] < height {
+			height = heights[idx]
+		}
+	}
+	if height < 0 {
+		return height, fmt.Errorf("%w: heights %v", errNotConverged, heights)
+	}
+
+	hashes := make([]string, len(nodes))
+	converged := true
+	for idx, simNode := range nodes {
+		block, err := simNode.Chain().GetBlockByHeight(height)
+		if err != nil || block == nil {
+			return height, fmt.Errorf("%w: node %d missing block #%d", errNotConverged, simNode.Index, height)
+		}
+
+		hashes[idx] = hex.EncodeToString(block.Header.BlockHash[:])[:8]
+		if hashes[idx] != hashes[0] {
+			converged = false
+		}
+	}
+
+	if !converged {
+		return height, fmt.Errorf("%w: block hashes at #%d %v", errNotConverged, height, hashes)
+	}
+	return height, nil
+}
@@ -0,0 +1,227 @@
+package simulation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/chain-lab/go-norn/common"
+	"github.com/chain-lab/go-norn/crypto"
+	"github.com/chain-lab/go-norn/utils"
+	"github.com/libp2p/go-libp2p/core/peer"
+	log "github.com/sirupsen/logrus"
+	karmem "karmem.org/golang"
+	"testing"
+	"time"
+)
+
+func testStartNetwork(t *testing.T, config *Config) *Network {
+	log.SetLevel(log.WarnLevel)
+
+	network, err := NewNetwork(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = network.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = network.Stop(ctx)
+	})
+	return network
+}
+
+func testWaitConverged(t *testing.T, network *Network, height int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := network.WaitHeight(ctx, height); err != nil {
+		t.Fatal(err)
+	}
+
+	converged, err := network.CheckConvergence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Chains converged at #%d, heights %v", converged, network.Heights())
+}
+
+func TestNetworkConvergence(t *testing.T) {
+	network := testStartNetwork(t, &Config{Nodes: 4})
+	testWaitConverged(t, network, 3)
+}
+
+func TestNetworkFakeClock(t *testing.T) {
+	network := testStartNetwork(t, &Config{
+		Nodes: 4,
+		Clock: testFakeClock(),
+	})
+	testWaitConverged(t, network, 3)
+}
+
+func TestNetworkFilter(t *testing.T) {
+	network, err := NewNetwork(&Config{Nodes: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Stop(context.Background())
+
+	id := func(idx int) peer.ID {
+		return network.Node(idx).Host.ID()
+	}
+
+	filter := network.filter(0)
+	if !filter(id(1)) || !filter(id(3)) {
+		t.Fatal("messages dropped without faults")
+	}
+
+	network.Partition([]int{0, 1}, []int{2})
+	if !filter(id(1)) || filter(id(2)) || filter(id(3)) {
+		t.Fatal("partition not applied")
+	}
+
+	network.Heal()
+	network.SetDropRate(1)
+	if filter(id(1)) {
+		t.Fatal("drop rate not applied")
+	}
+
+	network.SetDropRate(0)
+	if !filter(id(2)) {
+		t.Fatal("network not healed")
+	}
+}
+
+func testFakeClock() *utils.FakeClock {
+	return utils.NewFakeClock(time.UnixMilli(1700000000000))
+}
+
+// testMaxHeight 所有节点中的最大高度
+func testMaxHeight(heights []int64) int64 {
+	result := heights[0]
+	for _, h := range heights {
+		if h > result {
+			result = h
+		}
+	}
+	return result
+}
+
+func TestNetworkPartition(t *testing.T) {
+	network := testStartNetwork(t, &Config{
+		Nodes: 4,
+		Clock: testFakeClock(),
+	})
+	testWaitConverged(t, network, 1)
+
+	// 区块先进入缓冲区，超过 core.MaxBufferSize 个高度之后才写入数据库，
+	// 分区的起点需要按照缓冲区的高度计算，分区之前已经广播的区块仍然会被少数分区写入数据库
+	network.Partition([]int{0, 1, 2}, []int{3})
+	fork := testMaxHeight(network.BufferedHeights()) + 2
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := network.WaitBufferedHeight(ctx, fork); err != nil {
+		t.Fatal(err)
+	}
+
+	// 分区期间少数分区只能在自己的区块上继续出块，和多数分区的视图分叉
+	majority := network.Node(0).Chain().GetBufferedBlockByHeight(fork)
+	minority := network.Node(3).Chain().GetBufferedBlockByHeight(fork)
+	if majority == nil || minority == nil {
+		t.Fatalf("missing buffered block #%d", fork)
+	}
+	if majority.BlockHash() == minority.BlockHash() ||
+		network.Node(3).Chain().HasBlock(majority.Header.BlockHash) {
+		t.Fatalf("minority received block #%d during partition", fork)
+	}
+
+	// 缓冲区只在父区块相同的区块之间选择，已经处理过的分叉子树不会重新选择。
+	// 多数分区将分叉高度写入数据库之后再恢复网络，少数分区的区块不会再替换多数分区的区块
+	if err := network.WaitHeight(ctx, fork); err != nil {
+		t.Fatal(err)
+	}
+	converged, err := network.CheckConvergence(0, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Majority converged at #%d, heights %v", converged, network.Heights())
+	network.Heal()
+
+	// 恢复后少数分区不一定切换到多数分区的链上，只检查少数分区重新收到多数分区的新区块
+	healed := testMaxHeight(network.BufferedHeights()) + 1
+	err = network.waitUntil(ctx, fakePollInterval, true, func() error {
+		block := network.Node(0).Chain().GetBufferedBlockByHeight(healed)
+		if block == nil || !network.Node(3).Chain().HasBlock(block.Header.BlockHash) {
+			return fmt.Errorf("minority missing block #%d after heal", healed)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testTransaction 构建一笔随机数据的交易
+func testTransaction(t *testing.T, chainID int64) *common.Transaction {
+	prv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := time.Now().UnixMilli()
+	txBody := common.TransactionBody{
+		Data:      []byte("simulation"),
+		Timestamp: timestamp,
+		Expire:    timestamp + 3000,
+	}
+	txBody.Public = [33]byte(crypto.PublicKey2Bytes(&prv.PublicKey))
+	txBody.Address = crypto.PublicKeyBytes2Address(txBody.Public)
+
+	writer := karmem.NewWriter(1024)
+	if _, err = txBody.WriteAsRoot(writer); err != nil {
+		t.Fatal(err)
+	}
+
+	txHash := common.TransactionSigningHash(writer.Bytes(), chainID)
+	signature, err := ecdsa.SignASN1(rand.Reader, prv, txHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txBody.Hash = [32]byte(txHash)
+	txBody.Signature = signature
+	return &common.Transaction{Body: txBody}
+}
+
+func TestNetworkTransactionGossip(t *testing.T) {
+	network := testStartNetwork(t, &Config{Nodes: 4})
+	testWaitConverged(t, network, 1)
+
+	tx := testTransaction(t, network.Node(0).Chain().ChainID())
+	network.Node(0).Manager().AddTransaction(tx)
+
+	// 交易在其它节点的交易池中，或者已经被打包到区块中
+	txHash := hex.EncodeToString(tx.Body.Hash[:])
+	deadline := time.Now().Add(30 * time.Second)
+	for _, simNode := range network.Nodes()[1:] {
+		for {
+			if simNode.TxPool().Contain(txHash) {
+				break
+			}
+			if found, _ := simNode.Chain().GetTransactionByHash(tx.Body.Hash); found != nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("transaction not received by node %d", simNode.Index)
+			}
+			time.Sleep(pollInterval)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// Package utils
+// @Description: 时钟抽象，节点中与时间相关的组件（时间同步、区块打包、缓冲区和区块同步）通过 Clock 获取时间和创建定时器，
+// 运行时使用系统时钟，测试中使用手动推进的 FakeClock，使打包间隔、时间同步确认和请求重试等逻辑可以确定性地测试
+package utils
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 时钟接口
+type Clock interface {
+	Now() time.Time                         // 当前时间
+	Since(t time.Time) time.Duration        // 距离 t 经过的时间
+	NewTicker(d time.Duration) Ticker       // 创建周期为 d 的定时器
+	NewTimer(d time.Duration) Timer         // 创建 d 之后触发一次的定时器
+	After(d time.Duration) <-chan time.Time // d 之后触发的 channel
+}
+
+// Ticker 周期定时器，与 time.Ticker 的行为一致
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
+
+// Timer 单次定时器，与 time.Timer 的行为一致
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// realClock 系统时钟
+type realClock struct{}
+
+// NewRealClock 创建使用系统时间的时钟
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+func (t *realTicker) Reset(d time.Duration) {
+	t.ticker.Reset(d)
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t *realTimer) Reset(d time.Duration) bool {
+	return t.timer.Reset(d)
+}
+
+// FakeClock 手动推进的时钟，时间只在调用 Advance 或者 Set 时变化，到期的定时器在推进时触发
+type FakeClock struct {
+	lock    sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter // 等待触发的定时器
+}
+
+// fakeWaiter FakeClock 上的定时器，period 为 0 时为单次定时器
+type fakeWaiter struct {
+	clock  *FakeClock
+	ch     chan time.Time
+	next   time.Time
+	period time.Duration
+}
+
+// NewFakeClock
+//
+//	@Description: 创建手动推进的时钟
+//	@param now - 时钟的初始时间
+//	@return *FakeClock - 时钟实例
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.lock)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return &fakeTicker{waiter: c.addWaiter(d, d)}
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	return &fakeTimer{waiter: c.addWaiter(d, 0)}
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Advance
+//
+//	@Description: 将时钟推进 d，按照到期时间的顺序触发到期的定时器。与 time.Ticker 相同，
+//	接收方没有及时读取时丢弃多余的触发
+//	@receiver c
+//	@param d - 推进的时长
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	c.setLocked(c.now.Add(d))
+	c.lock.Unlock()
+}
+
+// Set 将时钟设置到时间 t，t 早于当前时间时不触发任何定时器
+func (c *FakeClock) Set(t time.Time) {
+	c.lock.Lock()
+	c.setLocked(t)
+	c.lock.Unlock()
+}
+
+// BlockUntil
+//
+//	@Description: 阻塞直到时钟上至少有 n 个等待中的定时器，用于等待被测协程创建定时器之后再推进时钟
+//	@receiver c
+//	@param n - 定时器数量
+func (c *FakeClock) BlockUntil(n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// Waiters 时钟上等待中的定时器数量
+func (c *FakeClock) Waiters() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.waiters)
+}
+
+func (c *FakeClock) setLocked(t time.Time) {
+	if t.Before(c.now) {
+		c.now = t
+		return
+	}
+
+	// 按照到期时间排序后依次触发，保证多个定时器的触发顺序确定
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		return c.waiters[i].next.Before(c.waiters[j].next)
+	})
+
+	remain := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.next.After(t) {
+			remain = append(remain, w)
+			continue
+		}
+
+		select {
+		case w.ch <- w.next:
+		default:
+		}
+
+		if w.period == 0 {
+			continue
+		}
+
+		// 周期定时器跳过推进期间错过的触发
+		for !w.next.After(t) {
+			w.next = w.next.Add(w.period)
+		}
+		remain = append(remain, w)
+	}
+	c.waiters = remain
+	c.now = t
+}
+
+func (c *FakeClock) addWaiter(d time.Duration, period time.Duration) *fakeWaiter {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	w := &fakeWaiter{
+		clock:  c,
+		ch:     make(chan time.Time, 1),
+		next:   c.now.Add(d),
+		period: period,
+	}
+
+	// 与 time.NewTimer 一致，时长不大于 0 的单次定时器立即触发
+	if period == 0 && d <= 0 {
+		w.ch <- c.now
+		return w
+	}
+
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return w
+}
+
+// remove 从时钟上移除定时器，返回定时器是否仍在等待
+func (c *FakeClock) remove(w *fakeWaiter) bool {
+	for idx, waiter := range c.waiters {
+		if waiter == w {
+			c.waiters = append(c.waiters[:idx], c.waiters[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (w *fakeWaiter) stop() bool {
+	w.clock.lock.Lock()
+	defer w.clock.lock.Unlock()
+
+	return w.clock.remove(w)
+}
+
+func (w *fakeWaiter) reset(d time.Duration) bool {
+	c := w.clock
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	active := c.remove(w)
+	w.next = c.now.Add(d)
+	if w.period != 0 {
+		w.period = d
+	} else if d <= 0 {
+		select {
+		case w.ch <- c.now:
+		default:
+		}
+		return active
+	}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return active
+}
+
+type fakeTicker struct {
+	waiter *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.waiter.stop()
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	t.waiter.reset(d)
+}
+
+type fakeTimer struct {
+	waiter *fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.waiter.stop()
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	return t.waiter.reset(d)
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockTicker(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	clock := NewFakeClock(start)
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired before interval")
+	default:
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case tick := <-ticker.C():
+		if !tick.Equal(start.Add(time.Second)) {
+			t.Fatalf("unexpected tick time %v", tick)
+		}
+	default:
+		t.Fatal("ticker not fired")
+	}
+
+	// 接收方没有读取时，多余的触发被丢弃
+	clock.Advance(3 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatal("missed ticks not dropped")
+	default:
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+	if clock.Since(start) != 5*time.Second {
+		t.Fatalf("unexpected elapsed time %v", clock.Since(start))
+	}
+}
+
+func TestFakeClockTimer(t *testing.T) {
+	clock := NewFakeClock(time.UnixMilli(0))
+	timer := clock.NewTimer(2 * time.Second)
+
+	clock.Advance(time.Second)
+	if !timer.Stop() {
+		t.Fatal("timer should be active")
+	}
+
+	timer.Reset(time.Second)
+	clock.Advance(time.Second)
+	select {
+	case <-timer.C():
+	default:
+		t.Fatal("timer not fired")
+	}
+
+	if timer.Stop() {
+		t.Fatal("fired timer should be inactive")
+	}
+	if clock.Waiters() != 0 {
+		t.Fatalf("unexpected waiters %d", clock.Waiters())
+	}
+}
+
+func TestFakeClockBlockUntil(t *testing.T) {
+	clock := NewFakeClock(time.UnixMilli(0))
+	done := make(chan struct{})
+
+	go func() {
+		<-clock.After(time.Second)
+		close(done)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-done
+}
@@ -25,3 +25,37 @@ func DataAddressKey2DBKey(address, key []byte) []byte {
 	dbKey := fmt.Sprintf("data#%s#%s", hex.EncodeToString(address), string(key))
 	return []byte(dbKey)
 }
+
+func StateNode2DBKey(hash common.Hash) []byte {
+	return append([]byte("state#"), hash[:]...)
+}
+
+func StateValue2DBKey(hash common.Hash) []byte {
+	return append([]byte("statev#"), hash[:]...)
+}
+
+func VDFCheckpointDBKey() []byte {
+	return []byte("vdf#checkpoint")
+}
+
+func GenesisWeightsDBKey() []byte {
+	return []byte("genesis#weights")
+}
+
+func PeerBansDBKey() []byte {
+	return []byte("p2p#bans")
+}
+
+func HeaderHash2DBKey(hash common.Hash) []byte {
+	return append([]byte("header#"), hash[:]...)
+}
+
+func HeaderHeight2DBKey(height int64) []byte {
+	strHeight := strconv.FormatInt(height, 10)
+
+	return append([]byte("header#"), []byte(strHeight)...)
+}
+
+func LatestHeaderDBKey() []byte {
+	return []byte("header#latest")
+}
@@ -1,11 +1,15 @@
 package utils
 
 import (
+	"encoding/binary"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/p2p"
+	"github.com/syndtr/goleveldb/leveldb/errors"
 	karmem "karmem.org/golang"
 )
 
+var errMalformedList = errors.New("malformed length prefixed list")
+
 // DeserializeBlock
 //
 //	@Description: 区块反序列化函数，将 bytes 数据反序列化为 karmem 的 block
@@ -32,6 +36,14 @@ func DeserializeTransaction(byteTxData []byte) (*common.Transaction, error) {
 	return transaction, nil
 }
 
+// DeserializeBlockHeader 区块头的反序列化函数
+func DeserializeBlockHeader(byteHeaderData []byte) (*common.BlockHeader, error) {
+	header := new(common.BlockHeader)
+	header.ReadAsRoot(karmem.NewReader(byteHeaderData))
+
+	return header, nil
+}
+
 func DeserializeBroadcastMessage(byteTxData []byte) (*p2p.BroadcastMessage, error) {
 	transaction := new(p2p.BroadcastMessage)
 	transaction.ReadAsRoot(karmem.NewReader(byteTxData))
@@ -46,6 +58,13 @@ func DeserializeStatusMsg(byteMsgData []byte) (*p2p.SyncStatusMsg, error) {
 	return msg, nil
 }
 
+func DeserializeHandshakeMsg(byteMsgData []byte) (*p2p.HandshakeMsg, error) {
+	msg := new(p2p.HandshakeMsg)
+	msg.ReadAsRoot(karmem.NewReader(byteMsgData))
+
+	return msg, nil
+}
+
 func DeserializeGeneralParams(byteParamsData []byte) (*common.GeneralParams, error) {
 	generalParams := new(common.GeneralParams)
 	generalParams.ReadAsRoot(karmem.NewReader(byteParamsData))
@@ -73,3 +92,116 @@ func DeserializeDataCommand(byteDataCommand []byte) (*common.DataCommand, error)
 
 	return dc, nil
 }
+
+func DeserializeVDFCheckpoint(byteCheckpoint []byte) (*common.VDFCheckpoint, error) {
+	cp := new(common.VDFCheckpoint)
+	cp.ReadAsRoot(karmem.NewReader(byteCheckpoint))
+
+	return cp, nil
+}
+
+func DeserializeSyncBlockRangeReq(byteReq []byte) (*p2p.SyncBlockRangeReq, error) {
+	req := new(p2p.SyncBlockRangeReq)
+	req.ReadAsRoot(karmem.NewReader(byteReq))
+
+	return req, nil
+}
+
+func DeserializeTxProofMsg(byteMsg []byte) (*p2p.TxProofMsg, error) {
+	msg := new(p2p.TxProofMsg)
+	msg.ReadAsRoot(karmem.NewReader(byteMsg))
+
+	return msg, nil
+}
+
+func DeserializeDataProofReq(byteReq []byte) (*p2p.DataProofReq, error) {
+	req := new(p2p.DataProofReq)
+	req.ReadAsRoot(karmem.NewReader(byteReq))
+
+	return req, nil
+}
+
+func DeserializeDataProofMsg(byteMsg []byte) (*p2p.DataProofMsg, error) {
+	msg := new(p2p.DataProofMsg)
+	msg.ReadAsRoot(karmem.NewReader(byteMsg))
+
+	return msg, nil
+}
+
+// DeserializeBlocks
+//
+//	@Description: 反序列化由 SerializeBlocks 生成的数据包
+//	@param byteBlocksData - 数据包
+//	@return []*common.Block - 数据包中的区块
+//	@return error - 长度前缀不正确时返回 errMalformedList
+func DeserializeBlocks(byteBlocksData []byte) ([]*common.Block, error) {
+	blocks := make([]*common.Block, 0)
+
+	err := deserializeList(byteBlocksData, func(data []byte) error {
+		block, err := DeserializeBlock(data)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, block)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// DeserializeBlockHeaders 反序列化由 SerializeBlockHeaders 生成的数据包
+func DeserializeBlockHeaders(byteHeadersData []byte) ([]*common.BlockHeader, error) {
+	headers := make([]*common.BlockHeader, 0)
+
+	err := deserializeList(byteHeadersData, func(data []byte) error {
+		header := new(common.BlockHeader)
+		header.ReadAsRoot(karmem.NewReader(data))
+		headers = append(headers, header)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return headers, nil
+}
+
+// DeserializeTransactions 反序列化由 SerializeTransactions 生成的数据包
+func DeserializeTransactions(byteTxsData []byte) ([]*common.Transaction, error) {
+	txs := make([]*common.Transaction, 0)
+
+	err := deserializeList(byteTxsData, func(data []byte) error {
+		tx, err := DeserializeTransaction(data)
+		if err != nil {
+			return err
+		}
+		txs = append(txs, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
+
+// deserializeList 依次取出带有 uvarint 长度前缀的元素，交给 deserialize 处理
+func deserializeList(data []byte, deserialize func(data []byte) error) error {
+	for len(data) > 0 {
+		size, n := binary.Uvarint(data)
+		if n <= 0 || size == 0 || size > uint64(len(data)-n) {
+			return errMalformedList
+		}
+		data = data[n:]
+
+		if err := deserialize(data[:size]); err != nil {
+			return err
+		}
+		data = data[size:]
+	}
+
+	return nil
+}
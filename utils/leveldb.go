@@ -4,6 +4,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/storage"
 )
 
 type LevelDB struct {
@@ -21,6 +22,18 @@ func NewLevelDB(path string) (*LevelDB, error) {
 	return &LevelDB{db: db}, nil
 }
 
+// NewMemoryLevelDB 创建数据只保存在内存中的数据库，用于模拟测试
+func NewMemoryLevelDB() (*LevelDB, error) {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+
+	if err != nil {
+		log.WithField("error", err).Errorln("Open memory leveldb failed.")
+		return nil, err
+	}
+
+	return &LevelDB{db: db}, nil
+}
+
 func (ld *LevelDB) Get(key []byte) ([]byte, error) {
 	return ld.db.Get(key, nil)
 }
@@ -55,3 +68,7 @@ func (ld *LevelDB) BatchDelete(key [][]byte) error {
 
 	return ld.db.Write(batch, nil)
 }
+
+func (ld *LevelDB) Close() error {
+	return ld.db.Close()
+}
@@ -0,0 +1,117 @@
+// Package utils
+// @Description: 子系统的生命周期管理，按照注册顺序启动，按照相反的顺序停止，
+// 因此需要先注册被依赖的子系统，例如数据库相关的子系统需要先于网络注册
+package utils
+
+import (
+	"context"
+	"github.com/chain-lab/go-norn/interfaces"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+type namedService struct {
+	name    string
+	service interfaces.Service
+}
+
+type Lifecycle struct {
+	services []namedService // 按照注册顺序排列的子系统
+	started  int            // 已经启动的子系统数量
+	lock     sync.Mutex
+}
+
+// NewLifecycle
+//
+//	@Description: 创建生命周期管理实例
+//	@return *Lifecycle
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{
+		services: make([]namedService, 0),
+	}
+}
+
+// Register
+//
+//	@Description: 注册子系统，子系统会在 Start 时按照注册顺序启动
+//	@receiver l
+//	@param name - 子系统名称，用于日志
+//	@param service - 子系统实例
+func (l *Lifecycle) Register(name string, service interfaces.Service) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.services = append(l.services, namedService{name: name, service: service})
+}
+
+// Start
+//
+//	@Description: 启动所有尚未启动的子系统，某个子系统启动失败时停止已经启动的子系统
+//	@receiver l
+//	@param ctx - 子系统协程的父 context
+//	@return error - 启动失败的子系统返回的错误
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for ; l.started < len(l.services); l.started++ {
+		s := l.services[l.started]
+		if err := s.service.Start(ctx); err != nil {
+			log.WithError(err).Errorf("Start %s failed.", s.name)
+			l.stop(ctx)
+			return err
+		}
+		log.Debugf("Service %s started.", s.name)
+	}
+
+	return nil
+}
+
+// Stop
+//
+//	@Description: 按照与启动相反的顺序停止所有已经启动的子系统
+//	@receiver l
+//	@param ctx - 停止的超时 context，超时后不再等待子系统的协程退出
+//	@return error - 最后一个停止失败的子系统返回的错误
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.stop(ctx)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var result error
+	for ; l.started > 0; l.started-- {
+		s := l.services[l.started-1]
+		if err := s.service.Stop(ctx); err != nil {
+			log.WithError(err).Warningf("Stop %s failed.", s.name)
+			result = err
+			continue
+		}
+		log.Infof("Service %s stopped.", s.name)
+	}
+
+	return result
+}
+
+// WaitGroupContext
+//
+//	@Description: 等待 WaitGroup 中的协程全部退出，或者 ctx 超时
+//	@param ctx - 等待的超时 context
+//	@param wg - 需要等待的 WaitGroup
+//	@return error - 超时时返回 ctx 的错误
+func WaitGroupContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"context"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"reflect"
+	"testing"
+)
+
+type recordService struct {
+	name     string
+	records  *[]string
+	startErr error
+}
+
+func (s *recordService) Start(ctx context.Context) error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+	*s.records = append(*s.records, "start "+s.name)
+	return nil
+}
+
+func (s *recordService) Stop(ctx context.Context) error {
+	*s.records = append(*s.records, "stop "+s.name)
+	return nil
+}
+
+func TestLifecycleOrder(t *testing.T) {
+	records := make([]string, 0)
+	lifecycle := NewLifecycle()
+	lifecycle.Register("chain", &recordService{name: "chain", records: &records})
+	lifecycle.Register("p2p", &recordService{name: "p2p", records: &records})
+
+	if err := lifecycle.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := lifecycle.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"start chain", "start p2p", "stop p2p", "stop chain"}
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatalf("unexpected order: %v", records)
+	}
+}
+
+func TestLifecycleStartFailed(t *testing.T) {
+	records := make([]string, 0)
+	lifecycle := NewLifecycle()
+	lifecycle.Register("chain", &recordService{name: "chain", records: &records})
+	lifecycle.Register("p2p", &recordService{name: "p2p", records: &records,
+		startErr: errors.New("listen failed")})
+
+	if err := lifecycle.Start(context.Background()); err == nil {
+		t.Fatal("expected start error")
+	}
+
+	// 启动失败时已经启动的子系统需要被停止
+	expected := []string{"start chain", "stop chain"}
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatalf("unexpected order: %v", records)
+	}
+}
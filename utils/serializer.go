@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/binary"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/p2p"
 	log "github.com/sirupsen/logrus"
@@ -85,6 +86,20 @@ func SerializeStatusMsg(msg *p2p.SyncStatusMsg) ([]byte, error) {
 	return result, nil
 }
 
+func SerializeHandshakeMsg(msg *p2p.HandshakeMsg) ([]byte, error) {
+	writer := karmem.NewWriter(KARMEM_CAP)
+
+	_, err := msg.WriteAsRoot(writer)
+	if err != nil {
+		log.WithField("error", err).Debugln("Message serialize failed.")
+		return nil, err
+	}
+
+	result := writer.Bytes()
+
+	return result, nil
+}
+
 func SerializeGenesisParams(p *common.GenesisParams) ([]byte, error) {
 	writer := karmem.NewWriter(KARMEM_CAP)
 
@@ -149,3 +164,130 @@ func SerializeBroadcastMessage(msg *p2p.BroadcastMessage) ([]byte, error) {
 	result := writer.Bytes()
 	return result, err
 }
+
+func SerializeVDFCheckpoint(cp *common.VDFCheckpoint) ([]byte, error) {
+	writer := karmem.NewWriter(KARMEM_CAP)
+
+	_, err := cp.WriteAsRoot(writer)
+	if err != nil {
+		log.WithError(err).Debugln("VDF checkpoint serialize failed.")
+		return nil, err
+	}
+
+	result := writer.Bytes()
+	return result, err
+}
+
+func SerializeSyncBlockRangeReq(req *p2p.SyncBlockRangeReq) ([]byte, error) {
+	writer := karmem.NewWriter(KARMEM_CAP)
+
+	_, err := req.WriteAsRoot(writer)
+	if err != nil {
+		log.WithError(err).Debugln("Block range request serialize failed.")
+		return nil, err
+	}
+
+	result := writer.Bytes()
+	return result, err
+}
+
+func SerializeTxProofMsg(msg *p2p.TxProofMsg) ([]byte, error) {
+	writer := karmem.NewWriter(KARMEM_CAP)
+
+	_, err := msg.WriteAsRoot(writer)
+	if err != nil {
+		log.WithError(err).Debugln("Transaction proof serialize failed.")
+		return nil, err
+	}
+
+	result := writer.Bytes()
+	return result, err
+}
+
+func SerializeDataProofReq(req *p2p.DataProofReq) ([]byte, error) {
+	writer := karmem.NewWriter(KARMEM_CAP)
+
+	_, err := req.WriteAsRoot(writer)
+	if err != nil {
+		log.WithError(err).Debugln("Data proof request serialize failed.")
+		return nil, err
+	}
+
+	result := writer.Bytes()
+	return result, err
+}
+
+func SerializeDataProofMsg(msg *p2p.DataProofMsg) ([]byte, error) {
+	writer := karmem.NewWriter(KARMEM_CAP)
+
+	_, err := msg.WriteAsRoot(writer)
+	if err != nil {
+		log.WithError(err).Debugln("Data proof serialize failed.")
+		return nil, err
+	}
+
+	result := writer.Bytes()
+	return result, err
+}
+
+// SerializeBlocks
+//
+//	@Description: 将多个区块序列化为一个数据包，每个区块前面带有 uvarint 编码的长度；
+//	加入下一个区块会超过 limit 字节时停止，至少包含一个区块
+//	@param blocks - 按高度排列的区块
+//	@param limit - 数据包的最大长度
+//	@return []byte - 序列化后的数据
+//	@return int - 实际写入的区块数量
+//	@return error - 区块序列化失败时返回错误
+func SerializeBlocks(blocks []*common.Block, limit int) ([]byte, int, error) {
+	return serializeList(len(blocks), limit, func(idx int) ([]byte, error) {
+		return SerializeBlock(blocks[idx])
+	})
+}
+
+// SerializeBlockHeaders 与 SerializeBlocks 相同，用于区块头列表
+func SerializeBlockHeaders(headers []*common.BlockHeader, limit int) ([]byte, int, error) {
+	return serializeList(len(headers), limit, func(idx int) ([]byte, error) {
+		return SerializeBlockHeader(headers[idx])
+	})
+}
+
+// SerializeTransactions 与 SerializeBlocks 相同，用于交易广播的交易列表
+func SerializeTransactions(txs []*common.Transaction, limit int) ([]byte, int, error) {
+	return serializeList(len(txs), limit, func(idx int) ([]byte, error) {
+		return SerializeTransaction(txs[idx])
+	})
+}
+
+// serializeList
+//
+//	@Description: 依次序列化 n 个元素并加上 uvarint 编码的长度前缀，加入下一个元素会超过 limit 字节时停止
+//	@param n - 元素数量
+//	@param limit - 数据包的最大长度
+//	@param serialize - 序列化第 idx 个元素
+//	@return []byte - 序列化后的数据
+//	@return int - 实际写入的元素数量
+//	@return error - 序列化失败时返回错误
+func serializeList(n int, limit int, serialize func(idx int) ([]byte, error)) ([]byte, int, error) {
+	result := make([]byte, 0, KARMEM_CAP)
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+
+	count := 0
+	for idx := 0; idx < n; idx++ {
+		data, err := serialize(idx)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		l := binary.PutUvarint(lenBuf, uint64(len(data)))
+		if count > 0 && len(result)+l+len(data) > limit {
+			break
+		}
+
+		result = append(result, lenBuf[:l]...)
+		result = append(result, data...)
+		count++
+	}
+
+	return result, count, nil
+}
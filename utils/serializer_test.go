@@ -4,7 +4,6 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/sha256"
 	"github.com/chain-lab/go-norn/common"
 	"github.com/chain-lab/go-norn/crypto"
 	log "github.com/sirupsen/logrus"
@@ -13,7 +12,7 @@ import (
 	"time"
 )
 
-func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
+func buildTransaction(key *ecdsa.PrivateKey, chainID int64) *common.Transaction {
 	data := make([]byte, 32)
 	rand.Read(data)
 	timestamp := time.Now().UnixMilli()
@@ -33,9 +32,7 @@ func buildTransaction(key *ecdsa.PrivateKey) *common.Transaction {
 	txBody.WriteAsRoot(writer)
 	txBodyBytes := writer.Bytes()
 
-	hash := sha256.New()
-	hash.Write(txBodyBytes)
-	txHashBytes := hash.Sum(nil)
+	txHashBytes := common.TransactionSigningHash(txBodyBytes, chainID)
 	txSignatureBytes, err := ecdsa.SignASN1(rand.Reader, key, txHashBytes)
 
 	if err != nil {
@@ -59,7 +56,7 @@ func TestSerializeTransaction(t *testing.T) {
 		t.Fatal("Generate keypair failed.")
 	}
 
-	tx := buildTransaction(prv)
+	tx := buildTransaction(prv, 0)
 
 	txByteData, err := SerializeTransaction(tx)
 	if err != nil {
@@ -71,7 +68,102 @@ func TestSerializeTransaction(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if !txCopyed.Verify() {
+	if !txCopyed.Verify(0) {
 		t.Fatal("Verify transaction failed.")
 	}
 }
+
+func TestTransactionChainID(t *testing.T) {
+	prv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("Generate keypair failed.")
+	}
+
+	// 在链 ID 为 7 的网络上签名
+	tx := buildTransaction(prv, 7)
+	if !tx.Verify(7) {
+		t.Fatal("Verify transaction failed.")
+	}
+
+	// 其他网络上验证失败
+	if tx.Verify(8) {
+		t.Fatal("Transaction signed for another chain passed verification.")
+	}
+
+	if tx.Verify(0) {
+		t.Fatal("Transaction signed for another chain passed verification.")
+	}
+}
+
+func TestSerializeBlocks(t *testing.T) {
+	blocks := make([]*common.Block, 0, 5)
+	for height := int64(10); height < 15; height++ {
+		blocks = append(blocks, &common.Block{Header: common.BlockHeader{Height: height}})
+	}
+
+	data, count, err := SerializeBlocks(blocks, 1<<20)
+	if err != nil || count != len(blocks) {
+		t.Fatalf("serialize blocks failed, count %d, error %v", count, err)
+	}
+
+	result, err := DeserializeBlocks(data)
+	if err != nil || len(result) != len(blocks) {
+		t.Fatalf("deserialize blocks failed, count %d, error %v", len(result), err)
+	}
+	for idx, block := range result {
+		if block.Header.Height != blocks[idx].Header.Height {
+			t.Fatalf("unexpected block #%d at %d", block.Header.Height, idx)
+		}
+	}
+
+	// 超出长度限制时截断，但至少包含一个区块
+	if _, count, _ = SerializeBlocks(blocks, 1); count != 1 {
+		t.Fatalf("unexpected truncated count %d", count)
+	}
+
+	// 长度前缀超出数据范围
+	if _, err = DeserializeBlocks(data[:len(data)-1]); err == nil {
+		t.Fatal("truncated block list accepted")
+	}
+
+	headers := make([]*common.BlockHeader, 0, len(blocks))
+	for _, block := range blocks {
+		headers = append(headers, &block.Header)
+	}
+	data, count, err = SerializeBlockHeaders(headers, 1<<20)
+	if err != nil || count != len(headers) {
+		t.Fatalf("serialize headers failed, count %d, error %v", count, err)
+	}
+
+	resultHeaders, err := DeserializeBlockHeaders(data)
+	if err != nil || len(resultHeaders) != len(headers) || resultHeaders[4].Height != 14 {
+		t.Fatalf("deserialize headers failed, count %d, error %v", len(resultHeaders), err)
+	}
+}
+
+func TestSerializeTransactions(t *testing.T) {
+	prv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("Generate keypair failed.")
+	}
+
+	txs := make([]*common.Transaction, 0, 3)
+	for i := 0; i < 3; i++ {
+		txs = append(txs, buildTransaction(prv, 0))
+	}
+
+	data, count, err := SerializeTransactions(txs, 1<<20)
+	if err != nil || count != len(txs) {
+		t.Fatalf("serialize transactions failed, count %d, error %v", count, err)
+	}
+
+	result, err := DeserializeTransactions(data)
+	if err != nil || len(result) != len(txs) {
+		t.Fatalf("deserialize transactions failed, count %d, error %v", len(result), err)
+	}
+	for idx, tx := range result {
+		if tx.Body.Hash != txs[idx].Body.Hash || !tx.Verify(0) {
+			t.Fatalf("unexpected transaction at %d", idx)
+		}
+	}
+}